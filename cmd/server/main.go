@@ -5,26 +5,66 @@ import (
 	"concert/internal/database"
 	httpTransport "concert/internal/http"
 	"concert/internal/utils"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func Run() error {
 	log.Println("Starting the server")
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	db, err := database.DbSetup()
 	if err != nil {
 		return fmt.Errorf("failed to setup database: %w", err)
 	}
 
+	// after DbSetup, which loads the .env file
+	if err := utils.CheckSigningSecret(); err != nil {
+		return err
+	}
+
 	if err := database.Migrate(db); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	if _, err := concert.LinkShowVenues(db); err != nil {
+		return fmt.Errorf("failed to link the shows to their venue: %w", err)
+	}
+
+	if _, err := concert.MigrateShowTimes(db); err != nil {
+		return fmt.Errorf("failed to migrate the show times: %w", err)
+	}
+
+	if n, err := concert.BackfillLedger(db); err != nil {
+		return fmt.Errorf("failed to backfill the ledger: %w", err)
+	} else if n > 0 {
+		log.Printf("Recorded %d past money movements in the ledger", n)
+	}
+
+	if err := concert.SetupSearch(db); err != nil {
+		return fmt.Errorf("failed to setup the search index: %w", err)
+	}
+
 	log.Println("Database connected and migrated successfully")
 
-	concertService := concert.NewConcert(db)
+	concertService, err := concert.NewConcertFromEnv(db)
+	if err != nil {
+		return fmt.Errorf("failed to setup the payments: %w", err)
+	}
+	go concertService.RunWebhookWorker(ctx, 5*time.Second)
+	go concertService.RunQueueAdmitter(ctx, 5*time.Second)
+	go concertService.RunPaymentReaper(ctx, time.Minute)
+	go concertService.RunResalePayouts(ctx, time.Minute)
+
 	handler, err := httpTransport.NewRouter(concertService, db)
 	if err != nil {
 		return fmt.Errorf("could not initialize the handler: %w", err)
@@ -34,10 +74,29 @@ func Run() error {
 
 	port := utils.GetEnvOrDefault("PORT", "8080")
 	addr := ":" + port
+	srv := &http.Server{Addr: addr, Handler: handler.Route}
+	// event streams never go idle on their own, close them when shutdown starts
+	srv.RegisterOnShutdown(handler.Hub.Close)
+
+	// closed once the requests in flight are done, ListenAndServe returns as soon as the
+	// shutdown starts
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		log.Println("Shutting down the server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error during shutdown: %v", err)
+		}
+	}()
+
 	log.Printf("Server starting on %s", addr)
-	if err := http.ListenAndServe(addr, handler.Route); err != nil {
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
+	<-done
 	return nil
 }
 
@@ -13,8 +13,10 @@ interface ConcertCardProps {
 const ConcertCard: React.FC<ConcertCardProps> = ({ concert }) => {
   const navigate = useNavigate();
 
+  // show times read in the timezone of the venue
   const formatDate = (dateString: string) => {
     return new Date(dateString).toLocaleDateString('en-US', { 
+      timeZone: concert.timezone || undefined,
       weekday: 'short',
       year: 'numeric', 
       month: 'short', 
@@ -24,6 +26,7 @@ const ConcertCard: React.FC<ConcertCardProps> = ({ concert }) => {
 
   const formatTime = (dateString: string) => {
     return new Date(dateString).toLocaleTimeString('en-US', { 
+      timeZone: concert.timezone || undefined,
       hour: '2-digit', 
       minute: '2-digit',
       hour12: true
@@ -90,7 +93,7 @@ const ConcertCard: React.FC<ConcertCardProps> = ({ concert }) => {
           <div className="flex items-center gap-2">
             <CalendarOutlined className="text-orange-500" />
             <Text type="secondary">
-              {formatDate(concert.date)} • {formatTime(concert.date)}
+              {formatDate(concert.startsAt)} • {formatTime(concert.startsAt)}
             </Text>
           </div>
         </div>
@@ -60,8 +60,9 @@ const AdminShows: React.FC = () => {
     setEditingShow(show);
     form.setFieldsValue({
       title: show.title,
-      date: show.date ? new Date(show.date).toISOString().split('T')[0] : '',
-      time: show.time || '20:00',
+      // the form edits the date and time in the timezone of the venue
+      date: show.local?.startsAt.slice(0, 10) ?? '',
+      time: show.local?.startsAt.slice(11, 16) ?? '20:00',
       artistId: show.artist?.ID || 0,
       venue: show.venue,
       price: show.price,
@@ -157,9 +158,10 @@ const AdminShows: React.FC = () => {
     },
     {
       title: 'Date',
-      dataIndex: 'date',
-      key: 'date',
-      render: (date: string) => new Date(date).toLocaleDateString('en-US', {
+      dataIndex: 'startsAt',
+      key: 'startsAt',
+      render: (startsAt: string, record: Concert) => new Date(startsAt).toLocaleDateString('en-US', {
+        timeZone: record.timezone || undefined,
         year: 'numeric',
         month: 'short',
         day: 'numeric'
@@ -164,7 +164,8 @@ const ArtistDetailPage: React.FC = () => {
                             </p>
                             <p className="text-gray-600 text-sm">
                               <CalendarOutlined className="mr-2" />
-                              {new Date(show.date).toLocaleDateString('en-US', {
+                              {new Date(show.startsAt).toLocaleDateString('en-US', {
+                                timeZone: show.timezone || undefined,
                                 weekday: 'long',
                                 year: 'numeric',
                                 month: 'long',
@@ -35,8 +35,10 @@ const ConcertDetailPage: React.FC = () => {
     }
   }, [id]);
 
-  const formatDateTimestamp = (dateString: string): string => {
+  // show times read in the timezone of the venue
+  const formatDateTimestamp = (dateString: string, timeZone?: string): string => {
     return new Date(dateString).toLocaleString('en-US', {
+      timeZone: timeZone || undefined,
       year: 'numeric',
       month: 'long',
       day: 'numeric',
@@ -140,7 +142,7 @@ const ConcertDetailPage: React.FC = () => {
               <Card.Meta
                 avatar={<CalendarOutlined className="text-2xl text-blue-600" />}
                 title={<span className="text-sm text-gray-600">Date & Time</span>}
-                description={<span className="text-lg font-bold text-gray-800">{formatDateTimestamp(concert.date)}</span>}
+                description={<span className="text-lg font-bold text-gray-800">{formatDateTimestamp(concert.startsAt, concert.timezone)}</span>}
               />
 
               <Card.Meta
@@ -175,7 +175,8 @@ const CreateBookingPage: React.FC = () => {
               <Col xs={24} sm={12} md={6}>
                 <Statistic
                   title="Date & Time"
-                  value={new Date(concert.date).toLocaleString('en-US', {
+                  value={new Date(concert.startsAt).toLocaleString('en-US', {
+                    timeZone: concert.timezone || undefined,
                     month: 'short',
                     day: 'numeric',
                     hour: '2-digit',
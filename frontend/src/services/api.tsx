@@ -1,4 +1,4 @@
-import type { Concert, Artist, User, Booking } from "../types";
+import type { Concert, Artist, User, Booking, ShowPage } from "../types";
 
 const API_BASE_URL = "/api";
 
@@ -57,8 +57,24 @@ async function fetchAPI<T>(
 }
 
 export const concertAPI = {
+  getPage: async (cursor?: string, limit = 100): Promise<ShowPage> => {
+    const params = new URLSearchParams({ limit: String(limit) });
+    if (cursor) {
+      params.set("cursor", cursor);
+    }
+    return fetchAPI<ShowPage>(`/public/shows?${params}`);
+  },
+
+  // every listed show, following the pages of the listing
   getAll: async (): Promise<Concert[]> => {
-    return fetchAPI<Concert[]>("/public/shows");
+    const concerts: Concert[] = [];
+    let cursor: string | undefined;
+    do {
+      const page = await concertAPI.getPage(cursor);
+      concerts.push(...page.items);
+      cursor = page.nextCursor;
+    } while (cursor);
+    return concerts;
   },
 
   getById: async (id: number): Promise<Concert> => {
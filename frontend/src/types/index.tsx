@@ -3,8 +3,11 @@ export interface Concert {
   title: string;
   artist: Artist;
   venue: string;
-  date: string;
-  time: string;
+  // startsAt is the UTC instant of the show, played in the IANA timezone of its venue;
+  // local has the times in that zone
+  startsAt: string;
+  timezone: string;
+  local?: ShowTimes;
   price: number;
   totalSeats: number;
   availableSeats: number;
@@ -12,6 +15,20 @@ export interface Concert {
   imageUrl?: string;
 }
 
+export interface ShowTimes {
+  startsAt: string;
+  doorsAt?: string;
+  endsAt?: string;
+}
+
+// ShowPage is a page of the public show listing, nextCursor is set when more shows follow
+export interface ShowPage {
+  items: Concert[];
+  total: number;
+  limit: number;
+  nextCursor?: string;
+}
+
 export interface User {
   ID: number;
   email: string;
@@ -0,0 +1,296 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ManifestValidity is how long door devices may trust a downloaded manifest.
+const ManifestValidity = 24 * time.Hour
+
+type ManifestTicket struct {
+	Code        string     `json:"code"`
+	TicketID    uint       `json:"ticketId"`
+	BookingID   uint       `json:"bookingId"`
+	Seq         int        `json:"seq"`
+	Status      string     `json:"status"`
+	CheckedInAt *time.Time `json:"checkedInAt,omitempty"`
+	Gate        string     `json:"gate,omitempty"`
+}
+
+type CheckInManifest struct {
+	ShowID      uint             `json:"showId"`
+	ShowTitle   string           `json:"showTitle"`
+	GeneratedAt time.Time        `json:"generatedAt"`
+	ValidUntil  time.Time        `json:"validUntil"`
+	Tickets     []ManifestTicket `json:"tickets"`
+}
+
+// SignedManifest carries the manifest as the exact bytes that were signed, scanners verify
+// Signature over Manifest with the key of ManifestPublicKey. A key coming with the manifest
+// would prove nothing, devices get it beforehand and keep it.
+type SignedManifest struct {
+	Manifest  json.RawMessage `json:"manifest"`
+	Signature string          `json:"signature"`
+	Algorithm string          `json:"algorithm"`
+}
+
+// ManifestKey is the public key checking the signature of the manifests.
+type ManifestKey struct {
+	PublicKey string `json:"publicKey"`
+	Algorithm string `json:"algorithm"`
+}
+
+// OfflineScan is a scan recorded by a door device while offline.
+type OfflineScan struct {
+	Code      string    `json:"code"`
+	Gate      string    `json:"gate"`
+	ScannedAt time.Time `json:"scannedAt"`
+}
+
+type ScanResult struct {
+	Code             string     `json:"code"`
+	Gate             string     `json:"gate"`
+	ScannedAt        time.Time  `json:"scannedAt"`
+	Result           string     `json:"result"`
+	Detail           string     `json:"detail,omitempty"`
+	TicketID         *uint      `json:"ticketId,omitempty"`
+	FirstGate        string     `json:"firstGate,omitempty"`
+	FirstCheckedInAt *time.Time `json:"firstCheckedInAt,omitempty"`
+}
+
+// GetCheckInManifest lists the codes admissible at the doors of a show, issuing the
+// tickets of confirmed bookings that don't have them yet.
+func (s Service) GetCheckInManifest(showID uint) (SignedManifest, error) {
+	show, err := s.GetShowByID(showID)
+	if err != nil {
+		return SignedManifest{}, err
+	}
+
+	var bookings []models.Booking
+	query := s.Db.Where("show_id = ?", showID)
+	if show.FestivalID != nil {
+		// the passes of the festival admit to this day too
+		query = s.Db.Where("show_id = ? OR festival_id = ?", showID, *show.FestivalID)
+	}
+	if err := query.Where("status = ?", "confirmed").Find(&bookings).Error; err != nil {
+		return SignedManifest{}, err
+	}
+	for _, booking := range bookings {
+		if _, err := s.IssueTickets(booking); err != nil {
+			return SignedManifest{}, err
+		}
+	}
+
+	var tickets []models.Ticket
+	if err := s.Db.Joins("Booking").
+		Where("tickets.show_id = ? AND tickets.status <> ? AND \"Booking\".status = ?", showID, models.TicketVoid, "confirmed").
+		Order("tickets.booking_id, tickets.seq").
+		Find(&tickets).Error; err != nil {
+		return SignedManifest{}, err
+	}
+
+	now := time.Now().UTC()
+	manifest := CheckInManifest{
+		ShowID:      show.ID,
+		ShowTitle:   show.Title,
+		GeneratedAt: now,
+		ValidUntil:  now.Add(ManifestValidity),
+		Tickets:     make([]ManifestTicket, len(tickets)),
+	}
+	for i, ticket := range tickets {
+		manifest.Tickets[i] = ManifestTicket{
+			Code:        ticket.Code,
+			TicketID:    ticket.ID,
+			BookingID:   ticket.BookingID,
+			Seq:         ticket.Seq,
+			Status:      ticket.Status,
+			CheckedInAt: ticket.CheckedInAt,
+			Gate:        ticket.Gate,
+		}
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return SignedManifest{}, err
+	}
+	return SignedManifest{
+		Manifest:  data,
+		Signature: utils.SignDocument(data),
+		Algorithm: "ed25519",
+	}, nil
+}
+
+// ManifestPublicKey returns the key door devices check the manifests with.
+func ManifestPublicKey() ManifestKey {
+	return ManifestKey{PublicKey: utils.DocumentPublicKey(), Algorithm: "ed25519"}
+}
+
+// SyncScans reconciles a batch of offline scans of a show in the order they happened.
+// Valid tickets are checked in, a ticket seen again at the same gate is a duplicate,
+// at another gate a double entry, and an admitted ticket that had been invalidated is a conflict.
+// A scan older than the admission already known admits the ticket, the later one is the repeat.
+// Uploading the same batch twice is harmless, already synced scans are reported as duplicates.
+func (s Service) SyncScans(showID, staffID uint, deviceID string, scans []OfflineScan) ([]ScanResult, error) {
+	for i := range scans {
+		// the database keeps microseconds, a scan uploaded again must compare equal to the stored one
+		scans[i].ScannedAt = scans[i].ScannedAt.UTC().Truncate(time.Microsecond)
+	}
+	sort.SliceStable(scans, func(i, j int) bool { return scans[i].ScannedAt.Before(scans[j].ScannedAt) })
+
+	results := make([]ScanResult, 0, len(scans))
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		for _, scan := range scans {
+			result, err := reconcileScan(tx, showID, deviceID, scan)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+
+			if result.Detail == alreadySynced {
+				continue
+			}
+			event := models.ScanEvent{
+				ShowID:    showID,
+				TicketID:  result.TicketID,
+				Code:      result.Code,
+				Gate:      scan.Gate,
+				DeviceID:  deviceID,
+				ScannedAt: scan.ScannedAt,
+				ScannedBy: staffID,
+				Result:    result.Result,
+				Detail:    result.Detail,
+			}
+			if err := tx.Create(&event).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+const alreadySynced = "scan already synced"
+
+func reconcileScan(tx *gorm.DB, showID uint, deviceID string, scan OfflineScan) (ScanResult, error) {
+	code := strings.TrimSpace(scan.Code)
+	result := ScanResult{Code: code, Gate: scan.Gate, ScannedAt: scan.ScannedAt}
+
+	var synced int64
+	if err := tx.Model(&models.ScanEvent{}).
+		Where("code = ? AND device_id = ? AND scanned_at = ?", code, deviceID, scan.ScannedAt).
+		Count(&synced).Error; err != nil {
+		return result, err
+	}
+	if synced > 0 {
+		result.Result, result.Detail = models.ScanDuplicate, alreadySynced
+		return result, nil
+	}
+
+	if verifyTicketCode(code) != nil {
+		result.Result, result.Detail = models.ScanInvalid, ErrTicketInvalid.Error()
+		return result, nil
+	}
+
+	var ticket models.Ticket
+	err := tx.Preload("Booking").Where("code = ?", code).First(&ticket).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && ticket.ShowID != showID) {
+		result.Result, result.Detail = models.ScanInvalid, ErrTicketWrongShow.Error()
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	result.TicketID = &ticket.ID
+
+	switch {
+	case ticket.Booking.Status == "cancelled":
+		result.Result, result.Detail = models.ScanConflict, ErrBookingCancelled.Error()
+		return result, nil
+	case ticket.Status == models.TicketVoid:
+		result.Result, result.Detail = models.ScanConflict, ErrTicketVoid.Error()
+		return result, nil
+	case ticket.Status == models.TicketUsed && ticket.CheckedInAt != nil && scan.ScannedAt.Before(*ticket.CheckedInAt):
+		// an offline scan may predate the admission the server knows of: it admitted the
+		// ticket, the later scan is the one seeing it again
+		if err := recordLaterScan(tx, ticket, scan); err != nil {
+			return result, err
+		}
+		if err := tx.Model(&ticket).Updates(map[string]any{"checked_in_at": scan.ScannedAt, "gate": scan.Gate}).Error; err != nil {
+			return result, err
+		}
+		result.Result = models.ScanAdmitted
+		return result, nil
+	case ticket.Status == models.TicketUsed:
+		result.FirstGate, result.FirstCheckedInAt = ticket.Gate, ticket.CheckedInAt
+		result.Result, result.Detail = repeatedScan(ticket.Gate, scan.Gate)
+		return result, nil
+	}
+
+	if err := tx.Model(&ticket).Updates(map[string]any{
+		"status":        models.TicketUsed,
+		"checked_in_at": scan.ScannedAt,
+		"gate":          scan.Gate,
+	}).Error; err != nil {
+		return result, err
+	}
+	result.Result = models.ScanAdmitted
+	return result, nil
+}
+
+// repeatedScan is the result of a scan at gate of a ticket admitted at firstGate
+func repeatedScan(firstGate, gate string) (string, string) {
+	if firstGate == gate {
+		return models.ScanDuplicate, ""
+	}
+	return models.ScanDoubleEntry, fmt.Sprintf("already admitted at gate %s", firstGate)
+}
+
+// recordLaterScan turns the admission of ticket into a repeated scan once an earlier scan
+// comes in. A synced scan already has its event, a check-in at the door gets one.
+func recordLaterScan(tx *gorm.DB, ticket models.Ticket, earlier OfflineScan) error {
+	result, detail := repeatedScan(earlier.Gate, ticket.Gate)
+	update := tx.Model(&models.ScanEvent{}).
+		Where("ticket_id = ? AND result = ? AND scanned_at = ?", ticket.ID, models.ScanAdmitted, *ticket.CheckedInAt).
+		Updates(map[string]any{"result": result, "detail": detail})
+	if update.Error != nil || update.RowsAffected > 0 {
+		return update.Error
+	}
+	event := models.ScanEvent{
+		ShowID:    ticket.ShowID,
+		TicketID:  &ticket.ID,
+		Code:      ticket.Code,
+		Gate:      ticket.Gate,
+		ScannedAt: *ticket.CheckedInAt,
+		Result:    result,
+		Detail:    detail,
+	}
+	if ticket.CheckedInBy != nil {
+		event.ScannedBy = *ticket.CheckedInBy
+	}
+	return tx.Create(&event).Error
+}
+
+// ListScanEvents returns the reconciled scans of a show, optionally only those with the given result.
+func (s Service) ListScanEvents(showID uint, result string) ([]models.ScanEvent, error) {
+	query := s.Db.Where("show_id = ?", showID)
+	if result != "" {
+		query = query.Where("result = ?", result)
+	}
+	var events []models.ScanEvent
+	if err := query.Order("scanned_at").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
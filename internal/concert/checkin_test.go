@@ -0,0 +1,123 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckInManifest(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	show := models.Show{Title: "Offline", Venue: "Nantes", StartsAt: time.Now()}
+	db.Create(&show)
+	db.Create(&models.Booking{ShowID: show.ID, TicketCount: 2, Status: "confirmed"})
+	db.Create(&models.Booking{ShowID: show.ID, TicketCount: 1, Status: "cancelled"})
+
+	signed, err := service.GetCheckInManifest(show.ID)
+	assert.NoError(t, err)
+
+	key, _ := base64.StdEncoding.DecodeString(ManifestPublicKey().PublicKey)
+	sig, _ := base64.StdEncoding.DecodeString(signed.Signature)
+	assert.True(t, ed25519.Verify(key, signed.Manifest, sig))
+
+	var manifest CheckInManifest
+	assert.NoError(t, json.Unmarshal(signed.Manifest, &manifest))
+	assert.Equal(t, show.ID, manifest.ShowID)
+	assert.Len(t, manifest.Tickets, 2)
+}
+
+func TestSyncScans(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	show := models.Show{Title: "Offline", Venue: "Nantes", StartsAt: time.Now()}
+	db.Create(&show)
+	booking := models.Booking{ShowID: show.ID, TicketCount: 3, Status: "confirmed"}
+	db.Create(&booking)
+	tickets, _ := service.IssueTickets(booking)
+	assert.NoError(t, service.VoidTickets(booking.ID))
+	voided := tickets[2].Code
+	tickets, _ = service.IssueTickets(booking)
+
+	// devices send nanoseconds, the database keeps microseconds
+	start := time.Now().Add(-time.Hour).Truncate(time.Second).Add(123456789)
+	scans := []OfflineScan{
+		// out of order on purpose, reconciliation follows scannedAt
+		{Code: tickets[0].Code, Gate: "B", ScannedAt: start.Add(5 * time.Minute)},
+		{Code: tickets[0].Code, Gate: "A", ScannedAt: start},
+		{Code: tickets[1].Code, Gate: "A", ScannedAt: start.Add(time.Minute)},
+		{Code: tickets[1].Code, Gate: "A", ScannedAt: start.Add(2 * time.Minute)},
+		{Code: voided, Gate: "C", ScannedAt: start.Add(3 * time.Minute)},
+		{Code: "bogus", Gate: "C", ScannedAt: start.Add(4 * time.Minute)},
+	}
+
+	results, err := service.SyncScans(show.ID, 1, "scanner-1", scans)
+	assert.NoError(t, err)
+	got := make([]string, len(results))
+	for i, r := range results {
+		got[i] = r.Result
+	}
+	assert.Equal(t, []string{
+		models.ScanAdmitted, models.ScanAdmitted, models.ScanDuplicate,
+		models.ScanConflict, models.ScanInvalid, models.ScanDoubleEntry,
+	}, got)
+	assert.Equal(t, "A", results[5].FirstGate)
+
+	// uploading the same batch again doesn't record anything new
+	results, err = service.SyncScans(show.ID, 1, "scanner-1", scans)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ScanDuplicate, results[0].Result)
+
+	events, err := service.ListScanEvents(show.ID, models.ScanDoubleEntry)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestSyncScansPredatingAdmission(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	show := models.Show{Title: "Offline", Venue: "Nantes", StartsAt: time.Now()}
+	db.Create(&show)
+	booking := models.Booking{ShowID: show.ID, TicketCount: 2, Status: "confirmed"}
+	db.Create(&booking)
+	tickets, _ := service.IssueTickets(booking)
+
+	// the first ticket is admitted online, the second by a device syncing first
+	_, err := service.CheckIn(tickets[0].Code, "A", show.ID, 7)
+	assert.NoError(t, err)
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	_, err = service.SyncScans(show.ID, 1, "scanner-1", []OfflineScan{{Code: tickets[1].Code, Gate: "A", ScannedAt: start.Add(time.Minute)}})
+	assert.NoError(t, err)
+
+	// another device scanned both earlier, at another gate
+	results, err := service.SyncScans(show.ID, 1, "scanner-2", []OfflineScan{
+		{Code: tickets[0].Code, Gate: "B", ScannedAt: start},
+		{Code: tickets[1].Code, Gate: "B", ScannedAt: start},
+	})
+	assert.NoError(t, err)
+	for _, result := range results {
+		assert.Equal(t, models.ScanAdmitted, result.Result)
+	}
+
+	events, err := service.ListScanEvents(show.ID, models.ScanDoubleEntry)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "A", events[0].Gate)
+		assert.Equal(t, "already admitted at gate B", events[0].Detail)
+	}
+	events, _ = service.ListScanEvents(show.ID, models.ScanAdmitted)
+	assert.Len(t, events, 2)
+
+	var ticket models.Ticket
+	db.First(&ticket, tickets[0].ID)
+	assert.Equal(t, "B", ticket.Gate)
+	assert.True(t, ticket.CheckedInAt.Equal(start))
+}
@@ -2,6 +2,7 @@ package concert
 
 import (
 	"concert/internal/models"
+	"concert/internal/payment"
 	"testing"
 	"time"
 
@@ -15,7 +16,34 @@ func SetupTestDB() *gorm.DB {
 	if err != nil {
 		panic("failed to connect database")
 	}
-	db.AutoMigrate(&models.Artist{}, &models.Show{}, &models.Booking{})
+	db.AutoMigrate(&models.Artist{}, &models.Show{}, &models.Booking{},
+		&models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.WebhookAttempt{},
+		&models.QueueTicket{}, &models.Presale{},
+		&models.User{}, &models.TicketLimitOverride{},
+		&models.Ticket{},
+		&models.ScanEvent{},
+		&models.Invoice{},
+		&models.Payment{},
+		&models.Transfer{},
+		&models.BookingAudit{},
+		&models.ResaleListing{},
+		&models.CreditEntry{},
+		&models.GiftCard{},
+		&models.LedgerAccount{},
+		&models.JournalEntry{},
+		&models.Posting{},
+		&models.Settlement{},
+		&models.TaxRate{},
+		&models.Venue{},
+		&models.LineupSlot{},
+		&models.Festival{},
+		&models.Tour{},
+		&models.Follow{},
+		&payment.MockIntent{},
+		&payment.MockRefund{})
+	if err := SetupSearch(db); err != nil {
+		panic(err)
+	}
 	return db
 }
 func TestGetFan(t *testing.T) {
@@ -28,7 +56,7 @@ func TestGetFan(t *testing.T) {
 	show := models.Show{
 		ArtistID:       artist.ID,
 		Venue:          "Paris",
-		Date:           time.Now(),
+		StartsAt:       time.Now(),
 		Title:          "Drake Concert",
 		Price:          50.0,
 		TotalSeats:     100,
@@ -65,7 +93,7 @@ func TestGetShow(t *testing.T) {
 	service := NewConcert(db)
 	artist := models.Artist{Name: "Drake", Genre: "Rock"}
 	db.Create(&artist)
-	show := models.Show{ArtistID: artist.ID, Venue: "Paris", Date: time.Now()}
+	show := models.Show{ArtistID: artist.ID, Venue: "Paris", StartsAt: time.Now()}
 	db.Create(&show)
 	found, err := service.GetShow("Drake")
 	assert.NoError(t, err)
@@ -77,7 +105,7 @@ func TestGetShowByID(t *testing.T) {
 	service := NewConcert(db)
 	artist := models.Artist{Name: "Drake", Genre: "Rock"}
 	db.Create(&artist)
-	show := models.Show{ArtistID: artist.ID, Venue: "Paris", Date: time.Now()}
+	show := models.Show{ArtistID: artist.ID, Venue: "Paris", StartsAt: time.Now()}
 	db.Create(&show)
 	found, err := service.GetShowByID(show.ID)
 	assert.NoError(t, err)
@@ -89,7 +117,7 @@ func TestSetShow(t *testing.T) {
 	service := NewConcert(db)
 	artist := models.Artist{Name: "Drake", Genre: "Rock"}
 	db.Create(&artist)
-	show := models.Show{ArtistID: artist.ID, Venue: "Paris", Date: time.Now()}
+	show := models.Show{ArtistID: artist.ID, Venue: "Paris", StartsAt: time.Now()}
 	db.Create(&show)
 	found, err := service.SetShow(show)
 	assert.NoError(t, err)
@@ -115,7 +143,7 @@ func TestParticipateShow(t *testing.T) {
 	show := models.Show{
 		ArtistID:       artist.ID,
 		Venue:          "Paris",
-		Date:           time.Now(),
+		StartsAt:       time.Now(),
 		Title:          "Drake Concert",
 		Price:          50.0,
 		TotalSeats:     100,
@@ -0,0 +1,369 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/payment"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInsufficientCredit = errors.New("not enough account credit")
+	ErrInvalidAmount      = errors.New("invalid amount")
+	ErrGiftCardInvalid    = errors.New("invalid gift card code")
+	ErrGiftCardRedeemed   = errors.New("this gift card has already been redeemed")
+	ErrGiftCardExpired    = errors.New("this gift card has expired")
+)
+
+// MaxGiftCardAmount bounds the value of a single gift card
+const MaxGiftCardAmount = 1000.0
+
+// CreditLiabilities is what the company owes in credit: balances of the accounts and
+// gift cards sold or issued but not redeemed yet.
+type CreditLiabilities struct {
+	AccountCredit float64       `json:"accountCredit"`
+	GiftCards     float64       `json:"giftCards"`
+	Total         float64       `json:"total"`
+	Accounts      []UserBalance `json:"accounts"`
+}
+
+type UserBalance struct {
+	UserID   uint    `json:"userId"`
+	Username string  `json:"username"`
+	Email    string  `json:"email"`
+	Balance  float64 `json:"balance"`
+}
+
+// AddCredit writes an entry to the credit ledger of entry.UserID and updates the cached
+// balance within tx. Debits fail with ErrInsufficientCredit instead of going below zero.
+func AddCredit(tx *gorm.DB, entry *models.CreditEntry) error {
+	if payment.ToCents(entry.Amount) == 0 {
+		return nil
+	}
+	update := tx.Model(&models.User{}).Where("id = ?", entry.UserID)
+	if entry.Amount < 0 {
+		update = update.Where("credit_balance >= ?", -entry.Amount-0.005)
+	}
+	result := update.Update("credit_balance", gorm.Expr("ROUND(credit_balance + ?, 2)", entry.Amount))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInsufficientCredit
+	}
+	if err := tx.Create(entry).Error; err != nil {
+		return err
+	}
+	return recordCredit(tx, *entry)
+}
+
+// ReturnBookingCredit gives back the credit spent on a booking that didn't go through.
+func ReturnBookingCredit(tx *gorm.DB, booking models.Booking) error {
+	if booking.CreditApplied <= 0 {
+		return nil
+	}
+	return AddCredit(tx, &models.CreditEntry{
+		UserID:    booking.UserID,
+		Amount:    booking.CreditApplied,
+		Kind:      models.CreditBookingReleased,
+		BookingID: &booking.ID,
+	})
+}
+
+// CreditToApply is the credit of user spent on a booking of total: requested, or as much
+// as possible when requested is zero.
+func CreditToApply(user models.User, total, requested float64) float64 {
+	credit := min(user.CreditBalance, total)
+	if requested > 0 {
+		credit = min(credit, requested)
+	}
+	return payment.FromCents(max(payment.ToCents(credit), 0))
+}
+
+// SpendCredit debits the credit applied to a new booking within the transaction creating it.
+func SpendCredit(tx *gorm.DB, booking models.Booking) error {
+	if booking.CreditApplied <= 0 {
+		return nil
+	}
+	return AddCredit(tx, &models.CreditEntry{
+		UserID:    booking.UserID,
+		Amount:    -booking.CreditApplied,
+		Kind:      models.CreditBooking,
+		BookingID: &booking.ID,
+	})
+}
+
+func (s Service) GetCreditEntries(userID uint) ([]models.CreditEntry, error) {
+	var entries []models.CreditEntry
+	if err := s.Db.Where("user_id = ?", userID).Order("id DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GrantCredit credits a user as a goodwill gesture, e.g. for a postponed show.
+func (s Service) GrantCredit(userID uint, amount float64, admin models.User, note string) (models.CreditEntry, error) {
+	if amount <= 0 || amount > MaxGiftCardAmount {
+		return models.CreditEntry{}, ErrInvalidAmount
+	}
+	entry := models.CreditEntry{UserID: userID, Amount: amount, Kind: models.CreditGoodwill, ActorID: &admin.ID, Note: note}
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		return AddCredit(tx, &entry)
+	})
+	if err != nil {
+		return models.CreditEntry{}, err
+	}
+	return entry, nil
+}
+
+// GetCreditLiabilities sums the outstanding credit, Accounts lists the positive balances.
+func (s Service) GetCreditLiabilities(now time.Time) (CreditLiabilities, error) {
+	var liabilities CreditLiabilities
+	if err := s.Db.Model(&models.User{}).
+		Select("id AS user_id, username, email, credit_balance AS balance").
+		Where("credit_balance > 0").
+		Order("credit_balance DESC").
+		Scan(&liabilities.Accounts).Error; err != nil {
+		return CreditLiabilities{}, err
+	}
+	if liabilities.Accounts == nil {
+		liabilities.Accounts = []UserBalance{}
+	}
+	var cents int64
+	for _, account := range liabilities.Accounts {
+		cents += payment.ToCents(account.Balance)
+	}
+	liabilities.AccountCredit = payment.FromCents(cents)
+
+	if err := s.Db.Model(&models.GiftCard{}).
+		Where("status = ? AND (expires_at IS NULL OR expires_at > ?)", models.GiftCardActive, now).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&liabilities.GiftCards).Error; err != nil {
+		return CreditLiabilities{}, err
+	}
+	liabilities.Total = payment.FromCents(cents + payment.ToCents(liabilities.GiftCards))
+	return liabilities, nil
+}
+
+// giftCardAlphabet leaves out the characters easily mistaken for one another
+const giftCardAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func newGiftCardCode() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	var code strings.Builder
+	code.WriteString("GC")
+	for i, c := range b {
+		if i%4 == 0 {
+			code.WriteByte('-')
+		}
+		code.WriteByte(giftCardAlphabet[int(c)%len(giftCardAlphabet)])
+	}
+	return code.String()
+}
+
+func normalizeGiftCardCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+}
+
+// IssueGiftCard creates an active gift card, e.g. sold at the box office or for a partner.
+func (s Service) IssueGiftCard(amount float64, admin models.User, recipientEmail, message string, expiresAt *time.Time) (models.GiftCard, error) {
+	if amount <= 0 || amount > MaxGiftCardAmount {
+		return models.GiftCard{}, ErrInvalidAmount
+	}
+	card := models.GiftCard{
+		Code:           newGiftCardCode(),
+		Amount:         amount,
+		Status:         models.GiftCardActive,
+		IssuedByID:     &admin.ID,
+		RecipientEmail: strings.ToLower(strings.TrimSpace(recipientEmail)),
+		Message:        message,
+		ExpiresAt:      expiresAt,
+	}
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&card).Error; err != nil {
+			return err
+		}
+		return recordGiftCardIssue(tx, card)
+	})
+	if err != nil {
+		return models.GiftCard{}, err
+	}
+	return card, nil
+}
+
+// BuyGiftCard creates a pending gift card for purchaser and starts its payment. The card
+// becomes active when the payment succeeds.
+func (s Service) BuyGiftCard(amount float64, purchaser models.User, recipientEmail, message string) (models.GiftCard, models.Payment, payment.Intent, error) {
+	if amount <= 0 || amount > MaxGiftCardAmount {
+		return models.GiftCard{}, models.Payment{}, payment.Intent{}, ErrInvalidAmount
+	}
+	card := models.GiftCard{
+		Code:           newGiftCardCode(),
+		Amount:         amount,
+		Status:         models.GiftCardPending,
+		PurchaserID:    &purchaser.ID,
+		RecipientEmail: strings.ToLower(strings.TrimSpace(recipientEmail)),
+		Message:        message,
+	}
+	if err := s.Db.Create(&card).Error; err != nil {
+		return models.GiftCard{}, models.Payment{}, payment.Intent{}, err
+	}
+
+	reference := fmt.Sprintf("giftcard_%d", card.ID)
+	intent, err := s.Payments.CreateIntent(context.Background(), payment.ToCents(amount), paymentCurrency(), reference)
+	if err != nil {
+		s.Db.Model(&card).Update("status", models.GiftCardVoid)
+		return models.GiftCard{}, models.Payment{}, payment.Intent{}, err
+	}
+	p := models.Payment{
+		GiftCardID: &card.ID,
+		Provider:   s.Payments.Name(),
+		IntentID:   intent.ID,
+		Amount:     payment.FromCents(intent.Amount),
+		Currency:   intent.Currency,
+		Status:     models.PaymentPending,
+	}
+	if err := s.Db.Create(&p).Error; err != nil {
+		return models.GiftCard{}, models.Payment{}, payment.Intent{}, err
+	}
+	return card, p, intent, nil
+}
+
+// hideCode blanks the code of a card that isn't paid yet
+func hideCode(card models.GiftCard) models.GiftCard {
+	if card.Status == models.GiftCardPending || card.Status == models.GiftCardVoid {
+		card.Code = ""
+	}
+	return card
+}
+
+// ListPurchasedGiftCards lists the cards bought by a user, with the codes of the paid ones.
+func (s Service) ListPurchasedGiftCards(userID uint) ([]models.GiftCard, error) {
+	var cards []models.GiftCard
+	if err := s.Db.Where("purchaser_id = ?", userID).Order("id DESC").Find(&cards).Error; err != nil {
+		return nil, err
+	}
+	for i := range cards {
+		cards[i] = hideCode(cards[i])
+	}
+	return cards, nil
+}
+
+func (s Service) ListGiftCards(status string) ([]models.GiftCard, error) {
+	query := s.Db.Order("id DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var cards []models.GiftCard
+	if err := query.Find(&cards).Error; err != nil {
+		return nil, err
+	}
+	for i := range cards {
+		cards[i] = hideCode(cards[i])
+	}
+	return cards, nil
+}
+
+// SimulateGiftCardPayment completes the pending payment of a gift card with the mock provider.
+func (s Service) SimulateGiftCardPayment(cardID uint, purchaser models.User) (models.GiftCard, error) {
+	mock, ok := s.Payments.(*payment.MockProvider)
+	if !ok {
+		return models.GiftCard{}, ErrNotMockProvider
+	}
+	var p models.Payment
+	if err := s.Db.Joins("JOIN gift_cards ON gift_cards.id = payments.gift_card_id").
+		Where("payments.gift_card_id = ? AND payments.status = ? AND gift_cards.purchaser_id = ?", cardID, models.PaymentPending, purchaser.ID).
+		First(&p).Error; err != nil {
+		return models.GiftCard{}, ErrNoPendingPayment
+	}
+	payload, header, err := mock.Complete(p.IntentID)
+	if err != nil {
+		return models.GiftCard{}, err
+	}
+	if _, _, err := s.HandlePaymentWebhook(payload, header); err != nil {
+		return models.GiftCard{}, err
+	}
+	var card models.GiftCard
+	if err := s.Db.First(&card, cardID).Error; err != nil {
+		return models.GiftCard{}, err
+	}
+	return hideCode(card), nil
+}
+
+// applyGiftCardPayment activates or voids a gift card once its payment is settled
+func (s Service) applyGiftCardPayment(p models.Payment, event payment.Event) error {
+	switch event.Type {
+	case payment.EventAuthorized:
+		if err := s.Payments.Capture(context.Background(), p.IntentID); err != nil {
+			return s.failGiftCardPayment(p, "capture failed: "+err.Error())
+		}
+		return s.Db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&p).Updates(map[string]any{"status": models.PaymentSucceeded, "captured_at": time.Now()}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.GiftCard{}).
+				Where("id = ? AND status = ?", *p.GiftCardID, models.GiftCardPending).
+				Update("status", models.GiftCardActive).Error; err != nil {
+				return err
+			}
+			return recordGiftCardSale(tx, p)
+		})
+	case payment.EventFailed:
+		return s.failGiftCardPayment(p, event.FailureReason)
+	}
+	return nil
+}
+
+func (s Service) failGiftCardPayment(p models.Payment, reason string) error {
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		// the payment may have come in, or another server failed it, meanwhile
+		result := tx.Model(&p).Where("status = ?", models.PaymentPending).
+			Updates(map[string]any{"status": models.PaymentFailed, "failure_reason": reason})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&models.GiftCard{}).
+			Where("id = ? AND status = ?", *p.GiftCardID, models.GiftCardPending).
+			Update("status", models.GiftCardVoid).Error
+	})
+}
+
+// RedeemGiftCard adds the value of an active gift card to the credit of user.
+func (s Service) RedeemGiftCard(code string, user models.User, now time.Time) (models.CreditEntry, error) {
+	var card models.GiftCard
+	if err := s.Db.Where("code = ?", normalizeGiftCardCode(code)).First(&card).Error; err != nil {
+		return models.CreditEntry{}, ErrGiftCardInvalid
+	}
+	switch {
+	case card.Status == models.GiftCardRedeemed:
+		return models.CreditEntry{}, ErrGiftCardRedeemed
+	case card.Status != models.GiftCardActive:
+		return models.CreditEntry{}, ErrGiftCardInvalid
+	case card.ExpiresAt != nil && card.ExpiresAt.Before(now):
+		return models.CreditEntry{}, ErrGiftCardExpired
+	}
+
+	entry := models.CreditEntry{UserID: user.ID, Amount: card.Amount, Kind: models.CreditGiftCard, GiftCardID: &card.ID}
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&card).Where("status = ?", models.GiftCardActive).
+			Updates(map[string]any{"status": models.GiftCardRedeemed, "redeemed_by_id": user.ID, "redeemed_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrGiftCardRedeemed
+		}
+		return AddCredit(tx, &entry)
+	})
+	if err != nil {
+		return models.CreditEntry{}, err
+	}
+	return entry, nil
+}
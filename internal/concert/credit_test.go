@@ -0,0 +1,131 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGiftCardPurchaseAndRedeem(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	buyer := models.User{Username: "buyer", Email: "buyer@example.com"}
+	friend := models.User{Username: "friend", Email: "friend@example.com"}
+	service.Db.Create(&buyer)
+	service.Db.Create(&friend)
+
+	_, _, _, err := service.BuyGiftCard(0, buyer, "", "")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+
+	card, p, intent, err := service.BuyGiftCard(50, buyer, "Friend@example.com", "Enjoy")
+	assert.NoError(t, err)
+	assert.Equal(t, models.GiftCardPending, card.Status)
+	assert.Equal(t, int64(5000), intent.Amount)
+	assert.Equal(t, card.ID, *p.GiftCardID)
+
+	// unpaid cards can't be redeemed and don't show their code
+	_, err = service.RedeemGiftCard(card.Code, friend, time.Now())
+	assert.ErrorIs(t, err, ErrGiftCardInvalid)
+	cards, _ := service.ListPurchasedGiftCards(buyer.ID)
+	assert.Empty(t, cards[0].Code)
+
+	paid, err := service.SimulateGiftCardPayment(card.ID, buyer)
+	assert.NoError(t, err)
+	assert.Equal(t, models.GiftCardActive, paid.Status)
+	assert.Equal(t, card.Code, paid.Code)
+
+	liabilities, _ := service.GetCreditLiabilities(time.Now())
+	assert.Equal(t, 50.0, liabilities.GiftCards)
+
+	entry, err := service.RedeemGiftCard(" "+card.Code[:7]+" "+card.Code[7:], friend, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, models.CreditGiftCard, entry.Kind)
+	_, err = service.RedeemGiftCard(card.Code, buyer, time.Now())
+	assert.ErrorIs(t, err, ErrGiftCardRedeemed)
+
+	got, _ := service.GetUserByID(friend.ID)
+	assert.Equal(t, 50.0, got.CreditBalance)
+
+	liabilities, _ = service.GetCreditLiabilities(time.Now())
+	assert.Equal(t, 0.0, liabilities.GiftCards)
+	assert.Equal(t, 50.0, liabilities.AccountCredit)
+	assert.Equal(t, 50.0, liabilities.Total)
+	assert.Len(t, liabilities.Accounts, 1)
+}
+
+func TestExpiredGiftCard(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	admin := models.User{Username: "admin", Email: "admin@example.com", Role: "admin"}
+	service.Db.Create(&admin)
+
+	expiresAt := time.Now().Add(-time.Hour)
+	card, err := service.IssueGiftCard(20, admin, "", "", &expiresAt)
+	assert.NoError(t, err)
+	assert.Equal(t, models.GiftCardActive, card.Status)
+
+	_, err = service.RedeemGiftCard(card.Code, admin, time.Now())
+	assert.ErrorIs(t, err, ErrGiftCardExpired)
+	liabilities, _ := service.GetCreditLiabilities(time.Now())
+	assert.Equal(t, 0.0, liabilities.Total)
+}
+
+func TestCreditAppliedToBooking(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	admin := models.User{Username: "admin", Email: "admin@example.com", Role: "admin"}
+	fan := models.User{Username: "fan", Email: "fan@example.com"}
+	service.Db.Create(&admin)
+	service.Db.Create(&fan)
+
+	_, err := service.GrantCredit(fan.ID, 30, admin, "show postponed")
+	assert.NoError(t, err)
+	fan, _ = service.GetUserByID(fan.ID)
+	assert.Equal(t, 30.0, fan.CreditBalance)
+
+	show := models.Show{Title: "Credit", Venue: "Nantes", StartsAt: time.Now().AddDate(0, 1, 0), Price: 40, TotalSeats: 10, AvailableSeats: 10}
+	service.Db.Create(&show)
+	credit := CreditToApply(fan, 80, 0)
+	assert.Equal(t, 30.0, credit)
+	booking := models.Booking{ShowID: show.ID, UserID: fan.ID, TicketCount: 2, TotalPrice: 80, Status: "pending", CreditApplied: credit}
+	service.Db.Create(&booking)
+	assert.NoError(t, SpendCredit(service.Db, booking))
+	assert.ErrorIs(t, SpendCredit(service.Db, booking), ErrInsufficientCredit)
+
+	// the payment covers what the credit doesn't
+	_, intent, err := service.StartPayment(booking)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5000), intent.Amount)
+
+	// the credit comes back when the payment fails
+	assert.NoError(t, service.ReleaseBooking(booking.ID, "declined"))
+	fan, _ = service.GetUserByID(fan.ID)
+	assert.Equal(t, 30.0, fan.CreditBalance)
+
+	entries, _ := service.GetCreditEntries(fan.ID)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, models.CreditBookingReleased, entries[0].Kind)
+}
+
+func TestRefundToCredit(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	show, booking := createPaidBooking(t, service)
+	fan := models.User{Username: "fan", Email: "fan@example.com"}
+	fan.ID = booking.UserID
+	service.Db.Create(&fan)
+
+	quote, err := service.QuoteRefund(booking, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, quote.Credit)
+
+	assert.NoError(t, service.RefundToCredit(booking.ID, quote.Amount))
+	assert.ErrorIs(t, service.RefundToCredit(booking.ID, quote.Amount), ErrNotRefundable)
+
+	cancelled, _ := service.GetBookingById(booking.ID)
+	assert.Equal(t, "cancelled", cancelled.Status)
+	assert.Equal(t, RefundStatusRefunded, cancelled.RefundStatus)
+	assert.Equal(t, 80.0, cancelled.RefundCredit)
+	fan, _ = service.GetUserByID(fan.ID)
+	assert.Equal(t, 80.0, fan.CreditBalance)
+	got, _ := service.GetShowByID(show.ID)
+	assert.Equal(t, 10, got.AvailableSeats)
+}
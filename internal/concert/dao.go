@@ -2,13 +2,19 @@ package concert
 
 import (
 	"concert/internal/models"
+	"concert/internal/payment"
+	"concert/internal/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type Service struct {
-	Db *gorm.DB
+	Db       *gorm.DB
+	Payments payment.PaymentProvider
 }
 
 // type Show struct {
@@ -42,12 +48,28 @@ type ArtistShow struct {
 	Shows  []models.Show `json:"shows"`
 }
 
+// NewConcert returns a service paid through the mock provider, whose webhooks are signed
+// with a random secret: only the service itself can complete its payments. The server and
+// the worker use NewConcertFromEnv.
 func NewConcert(db *gorm.DB) *Service {
+	secret := make([]byte, 32)
+	rand.Read(secret)
 	return &Service{
-		Db: db,
+		Db:       db,
+		Payments: payment.NewMockProvider(hex.EncodeToString(secret), db),
 	}
 }
 
+// NewConcertFromEnv returns a service paid through the provider of PAYMENT_PROVIDER, mock by
+// default, with the webhook secret of PAYMENT_WEBHOOK_SECRET. It fails without a secret.
+func NewConcertFromEnv(db *gorm.DB) (*Service, error) {
+	provider, err := payment.NewProvider(utils.GetEnvOrDefault("PAYMENT_PROVIDER", "mock"), os.Getenv("PAYMENT_WEBHOOK_SECRET"), db)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{Db: db, Payments: provider}, nil
+}
+
 func (s Service) GetFan(username string) ([]models.Booking, error) {
 	var fan []models.Booking
 	if result := s.Db.
@@ -78,23 +100,44 @@ func (s Service) GetShow(artistName string) ([]models.Show, error) {
 }
 func (s Service) GetShowByID(id uint) (models.Show, error) {
 	var show models.Show
-	if result := s.Db.
+	if result := preloadLineup(s.Db).
 		Preload("Artist").
+		Preload("Presales").
+		Preload("Location").
+		Preload("Festival").
+		Preload("Tour").
 		First(&show, id); result.Error != nil {
 		return show, result.Error
 	}
-	var fanCount int64
-	s.Db.Model(&models.Booking{}).Where("show_id = ?", show.ID).Count(&fanCount)
-	show.AvailableSeats = show.TotalSeats - int(fanCount)
+	show.AvailableSeats = show.TotalSeats - heldSeats(s.Db, show)
+	applySaleStatus(&show, time.Now())
 
 	return show, nil
 }
 
+// heldSeats counts the seats of show held by its bookings.
+func heldSeats(db *gorm.DB, show models.Show) int {
+	var held int64
+	query := db.Model(&models.Booking{}).Where("show_id = ?", show.ID)
+	if show.FestivalID != nil {
+		// the passes of the festival hold a seat on each day
+		query = db.Model(&models.Booking{}).Where("show_id = ? OR festival_id = ?", show.ID, *show.FestivalID)
+	}
+	query.
+		Where("status IN ?", heldTicketStatuses).
+		// seats bought on the resale marketplace are still held by the seller until paid
+		Where("NOT (status = ? AND resale_listing_id IS NOT NULL)", "pending").
+		Select("COALESCE(SUM(ticket_count), 0)").
+		Scan(&held)
+	return int(held)
+}
+
 func (s Service) SetShow(show models.Show) (models.Show, error) {
-	if result := s.Db.Save(&show); result.Error != nil {
+	// only the alert marks a show as announced, only the admitter lets clients in
+	if result := s.Db.Omit("alerted_at", "queue_admitted_at").Save(&show); result.Error != nil {
 		return models.Show{}, result.Error
 	}
-	if err := s.Db.Preload("Artist").First(&show, show.ID).Error; err != nil {
+	if err := preloadLineup(s.Db).Preload("Artist").Preload("Location").First(&show, show.ID).Error; err != nil {
 		return models.Show{}, err
 	}
 	return show, nil
@@ -121,9 +164,13 @@ func (s Service) ParticipateShow(info models.Booking) (models.Show, error) {
 
 func (s Service) ListAllShow() ([]models.Show, error) {
 	var shows []models.Show
-	if result := s.Db.Preload("Artist").Find(&shows); result.Error != nil {
+	if result := s.Db.Preload("Artist").Preload("Presales").Find(&shows); result.Error != nil {
 		return nil, result.Error
 	}
+	now := time.Now()
+	for i := range shows {
+		applySaleStatus(&shows[i], now)
+	}
 	return shows, nil
 }
 
@@ -161,7 +208,7 @@ func (s Service) GetAllUsers() ([]models.User, error) {
 
 func (s Service) GetArtistByID(id uint) (models.Artist, error) {
 	var artist models.Artist
-	if result := s.Db.Preload("Show").Preload("Show.Artist").First(&artist, id); result.Error != nil {
+	if result := s.Db.Preload("Shows").First(&artist, id); result.Error != nil {
 		return models.Artist{}, result.Error
 	}
 
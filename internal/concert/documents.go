@@ -0,0 +1,235 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/payment"
+	"concert/internal/pdf"
+	"concert/internal/qrcode"
+	"concert/internal/utils"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const invoiceAttempts = 3
+
+func invoiceNumber(seq int) string {
+	return fmt.Sprintf("INV-%06d", seq)
+}
+
+// GetInvoice returns the invoice of a booking, numbering it with the next sequence
+// value the first time. Cancelled bookings keep their invoice but don't get a new one.
+func (s Service) GetInvoice(booking models.Booking) (models.Invoice, error) {
+	var invoice models.Invoice
+	err := s.Db.Where("booking_id = ?", booking.ID).First(&invoice).Error
+	if err == nil {
+		return invoice, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return invoice, err
+	}
+	if booking.Status != "confirmed" {
+		return invoice, ErrBookingNotConfirmed
+	}
+	// an account closed since keeps its name on the invoice
+	var purchaser models.User
+	if err := s.Db.Unscoped().Limit(1).Find(&purchaser, booking.UserID).Error; err != nil {
+		return invoice, err
+	}
+
+	// two bookings may take the same number concurrently, the unique index rejects one and it retries
+	for attempt := 0; attempt < invoiceAttempts; attempt++ {
+		err = s.Db.Transaction(func(tx *gorm.DB) error {
+			var last int
+			if err := tx.Model(&models.Invoice{}).Unscoped().Select("COALESCE(MAX(seq), 0)").Scan(&last).Error; err != nil {
+				return err
+			}
+			invoice = models.Invoice{
+				BookingID: booking.ID,
+				Seq:       last + 1,
+				Number:    invoiceNumber(last + 1),
+				Amount:    booking.TotalPrice,
+				IssuedAt:  time.Now(),
+
+				BilledName:    purchaser.Username,
+				BilledEmail:   purchaser.Email,
+				CreditApplied: booking.CreditApplied,
+			}
+			return tx.Create(&invoice).Error
+		})
+		if err == nil {
+			return invoice, nil
+		}
+		// the booking may have been invoiced in the meantime
+		if s.Db.Where("booking_id = ?", booking.ID).First(&invoice).Error == nil {
+			return invoice, nil
+		}
+	}
+	return models.Invoice{}, err
+}
+
+func (s Service) loadBookingDocuments(booking models.Booking) (models.Booking, error) {
+	var full models.Booking
+	if err := s.Db.Preload("Show.Artist").Preload("User").First(&full, booking.ID).Error; err != nil {
+		return full, err
+	}
+	return full, nil
+}
+
+func drawQRCode(doc *pdf.Document, code string, x, y, size float64) error {
+	q, err := qrcode.Encode(code)
+	if err != nil {
+		return err
+	}
+	module := size / float64(q.Size)
+	for row := 0; row < q.Size; row++ {
+		for col := 0; col < q.Size; col++ {
+			if q.Dark(col, row) {
+				// a little overlap avoids hairlines between modules in some viewers
+				doc.Rect(x+float64(col)*module, y+float64(row)*module, module+0.1, module+0.1, true)
+			}
+		}
+	}
+	return nil
+}
+
+// TicketsPDF renders one page per ticket of a confirmed booking.
+func (s Service) TicketsPDF(booking models.Booking) ([]byte, error) {
+	tickets, err := s.IssueTickets(booking)
+	if err != nil {
+		return nil, err
+	}
+	booking, err = s.loadBookingDocuments(booking)
+	if err != nil {
+		return nil, err
+	}
+	days := map[uint]models.Show{booking.ShowID: booking.Show}
+	if booking.FestivalID != nil {
+		var shows []models.Show
+		if err := s.Db.Preload("Artist").Where("festival_id = ?", *booking.FestivalID).Find(&shows).Error; err != nil {
+			return nil, err
+		}
+		for _, show := range shows {
+			days[show.ID] = show
+		}
+	}
+
+	doc := pdf.New(fmt.Sprintf("Tickets - %s", booking.Show.Title))
+	for _, ticket := range tickets {
+		show, ok := days[ticket.ShowID]
+		if !ok {
+			show = booking.Show
+		}
+		doc.AddPage()
+		doc.Gray(0.93)
+		doc.Rect(40, 40, pdf.PageWidth-80, 330, true)
+		doc.Gray(0)
+		doc.Rect(40, 40, pdf.PageWidth-80, 330, false)
+
+		doc.Text(60, 80, 22, true, show.Title)
+		doc.Text(60, 108, 14, false, show.Artist.Name)
+		doc.Line(60, 122, 320, 122, 0.5)
+		doc.Text(60, 150, 9, true, "VENUE")
+		doc.Text(60, 166, 12, false, show.Venue)
+		doc.Text(60, 196, 9, true, "DATE")
+		doc.Text(60, 212, 12, false, FormatShowDate(show))
+		doc.Text(60, 242, 9, true, "SEAT")
+		doc.Text(60, 258, 12, false, fmt.Sprintf("General admission - ticket %d of %d", ticket.Seq, booking.TicketCount))
+		doc.Text(60, 288, 9, true, "HOLDER")
+		doc.Text(60, 304, 12, false, booking.User.Username)
+		doc.Text(60, 350, 8, false, fmt.Sprintf("Booking #%d - ticket #%d", booking.ID, ticket.ID))
+
+		if err := drawQRCode(doc, ticket.Code, 350, 80, 180); err != nil {
+			return nil, err
+		}
+		doc.Text(350, 285, 8, false, "Show this code at the entrance.")
+		doc.Text(350, 297, 8, false, "Each ticket admits one person once.")
+	}
+	return doc.Bytes(), nil
+}
+
+// ReceiptPDF renders the invoice of a booking.
+func (s Service) ReceiptPDF(booking models.Booking) ([]byte, error) {
+	invoice, err := s.GetInvoice(booking)
+	if err != nil {
+		return nil, err
+	}
+	booking, err = s.loadBookingDocuments(booking)
+	if err != nil {
+		return nil, err
+	}
+	show := booking.Show
+
+	doc := pdf.New("Receipt " + invoice.Number)
+	doc.AddPage()
+	doc.Text(40, 70, 24, true, "Receipt")
+	doc.Text(40, 92, 10, false, utils.GetEnvOrDefault("INVOICE_ISSUER", "Concert Booking System"))
+
+	doc.Text(380, 70, 10, true, "Invoice number")
+	doc.Text(480, 70, 10, false, invoice.Number)
+	doc.Text(380, 86, 10, true, "Date")
+	doc.Text(480, 86, 10, false, invoice.IssuedAt.Format("2006-01-02"))
+	doc.Text(380, 102, 10, true, "Booking")
+	doc.Text(480, 102, 10, false, fmt.Sprintf("#%d", booking.ID))
+
+	doc.Text(40, 140, 10, true, "Billed to")
+	doc.Text(40, 156, 10, false, invoice.BilledName)
+	doc.Text(40, 170, 10, false, invoice.BilledEmail)
+
+	doc.Gray(0.9)
+	doc.Rect(40, 200, pdf.PageWidth-80, 22, true)
+	doc.Gray(0)
+	doc.Text(48, 215, 10, true, "Description")
+	doc.Text(360, 215, 10, true, "Qty")
+	doc.Text(410, 215, 10, true, "Unit price")
+	doc.Text(490, 215, 10, true, "Amount")
+
+	// the invoice keeps the amount billed, the seats of a booking may have been resold since
+	tickets := invoice.Amount
+	fees, tax := booking.FeeAmount, booking.TaxAmount
+	if payment.ToCents(booking.TotalPrice) == payment.ToCents(invoice.Amount) {
+		tickets = payment.FromCents(payment.ToCents(invoice.Amount) - payment.ToCents(fees) - payment.ToCents(tax))
+	} else {
+		fees, tax = 0, 0
+	}
+	unit := 0.0
+	if booking.TicketCount > 0 {
+		unit = tickets / float64(booking.TicketCount)
+	}
+	doc.Text(48, 242, 10, false, fmt.Sprintf("%s - %s", show.Title, show.Artist.Name))
+	doc.Text(48, 256, 8, false, fmt.Sprintf("%s, %s", show.Venue, FormatShowDate(show)))
+	doc.Text(360, 242, 10, false, fmt.Sprintf("%d", booking.TicketCount))
+	doc.Text(410, 242, 10, false, fmt.Sprintf("%.2f", unit))
+	doc.Text(490, 242, 10, false, fmt.Sprintf("%.2f", tickets))
+
+	y := 272.0
+	if fees > 0 {
+		doc.Text(48, y, 10, false, "Booking fees")
+		doc.Text(490, y, 10, false, fmt.Sprintf("%.2f", fees))
+		y += 18
+	}
+	if tax > 0 {
+		doc.Text(48, y, 10, false, fmt.Sprintf("%s %s%%", booking.TaxName, strconv.FormatFloat(booking.TaxPercent, 'f', -1, 64)))
+		doc.Text(490, y, 10, false, fmt.Sprintf("%.2f", tax))
+		y += 18
+	}
+
+	doc.Line(40, y, pdf.PageWidth-40, y, 0.5)
+	doc.Text(410, y+20, 11, true, "Total")
+	doc.Text(490, y+20, 11, true, fmt.Sprintf("%.2f", invoice.Amount))
+	if credit := min(invoice.CreditApplied, invoice.Amount); credit > 0 {
+		doc.Text(410, y+38, 10, false, "Credit")
+		doc.Text(490, y+38, 10, false, fmt.Sprintf("-%.2f", credit))
+		doc.Text(410, y+56, 11, true, "Paid")
+		doc.Text(490, y+56, 11, true, fmt.Sprintf("%.2f", payment.FromCents(payment.ToCents(invoice.Amount)-payment.ToCents(credit))))
+		y += 36
+	}
+
+	if booking.Status == "cancelled" {
+		doc.Text(40, y+58, 14, true, "This booking has been cancelled.")
+	}
+	return doc.Bytes(), nil
+}
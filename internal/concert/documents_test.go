@@ -0,0 +1,90 @@
+package concert
+
+import (
+	"bytes"
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoiceNumbersAreSequential(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	first := models.Booking{ShowID: 1, TicketCount: 1, TotalPrice: 20, Status: "confirmed"}
+	second := models.Booking{ShowID: 1, TicketCount: 2, TotalPrice: 40, Status: "confirmed"}
+	db.Create(&first)
+	db.Create(&second)
+
+	a, err := service.GetInvoice(first)
+	assert.NoError(t, err)
+	b, err := service.GetInvoice(second)
+	assert.NoError(t, err)
+	again, err := service.GetInvoice(first)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "INV-000001", a.Number)
+	assert.Equal(t, "INV-000002", b.Number)
+	assert.Equal(t, a.ID, again.ID)
+
+	_, err = service.GetInvoice(models.Booking{Status: "cancelled"})
+	assert.ErrorIs(t, err, ErrBookingNotConfirmed)
+}
+
+func TestTicketAndReceiptPDF(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	artist := models.Artist{Name: "Zaz"}
+	db.Create(&artist)
+	show := models.Show{Title: "Récital", Venue: "Olympia", StartsAt: time.Now(), ArtistID: artist.ID, Price: 35}
+	db.Create(&show)
+	user := models.User{Username: "fan", Email: "fan@example.com"}
+	db.Create(&user)
+	booking := models.Booking{UserID: user.ID, ShowID: show.ID, TicketCount: 2, TotalPrice: 70, Status: "confirmed"}
+	db.Create(&booking)
+
+	tickets, err := service.TicketsPDF(booking)
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(tickets, []byte("%PDF-")))
+	assert.Contains(t, string(tickets), "/Count 2")
+	assert.Contains(t, string(tickets), `(R\351cital)`)
+
+	receipt, err := service.ReceiptPDF(booking)
+	assert.NoError(t, err)
+	assert.Contains(t, string(receipt), "(INV-000001)")
+	assert.Contains(t, string(receipt), "(70.00)")
+}
+
+func TestReceiptKeepsPurchaser(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	show := models.Show{Title: "Gift", Venue: "Rennes", StartsAt: time.Now().AddDate(0, 0, 10)}
+	db.Create(&show)
+	alice := models.User{Username: "alice", Email: "alice@example.com"}
+	bob := models.User{Username: "bob", Email: "bob@example.com"}
+	db.Create(&alice)
+	db.Create(&bob)
+	booking := models.Booking{UserID: alice.ID, ShowID: show.ID, TicketCount: 1, TotalPrice: 50, CreditApplied: 20, Status: "confirmed"}
+	db.Create(&booking)
+
+	invoice, err := service.GetInvoice(booking)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice@example.com", invoice.BilledEmail)
+	assert.Equal(t, 20.0, invoice.CreditApplied)
+
+	// the receipt still bills the purchaser once the tickets are given away
+	_, token, err := service.CreateTransfer(booking, alice, "bob@example.com")
+	assert.NoError(t, err)
+	moved, err := service.AcceptTransfer(token, bob)
+	assert.NoError(t, err)
+	receipt, err := service.ReceiptPDF(moved)
+	assert.NoError(t, err)
+	assert.Contains(t, string(receipt), "(alice@example.com)")
+	assert.NotContains(t, string(receipt), "(bob@example.com)")
+	assert.Contains(t, string(receipt), "(-20.00)")
+	assert.Contains(t, string(receipt), "(30.00)")
+}
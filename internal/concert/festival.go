@@ -0,0 +1,185 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrFestivalInUse   = errors.New("the festival still has shows")
+	ErrFestivalNoShows = errors.New("the festival has no shows yet")
+	ErrFestivalPass    = errors.New("festival passes can't be resold")
+)
+
+// BookingSeats scopes the shows whose seats a booking holds: its show, or each day of
+// the festival of a pass.
+func BookingSeats(tx *gorm.DB, booking models.Booking) *gorm.DB {
+	if booking.FestivalID != nil {
+		return tx.Model(&models.Show{}).Where("festival_id = ?", *booking.FestivalID)
+	}
+	return tx.Model(&models.Show{}).Where("id = ?", booking.ShowID)
+}
+
+// festivalDays are the published shows of a festival by start time
+func (s Service) festivalDays(festivalID uint) ([]models.Show, error) {
+	var shows []models.Show
+	if err := listedShows(preloadLineup(s.Db).Preload("Artist").Preload("Location"), time.Now()).
+		Where("festival_id = ?", festivalID).
+		Order("starts_at, id").
+		Find(&shows).Error; err != nil {
+		return nil, err
+	}
+	return shows, nil
+}
+
+// passesAvailable is the number of passes still for sale: the fewest seats left on a
+// day, bounded by the passes left when the festival caps them.
+func passesAvailable(db *gorm.DB, festival models.Festival, days []models.Show) (int, error) {
+	available := -1
+	for _, day := range days {
+		if seats := day.TotalSeats - heldSeats(db, day); available < 0 || seats < available {
+			available = seats
+		}
+	}
+	if available < 0 {
+		return 0, nil
+	}
+	if festival.PassSeats > 0 {
+		var held int64
+		if err := db.Model(&models.Booking{}).
+			Where("festival_id = ? AND status IN ?", festival.ID, heldTicketStatuses).
+			Select("COALESCE(SUM(ticket_count), 0)").
+			Scan(&held).Error; err != nil {
+			return 0, err
+		}
+		available = min(available, festival.PassSeats-int(held))
+	}
+	return max(available, 0), nil
+}
+
+// LockFestivalPasses checks the passes left again inside the transaction creating a pass
+// booking, holding the row locks of the festival and of each of its shows: concurrent
+// orders of passes or of a day are checked one at a time. It returns the number of shows
+// a pass takes a seat on.
+func LockFestivalPasses(tx *gorm.DB, festivalID uint, count int) (int, error) {
+	var festival models.Festival
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&festival, festivalID).Error; err != nil {
+		return 0, err
+	}
+	var shows []models.Show
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("festival_id = ?", festivalID).
+		Order("id").
+		Find(&shows).Error; err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	var days []models.Show
+	for _, show := range shows {
+		if show.Listed(now) {
+			days = append(days, show)
+		}
+	}
+	available, err := passesAvailable(tx, festival, days)
+	if err != nil {
+		return 0, err
+	}
+	if available < count {
+		return 0, ErrNotEnoughSeats
+	}
+	return len(shows), nil
+}
+
+func (s Service) ListFestivals() ([]models.Festival, error) {
+	var festivals []models.Festival
+	now := time.Now()
+	if err := s.Db.Preload("Shows", func(db *gorm.DB) *gorm.DB { return listedShows(db, now).Order("starts_at, id") }).
+		Order("id").
+		Find(&festivals).Error; err != nil {
+		return nil, err
+	}
+	return festivals, nil
+}
+
+// GetFestival returns a festival with its days and the passes left.
+func (s Service) GetFestival(id uint) (models.Festival, error) {
+	var festival models.Festival
+	if err := s.Db.First(&festival, id).Error; err != nil {
+		return models.Festival{}, err
+	}
+	days, err := s.festivalDays(festival.ID)
+	if err != nil {
+		return models.Festival{}, err
+	}
+	festival.Shows = days
+	if festival.PassesAvailable, err = passesAvailable(s.Db, festival, days); err != nil {
+		return models.Festival{}, err
+	}
+	return festival, nil
+}
+
+func (s Service) CreateFestival(festival models.Festival) (models.Festival, error) {
+	festival.ID = 0
+	festival.Shows = nil
+	if err := festival.Validate(); err != nil {
+		return models.Festival{}, err
+	}
+	if err := s.Db.Create(&festival).Error; err != nil {
+		return models.Festival{}, err
+	}
+	return festival, nil
+}
+
+func (s Service) UpdateFestival(festival models.Festival) (models.Festival, error) {
+	festival.Shows = nil
+	if err := festival.Validate(); err != nil {
+		return models.Festival{}, err
+	}
+	if err := s.Db.Save(&festival).Error; err != nil {
+		return models.Festival{}, err
+	}
+	return s.GetFestival(festival.ID)
+}
+
+// DeleteFestival deletes a festival without shows.
+func (s Service) DeleteFestival(id uint) error {
+	var shows int64
+	if err := s.Db.Model(&models.Show{}).Where("festival_id = ?", id).Count(&shows).Error; err != nil {
+		return err
+	}
+	if shows > 0 {
+		return ErrFestivalInUse
+	}
+	result := s.Db.Delete(&models.Festival{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FestivalPassShow is what a pass is booked on: the opening show of the festival, priced
+// at the pass price and with the passes left as its seats. Its sale window, queue and
+// ticket limits are those of the pass.
+func (s Service) FestivalPassShow(festivalID uint) (models.Show, error) {
+	festival, err := s.GetFestival(festivalID)
+	if err != nil {
+		return models.Show{}, err
+	}
+	if len(festival.Shows) == 0 {
+		return models.Show{}, ErrFestivalNoShows
+	}
+	show, err := s.GetShowByID(festival.Shows[0].ID)
+	if err != nil {
+		return models.Show{}, err
+	}
+	show.Price = festival.PassPrice
+	show.AvailableSeats = festival.PassesAvailable
+	return show, nil
+}
@@ -0,0 +1,118 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFestivalPasses(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	artist, _ := service.SetArtist(models.Artist{Name: "Band"})
+	fan := models.User{Email: "fan@test.com", Username: "fan", PasswordHash: "x"}
+	service.Db.Create(&fan)
+
+	festival, err := service.CreateFestival(models.Festival{Name: "Summer Fest", PassPrice: 120, PassSeats: 5})
+	assert.NoError(t, err)
+	_, err = service.FestivalPassShow(festival.ID)
+	assert.ErrorIs(t, err, ErrFestivalNoShows)
+
+	start := time.Now().AddDate(0, 1, 0)
+	var days []models.Show
+	for i := range 2 {
+		day, err := service.SetShow(models.Show{Title: "Day", ArtistID: artist.ID, Venue: "Park", StartsAt: start.AddDate(0, 0, 1-i), TotalSeats: 10, AvailableSeats: 10, Price: 70, FestivalID: &festival.ID})
+		assert.NoError(t, err)
+		days = append(days, day)
+	}
+	// the second show created opens the festival
+	opening, second := days[1], days[0]
+
+	show, err := service.FestivalPassShow(festival.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, opening.ID, show.ID)
+	assert.Equal(t, 120.0, show.Price)
+	assert.Equal(t, 5, show.AvailableSeats)
+
+	pass := models.Booking{UserID: fan.ID, ShowID: opening.ID, FestivalID: &festival.ID, TicketCount: 2, TotalPrice: 240, Status: "confirmed"}
+	service.Db.Create(&pass)
+	service.Db.Create(&models.Booking{UserID: fan.ID, ShowID: second.ID, TicketCount: 7, TotalPrice: 490, Status: "confirmed"})
+
+	// a pass holds a seat on each day, the fullest day bounds the passes
+	got, _ := service.GetShowByID(second.ID)
+	assert.Equal(t, 1, got.AvailableSeats)
+	got, _ = service.GetShowByID(opening.ID)
+	assert.Equal(t, 8, got.AvailableSeats)
+	festival, err = service.GetFestival(festival.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, festival.PassesAvailable)
+	assert.Len(t, festival.Shows, 2)
+
+	tickets, err := service.IssueTickets(pass)
+	assert.NoError(t, err)
+	if assert.Len(t, tickets, 4) {
+		assert.Equal(t, opening.ID, tickets[0].ShowID)
+		assert.Equal(t, second.ID, tickets[3].ShowID)
+		assert.Equal(t, 2, tickets[3].Seq)
+	}
+	again, err := service.IssueTickets(pass)
+	assert.NoError(t, err)
+	assert.Equal(t, tickets[3].Code, again[3].Code)
+
+	signed, err := service.GetCheckInManifest(second.ID)
+	assert.NoError(t, err)
+	var manifest CheckInManifest
+	assert.NoError(t, json.Unmarshal(signed.Manifest, &manifest))
+	assert.Len(t, manifest.Tickets, 9)
+
+	// a ticket of the second day doesn't get in on the first
+	_, err = service.CheckIn(tickets[3].Code, "A", opening.ID, 1)
+	assert.ErrorIs(t, err, ErrTicketWrongShow)
+	result, err := service.CheckIn(tickets[3].Code, "A", second.ID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, second.ID, result.Ticket.ShowID)
+
+	_, err = service.CreateResaleListing(pass, fan, 1, 100)
+	assert.ErrorIs(t, err, ErrFestivalPass)
+	assert.ErrorIs(t, service.DeleteFestival(festival.ID), ErrFestivalInUse)
+
+	// cancelling the pass gives a seat back on each day
+	assert.NoError(t, cancelBooking(service.Db, pass))
+	var shows []models.Show
+	service.Db.Order("id").Find(&shows)
+	assert.Equal(t, 12, shows[0].AvailableSeats)
+	assert.Equal(t, 12, shows[1].AvailableSeats)
+}
+
+func TestLockFestivalPasses(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	artist, _ := service.SetArtist(models.Artist{Name: "Band"})
+	fan := models.User{Email: "fan@test.com", Username: "fan", PasswordHash: "x"}
+	service.Db.Create(&fan)
+	festival, _ := service.CreateFestival(models.Festival{Name: "Summer Fest", PassPrice: 120, PassSeats: 3})
+
+	start := time.Now().AddDate(0, 1, 0)
+	var days []models.Show
+	for i := range 2 {
+		day, _ := service.SetShow(models.Show{Title: "Day", ArtistID: artist.ID, Venue: "Park", StartsAt: start.AddDate(0, 0, i), TotalSeats: 4, AvailableSeats: 4, Price: 70, FestivalID: &festival.ID})
+		days = append(days, day)
+	}
+
+	count, err := LockFestivalPasses(service.Db, festival.ID, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// the passes sold count against the cap of the festival
+	service.Db.Create(&models.Booking{UserID: fan.ID, ShowID: days[0].ID, FestivalID: &festival.ID, TicketCount: 2, TotalPrice: 240, Status: "pending"})
+	_, err = LockFestivalPasses(service.Db, festival.ID, 2)
+	assert.ErrorIs(t, err, ErrNotEnoughSeats)
+	_, err = LockFestivalPasses(service.Db, festival.ID, 1)
+	assert.NoError(t, err)
+
+	// and the fullest day bounds them
+	service.Db.Create(&models.Booking{UserID: fan.ID, ShowID: days[1].ID, TicketCount: 2, TotalPrice: 140, Status: "confirmed"})
+	_, err = LockFestivalPasses(service.Db, festival.ID, 1)
+	assert.ErrorIs(t, err, ErrNotEnoughSeats)
+}
@@ -0,0 +1,127 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FollowingFeed is what a fan follows: the artists by name and their shows to come by
+// start time, headlined or on the bill.
+type FollowingFeed struct {
+	Artists []models.Artist `json:"artists"`
+	Shows   []models.Show   `json:"shows"`
+}
+
+// FollowArtist makes a user follow an artist, following twice changes nothing.
+func (s Service) FollowArtist(userID, artistID uint) (models.Follow, error) {
+	var artist models.Artist
+	if err := s.Db.First(&artist, artistID).Error; err != nil {
+		return models.Follow{}, err
+	}
+	follow := models.Follow{UserID: userID, ArtistID: artistID}
+	if err := s.Db.Where(&follow).FirstOrCreate(&follow).Error; err != nil {
+		return models.Follow{}, err
+	}
+	follow.Artist = artist
+	return follow, nil
+}
+
+// UnfollowArtist stops a user following an artist.
+func (s Service) UnfollowArtist(userID, artistID uint) error {
+	// the follow is deleted for good, a new one takes its place in the unique index
+	result := s.Db.Unscoped().Where("user_id = ? AND artist_id = ?", userID, artistID).Delete(&models.Follow{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetFollowingFeed returns the artists a user follows and the first limit published
+// shows they play after now.
+func (s Service) GetFollowingFeed(userID uint, limit int, now time.Time) (FollowingFeed, error) {
+	if limit <= 0 {
+		limit = DefaultShowPageSize
+	}
+	limit = min(limit, MaxShowPageSize)
+	followed := s.Db.Model(&models.Follow{}).Select("artist_id").Where("user_id = ?", userID)
+
+	feed := FollowingFeed{Artists: []models.Artist{}, Shows: []models.Show{}}
+	if err := s.Db.Where("id IN (?)", followed).Order("name, id").Find(&feed.Artists).Error; err != nil {
+		return FollowingFeed{}, err
+	}
+	if len(feed.Artists) == 0 {
+		return feed, nil
+	}
+	query := preloadLineup(s.Db.Preload("Artist").Preload("Presales").Preload("Location"))
+	if err := listedShows(query, now).
+		Where("shows.starts_at >= ?", now).
+		Where("shows.artist_id IN (?) OR shows.id IN (SELECT show_id FROM lineup_slots WHERE deleted_at IS NULL AND artist_id IN (?))", followed, followed).
+		Order("shows.starts_at, shows.id").
+		Limit(limit).
+		Find(&feed.Shows).Error; err != nil {
+		return FollowingFeed{}, err
+	}
+	for i := range feed.Shows {
+		applySaleStatus(&feed.Shows[i], now)
+	}
+	return feed, nil
+}
+
+// SetShowAlerts turns on or off the emails a user gets about the new shows of the
+// artists they follow.
+func (s Service) SetShowAlerts(userID uint, enabled bool) error {
+	result := s.Db.Model(&models.User{}).Where("id = ?", userID).Update("show_alerts_off", !enabled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ShowAlertRecipients lists by id, after afterUserID, the next limit users to email
+// about a new show: the followers of its artists who didn't opt out. There are none when
+// the show is gone, past or not bookable for its status, nor for a first batch of a show
+// already announced.
+func (s Service) ShowAlertRecipients(showID, afterUserID uint, limit int, now time.Time) ([]models.User, error) {
+	var show models.Show
+	err := s.Db.First(&show, showID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if checkShowStatus(show, now) != nil || show.StartsAt.Before(now) {
+		return nil, nil
+	}
+	if afterUserID == 0 && show.AlertedAt != nil {
+		return nil, nil
+	}
+
+	lineup := s.Db.Model(&models.LineupSlot{}).Select("artist_id").Where("show_id = ?", show.ID)
+	var users []models.User
+	if err := s.Db.
+		Where("id > ? AND show_alerts_off = ?", afterUserID, false).
+		Where("id IN (SELECT user_id FROM follows WHERE deleted_at IS NULL AND (artist_id = ? OR artist_id IN (?)))", show.ArtistID, lineup).
+		Order("id").
+		Limit(limit).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// MarkShowAlerted marks the show as announced at now, unless it already was.
+func (s Service) MarkShowAlerted(showID uint, now time.Time) error {
+	return s.Db.Model(&models.Show{}).
+		Where("id = ? AND alerted_at IS NULL", showID).
+		UpdateColumn("alerted_at", now).Error
+}
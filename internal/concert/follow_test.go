@@ -0,0 +1,114 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestFollowingFeed(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	band, _ := service.SetArtist(models.Artist{Name: "Band"})
+	guest, _ := service.SetArtist(models.Artist{Name: "Guest"})
+	other, _ := service.SetArtist(models.Artist{Name: "Other"})
+	fan := models.User{Email: "fan@test.com", Username: "fan", PasswordHash: "x"}
+	service.Db.Create(&fan)
+
+	_, err := service.FollowArtist(fan.ID, 999)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	follow, err := service.FollowArtist(fan.ID, band.ID)
+	assert.NoError(t, err)
+	again, err := service.FollowArtist(fan.ID, band.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, follow.ID, again.ID)
+	service.FollowArtist(fan.ID, guest.ID)
+
+	now := time.Now()
+	show := func(title string, artistID uint, startsAt time.Time, status string) models.Show {
+		show, err := service.SetShow(models.Show{Title: title, ArtistID: artistID, Venue: "Olympia", StartsAt: startsAt, TotalSeats: 10, AvailableSeats: 10, Status: status})
+		assert.NoError(t, err)
+		return show
+	}
+	later := show("Later", band.ID, now.AddDate(0, 2, 0), "")
+	billed := show("Billed", other.ID, now.AddDate(0, 1, 0), "")
+	service.SetLineup(billed.ID, []LineupEntry{{ArtistID: other.ID}, {ArtistID: guest.ID}})
+	show("Past", band.ID, now.AddDate(0, -1, 0), "")
+	show("Draft", band.ID, now.AddDate(0, 1, 0), models.ShowDraft)
+	show("Not followed", other.ID, now.AddDate(0, 1, 0), "")
+
+	feed, err := service.GetFollowingFeed(fan.ID, 0, now)
+	assert.NoError(t, err)
+	assert.Len(t, feed.Artists, 2)
+	if assert.Len(t, feed.Shows, 2) {
+		assert.Equal(t, billed.ID, feed.Shows[0].ID)
+		assert.Equal(t, later.ID, feed.Shows[1].ID)
+	}
+
+	assert.NoError(t, service.UnfollowArtist(fan.ID, guest.ID))
+	assert.ErrorIs(t, service.UnfollowArtist(fan.ID, guest.ID), gorm.ErrRecordNotFound)
+	_, err = service.FollowArtist(fan.ID, guest.ID)
+	assert.NoError(t, err)
+}
+
+func TestShowAlertRecipients(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	band, _ := service.SetArtist(models.Artist{Name: "Band"})
+	guest, _ := service.SetArtist(models.Artist{Name: "Guest"})
+	var fans []models.User
+	for _, name := range []string{"a", "b", "c", "d"} {
+		fan := models.User{Email: name + "@test.com", Username: name, PasswordHash: "x"}
+		service.Db.Create(&fan)
+		fans = append(fans, fan)
+	}
+	service.FollowArtist(fans[0].ID, band.ID)
+	service.FollowArtist(fans[1].ID, band.ID)
+	service.FollowArtist(fans[1].ID, guest.ID)
+	service.FollowArtist(fans[2].ID, guest.ID)
+	service.FollowArtist(fans[3].ID, band.ID)
+	assert.NoError(t, service.SetShowAlerts(fans[3].ID, false))
+
+	now := time.Now()
+	show, _ := service.SetShow(models.Show{Title: "Night", ArtistID: band.ID, Venue: "Olympia", StartsAt: now.AddDate(0, 1, 0), TotalSeats: 10, Status: models.ShowDraft})
+	service.SetLineup(show.ID, []LineupEntry{{ArtistID: band.ID}, {ArtistID: guest.ID}})
+
+	// nothing is sent about a draft
+	users, err := service.ShowAlertRecipients(show.ID, 0, 10, now)
+	assert.NoError(t, err)
+	assert.Empty(t, users)
+
+	service.SetShowStatus(show.ID, ShowStatusChange{Status: models.ShowPublished}, now)
+	users, err = service.ShowAlertRecipients(show.ID, 0, 2, now)
+	assert.NoError(t, err)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, fans[0].ID, users[0].ID)
+		assert.Equal(t, fans[1].ID, users[1].ID)
+	}
+	// the next batch, without the fan who opted out
+	users, err = service.ShowAlertRecipients(show.ID, fans[1].ID, 2, now)
+	assert.NoError(t, err)
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, fans[2].ID, users[0].ID)
+	}
+
+	// announced once: a later alert starts nothing, the one under way goes on
+	assert.NoError(t, service.MarkShowAlerted(show.ID, now))
+	assert.NoError(t, service.MarkShowAlerted(show.ID, now.Add(time.Hour)))
+	service.SetShowStatus(show.ID, ShowStatusChange{Status: models.ShowDraft}, now)
+	service.SetShowStatus(show.ID, ShowStatusChange{Status: models.ShowPublished}, now)
+	users, _ = service.ShowAlertRecipients(show.ID, 0, 2, now)
+	assert.Empty(t, users)
+	users, _ = service.ShowAlertRecipients(show.ID, fans[1].ID, 2, now)
+	assert.Len(t, users, 1)
+	got, _ := service.GetShowByID(show.ID)
+	got, _ = service.SetShow(got)
+	if assert.NotNil(t, got.AlertedAt) {
+		assert.WithinDuration(t, now, *got.AlertedAt, time.Second)
+	}
+
+	users, err = service.ShowAlertRecipients(999, 0, 2, now)
+	assert.NoError(t, err)
+	assert.Empty(t, users)
+}
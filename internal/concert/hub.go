@@ -0,0 +1,133 @@
+package concert
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	AvailabilityChanged = "availability"
+	AvailabilitySoldOut = "sold_out"
+	ShowCancelled       = "cancelled"
+)
+
+var (
+	ErrHubClosed      = errors.New("availability hub is closed")
+	ErrTooManyClients = errors.New("too many clients listening")
+)
+
+type AvailabilityEvent struct {
+	Type           string    `json:"type"`
+	ShowID         uint      `json:"showId"`
+	AvailableSeats int       `json:"availableSeats"`
+	TotalSeats     int       `json:"totalSeats"`
+	At             time.Time `json:"at"`
+}
+
+type Subscription struct {
+	ShowID uint
+	C      chan AvailabilityEvent
+}
+
+// Hub is an in-process pub/sub of seat availability, keyed by show.
+// Publishing never blocks: a client that can't keep up loses older events,
+// which is fine because every event carries the full current count.
+type Hub struct {
+	mu         sync.Mutex
+	subs       map[uint]map[*Subscription]struct{}
+	total      int
+	closed     bool
+	maxPerShow int
+	maxTotal   int
+}
+
+func NewHub(maxPerShow, maxTotal int) *Hub {
+	return &Hub{
+		subs:       make(map[uint]map[*Subscription]struct{}),
+		maxPerShow: maxPerShow,
+		maxTotal:   maxTotal,
+	}
+}
+
+func (h *Hub) Subscribe(showID uint) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+	if h.total >= h.maxTotal || len(h.subs[showID]) >= h.maxPerShow {
+		return nil, ErrTooManyClients
+	}
+
+	sub := &Subscription{ShowID: showID, C: make(chan AvailabilityEvent, 8)}
+	if h.subs[showID] == nil {
+		h.subs[showID] = make(map[*Subscription]struct{})
+	}
+	h.subs[showID][sub] = struct{}{}
+	h.total++
+	return sub, nil
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[sub.ShowID][sub]; !ok {
+		return
+	}
+	delete(h.subs[sub.ShowID], sub)
+	if len(h.subs[sub.ShowID]) == 0 {
+		delete(h.subs, sub.ShowID)
+	}
+	h.total--
+	close(sub.C)
+}
+
+func (h *Hub) Publish(event AvailabilityEvent) {
+	if event.At.IsZero() {
+		event.At = time.Now().UTC()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs[event.ShowID] {
+		select {
+		case sub.C <- event:
+		default:
+			// drop the oldest queued event to make room for the newest one
+			select {
+			case <-sub.C:
+			default:
+			}
+			sub.C <- event
+		}
+	}
+}
+
+// Clients returns how many subscribers are listening to the show.
+func (h *Hub) Clients(showID uint) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs[showID])
+}
+
+// Close disconnects every subscriber, their channels are closed so streams can end.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for showID, subs := range h.subs {
+		for sub := range subs {
+			close(sub.C)
+		}
+		delete(h.subs, showID)
+	}
+	h.total = 0
+}
@@ -0,0 +1,68 @@
+package concert
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHubPublishToShowSubscribers(t *testing.T) {
+	hub := NewHub(10, 100)
+	first, err := hub.Subscribe(1)
+	assert.NoError(t, err)
+	other, err := hub.Subscribe(2)
+	assert.NoError(t, err)
+
+	hub.Publish(AvailabilityEvent{Type: AvailabilityChanged, ShowID: 1, AvailableSeats: 3})
+
+	event := <-first.C
+	assert.Equal(t, 3, event.AvailableSeats)
+	assert.False(t, event.At.IsZero())
+	assert.Len(t, other.C, 0)
+}
+
+func TestHubClientLimits(t *testing.T) {
+	hub := NewHub(1, 2)
+	sub, err := hub.Subscribe(1)
+	assert.NoError(t, err)
+
+	_, err = hub.Subscribe(1)
+	assert.ErrorIs(t, err, ErrTooManyClients)
+	_, err = hub.Subscribe(2)
+	assert.NoError(t, err)
+	_, err = hub.Subscribe(3)
+	assert.ErrorIs(t, err, ErrTooManyClients)
+
+	hub.Unsubscribe(sub)
+	assert.Equal(t, 0, hub.Clients(1))
+	_, err = hub.Subscribe(3)
+	assert.NoError(t, err)
+}
+
+func TestHubSlowClientKeepsLatestEvent(t *testing.T) {
+	hub := NewHub(10, 100)
+	sub, _ := hub.Subscribe(1)
+	for i := 0; i < 20; i++ {
+		hub.Publish(AvailabilityEvent{ShowID: 1, AvailableSeats: 100 - i})
+	}
+
+	var last AvailabilityEvent
+	for len(sub.C) > 0 {
+		last = <-sub.C
+	}
+	assert.Equal(t, 81, last.AvailableSeats)
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub(10, 100)
+	sub, _ := hub.Subscribe(1)
+
+	hub.Close()
+	_, ok := <-sub.C
+	assert.False(t, ok)
+
+	// unsubscribing after close must not close the channel twice
+	hub.Unsubscribe(sub)
+	_, err := hub.Subscribe(1)
+	assert.ErrorIs(t, err, ErrHubClosed)
+}
@@ -0,0 +1,586 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/payment"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ledger accounts, revenue:refunds reduces the ticket and fee revenue
+const (
+	AccountPayments       = "assets:payments"
+	AccountCustomerCredit = "liabilities:customer_credit"
+	AccountCreditHeld     = "liabilities:credit_held"
+	AccountGiftCards      = "liabilities:gift_cards"
+	AccountTicketRevenue  = "revenue:tickets"
+	AccountFeeRevenue     = "revenue:fees"
+	AccountSalesTax       = "liabilities:sales_tax"
+	AccountRefunds        = "revenue:refunds"
+	AccountGoodwill       = "expenses:goodwill"
+	AccountArtistsPayable = "liabilities:artists_payable"
+	AccountArtistFees     = "expenses:artist_fees"
+	AccountBank           = "assets:bank"
+)
+
+var ledgerAccounts = []models.LedgerAccount{
+	{Code: AccountPayments, Name: "Payments collected by the provider", Type: models.AccountAsset},
+	{Code: AccountCustomerCredit, Name: "Account credit of the fans", Type: models.AccountLiability},
+	{Code: AccountCreditHeld, Name: "Credit spent on bookings awaiting payment", Type: models.AccountLiability},
+	{Code: AccountGiftCards, Name: "Gift cards not redeemed yet", Type: models.AccountLiability},
+	{Code: AccountTicketRevenue, Name: "Ticket sales", Type: models.AccountRevenue},
+	{Code: AccountFeeRevenue, Name: "Booking fees", Type: models.AccountRevenue},
+	{Code: AccountSalesTax, Name: "Sales tax collected on tickets and fees", Type: models.AccountLiability},
+	{Code: AccountRefunds, Name: "Refunds and resale payouts", Type: models.AccountRevenue},
+	{Code: AccountGoodwill, Name: "Goodwill credit and gift cards given away", Type: models.AccountExpense},
+	{Code: AccountArtistsPayable, Name: "Approved settlements not paid to the artists yet", Type: models.AccountLiability},
+	{Code: AccountArtistFees, Name: "Artist shares of the settled shows", Type: models.AccountExpense},
+	{Code: AccountBank, Name: "Bank account the artists are paid from", Type: models.AccountAsset},
+}
+
+// creditCounterAccount is the other side of the credit ledger entries of each kind
+var creditCounterAccount = map[string]string{
+	models.CreditGiftCard:        AccountGiftCards,
+	models.CreditGoodwill:        AccountGoodwill,
+	models.CreditRefund:          AccountRefunds,
+	models.CreditBooking:         AccountCreditHeld,
+	models.CreditBookingReleased: AccountCreditHeld,
+}
+
+var ErrUnbalancedEntry = errors.New("ledger: postings don't balance")
+
+// posting is a line to record, amount in cents with debits positive
+type posting struct {
+	account string
+	amount  int64
+}
+
+func ledgerAccount(tx *gorm.DB, code string) (models.LedgerAccount, error) {
+	account := models.LedgerAccount{Code: code}
+	for _, known := range ledgerAccounts {
+		if known.Code == code {
+			account = known
+		}
+	}
+	if account.Type == "" {
+		return models.LedgerAccount{}, fmt.Errorf("ledger: unknown account %s", code)
+	}
+	err := tx.Where(models.LedgerAccount{Code: code}).Attrs(account).FirstOrCreate(&account).Error
+	return account, err
+}
+
+// postedAtSetting dates the entries posted through a session, to record past movements
+const postedAtSetting = "ledger:posted_at"
+
+// postJournal records entry with its postings within tx. An entry whose reference was
+// already recorded is skipped, so the callers can safely retry.
+func postJournal(tx *gorm.DB, entry models.JournalEntry, postings ...posting) error {
+	var sum int64
+	for _, p := range postings {
+		sum += p.amount
+	}
+	if sum != 0 {
+		return ErrUnbalancedEntry
+	}
+
+	var count int64
+	if err := tx.Model(&models.JournalEntry{}).Where("reference = ?", entry.Reference).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	for _, p := range postings {
+		if p.amount == 0 {
+			continue
+		}
+		account, err := ledgerAccount(tx, p.account)
+		if err != nil {
+			return err
+		}
+		entry.Postings = append(entry.Postings, models.Posting{AccountID: account.ID, Amount: p.amount})
+	}
+	if len(entry.Postings) == 0 {
+		return nil
+	}
+	if at, ok := tx.Get(postedAtSetting); ok && entry.PostedAt.IsZero() {
+		entry.PostedAt = at.(time.Time)
+	}
+	if entry.PostedAt.IsZero() {
+		entry.PostedAt = time.Now()
+	}
+	return tx.Create(&entry).Error
+}
+
+// RecordSale records the revenue and the tax of a confirmed booking, paid by the provider
+// and the credit held for it.
+func RecordSale(tx *gorm.DB, booking models.Booking) error {
+	total := payment.ToCents(booking.TotalPrice)
+	credit := payment.ToCents(booking.CreditApplied)
+	net, fees, tax := bookingBreakdown(booking)
+	return postJournal(tx, models.JournalEntry{
+		Kind:      models.JournalSale,
+		Reference: fmt.Sprintf("sale:booking:%d", booking.ID),
+		BookingID: &booking.ID,
+		ShowID:    &booking.ShowID,
+	},
+		posting{AccountPayments, total - credit},
+		posting{AccountCreditHeld, credit},
+		posting{AccountTicketRevenue, -net},
+		posting{AccountFeeRevenue, -fees},
+		posting{AccountSalesTax, -tax},
+	)
+}
+
+// recordRefund records amount given back through the payment provider for a booking,
+// the tax in it is owed no more
+func recordRefund(tx *gorm.DB, booking models.Booking, reference string, amount int64) error {
+	tax := refundTax(booking, amount)
+	return postJournal(tx, models.JournalEntry{
+		Kind:      models.JournalRefund,
+		Reference: reference,
+		BookingID: &booking.ID,
+		ShowID:    &booking.ShowID,
+	},
+		posting{AccountRefunds, amount - tax},
+		posting{AccountSalesTax, tax},
+		posting{AccountPayments, -amount},
+	)
+}
+
+// recordCredit records a line of the credit ledger against the account of its kind
+func recordCredit(tx *gorm.DB, entry models.CreditEntry) error {
+	journal := models.JournalEntry{
+		Kind:      models.JournalCredit,
+		Reference: fmt.Sprintf("credit:%d", entry.ID),
+		BookingID: entry.BookingID,
+		Memo:      entry.Kind,
+	}
+	amount := payment.ToCents(entry.Amount)
+	var tax int64
+	if entry.BookingID != nil {
+		var booking models.Booking
+		if err := tx.First(&booking, *entry.BookingID).Error; err != nil {
+			return err
+		}
+		journal.ShowID = &booking.ShowID
+		if entry.Kind == models.CreditRefund {
+			tax = refundTax(booking, amount)
+		}
+	}
+	return postJournal(tx, journal,
+		posting{AccountCustomerCredit, -amount},
+		posting{creditCounterAccount[entry.Kind], amount - tax},
+		posting{AccountSalesTax, tax},
+	)
+}
+
+func recordGiftCardSale(tx *gorm.DB, p models.Payment) error {
+	amount := payment.ToCents(p.Amount)
+	return postJournal(tx, models.JournalEntry{
+		Kind:      models.JournalGiftCardSale,
+		Reference: fmt.Sprintf("sale:giftcard:%d", *p.GiftCardID),
+	},
+		posting{AccountPayments, amount},
+		posting{AccountGiftCards, -amount},
+	)
+}
+
+// recordGiftCardIssue records a gift card issued by an admin, nobody paid for it
+func recordGiftCardIssue(tx *gorm.DB, card models.GiftCard) error {
+	amount := payment.ToCents(card.Amount)
+	return postJournal(tx, models.JournalEntry{
+		Kind:      models.JournalGiftCardIssue,
+		Reference: fmt.Sprintf("issue:giftcard:%d", card.ID),
+	},
+		posting{AccountGoodwill, amount},
+		posting{AccountGiftCards, -amount},
+	)
+}
+
+// AccountBalance is the balance of a ledger account in cents, debits positive.
+type AccountBalance struct {
+	Code    string  `json:"code"`
+	Name    string  `json:"name"`
+	Type    string  `json:"type"`
+	Debit   int64   `json:"debit"`
+	Credit  int64   `json:"credit"`
+	Balance int64   `json:"balance"`
+	Amount  float64 `json:"amount"`
+}
+
+// LedgerFilter restricts the postings of a report, zero values don't filter.
+type LedgerFilter struct {
+	ShowID   uint
+	ArtistID uint
+	// FestivalID keeps the passes of a festival
+	FestivalID uint
+	From       time.Time
+	To         time.Time
+}
+
+// LedgerReport is the activity of the accounts over a filter. GrossSales are the tickets and
+// fees before tax, NetRevenue is what is left after refunds and payouts and Taxes the tax
+// owed, in currency units like the bookings.
+type LedgerReport struct {
+	Accounts     []AccountBalance `json:"accounts"`
+	GrossSales   float64          `json:"grossSales"`
+	Fees         float64          `json:"fees"`
+	Refunds      float64          `json:"refunds"`
+	NetRevenue   float64          `json:"netRevenue"`
+	Taxes        float64          `json:"taxes"`
+	Reconciled   bool             `json:"reconciled"`
+	FromBookings float64          `json:"fromBookings"`
+}
+
+func (s Service) accountBalances(filter LedgerFilter) ([]AccountBalance, error) {
+	query := s.Db.Table("postings").
+		Select("ledger_accounts.code, ledger_accounts.name, ledger_accounts.type, " +
+			"COALESCE(SUM(CASE WHEN postings.amount > 0 THEN postings.amount ELSE 0 END), 0) AS debit, " +
+			"COALESCE(SUM(CASE WHEN postings.amount < 0 THEN -postings.amount ELSE 0 END), 0) AS credit, " +
+			"COALESCE(SUM(postings.amount), 0) AS balance").
+		Joins("JOIN ledger_accounts ON ledger_accounts.id = postings.account_id").
+		Joins("JOIN journal_entries ON journal_entries.id = postings.journal_entry_id").
+		Group("ledger_accounts.code, ledger_accounts.name, ledger_accounts.type").
+		Order("ledger_accounts.code")
+	if filter.ShowID != 0 {
+		query = query.Where("journal_entries.show_id = ?", filter.ShowID)
+	}
+	if filter.ArtistID != 0 {
+		query = query.Where("journal_entries.show_id IN (?)",
+			s.Db.Model(&models.Show{}).Select("id").Where("artist_id = ?", filter.ArtistID))
+	}
+	if filter.FestivalID != 0 {
+		query = query.Where("journal_entries.booking_id IN (?)",
+			s.Db.Model(&models.Booking{}).Select("id").Where("festival_id = ?", filter.FestivalID))
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("journal_entries.posted_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("journal_entries.posted_at < ?", filter.To)
+	}
+
+	var balances []AccountBalance
+	if err := query.Scan(&balances).Error; err != nil {
+		return nil, err
+	}
+	for i := range balances {
+		balances[i].Amount = payment.FromCents(balances[i].Balance)
+	}
+	if balances == nil {
+		balances = []AccountBalance{}
+	}
+	return balances, nil
+}
+
+// TrialBalance lists the balance of every account, they sum to zero.
+func (s Service) TrialBalance() ([]AccountBalance, error) {
+	return s.accountBalances(LedgerFilter{})
+}
+
+// GetLedgerReport sums the ledger over filter. Without a period, the net revenue and the tax
+// are checked against what the bookings of the same shows kept, FromBookings.
+func (s Service) GetLedgerReport(filter LedgerFilter) (LedgerReport, error) {
+	balances, err := s.accountBalances(filter)
+	if err != nil {
+		return LedgerReport{}, err
+	}
+	report := LedgerReport{Accounts: balances}
+	var tickets, fees, refunds, taxes int64
+	for _, b := range balances {
+		switch b.Code {
+		case AccountTicketRevenue:
+			tickets = -b.Balance
+		case AccountFeeRevenue:
+			fees = -b.Balance
+		case AccountRefunds:
+			refunds = b.Balance
+		case AccountSalesTax:
+			taxes = -b.Balance
+		}
+	}
+	gross := tickets + fees
+	report.GrossSales = payment.FromCents(gross)
+	report.Fees = payment.FromCents(fees)
+	report.Refunds = payment.FromCents(refunds)
+	report.NetRevenue = payment.FromCents(gross - refunds)
+	report.Taxes = payment.FromCents(taxes)
+
+	if filter.From.IsZero() && filter.To.IsZero() {
+		kept, err := s.bookingsRevenue(filter)
+		if err != nil {
+			return LedgerReport{}, err
+		}
+		report.FromBookings = payment.FromCents(kept)
+		// the bookings kept the tax they collected
+		report.Reconciled = kept == gross-refunds+taxes
+	}
+	return report, nil
+}
+
+// bookingsRevenue is what the confirmed bookings kept after refunds: the price of the
+// seats still held, of the cancelled ones less their refund, and of the resold ones
+// less the payout of the seller.
+func (s Service) bookingsRevenue(filter LedgerFilter) (int64, error) {
+	bookings := s.Db.Model(&models.Booking{}).Where("confirmed_at IS NOT NULL")
+	listings := s.Db.Model(&models.ResaleListing{}).Where("status = ?", models.ResaleSold)
+	if filter.ShowID != 0 {
+		bookings = bookings.Where("show_id = ?", filter.ShowID)
+		listings = listings.Where("show_id = ?", filter.ShowID)
+	}
+	if filter.ArtistID != 0 {
+		shows := s.Db.Model(&models.Show{}).Select("id").Where("artist_id = ?", filter.ArtistID)
+		bookings = bookings.Where("show_id IN (?)", shows)
+		listings = listings.Where("show_id IN (?)", shows)
+	}
+	if filter.FestivalID != 0 {
+		bookings = bookings.Where("festival_id = ?", filter.FestivalID)
+		listings = listings.Where("booking_id IN (?)",
+			s.Db.Model(&models.Booking{}).Select("id").Where("festival_id = ?", filter.FestivalID))
+	}
+
+	var rows []models.Booking
+	if err := bookings.Select("id, total_price, refund_amount, refund_status").Find(&rows).Error; err != nil {
+		return 0, err
+	}
+	var kept int64
+	for _, b := range rows {
+		kept += payment.ToCents(b.TotalPrice)
+		// failed refunds were given back, pending ones aren't recorded yet
+		if b.RefundStatus == RefundStatusRefunded {
+			kept -= payment.ToCents(b.RefundAmount)
+		}
+	}
+
+	var sold []models.ResaleListing
+	if err := listings.Find(&sold).Error; err != nil {
+		return 0, err
+	}
+	for _, l := range sold {
+		// the seats left the seller's booking at face value
+		kept += payment.ToCents(l.FaceValue) * int64(l.TicketCount)
+		if l.PayoutStatus == RefundStatusRefunded {
+			kept -= payment.ToCents(l.Payout)
+		}
+	}
+	return kept, nil
+}
+
+// LedgerCheck compares an account of the ledger with the table it mirrors.
+type LedgerCheck struct {
+	Name     string  `json:"name"`
+	Ledger   float64 `json:"ledger"`
+	Expected float64 `json:"expected"`
+	OK       bool    `json:"ok"`
+}
+
+// CheckLedger verifies that the ledger balances and agrees with the payments,
+// the account credit, the gift cards and the settlements owed to the artists.
+func (s Service) CheckLedger() ([]LedgerCheck, error) {
+	balances, err := s.TrialBalance()
+	if err != nil {
+		return nil, err
+	}
+	balance := map[string]int64{}
+	var total int64
+	for _, b := range balances {
+		balance[b.Code] = b.Balance
+		total += b.Balance
+	}
+
+	var collected, credit, giftCards, payable float64
+	if err := s.Db.Model(&models.Payment{}).
+		Where("status IN ?", []string{models.PaymentSucceeded, models.PaymentRefunded}).
+		Select("COALESCE(SUM(amount - refunded), 0)").Scan(&collected).Error; err != nil {
+		return nil, err
+	}
+	if err := s.Db.Model(&models.User{}).Select("COALESCE(SUM(credit_balance), 0)").Scan(&credit).Error; err != nil {
+		return nil, err
+	}
+	if err := s.Db.Model(&models.GiftCard{}).Where("status = ?", models.GiftCardActive).
+		Select("COALESCE(SUM(amount), 0)").Scan(&giftCards).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.Db.Model(&models.Settlement{}).Where("status = ?", models.SettlementApproved).
+		Select("COALESCE(SUM(artist_share), 0)").Scan(&payable).Error; err != nil {
+		return nil, err
+	}
+
+	check := func(name string, ledger, expected int64) LedgerCheck {
+		return LedgerCheck{Name: name, Ledger: payment.FromCents(ledger), Expected: payment.FromCents(expected), OK: ledger == expected}
+	}
+	return []LedgerCheck{
+		check("balanced", total, 0),
+		check(AccountPayments, balance[AccountPayments], payment.ToCents(collected)),
+		check(AccountCustomerCredit, -balance[AccountCustomerCredit], payment.ToCents(credit)),
+		check(AccountGiftCards, -balance[AccountGiftCards], payment.ToCents(giftCards)),
+		check(AccountArtistsPayable, -balance[AccountArtistsPayable], payment.ToCents(payable)),
+	}, nil
+}
+
+// ListJournalEntries lists the latest entries of a booking, or of every booking when bookingID is zero.
+func (s Service) ListJournalEntries(bookingID uint, limit int) ([]models.JournalEntry, error) {
+	query := s.Db.Preload("Postings.Account").Order("id DESC").Limit(limit)
+	if bookingID != 0 {
+		query = query.Where("booking_id = ?", bookingID)
+	}
+	var entries []models.JournalEntry
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// BackfillLedger records the money that moved before the ledger existed: the sales, refunds
+// and resale payouts of the bookings, the account credit and the gift cards, each posted at
+// the time it happened. The bookings confirmed back then get their creation time as
+// confirmation time. It runs once, while the journal is still empty: everything since is
+// posted when it happens.
+func BackfillLedger(db *gorm.DB) (int, error) {
+	if posted := db.Model(&models.JournalEntry{}).Select("id").Limit(1).Find(&[]models.JournalEntry{}); posted.Error != nil {
+		return 0, posted.Error
+	} else if posted.RowsAffected > 0 {
+		return 0, nil
+	}
+	missing := func(query *gorm.DB, reference string) *gorm.DB {
+		return query.Where("NOT EXISTS (SELECT 1 FROM journal_entries WHERE journal_entries.reference = " + reference + ")")
+	}
+	count := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		postedAt := func(at time.Time) *gorm.DB {
+			return tx.Set(postedAtSetting, at).Session(&gorm.Session{})
+		}
+		// a cancelled booking was confirmed if its refund began or it was paid, the others
+		// were cancelled during their checkout
+		if err := tx.Model(&models.Booking{}).
+			Where("confirmed_at IS NULL").
+			Where("status IN ? OR (status = ? AND (refund_status <> '' OR id IN (?)))",
+				[]string{"confirmed", "resold", "refunding"}, "cancelled",
+				tx.Model(&models.Payment{}).Select("booking_id").
+					Where("status IN ?", []string{models.PaymentSucceeded, models.PaymentRefunded})).
+			UpdateColumn("confirmed_at", gorm.Expr("created_at")).Error; err != nil {
+			return err
+		}
+
+		var sales []models.Booking
+		if err := missing(tx.Where("confirmed_at IS NOT NULL AND (total_price > 0 OR status = ?)", "resold"), "'sale:booking:' || bookings.id").
+			Find(&sales).Error; err != nil {
+			return err
+		}
+		for _, booking := range sales {
+			// the seats resold left the booking, they were sold with it
+			var resold float64
+			if err := tx.Model(&models.ResaleListing{}).
+				Select("COALESCE(SUM(face_value * ticket_count), 0)").
+				Where("booking_id = ? AND status = ?", booking.ID, models.ResaleSold).
+				Scan(&resold).Error; err != nil {
+				return err
+			}
+			sold := booking
+			if resold > 0 {
+				total := payment.ToCents(booking.TotalPrice) + payment.ToCents(resold)
+				if current := payment.ToCents(booking.TotalPrice); current > 0 {
+					sold.FeeAmount = payment.FromCents(payment.ToCents(booking.FeeAmount) * total / current)
+					sold.TaxAmount = payment.FromCents(payment.ToCents(booking.TaxAmount) * total / current)
+				}
+				sold.TotalPrice = payment.FromCents(total)
+			}
+			if err := RecordSale(postedAt(*booking.ConfirmedAt), sold); err != nil {
+				return err
+			}
+		}
+
+		var refunds []models.Booking
+		if err := missing(tx.Where("refund_status = ? AND refund_amount > refund_credit", RefundStatusRefunded), "'refund:booking:' || bookings.id").
+			Find(&refunds).Error; err != nil {
+			return err
+		}
+		for _, booking := range refunds {
+			amount := payment.ToCents(booking.RefundAmount) - payment.ToCents(booking.RefundCredit)
+			if err := recordRefund(postedAt(booking.UpdatedAt), booking,
+				fmt.Sprintf("refund:booking:%d", booking.ID), amount); err != nil {
+				return err
+			}
+		}
+
+		var payouts []models.ResaleListing
+		if err := missing(tx.Where("status = ? AND payout_status = ? AND payout > 0", models.ResaleSold, RefundStatusRefunded),
+			"'payout:resale:' || resale_listings.id").Order("id").Find(&payouts).Error; err != nil {
+			return err
+		}
+		// the part of the payouts the payment couldn't take back went to the credit
+		credited := map[uint]int64{}
+		for _, listing := range payouts {
+			if _, ok := credited[listing.BookingID]; !ok {
+				var credit float64
+				if err := tx.Model(&models.CreditEntry{}).Select("COALESCE(SUM(amount), 0)").
+					Where("booking_id = ? AND kind = ? AND note = ?", listing.BookingID, models.CreditRefund, "resale payout").
+					Scan(&credit).Error; err != nil {
+					return err
+				}
+				credited[listing.BookingID] = payment.ToCents(credit)
+			}
+			payout := payment.ToCents(listing.Payout)
+			toCredit := min(payout, credited[listing.BookingID])
+			credited[listing.BookingID] -= toCredit
+			var seller models.Booking
+			if err := tx.First(&seller, listing.BookingID).Error; err != nil {
+				return err
+			}
+			at := listing.UpdatedAt
+			if listing.SoldAt != nil {
+				at = *listing.SoldAt
+			}
+			if err := recordRefund(postedAt(at), seller,
+				fmt.Sprintf("payout:resale:%d", listing.ID), payout-toCredit); err != nil {
+				return err
+			}
+		}
+
+		var credits []models.CreditEntry
+		if err := missing(tx.Model(&models.CreditEntry{}), "'credit:' || credit_entries.id").
+			Find(&credits).Error; err != nil {
+			return err
+		}
+		for _, entry := range credits {
+			if err := recordCredit(postedAt(entry.CreatedAt), entry); err != nil {
+				return err
+			}
+		}
+
+		var giftCardSales []models.Payment
+		if err := missing(tx.Where("gift_card_id IS NOT NULL AND status = ?", models.PaymentSucceeded),
+			"'sale:giftcard:' || payments.gift_card_id").Find(&giftCardSales).Error; err != nil {
+			return err
+		}
+		for _, p := range giftCardSales {
+			if err := recordGiftCardSale(postedAt(p.CreatedAt), p); err != nil {
+				return err
+			}
+		}
+
+		var issued []models.GiftCard
+		if err := missing(tx.Where("issued_by_id IS NOT NULL"), "'issue:giftcard:' || gift_cards.id").
+			Find(&issued).Error; err != nil {
+			return err
+		}
+		for _, card := range issued {
+			if err := recordGiftCardIssue(postedAt(card.CreatedAt), card); err != nil {
+				return err
+			}
+		}
+
+		count = len(sales) + len(refunds) + len(payouts) + len(credits) + len(giftCardSales) + len(issued)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,165 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func assertLedgerChecks(t *testing.T, service *Service) {
+	t.Helper()
+	checks, err := service.CheckLedger()
+	assert.NoError(t, err)
+	for _, check := range checks {
+		assert.True(t, check.OK, "%s: ledger %.2f, expected %.2f", check.Name, check.Ledger, check.Expected)
+	}
+}
+
+func TestPostJournal(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+
+	err := postJournal(service.Db, models.JournalEntry{Kind: models.JournalSale, Reference: "test:1"},
+		posting{AccountPayments, 100}, posting{AccountTicketRevenue, -90})
+	assert.ErrorIs(t, err, ErrUnbalancedEntry)
+
+	for range 2 {
+		assert.NoError(t, postJournal(service.Db, models.JournalEntry{Kind: models.JournalSale, Reference: "test:1"},
+			posting{AccountPayments, 100}, posting{AccountTicketRevenue, -100}))
+	}
+	entries, _ := service.ListJournalEntries(0, 10)
+	assert.Len(t, entries, 1)
+	assert.Len(t, entries[0].Postings, 2)
+}
+
+func TestLedgerFollowsBookingAndRefund(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	show, booking := createPaidBooking(t, service)
+	// half refund from now on
+	service.Db.Model(&show).Updates(map[string]any{"cancel_full_refund_days": 60, "cancel_partial_refund_percent": 50})
+
+	report, err := service.GetLedgerReport(LedgerFilter{ShowID: show.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, 80.0, report.GrossSales)
+	assert.Equal(t, 80.0, report.NetRevenue)
+	assert.True(t, report.Reconciled)
+	assertLedgerChecks(t, service)
+
+	quote, _ := service.QuoteRefund(booking, time.Now())
+	assert.Equal(t, 40.0, quote.Amount)
+	assert.NoError(t, service.BeginRefund(booking.ID, quote.Amount, 0))
+	refundID, err := service.IssueRefund(booking.ID, quote.Amount)
+	assert.NoError(t, err)
+	assert.NoError(t, service.CompleteRefund(booking.ID, refundID))
+	// retried activities don't post twice
+	assert.NoError(t, service.CompleteRefund(booking.ID, refundID))
+
+	report, _ = service.GetLedgerReport(LedgerFilter{ShowID: show.ID})
+	assert.Equal(t, 40.0, report.Refunds)
+	assert.Equal(t, 40.0, report.NetRevenue)
+	assert.Equal(t, 40.0, report.FromBookings)
+	assert.True(t, report.Reconciled)
+	assertLedgerChecks(t, service)
+
+	// nothing was posted in the future
+	report, _ = service.GetLedgerReport(LedgerFilter{From: time.Now().Add(time.Hour)})
+	assert.Equal(t, 0.0, report.GrossSales)
+}
+
+func TestLedgerFollowsCreditAndResale(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	seller := models.User{Username: "seller", Email: "seller@example.com"}
+	buyer := models.User{Username: "buyer", Email: "buyer@example.com"}
+	admin := models.User{Username: "admin", Email: "admin@example.com", Role: "admin"}
+	service.Db.Create(&seller)
+	service.Db.Create(&buyer)
+	service.Db.Create(&admin)
+
+	card, err := service.IssueGiftCard(30, admin, "", "", nil)
+	assert.NoError(t, err)
+	_, err = service.RedeemGiftCard(card.Code, seller, time.Now())
+	assert.NoError(t, err)
+
+	show := models.Show{Title: "Ledger", Venue: "Metz", StartsAt: time.Now().AddDate(0, 1, 0), Price: 40, TotalSeats: 10, AvailableSeats: 10}
+	service.Db.Create(&show)
+	booking := models.Booking{ShowID: show.ID, UserID: seller.ID, TicketCount: 2, TotalPrice: 80, Status: "pending", CreditApplied: 30}
+	service.Db.Create(&booking)
+	assert.NoError(t, SpendCredit(service.Db, booking))
+	_, _, err = service.StartPayment(booking)
+	assert.NoError(t, err)
+	booking, _, err = service.SimulatePayment(booking)
+	assert.NoError(t, err)
+	assert.Equal(t, "confirmed", booking.Status)
+	assertLedgerChecks(t, service)
+
+	listing, err := service.CreateResaleListing(booking, seller, 1, 40)
+	assert.NoError(t, err)
+	bought, err := service.BuyResaleListing(listing.ID, buyer)
+	assert.NoError(t, err)
+	_, _, err = service.StartPayment(bought)
+	assert.NoError(t, err)
+	_, _, err = service.SimulatePayment(bought)
+	assert.NoError(t, err)
+
+	report, err := service.GetLedgerReport(LedgerFilter{ShowID: show.ID})
+	assert.NoError(t, err)
+	// 80 from the seller, 40 from the buyer, 36 paid out to the seller
+	assert.Equal(t, 120.0, report.GrossSales)
+	assert.Equal(t, 84.0, report.NetRevenue)
+	assert.True(t, report.Reconciled, "ledger %.2f, bookings %.2f", report.NetRevenue, report.FromBookings)
+	assertLedgerChecks(t, service)
+
+	balances, _ := service.TrialBalance()
+	var total int64
+	for _, b := range balances {
+		total += b.Balance
+	}
+	assert.Equal(t, int64(0), total)
+}
+
+func TestBackfillLedger(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	show := models.Show{Title: "Paid", Venue: "Lille", StartsAt: time.Now(), Price: 40, TotalSeats: 10, AvailableSeats: 4}
+	service.Db.Create(&show)
+	sold := time.Now().AddDate(0, -1, 0)
+	// bookings from before the ledger, without a confirmation time
+	kept := models.Booking{ShowID: show.ID, UserID: 1, TicketCount: 2, TotalPrice: 80, Status: "confirmed"}
+	refunded := models.Booking{ShowID: show.ID, UserID: 2, TicketCount: 2, TotalPrice: 80, Status: "cancelled",
+		RefundAmount: 40, RefundStatus: RefundStatusRefunded}
+	abandoned := models.Booking{ShowID: show.ID, UserID: 3, TicketCount: 2, TotalPrice: 80, Status: "cancelled"}
+	for _, booking := range []*models.Booking{&kept, &refunded, &abandoned} {
+		booking.CreatedAt, booking.UpdatedAt = sold, sold
+		service.Db.Create(booking)
+	}
+	service.Db.Create(&models.Payment{BookingID: kept.ID, IntentID: "pi_1", Amount: 80, Status: models.PaymentSucceeded})
+	service.Db.Create(&models.Payment{BookingID: refunded.ID, IntentID: "pi_2", Amount: 80, Refunded: 40, Status: models.PaymentRefunded})
+
+	n, err := BackfillLedger(service.Db)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	got, _ := service.GetBookingById(kept.ID)
+	assert.WithinDuration(t, sold, *got.ConfirmedAt, time.Second)
+	got, _ = service.GetBookingById(abandoned.ID)
+	assert.Nil(t, got.ConfirmedAt)
+
+	report, err := service.GetLedgerReport(LedgerFilter{ShowID: show.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, 160.0, report.GrossSales)
+	assert.Equal(t, 120.0, report.NetRevenue)
+	assert.True(t, report.Reconciled)
+	assertLedgerChecks(t, service)
+	// posted when the money moved
+	report, _ = service.GetLedgerReport(LedgerFilter{From: time.Now().AddDate(0, 0, -1)})
+	assert.Equal(t, 0.0, report.GrossSales)
+	assert.Equal(t, 0.0, report.Refunds)
+
+	// it runs once, the journal isn't scanned again on the next starts
+	late := models.Booking{ShowID: show.ID, UserID: 4, TicketCount: 1, TotalPrice: 40, Status: "confirmed"}
+	service.Db.Create(&late)
+	n, err = BackfillLedger(service.Db)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	got, _ = service.GetBookingById(late.ID)
+	assert.Nil(t, got.ConfirmedAt)
+}
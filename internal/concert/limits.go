@@ -0,0 +1,204 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrNotEnoughSeats = errors.New("not enough seats available")
+
+const (
+	LimitPerOrder       = "order"
+	LimitPerUser        = "user"
+	LimitPerEmailDomain = "email_domain"
+	LimitPerInstrument  = "instrument"
+)
+
+// TicketLimitError explains which limit a booking request hits and how many tickets are still allowed.
+type TicketLimitError struct {
+	Scope     string `json:"scope"`
+	Limit     int    `json:"limit"`
+	Remaining int    `json:"remaining"`
+}
+
+func (e *TicketLimitError) Error() string {
+	switch e.Scope {
+	case LimitPerOrder:
+		return fmt.Sprintf("at most %d tickets can be bought in a single order", e.Limit)
+	case LimitPerEmailDomain:
+		return fmt.Sprintf("at most %d tickets can be bought by accounts of the same email domain, %d left", e.Limit, e.Remaining)
+	case LimitPerInstrument:
+		return fmt.Sprintf("at most %d tickets can be paid with the same card, %d left", e.Limit, e.Remaining)
+	default:
+		return fmt.Sprintf("at most %d tickets per person for this show, you can still buy %d", e.Limit, e.Remaining)
+	}
+}
+
+// webmail domains are shared by everyone, a per domain limit only makes sense for the others
+var publicEmailDomains = map[string]bool{
+	"gmail.com":      true,
+	"googlemail.com": true,
+	"yahoo.com":      true,
+	"yahoo.fr":       true,
+	"hotmail.com":    true,
+	"hotmail.fr":     true,
+	"outlook.com":    true,
+	"live.com":       true,
+	"icloud.com":     true,
+	"orange.fr":      true,
+	"free.fr":        true,
+	"proton.me":      true,
+}
+
+func emailDomain(email string) string {
+	_, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	if !ok {
+		return ""
+	}
+	return domain
+}
+
+// heldTicketStatuses are the booking statuses holding seats, pending ones wait for their
+// payment and refunding ones for their refund
+var heldTicketStatuses = []string{"confirmed", "pending", "refunding"}
+
+// CheckTicketLimits verifies a new order of count tickets against the limits of the show,
+// taking into account what the user already holds and any admin override.
+func (s Service) CheckTicketLimits(show models.Show, user models.User, count int) error {
+	return checkTicketLimits(s.Db, show, user, count)
+}
+
+// LockTicketLimits checks the limits and the seats again inside the transaction creating
+// the booking, holding the row lock of the show: concurrent orders of the show are checked
+// one at a time and can't pass the limits or take the last seats together. It returns the
+// locked show, its AvailableSeats counted from the bookings.
+func LockTicketLimits(tx *gorm.DB, show models.Show, user models.User, count int) (models.Show, error) {
+	locked, err := lockShow(tx, show.ID)
+	if err != nil {
+		return locked, err
+	}
+	if err := checkTicketLimits(tx, locked, user, count); err != nil {
+		return locked, err
+	}
+	locked.AvailableSeats = locked.TotalSeats - heldSeats(tx, locked)
+	if locked.AvailableSeats < count {
+		return locked, ErrNotEnoughSeats
+	}
+	return locked, nil
+}
+
+func lockShow(tx *gorm.DB, showID uint) (models.Show, error) {
+	var show models.Show
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&show, showID).Error
+	return show, err
+}
+
+func hasLimitOverride(db *gorm.DB, showID, userID uint) (models.TicketLimitOverride, bool) {
+	var override models.TicketLimitOverride
+	err := db.Where("show_id = ? AND user_id = ?", showID, userID).First(&override).Error
+	return override, err == nil
+}
+
+func checkTicketLimits(db *gorm.DB, show models.Show, user models.User, count int) error {
+	override, hasOverride := hasLimitOverride(db, show.ID, user.ID)
+
+	perUser := show.MaxTicketsPerUser
+	perOrder := show.MaxTicketsPerOrder
+	if hasOverride {
+		// an override raises both the per person and per order limits for that user
+		perUser = override.MaxTickets
+		perOrder = override.MaxTickets
+	}
+
+	if perOrder > 0 && count > perOrder {
+		return &TicketLimitError{Scope: LimitPerOrder, Limit: perOrder, Remaining: perOrder}
+	}
+
+	if perUser > 0 {
+		var held int64
+		if err := db.Model(&models.Booking{}).
+			Where("show_id = ? AND user_id = ? AND status IN ?", show.ID, user.ID, heldTicketStatuses).
+			Select("COALESCE(SUM(ticket_count), 0)").
+			Scan(&held).Error; err != nil {
+			return err
+		}
+		if int(held)+count > perUser {
+			return &TicketLimitError{Scope: LimitPerUser, Limit: perUser, Remaining: max(perUser-int(held), 0)}
+		}
+	}
+
+	domain := emailDomain(user.Email)
+	if show.MaxTicketsPerEmailDomain > 0 && !hasOverride && domain != "" && !publicEmailDomains[domain] {
+		var held int64
+		if err := db.Model(&models.Booking{}).
+			Joins("JOIN users ON users.id = bookings.user_id").
+			Where(`bookings.show_id = ? AND bookings.status IN ? AND LOWER(users.email) LIKE ? ESCAPE '\'`, show.ID, heldTicketStatuses, "%@"+likeEscape(domain)).
+			Select("COALESCE(SUM(bookings.ticket_count), 0)").
+			Scan(&held).Error; err != nil {
+			return err
+		}
+		limit := show.MaxTicketsPerEmailDomain
+		if int(held)+count > limit {
+			return &TicketLimitError{Scope: LimitPerEmailDomain, Limit: limit, Remaining: max(limit-int(held), 0)}
+		}
+	}
+	return nil
+}
+
+// checkInstrumentLimit verifies, once a booking is paid, the tickets of the show paid with
+// the same instrument, which is only known then. The caller holds the row lock of the show.
+func checkInstrumentLimit(tx *gorm.DB, show models.Show, booking models.Booking, instrument string) error {
+	limit := show.MaxTicketsPerInstrument
+	if limit == 0 || instrument == "" {
+		return nil
+	}
+	if _, hasOverride := hasLimitOverride(tx, show.ID, booking.UserID); hasOverride {
+		return nil
+	}
+	paid := tx.Model(&models.Payment{}).Select("booking_id").Where("instrument = ? AND status = ?", instrument, models.PaymentSucceeded)
+	var held int64
+	if err := tx.Model(&models.Booking{}).
+		Where("show_id = ? AND status = ? AND id IN (?)", show.ID, "confirmed", paid).
+		Select("COALESCE(SUM(ticket_count), 0)").
+		Scan(&held).Error; err != nil {
+		return err
+	}
+	if int(held)+booking.TicketCount > limit {
+		return &TicketLimitError{Scope: LimitPerInstrument, Limit: limit, Remaining: max(limit-int(held), 0)}
+	}
+	return nil
+}
+
+func (s Service) ListLimitOverrides(showID uint) ([]models.TicketLimitOverride, error) {
+	var overrides []models.TicketLimitOverride
+	if err := s.Db.Preload("User").Where("show_id = ?", showID).Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// SetLimitOverride creates or replaces the override of a user for a show.
+func (s Service) SetLimitOverride(override models.TicketLimitOverride) (models.TicketLimitOverride, error) {
+	var existing models.TicketLimitOverride
+	if err := s.Db.Where("show_id = ? AND user_id = ?", override.ShowID, override.UserID).First(&existing).Error; err == nil {
+		override.ID = existing.ID
+		override.CreatedAt = existing.CreatedAt
+	}
+	if err := s.Db.Omit("User").Save(&override).Error; err != nil {
+		return models.TicketLimitOverride{}, err
+	}
+	return override, nil
+}
+
+func (s Service) DeleteLimitOverride(id uint) error {
+	// hard delete, the (show, user) pair must be reusable
+	if result := s.Db.Unscoped().Delete(&models.TicketLimitOverride{}, id); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCheckTicketLimits(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	show := models.Show{Title: "Limited", Venue: "Paris", StartsAt: time.Now(), MaxTicketsPerUser: 4, MaxTicketsPerOrder: 3}
+	db.Create(&show)
+	user := models.User{Email: "fan@example.com", Username: "fan"}
+	db.Create(&user)
+	db.Create(&models.Booking{UserID: user.ID, ShowID: show.ID, TicketCount: 2, Status: "confirmed"})
+	// cancelled bookings don't count
+	db.Create(&models.Booking{UserID: user.ID, ShowID: show.ID, TicketCount: 3, Status: "cancelled"})
+
+	var limitErr *TicketLimitError
+	err := service.CheckTicketLimits(show, user, 4)
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, LimitPerOrder, limitErr.Scope)
+
+	err = service.CheckTicketLimits(show, user, 3)
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, LimitPerUser, limitErr.Scope)
+	assert.Equal(t, 2, limitErr.Remaining)
+
+	assert.NoError(t, service.CheckTicketLimits(show, user, 2))
+
+	_, err = service.SetLimitOverride(models.TicketLimitOverride{ShowID: show.ID, UserID: user.ID, MaxTickets: 10})
+	assert.NoError(t, err)
+	assert.NoError(t, service.CheckTicketLimits(show, user, 8))
+}
+
+func TestCheckTicketLimitsPerEmailDomain(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	show := models.Show{Title: "Limited", Venue: "Paris", StartsAt: time.Now(), MaxTicketsPerEmailDomain: 5}
+	db.Create(&show)
+	first := models.User{Email: "a@resell.biz", Username: "a"}
+	second := models.User{Email: "b@Resell.biz", Username: "b"}
+	webmail := models.User{Email: "c@gmail.com", Username: "c"}
+	db.Create(&first)
+	db.Create(&second)
+	db.Create(&webmail)
+	db.Create(&models.Booking{UserID: first.ID, ShowID: show.ID, TicketCount: 4, Status: "confirmed"})
+	db.Create(&models.Booking{UserID: webmail.ID, ShowID: show.ID, TicketCount: 4, Status: "confirmed"})
+
+	var limitErr *TicketLimitError
+	err := service.CheckTicketLimits(show, second, 2)
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, LimitPerEmailDomain, limitErr.Scope)
+	assert.Equal(t, 1, limitErr.Remaining)
+
+	assert.NoError(t, service.CheckTicketLimits(show, webmail, 4))
+
+	// the domain is matched as it is, not as a pattern
+	wildcard := models.User{Email: "d@re_ell.biz", Username: "d"}
+	db.Create(&wildcard)
+	assert.NoError(t, service.CheckTicketLimits(show, wildcard, 5))
+}
+
+func TestLockTicketLimits(t *testing.T) {
+	db := SetupTestDB()
+
+	show := models.Show{Title: "Limited", Venue: "Paris", StartsAt: time.Now(), MaxTicketsPerUser: 4}
+	db.Create(&show)
+	user := models.User{Email: "fan@example.com", Username: "fan"}
+	db.Create(&user)
+
+	// an order created since the show was read counts too
+	db.Create(&models.Booking{UserID: user.ID, ShowID: show.ID, TicketCount: 3, Status: "pending"})
+	var limitErr *TicketLimitError
+	err := db.Transaction(func(tx *gorm.DB) error {
+		_, err := LockTicketLimits(tx, show, user, 2)
+		return err
+	})
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, 1, limitErr.Remaining)
+
+	// nor can an order take the seats another one took since the show was read
+	db.Model(&show).Updates(map[string]any{"total_seats": 5, "available_seats": 5})
+	show.TotalSeats, show.AvailableSeats = 5, 5
+	other := models.User{Email: "other@example.com", Username: "other"}
+	db.Create(&other)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, err := LockTicketLimits(tx, show, other, 3)
+		return err
+	})
+	assert.ErrorIs(t, err, ErrNotEnoughSeats)
+	db.Transaction(func(tx *gorm.DB) error {
+		locked, err := LockTicketLimits(tx, show, other, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, locked.AvailableSeats)
+		return err
+	})
+}
+
+func TestInstrumentLimitRefundsPayment(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	show := models.Show{Title: "Limited", Venue: "Paris", StartsAt: time.Now(), Price: 20, TotalSeats: 10, AvailableSeats: 6, MaxTicketsPerInstrument: 3}
+	db.Create(&show)
+	first := models.Booking{ShowID: show.ID, UserID: 1, TicketCount: 2, TotalPrice: 40, Status: "pending"}
+	second := models.Booking{ShowID: show.ID, UserID: 2, TicketCount: 2, TotalPrice: 40, Status: "pending"}
+	db.Create(&first)
+	db.Create(&second)
+
+	service.StartPayment(first)
+	service.StartPayment(second)
+	paid, _, err := service.SimulatePayment(first)
+	assert.NoError(t, err)
+	assert.Equal(t, "confirmed", paid.Status)
+
+	// the second account pays with the same card
+	refused, _, err := service.SimulatePayment(second)
+	assert.NoError(t, err)
+	assert.Equal(t, "payment_failed", refused.Status)
+	payments, _ := service.GetPaymentsByBooking(second.ID)
+	assert.Equal(t, models.PaymentRefunded, payments[0].Status)
+	assert.Contains(t, payments[0].FailureReason, "same card")
+
+	var stored models.Show
+	db.First(&stored, show.ID)
+	assert.Equal(t, 8, stored.AvailableSeats)
+}
@@ -0,0 +1,183 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidLineup = errors.New("invalid lineup")
+
+// LineupEntry is an artist of the bill as typed by an admin, the set times are wall clock
+// times in the show timezone or RFC 3339 instants, like the times of the show.
+type LineupEntry struct {
+	ArtistID    uint
+	Role        string
+	SetStartsAt string
+	SetEndsAt   string
+}
+
+// Appearance is a show an artist plays, as headliner or on the bill.
+type Appearance struct {
+	Show        models.Show `json:"show"`
+	Role        string      `json:"role"`
+	Position    int         `json:"position"`
+	SetStartsAt *time.Time  `json:"setStartsAt,omitempty"`
+	SetEndsAt   *time.Time  `json:"setEndsAt,omitempty"`
+}
+
+// preloadLineup loads the lineup of shows in the order of the bill
+func preloadLineup(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Lineup", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Preload("Lineup.Artist")
+}
+
+// buildLineup turns entries into the slots of show, in the order given. The sets are
+// played between the doors and the end of the show.
+func buildLineup(show models.Show, entries []LineupEntry) ([]models.LineupSlot, error) {
+	loc := models.LoadLocation(show.Timezone)
+	latest := show.StartsAt.Add(maxShowDuration)
+	if show.EndsAt != nil {
+		latest = *show.EndsAt
+	}
+	parse := func(value string) (*time.Time, error) {
+		if value == "" {
+			return nil, nil
+		}
+		t, err := parseShowTime(value, loc)
+		if err != nil {
+			return nil, err
+		}
+		if t.Before(show.DoorsTime()) || t.After(latest) {
+			return nil, errors.New("sets must be played between the doors and the end of the show")
+		}
+		return &t, nil
+	}
+
+	slots := make([]models.LineupSlot, len(entries))
+	headliners := 0
+	for i, entry := range entries {
+		slot := models.LineupSlot{ShowID: show.ID, ArtistID: entry.ArtistID, Position: i + 1, Role: entry.Role}
+		if slot.Role == "" {
+			slot.Role = models.LineupSupport
+			if i == 0 {
+				slot.Role = models.LineupHeadliner
+			}
+		}
+		var err error
+		if slot.SetStartsAt, err = parse(entry.SetStartsAt); err == nil {
+			slot.SetEndsAt, err = parse(entry.SetEndsAt)
+		}
+		if err == nil {
+			err = slot.Validate()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: slot %d: %v", ErrInvalidLineup, i+1, err)
+		}
+		if slot.Role == models.LineupHeadliner {
+			headliners++
+		}
+		slots[i] = slot
+	}
+	if len(slots) > 0 && headliners == 0 {
+		return nil, fmt.Errorf("%w: a show needs a headliner", ErrInvalidLineup)
+	}
+	return slots, nil
+}
+
+// SetLineup replaces the lineup of a show. The first headliner becomes the artist of the
+// show, an empty lineup leaves the show to its artist alone.
+func (s Service) SetLineup(showID uint, entries []LineupEntry) (models.Show, error) {
+	var show models.Show
+	if err := s.Db.First(&show, showID).Error; err != nil {
+		return models.Show{}, err
+	}
+	slots, err := buildLineup(show, entries)
+	if err != nil {
+		return models.Show{}, err
+	}
+	artistIDs := make([]uint, len(slots))
+	for i, slot := range slots {
+		artistIDs[i] = slot.ArtistID
+	}
+	seen := map[uint]bool{}
+	for _, id := range artistIDs {
+		if seen[id] {
+			return models.Show{}, fmt.Errorf("%w: artist %d is on the bill twice", ErrInvalidLineup, id)
+		}
+		seen[id] = true
+	}
+	var artists int64
+	if err := s.Db.Model(&models.Artist{}).Where("id IN ?", artistIDs).Count(&artists).Error; err != nil {
+		return models.Show{}, err
+	}
+	if int(artists) != len(seen) {
+		return models.Show{}, fmt.Errorf("%w: unknown artist", ErrInvalidLineup)
+	}
+
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("show_id = ?", show.ID).Delete(&models.LineupSlot{}).Error; err != nil {
+			return err
+		}
+		if len(slots) == 0 {
+			return nil
+		}
+		if err := tx.Create(&slots).Error; err != nil {
+			return err
+		}
+		for _, slot := range slots {
+			if slot.Role == models.LineupHeadliner {
+				return tx.Model(&show).Update("artist_id", slot.ArtistID).Error
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Show{}, err
+	}
+	return s.GetShowByID(show.ID)
+}
+
+// GetArtistAppearances lists the published shows an artist plays, headlined or on the
+// bill of someone else, by start time.
+func (s Service) GetArtistAppearances(artistID uint) ([]Appearance, error) {
+	var slots []models.LineupSlot
+	if err := s.Db.Where("artist_id = ?", artistID).Find(&slots).Error; err != nil {
+		return nil, err
+	}
+	slotOf := map[uint]models.LineupSlot{}
+	showIDs := make([]uint, len(slots))
+	for i, slot := range slots {
+		slotOf[slot.ShowID] = slot
+		showIDs[i] = slot.ShowID
+	}
+
+	now := time.Now()
+	var shows []models.Show
+	plays := s.Db.Where("artist_id = ?", artistID)
+	if len(showIDs) > 0 {
+		plays = plays.Or("id IN ?", showIDs)
+	}
+	query := preloadLineup(s.Db.Preload("Artist").Preload("Location").Preload("Festival")).Where(plays)
+	if err := listedShows(query, now).Order("starts_at, id").Find(&shows).Error; err != nil {
+		return nil, err
+	}
+
+	appearances := make([]Appearance, len(shows))
+	for i, show := range shows {
+		applySaleStatus(&show, now)
+		appearance := Appearance{Show: show, Role: models.LineupHeadliner, Position: 1}
+		if slot, ok := slotOf[show.ID]; ok {
+			appearance.Role = slot.Role
+			appearance.Position = slot.Position
+			appearance.SetStartsAt = slot.SetStartsAt
+			appearance.SetEndsAt = slot.SetEndsAt
+		}
+		appearances[i] = appearance
+	}
+	return appearances, nil
+}
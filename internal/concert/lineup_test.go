@@ -0,0 +1,73 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLineup(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	headliner, _ := service.SetArtist(models.Artist{Name: "Headliner"})
+	support, _ := service.SetArtist(models.Artist{Name: "Support"})
+	doors := time.Date(2030, 6, 21, 17, 0, 0, 0, time.UTC)
+	show, err := service.SetShow(models.Show{Title: "Summer night", ArtistID: support.ID, Venue: "Olympia", StartsAt: time.Date(2030, 6, 21, 18, 0, 0, 0, time.UTC), DoorsAt: &doors, Timezone: "Europe/Paris", TotalSeats: 10})
+	assert.NoError(t, err)
+
+	show, err = service.SetLineup(show.ID, []LineupEntry{
+		{ArtistID: headliner.ID, SetStartsAt: "2030-06-21T22:00", SetEndsAt: "2030-06-21T23:30"},
+		{ArtistID: support.ID, SetStartsAt: "2030-06-21T20:00", SetEndsAt: "2030-06-21T21:00"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, headliner.ID, show.ArtistID)
+	if assert.Len(t, show.Lineup, 2) {
+		assert.Equal(t, models.LineupHeadliner, show.Lineup[0].Role)
+		assert.Equal(t, "Headliner", show.Lineup[0].Artist.Name)
+		assert.Equal(t, models.LineupSupport, show.Lineup[1].Role)
+		assert.Equal(t, 2, show.Lineup[1].Position)
+		assert.Equal(t, time.Date(2030, 6, 21, 18, 0, 0, 0, time.UTC), show.Lineup[1].SetStartsAt.UTC())
+	}
+
+	_, err = service.SetLineup(show.ID, []LineupEntry{{ArtistID: support.ID, Role: models.LineupSupport}})
+	assert.ErrorIs(t, err, ErrInvalidLineup)
+	_, err = service.SetLineup(show.ID, []LineupEntry{{ArtistID: headliner.ID}, {ArtistID: headliner.ID}})
+	assert.ErrorIs(t, err, ErrInvalidLineup)
+	_, err = service.SetLineup(show.ID, []LineupEntry{{ArtistID: headliner.ID}, {ArtistID: 999}})
+	assert.ErrorIs(t, err, ErrInvalidLineup)
+	// sets are played once the doors are open
+	_, err = service.SetLineup(show.ID, []LineupEntry{{ArtistID: headliner.ID, SetStartsAt: "2030-06-21T18:30"}})
+	assert.ErrorIs(t, err, ErrInvalidLineup)
+
+	// the lineup is replaced as a whole
+	show, err = service.SetLineup(show.ID, []LineupEntry{{ArtistID: support.ID, Role: models.LineupHeadliner}})
+	assert.NoError(t, err)
+	assert.Len(t, show.Lineup, 1)
+	assert.Equal(t, support.ID, show.ArtistID)
+}
+
+func TestGetArtistAppearances(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	headliner, _ := service.SetArtist(models.Artist{Name: "Headliner"})
+	support, _ := service.SetArtist(models.Artist{Name: "Support"})
+	later, _ := service.SetShow(models.Show{Title: "Own tour", ArtistID: support.ID, Venue: "Bataclan", StartsAt: time.Now().AddDate(0, 2, 0), TotalSeats: 10})
+	opening, _ := service.SetShow(models.Show{Title: "Opening", ArtistID: headliner.ID, Venue: "Olympia", StartsAt: time.Now().AddDate(0, 1, 0), TotalSeats: 10})
+	_, err := service.SetLineup(opening.ID, []LineupEntry{{ArtistID: headliner.ID}, {ArtistID: support.ID}})
+	assert.NoError(t, err)
+
+	appearances, err := service.GetArtistAppearances(support.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, appearances, 2) {
+		assert.Equal(t, opening.ID, appearances[0].Show.ID)
+		assert.Equal(t, models.LineupSupport, appearances[0].Role)
+		assert.Len(t, appearances[0].Show.Lineup, 2)
+		assert.Equal(t, later.ID, appearances[1].Show.ID)
+		assert.Equal(t, models.LineupHeadliner, appearances[1].Role)
+	}
+
+	// the lineup artists are found by the artist filter of the listing
+	page, err := service.SearchShows(ShowQuery{ArtistID: support.ID})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+}
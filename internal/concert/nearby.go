@@ -0,0 +1,131 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// radius of the searches around a point, in km
+const (
+	DefaultNearbyRadius = 25
+	MaxNearbyRadius     = 500
+)
+
+const earthRadiusKm = 6371.0
+
+var ErrInvalidLocation = errors.New("invalid location, lat must be between -90 and 90, lng between -180 and 180 and radius between 0 and 500")
+
+// NearbyQuery looks for the shows whose venue is within RadiusKm of a point. The filters
+// of ShowQuery apply, its sort and cursor don't: the nearest shows come first.
+type NearbyQuery struct {
+	ShowQuery
+	Latitude  float64
+	Longitude float64
+	RadiusKm  float64
+}
+
+type NearbyShow struct {
+	models.Show
+	DistanceKm float64 `json:"distanceKm"`
+}
+
+type NearbyPage struct {
+	Items    []NearbyShow `json:"items"`
+	RadiusKm float64      `json:"radiusKm"`
+	Limit    int          `json:"limit"`
+}
+
+// haversine is the great-circle distance between two points, in km
+func haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// boundingBox is the latitude and longitude range around a point holding every point
+// within radius km. Near the poles it spans every longitude, across the antimeridian
+// minLng is above maxLng.
+func boundingBox(lat, lng, radius float64) (minLat, maxLat, minLng, maxLng float64) {
+	dLat := radius / earthRadiusKm * 180 / math.Pi
+	minLat, maxLat = lat-dLat, lat+dLat
+	if minLat <= -90 || maxLat >= 90 {
+		return max(minLat, -90), min(maxLat, 90), -180, 180
+	}
+	dLng := math.Asin(math.Sin(radius/earthRadiusKm)/math.Cos(lat*math.Pi/180)) * 180 / math.Pi
+	minLng, maxLng = lng-dLng, lng+dLng
+	if minLng < -180 {
+		minLng += 360
+	}
+	if maxLng > 180 {
+		maxLng -= 360
+	}
+	return minLat, maxLat, minLng, maxLng
+}
+
+// ShowsNearby lists the shows within the radius of q, nearest first. The database only
+// prefilters on a bounding box of the venue coordinates, the distance is computed here.
+func (s Service) ShowsNearby(q NearbyQuery) (NearbyPage, error) {
+	if q.RadiusKm == 0 {
+		q.RadiusKm = DefaultNearbyRadius
+	}
+	if q.Latitude < -90 || q.Latitude > 90 || q.Longitude < -180 || q.Longitude > 180 ||
+		q.RadiusKm < 0 || q.RadiusKm > MaxNearbyRadius {
+		return NearbyPage{}, ErrInvalidLocation
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultShowPageSize
+	}
+	q.Limit = min(q.Limit, MaxShowPageSize)
+	now := time.Now()
+
+	minLat, maxLat, minLng, maxLng := boundingBox(q.Latitude, q.Longitude, q.RadiusKm)
+	query := q.filter(s.Db, now).
+		Joins("JOIN venues ON venues.id = shows.venue_id AND venues.deleted_at IS NULL").
+		Where("venues.latitude BETWEEN ? AND ?", minLat, maxLat)
+	if minLng <= maxLng {
+		query = query.Where("venues.longitude BETWEEN ? AND ?", minLng, maxLng)
+	} else {
+		query = query.Where("(venues.longitude >= ? OR venues.longitude <= ?)", minLng, maxLng)
+	}
+
+	var shows []models.Show
+	if err := query.Preload("Artist").Preload("Presales").Preload("Location").Find(&shows).Error; err != nil {
+		return NearbyPage{}, err
+	}
+
+	page := NearbyPage{Items: []NearbyShow{}, RadiusKm: q.RadiusKm, Limit: q.Limit}
+	for _, show := range shows {
+		if show.Location == nil || show.Location.Latitude == nil || show.Location.Longitude == nil {
+			continue
+		}
+		distance := haversine(q.Latitude, q.Longitude, *show.Location.Latitude, *show.Location.Longitude)
+		if distance > q.RadiusKm {
+			continue
+		}
+		applySaleStatus(&show, now)
+		page.Items = append(page.Items, NearbyShow{Show: show, DistanceKm: distance})
+	}
+	sort.SliceStable(page.Items, func(i, j int) bool {
+		a, b := page.Items[i], page.Items[j]
+		if a.DistanceKm != b.DistanceKm {
+			return a.DistanceKm < b.DistanceKm
+		}
+		if !a.StartsAt.Equal(b.StartsAt) {
+			return a.StartsAt.Before(b.StartsAt)
+		}
+		return a.ID < b.ID
+	})
+	if len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+	}
+	for i := range page.Items {
+		page.Items[i].DistanceKm = math.Round(page.Items[i].DistanceKm*10) / 10
+	}
+	return page, nil
+}
@@ -0,0 +1,80 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createVenueShow(t *testing.T, service *Service, name string, lat, lng *float64) models.Show {
+	venue, err := service.CreateVenue(models.Venue{Name: name, Latitude: lat, Longitude: lng})
+	assert.NoError(t, err)
+	show := models.Show{Title: name + " night", StartsAt: time.Now().AddDate(0, 1, 0), VenueID: &venue.ID, TotalSeats: 10, AvailableSeats: 10}
+	assert.NoError(t, service.ApplyVenue(&show))
+	show, err = service.SetShow(show)
+	assert.NoError(t, err)
+	return show
+}
+
+func TestHaversine(t *testing.T) {
+	assert.InDelta(t, 392, haversine(48.8566, 2.3522, 45.764, 4.8357), 1)
+	assert.InDelta(t, 0, haversine(10, 10, 10, 10), 0.001)
+}
+
+func TestShowsNearby(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	coords := func(v float64) *float64 { return &v }
+	paris := createVenueShow(t, service, "Olympia", coords(48.8708), coords(2.3284))
+	versailles := createVenueShow(t, service, "Versailles", coords(48.8049), coords(2.1204))
+	createVenueShow(t, service, "Lyon", coords(45.764), coords(4.8357))
+	createVenueShow(t, service, "Nowhere", nil, nil)
+	service.SetShow(models.Show{Title: "No venue", Venue: "", StartsAt: time.Now().AddDate(0, 1, 0), TotalSeats: 10})
+
+	page, err := service.ShowsNearby(NearbyQuery{Latitude: 48.8566, Longitude: 2.3522})
+	assert.NoError(t, err)
+	assert.Equal(t, float64(DefaultNearbyRadius), page.RadiusKm)
+	if assert.Len(t, page.Items, 2) {
+		assert.Equal(t, paris.ID, page.Items[0].ID)
+		assert.InDelta(t, 2.4, page.Items[0].DistanceKm, 0.2)
+		assert.Equal(t, versailles.ID, page.Items[1].ID)
+		assert.Equal(t, "Versailles", page.Items[1].Location.Name)
+	}
+
+	page, err = service.ShowsNearby(NearbyQuery{Latitude: 48.8566, Longitude: 2.3522, RadiusKm: 5})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+
+	page, err = service.ShowsNearby(NearbyQuery{Latitude: 48.8566, Longitude: 2.3522, RadiusKm: 400})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 3)
+
+	// the filters of the listing apply
+	page, err = service.ShowsNearby(NearbyQuery{ShowQuery: ShowQuery{Text: "versailles"}, Latitude: 48.8566, Longitude: 2.3522})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+
+	_, err = service.ShowsNearby(NearbyQuery{Latitude: 91, Longitude: 0})
+	assert.ErrorIs(t, err, ErrInvalidLocation)
+	_, err = service.ShowsNearby(NearbyQuery{Latitude: 0, Longitude: 0, RadiusKm: MaxNearbyRadius + 1})
+	assert.ErrorIs(t, err, ErrInvalidLocation)
+}
+
+func TestShowsNearbyAcrossAntimeridian(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	coords := func(v float64) *float64 { return &v }
+	taveuni := createVenueShow(t, service, "Taveuni", coords(-16.9), coords(-179.95))
+
+	page, err := service.ShowsNearby(NearbyQuery{Latitude: -17.0, Longitude: 179.9, RadiusKm: 50})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, taveuni.ID, page.Items[0].ID)
+	}
+
+	minLat, maxLat, minLng, maxLng := boundingBox(89.9, 0, 100)
+	assert.Equal(t, 90.0, maxLat)
+	assert.Less(t, minLat, 89.9)
+	assert.Equal(t, -180.0, minLng)
+	assert.Equal(t, 180.0, maxLng)
+}
@@ -0,0 +1,118 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotOnSale     = errors.New("tickets for this show are not on sale yet")
+	ErrSaleEnded     = errors.New("ticket sales for this show have ended")
+	ErrPresaleLocked = errors.New("this show is in presale, a valid access code or invitation is required")
+)
+
+// applySaleStatus fills the computed sale fields of a show loaded with its presales.
+// NextSaleAt is the countdown target: the next presale start or the general on-sale time.
+// Without an off-sale time, sales end with the show. Shows not bookable for their status
+// are off sale.
+func applySaleStatus(show *models.Show, now time.Time) {
+	show.NextSaleAt = nil
+	applyShowStatus(show, now)
+	switch {
+	case checkShowStatus(*show, now) != nil,
+		show.OffSaleAt != nil && !now.Before(*show.OffSaleAt),
+		show.OffSaleAt == nil && !show.StartsAt.IsZero() && now.After(show.EndTime()):
+		show.SaleStatus = models.SaleOffSale
+		return
+	case show.OnSaleAt == nil || !now.Before(*show.OnSaleAt):
+		show.SaleStatus = models.SaleOnSale
+		return
+	}
+
+	show.SaleStatus = models.SaleOnSaleSoon
+	next := *show.OnSaleAt
+	for _, presale := range show.Presales {
+		if presale.ActiveAt(now) {
+			show.SaleStatus = models.SalePresale
+		} else if presale.StartsAt.After(now) && presale.StartsAt.Before(next) {
+			next = presale.StartsAt
+		}
+	}
+	if show.SaleStatus == models.SalePresale {
+		next = *show.OnSaleAt
+	}
+	show.NextSaleAt = &next
+}
+
+func presaleUnlocked(presale models.Presale, email, accessCode string) bool {
+	if presale.AccessCode != "" && accessCode != "" &&
+		subtle.ConstantTimeCompare([]byte(strings.ToUpper(presale.AccessCode)), []byte(strings.ToUpper(accessCode))) == 1 {
+		return true
+	}
+	return email != "" && presale.AllowedEmails.Contains(email)
+}
+
+// CheckSaleWindow tells whether the user can book the show right now, through a presale if needed.
+func (s Service) CheckSaleWindow(show models.Show, user models.User, accessCode string) error {
+	now := time.Now()
+	if err := checkShowStatus(show, now); err != nil {
+		return err
+	}
+	if show.OffSaleAt != nil && !now.Before(*show.OffSaleAt) {
+		return ErrSaleEnded
+	}
+	if show.OffSaleAt == nil && !show.StartsAt.IsZero() && now.After(show.EndTime()) {
+		return ErrSaleEnded
+	}
+	if show.OnSaleAt == nil || !now.Before(*show.OnSaleAt) {
+		return nil
+	}
+
+	var presales []models.Presale
+	if err := s.Db.Where("show_id = ? AND starts_at <= ? AND ends_at > ?", show.ID, now, now).Find(&presales).Error; err != nil {
+		return err
+	}
+	if len(presales) == 0 {
+		return fmt.Errorf("%w, they go on sale at %s", ErrNotOnSale, show.OnSaleAt.UTC().Format(time.RFC3339))
+	}
+	for _, presale := range presales {
+		if presaleUnlocked(presale, user.Email, accessCode) {
+			return nil
+		}
+	}
+	return ErrPresaleLocked
+}
+
+func (s Service) ListPresales(showID uint) ([]models.Presale, error) {
+	var presales []models.Presale
+	if err := s.Db.Where("show_id = ?", showID).Order("starts_at").Find(&presales).Error; err != nil {
+		return nil, err
+	}
+	return presales, nil
+}
+
+func (s Service) GetPresaleByID(id uint) (models.Presale, error) {
+	var presale models.Presale
+	if err := s.Db.First(&presale, id).Error; err != nil {
+		return models.Presale{}, err
+	}
+	return presale, nil
+}
+
+func (s Service) SetPresale(presale models.Presale) (models.Presale, error) {
+	if err := s.Db.Save(&presale).Error; err != nil {
+		return models.Presale{}, err
+	}
+	return presale, nil
+}
+
+func (s Service) DeletePresale(id uint) error {
+	if result := s.Db.Delete(&models.Presale{}, id); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSaleWindow(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	onSale := time.Now().Add(48 * time.Hour)
+	show := models.Show{Title: "Big night", Venue: "Paris", StartsAt: time.Now().Add(30 * 24 * time.Hour), OnSaleAt: &onSale}
+	db.Create(&show)
+	fan := models.User{Email: "fan@example.com"}
+
+	err := service.CheckSaleWindow(show, fan, "")
+	assert.ErrorIs(t, err, ErrNotOnSale)
+
+	db.Create(&models.Presale{
+		ShowID:        show.ID,
+		Name:          "Fan club",
+		StartsAt:      time.Now().Add(-time.Hour),
+		EndsAt:        onSale,
+		AccessCode:    "FANCLUB",
+		AllowedEmails: models.StringList{"vip@example.com"},
+	})
+
+	assert.ErrorIs(t, service.CheckSaleWindow(show, fan, ""), ErrPresaleLocked)
+	assert.ErrorIs(t, service.CheckSaleWindow(show, fan, "WRONG"), ErrPresaleLocked)
+	assert.NoError(t, service.CheckSaleWindow(show, fan, "fanclub"))
+	assert.NoError(t, service.CheckSaleWindow(show, models.User{Email: "VIP@example.com"}, ""))
+
+	ended := time.Now().Add(-time.Minute)
+	show.OnSaleAt = nil
+	show.OffSaleAt = &ended
+	assert.ErrorIs(t, service.CheckSaleWindow(show, fan, "FANCLUB"), ErrSaleEnded)
+}
+
+func TestSaleStatusInListings(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	onSale := time.Now().Add(24 * time.Hour)
+	presaleStart := time.Now().Add(2 * time.Hour)
+	soon := models.Show{Title: "Soon", Venue: "Paris", StartsAt: time.Now().AddDate(0, 0, 7), OnSaleAt: &onSale}
+	db.Create(&soon)
+	db.Create(&models.Presale{ShowID: soon.ID, Name: "Early", StartsAt: presaleStart, EndsAt: onSale, AccessCode: "EARLY"})
+	open := models.Show{Title: "Open", Venue: "Lyon", StartsAt: time.Now().AddDate(0, 0, 7)}
+	db.Create(&open)
+
+	shows, err := service.ListAllShow()
+	assert.NoError(t, err)
+	assert.Len(t, shows, 2)
+	assert.Equal(t, models.SaleOnSaleSoon, shows[0].SaleStatus)
+	assert.WithinDuration(t, presaleStart, *shows[0].NextSaleAt, time.Second)
+	assert.Equal(t, models.SaleOnSale, shows[1].SaleStatus)
+	assert.Nil(t, shows[1].NextSaleAt)
+}
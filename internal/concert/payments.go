@@ -0,0 +1,287 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/payment"
+	"concert/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PaymentHoldTimeout is how long the seats of a pending booking are held for its payment.
+const PaymentHoldTimeout = 15 * time.Minute
+
+var (
+	ErrNoPendingPayment = errors.New("this booking has no pending payment")
+	ErrNotMockProvider  = errors.New("payments are not handled by the mock provider")
+)
+
+func paymentCurrency() string {
+	return utils.GetEnvOrDefault("PAYMENT_CURRENCY", "eur")
+}
+
+func paymentReference(booking models.Booking) string {
+	return fmt.Sprintf("booking_%d", booking.ID)
+}
+
+// StartPayment creates the payment intent of a pending booking, for the part of its
+// price not covered by account credit.
+func (s Service) StartPayment(booking models.Booking) (models.Payment, payment.Intent, error) {
+	amount := payment.ToCents(booking.TotalPrice) - payment.ToCents(booking.CreditApplied)
+	intent, err := s.Payments.CreateIntent(context.Background(), amount, paymentCurrency(), paymentReference(booking))
+	if err != nil {
+		return models.Payment{}, payment.Intent{}, err
+	}
+
+	p := models.Payment{
+		BookingID: booking.ID,
+		Provider:  s.Payments.Name(),
+		IntentID:  intent.ID,
+		Amount:    payment.FromCents(intent.Amount),
+		Currency:  intent.Currency,
+		Status:    models.PaymentPending,
+	}
+	if err := s.Db.Where(models.Payment{IntentID: intent.ID}).FirstOrCreate(&p).Error; err != nil {
+		return models.Payment{}, payment.Intent{}, err
+	}
+	return p, intent, nil
+}
+
+func (s Service) GetPaymentsByBooking(bookingID uint) ([]models.Payment, error) {
+	var payments []models.Payment
+	if err := s.Db.Where("booking_id = ?", bookingID).Order("id").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// HandlePaymentWebhook verifies a webhook of the provider and applies its event. It returns
+// the booking and whether its status changed, events already applied change nothing.
+func (s Service) HandlePaymentWebhook(payload []byte, header http.Header) (models.Booking, bool, error) {
+	event, err := s.Payments.VerifyWebhook(payload, header)
+	if err != nil {
+		return models.Booking{}, false, err
+	}
+	return s.applyPaymentEvent(event)
+}
+
+// SimulatePayment completes the pending payment of a booking with the mock provider, for development.
+func (s Service) SimulatePayment(booking models.Booking) (models.Booking, bool, error) {
+	mock, ok := s.Payments.(*payment.MockProvider)
+	if !ok {
+		return booking, false, ErrNotMockProvider
+	}
+	var p models.Payment
+	if err := s.Db.Where("booking_id = ? AND status = ?", booking.ID, models.PaymentPending).First(&p).Error; err != nil {
+		return booking, false, ErrNoPendingPayment
+	}
+	payload, header, err := mock.Complete(p.IntentID)
+	if err != nil {
+		return booking, false, err
+	}
+	return s.HandlePaymentWebhook(payload, header)
+}
+
+func (s Service) applyPaymentEvent(event payment.Event) (models.Booking, bool, error) {
+	var p models.Payment
+	if err := s.Db.Where("intent_id = ?", event.IntentID).First(&p).Error; err != nil {
+		return models.Booking{}, false, payment.ErrUnknownIntent
+	}
+	if p.GiftCardID != nil {
+		if p.Status != models.PaymentPending {
+			return models.Booking{}, false, nil
+		}
+		return models.Booking{}, false, s.applyGiftCardPayment(p, event)
+	}
+
+	switch event.Type {
+	case payment.EventAuthorized:
+		if p.Status != models.PaymentPending {
+			return s.bookingOf(p)
+		}
+		if err := s.Payments.Capture(context.Background(), p.IntentID); err != nil {
+			log.Printf("Error capturing payment %s: %v", p.IntentID, err)
+			return s.failPayment(p, "capture failed: "+err.Error())
+		}
+		return s.confirmPayment(p, event.Instrument)
+	case payment.EventFailed:
+		if p.Status != models.PaymentPending {
+			return s.bookingOf(p)
+		}
+		return s.failPayment(p, event.FailureReason)
+	}
+	// other events, e.g. refunds, don't change the booking here
+	return s.bookingOf(p)
+}
+
+func (s Service) bookingOf(p models.Payment) (models.Booking, bool, error) {
+	booking, err := s.GetBookingById(p.BookingID)
+	return booking, false, err
+}
+
+func (s Service) confirmPayment(p models.Payment, instrument string) (models.Booking, bool, error) {
+	now := time.Now()
+	var confirmed bool
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&p).Updates(map[string]any{"status": models.PaymentSucceeded, "captured_at": now, "instrument": instrument}).Error; err != nil {
+			return err
+		}
+		var pending models.Booking
+		if err := tx.First(&pending, p.BookingID).Error; err != nil {
+			return err
+		}
+		show, err := lockShow(tx, pending.ShowID)
+		if err != nil {
+			return err
+		}
+		var limitErr *TicketLimitError
+		if err := checkInstrumentLimit(tx, show, pending, instrument); errors.As(err, &limitErr) {
+			// over the limit of the card, the booking is released and the payment refunded below
+			if err := tx.Model(&p).Update("failure_reason", limitErr.Error()).Error; err != nil {
+				return err
+			}
+			_, err := releaseBooking(tx, p.BookingID)
+			return err
+		} else if err != nil {
+			return err
+		}
+		result := tx.Model(&models.Booking{}).
+			Where("id = ? AND status = ?", p.BookingID, "pending").
+			Updates(map[string]any{"status": "confirmed", "confirmed_at": now})
+		confirmed = result.RowsAffected == 1
+		if result.Error != nil || !confirmed {
+			return result.Error
+		}
+		var booking models.Booking
+		if err := tx.First(&booking, p.BookingID).Error; err != nil {
+			return err
+		}
+		if err := RecordSale(tx, booking); err != nil {
+			return err
+		}
+		return s.completeResale(tx, p.BookingID)
+	})
+	if err != nil {
+		return models.Booking{}, false, err
+	}
+	if confirmed {
+		s.settleResale(p.BookingID)
+	}
+
+	if !confirmed {
+		// the hold expired, the booking was cancelled before the payment came in or went
+		// over the limit of the card
+		if _, err := s.Payments.Refund(context.Background(), p.IntentID, payment.ToCents(p.Amount), fmt.Sprintf("refund-payment-%d", p.ID)); err != nil {
+			log.Printf("Error refunding late payment %s: %v", p.IntentID, err)
+		} else {
+			// the money came in and went out, there is nothing for the ledger
+			s.Db.Model(&p).Updates(map[string]any{"status": models.PaymentRefunded, "refunded": p.Amount})
+		}
+	}
+
+	booking, err := s.GetBookingById(p.BookingID)
+	return booking, confirmed, err
+}
+
+func (s Service) failPayment(p models.Payment, reason string) (models.Booking, bool, error) {
+	var released bool
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&p).Updates(map[string]any{"status": models.PaymentFailed, "failure_reason": reason}).Error; err != nil {
+			return err
+		}
+		var err error
+		released, err = releaseBooking(tx, p.BookingID)
+		return err
+	})
+	if err != nil {
+		return models.Booking{}, false, err
+	}
+	booking, err := s.GetBookingById(p.BookingID)
+	return booking, released, err
+}
+
+// releaseBooking marks a pending booking as failed and gives its seats back.
+func releaseBooking(tx *gorm.DB, bookingID uint) (bool, error) {
+	var booking models.Booking
+	if err := tx.First(&booking, bookingID).Error; err != nil {
+		return false, err
+	}
+	result := tx.Model(&booking).Where("status = ?", "pending").Update("status", "payment_failed")
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	if err := ReturnBookingCredit(tx, booking); err != nil {
+		return false, err
+	}
+	if booking.ResaleListingID != nil {
+		// the seats never left the seller, the listing is offered again
+		err := tx.Model(&models.ResaleListing{}).
+			Where("id = ? AND status = ? AND reserved_booking_id = ?", *booking.ResaleListingID, models.ResaleReserved, booking.ID).
+			Updates(map[string]any{"status": models.ResaleActive, "reserved_booking_id": nil}).Error
+		return err == nil, err
+	}
+	err := BookingSeats(tx, booking).
+		Update("available_seats", gorm.Expr("available_seats + ?", booking.TicketCount)).Error
+	return err == nil, err
+}
+
+// ReleaseBooking gives back the seats of a pending booking whose payment could not start.
+func (s Service) ReleaseBooking(bookingID uint, reason string) error {
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Payment{}).
+			Where("booking_id = ? AND status = ?", bookingID, models.PaymentPending).
+			Updates(map[string]any{"status": models.PaymentFailed, "failure_reason": reason}).Error; err != nil {
+			return err
+		}
+		_, err := releaseBooking(tx, bookingID)
+		return err
+	})
+}
+
+// ExpirePendingPayments releases the bookings and gift cards still waiting for their payment after PaymentHoldTimeout.
+func (s Service) ExpirePendingPayments(now time.Time) (int, error) {
+	var bookings []models.Booking
+	if err := s.Db.Where("status = ? AND created_at < ?", "pending", now.Add(-PaymentHoldTimeout)).Find(&bookings).Error; err != nil {
+		return 0, err
+	}
+	for _, booking := range bookings {
+		if err := s.ReleaseBooking(booking.ID, "payment hold expired"); err != nil {
+			return 0, err
+		}
+	}
+
+	var giftCardPayments []models.Payment
+	if err := s.Db.Where("gift_card_id IS NOT NULL AND status = ? AND created_at < ?", models.PaymentPending, now.Add(-PaymentHoldTimeout)).
+		Find(&giftCardPayments).Error; err != nil {
+		return 0, err
+	}
+	for _, p := range giftCardPayments {
+		if err := s.failGiftCardPayment(p, "payment hold expired"); err != nil {
+			return 0, err
+		}
+	}
+	return len(bookings) + len(giftCardPayments), nil
+}
+
+func (s Service) RunPaymentReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n, err := s.ExpirePendingPayments(now); err != nil {
+				log.Printf("Error expiring pending payments: %v", err)
+			} else if n > 0 {
+				log.Printf("Released %d payments with an expired hold", n)
+			}
+		}
+	}
+}
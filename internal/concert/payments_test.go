@@ -0,0 +1,107 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/payment"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createPendingBooking(service *Service, price float64) (models.Show, models.Booking) {
+	show := models.Show{Title: "Paid", Venue: "Lille", StartsAt: time.Now(), Price: price, TotalSeats: 10, AvailableSeats: 8}
+	service.Db.Create(&show)
+	booking := models.Booking{ShowID: show.ID, UserID: 1, TicketCount: 2, TotalPrice: price * 2, Status: "pending"}
+	service.Db.Create(&booking)
+	return show, booking
+}
+
+func TestPaymentConfirmsBooking(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	show, booking := createPendingBooking(service, 25)
+
+	p, intent, err := service.StartPayment(booking)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5000), intent.Amount)
+	assert.Equal(t, models.PaymentPending, p.Status)
+
+	got, _ := service.GetShowByID(show.ID)
+	assert.Equal(t, 8, got.AvailableSeats)
+
+	mock := service.Payments.(*payment.MockProvider)
+	payload, header, err := mock.Complete(intent.ID)
+	assert.NoError(t, err)
+
+	confirmed, changed, err := service.HandlePaymentWebhook(payload, header)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "confirmed", confirmed.Status)
+
+	// the provider may deliver the same webhook again
+	_, changed, err = service.HandlePaymentWebhook(payload, header)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	header.Set(payment.MockSignatureHeader, "forged")
+	_, _, err = service.HandlePaymentWebhook(payload, header)
+	assert.ErrorIs(t, err, payment.ErrInvalidSignature)
+}
+
+func TestPaymentFailureReleasesSeats(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	// amounts ending with .02 are declined by the mock provider
+	show, booking := createPendingBooking(service, 10.01)
+
+	_, _, err := service.StartPayment(booking)
+	assert.NoError(t, err)
+
+	failed, changed, err := service.SimulatePayment(booking)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "payment_failed", failed.Status)
+
+	var stored models.Show
+	service.Db.First(&stored, show.ID)
+	assert.Equal(t, 10, stored.AvailableSeats)
+	got, _ := service.GetShowByID(show.ID)
+	assert.Equal(t, 10, got.AvailableSeats)
+
+	payments, err := service.GetPaymentsByBooking(booking.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PaymentFailed, payments[0].Status)
+}
+
+func TestExpirePendingPayments(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	_, booking := createPendingBooking(service, 30)
+
+	n, err := service.ExpirePendingPayments(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = service.ExpirePendingPayments(time.Now().Add(PaymentHoldTimeout + time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	expired, _ := service.GetBookingById(booking.ID)
+	assert.Equal(t, "payment_failed", expired.Status)
+}
+
+func TestMockIntentsSurviveRestart(t *testing.T) {
+	db := SetupTestDB()
+	_, booking := createPendingBooking(NewConcert(db), 25)
+	_, _, err := NewConcert(db).StartPayment(booking)
+	assert.NoError(t, err)
+
+	// another process, or the same one restarted, completes the payment
+	confirmed, changed, err := NewConcert(db).SimulatePayment(booking)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "confirmed", confirmed.Status)
+
+	_, err = payment.NewProvider("mock", "", db)
+	assert.ErrorIs(t, err, payment.ErrNoWebhookSecret)
+	_, err = payment.NewProvider("unknown", "s3cret", db)
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/payment"
+	"errors"
+	"math"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// TaxRateFor returns the tax rate of the venue of show, or of its country. Venues are
+// told apart by their country, two cities may have a venue of the same name. A show
+// without a rate isn't taxed.
+func (s Service) TaxRateFor(show models.Show) (models.TaxRate, bool, error) {
+	if show.Country == "" {
+		return models.TaxRate{}, false, nil
+	}
+	var rates []models.TaxRate
+	if err := s.Db.Where("country = ? AND (venue = '' OR LOWER(venue) = ?)", strings.ToUpper(show.Country), strings.ToLower(show.Venue)).
+		Order("venue DESC").Limit(1).Find(&rates).Error; err != nil {
+		return models.TaxRate{}, false, err
+	}
+	if len(rates) == 0 {
+		return models.TaxRate{}, false, nil
+	}
+	return rates[0], true, nil
+}
+
+// PriceBooking prices count tickets of show: the tickets, the per ticket and per order
+// fees, and the tax on both. The gross price is Total of the breakdown.
+func (s Service) PriceBooking(show models.Show, count int) (models.PriceBreakdown, error) {
+	net := payment.ToCents(show.Price) * int64(count)
+	var fees int64
+	if count > 0 {
+		fees = payment.ToCents(show.TicketFee)*int64(count) + payment.ToCents(show.OrderFee)
+	}
+
+	rate, ok, err := s.TaxRateFor(show)
+	if err != nil {
+		return models.PriceBreakdown{}, err
+	}
+	breakdown := models.PriceBreakdown{
+		NetAmount: payment.FromCents(net),
+		FeeAmount: payment.FromCents(fees),
+	}
+	if ok {
+		tax := int64(math.Round(float64(net+fees) * rate.Percent / 100))
+		breakdown.TaxAmount = payment.FromCents(tax)
+		breakdown.TaxName = rate.Name
+		breakdown.TaxPercent = rate.Percent
+	}
+	return breakdown, nil
+}
+
+// GrossPrice is what the fan pays for a breakdown.
+func GrossPrice(breakdown models.PriceBreakdown) float64 {
+	return payment.FromCents(payment.ToCents(breakdown.NetAmount) +
+		payment.ToCents(breakdown.FeeAmount) + payment.ToCents(breakdown.TaxAmount))
+}
+
+// bookingBreakdown returns the breakdown of booking in cents, the whole price being
+// the tickets for the bookings made before the breakdown existed.
+func bookingBreakdown(booking models.Booking) (net, fees, tax int64) {
+	total := payment.ToCents(booking.TotalPrice)
+	fees = payment.ToCents(booking.FeeAmount)
+	tax = payment.ToCents(booking.TaxAmount)
+	return total - fees - tax, fees, tax
+}
+
+// refundTax is the part of amount cents refunded on booking that gives back tax,
+// in the proportion of the tax in its price.
+func refundTax(booking models.Booking, amount int64) int64 {
+	total := payment.ToCents(booking.TotalPrice)
+	_, _, tax := bookingBreakdown(booking)
+	if total <= 0 || tax <= 0 {
+		return 0
+	}
+	return amount * tax / total
+}
+
+// shrinkBreakdown scales the breakdown of booking down to a new total, the rounding
+// going to the tickets.
+func shrinkBreakdown(booking models.Booking, total int64) map[string]any {
+	old := payment.ToCents(booking.TotalPrice)
+	if old <= 0 {
+		return map[string]any{"net_amount": payment.FromCents(total)}
+	}
+	_, fees, tax := bookingBreakdown(booking)
+	fees = fees * total / old
+	tax = tax * total / old
+	return map[string]any{
+		"net_amount": payment.FromCents(total - fees - tax),
+		"fee_amount": payment.FromCents(fees),
+		"tax_amount": payment.FromCents(tax),
+	}
+}
+
+func (s Service) ListTaxRates() ([]models.TaxRate, error) {
+	var rates []models.TaxRate
+	if err := s.Db.Order("country, venue").Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// SetTaxRate creates or updates a rate, there is one rate per country and venue.
+func (s Service) SetTaxRate(rate models.TaxRate) (models.TaxRate, error) {
+	rate.Country = strings.ToUpper(rate.Country)
+	if err := rate.Validate(); err != nil {
+		return models.TaxRate{}, err
+	}
+	var existing models.TaxRate
+	err := s.Db.Where("country = ? AND LOWER(venue) = ?", rate.Country, strings.ToLower(rate.Venue)).First(&existing).Error
+	if err == nil {
+		rate.ID = existing.ID
+		rate.CreatedAt = existing.CreatedAt
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.TaxRate{}, err
+	}
+	if err := s.Db.Save(&rate).Error; err != nil {
+		return models.TaxRate{}, err
+	}
+	return rate, nil
+}
+
+func (s Service) DeleteTaxRate(id uint) error {
+	result := s.Db.Delete(&models.TaxRate{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceBooking(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	show := models.Show{Title: "Taxed", Venue: "Zenith", Country: "fr", Price: 40, TicketFee: 1.5, OrderFee: 2}
+
+	breakdown, err := service.PriceBooking(show, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PriceBreakdown{NetAmount: 80, FeeAmount: 5}, breakdown)
+
+	_, err = service.SetTaxRate(models.TaxRate{Country: "fr", Name: "VAT", Percent: 5.5})
+	assert.NoError(t, err)
+	_, err = service.SetTaxRate(models.TaxRate{Country: "FR", Venue: "Olympia", Name: "VAT", Percent: 20})
+	assert.NoError(t, err)
+	// a second rate for the same country replaces the first one
+	_, err = service.SetTaxRate(models.TaxRate{Country: "FR", Name: "TVA", Percent: 5.5})
+	assert.NoError(t, err)
+	rates, _ := service.ListTaxRates()
+	assert.Len(t, rates, 2)
+
+	breakdown, err = service.PriceBooking(show, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 4.68, breakdown.TaxAmount)
+	assert.Equal(t, "TVA", breakdown.TaxName)
+	assert.Equal(t, 89.68, GrossPrice(breakdown))
+
+	show.Venue = "olympia"
+	breakdown, err = service.PriceBooking(show, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 17.0, breakdown.TaxAmount)
+	assert.Equal(t, 102.0, GrossPrice(breakdown))
+
+	// a venue of the same name abroad has the rate of its own country
+	_, err = service.SetTaxRate(models.TaxRate{Country: "BE", Name: "BTW", Percent: 6})
+	assert.NoError(t, err)
+	show.Country = "BE"
+	breakdown, err = service.PriceBooking(show, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "BTW", breakdown.TaxName)
+	assert.Equal(t, 5.1, breakdown.TaxAmount)
+}
+
+func TestLedgerSeparatesTaxAndFees(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	fan := models.User{Username: "fan", Email: "fan@example.com"}
+	service.Db.Create(&fan)
+	_, err := service.SetTaxRate(models.TaxRate{Country: "BE", Name: "VAT", Percent: 20})
+	assert.NoError(t, err)
+
+	show := models.Show{Title: "Taxed", Venue: "Forest", Country: "BE", StartsAt: time.Now().AddDate(0, 1, 0), Price: 40, TicketFee: 1, TotalSeats: 10, AvailableSeats: 8}
+	service.Db.Create(&show)
+	breakdown, err := service.PriceBooking(show, 2)
+	assert.NoError(t, err)
+	booking := models.Booking{ShowID: show.ID, UserID: fan.ID, TicketCount: 2, TotalPrice: GrossPrice(breakdown), Status: "pending", PriceBreakdown: breakdown}
+	service.Db.Create(&booking)
+	assert.Equal(t, 98.4, booking.TotalPrice)
+
+	_, _, err = service.StartPayment(booking)
+	assert.NoError(t, err)
+	booking, _, err = service.SimulatePayment(booking)
+	assert.NoError(t, err)
+	assert.Equal(t, "confirmed", booking.Status)
+
+	report, err := service.GetLedgerReport(LedgerFilter{ShowID: show.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, 82.0, report.GrossSales)
+	assert.Equal(t, 2.0, report.Fees)
+	assert.Equal(t, 16.4, report.Taxes)
+	assert.Equal(t, 82.0, report.NetRevenue)
+	assert.True(t, report.Reconciled)
+
+	// half of the price comes back, with half of the tax
+	assert.NoError(t, service.RefundToCredit(booking.ID, 49.2))
+	report, err = service.GetLedgerReport(LedgerFilter{ShowID: show.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, 41.0, report.Refunds)
+	assert.Equal(t, 8.2, report.Taxes)
+	assert.Equal(t, 41.0, report.NetRevenue)
+	assert.True(t, report.Reconciled)
+	assertLedgerChecks(t, service)
+
+	statement, err := service.GetSettlement(show.ID)
+	assert.NoError(t, err)
+	// half of the fees went back with the refund
+	assert.Equal(t, 1.0, statement.Fees)
+	assert.Equal(t, 8.2, statement.Taxes)
+	assert.Equal(t, 40.0, statement.NetReceipts)
+}
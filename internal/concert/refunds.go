@@ -0,0 +1,312 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/payment"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RefundFull    = "full"
+	RefundPartial = "partial"
+	RefundNone    = "none"
+
+	RefundStatusPending  = "pending"
+	RefundStatusRefunded = "refunded"
+	RefundStatusFailed   = "failed"
+)
+
+var (
+	ErrNotRefundable = errors.New("this booking can't be refunded")
+	ErrSeatsResold   = errors.New("the seats of this booking were sold again")
+)
+
+// RefundQuote is what a fan gets back when cancelling a booking now.
+type RefundQuote struct {
+	BookingID       uint    `json:"bookingId"`
+	Rule            string  `json:"rule"`
+	Percent         int     `json:"percent"`
+	Fee             float64 `json:"fee"`
+	Amount          float64 `json:"amount"`
+	HoursBeforeShow int     `json:"hoursBeforeShow"`
+	// Credit is the part of Amount returned to the account credit, the rest goes back
+	// to the payment method
+	Credit float64 `json:"credit,omitempty"`
+}
+
+// calculateRefund applies the cancellation policy of the show to a paid amount.
+func calculateRefund(policy models.CancellationPolicy, paid float64, showDate, now time.Time) RefundQuote {
+	hours := showDate.Sub(now).Hours()
+	quote := RefundQuote{HoursBeforeShow: int(math.Floor(hours))}
+
+	switch {
+	case hours < float64(policy.NoRefundHours):
+		quote.Rule = RefundNone
+		return quote
+	case policy.FullRefundDays == 0 || hours >= float64(policy.FullRefundDays*24):
+		quote.Rule, quote.Percent = RefundFull, 100
+	default:
+		quote.Rule, quote.Percent = RefundPartial, policy.PartialRefundPercent
+	}
+
+	cents := payment.ToCents(paid)*int64(quote.Percent)/100 - payment.ToCents(policy.Fee)
+	if cents <= 0 {
+		quote.Rule, quote.Percent = RefundNone, 0
+		return quote
+	}
+	quote.Fee = policy.Fee
+	quote.Amount = payment.FromCents(cents)
+	return quote
+}
+
+// QuoteRefund computes the refund of a booking under the policy of its show. What was
+// paid with account credit is returned as credit first. Bookings without a captured
+// payment or credit get a zero quote.
+func (s Service) QuoteRefund(booking models.Booking, now time.Time) (RefundQuote, error) {
+	quote := RefundQuote{BookingID: booking.ID, Rule: RefundNone}
+	if booking.Status != "confirmed" {
+		return quote, nil
+	}
+	if listed, err := hasOpenListing(s.Db, booking.ID); err != nil || listed {
+		if err == nil {
+			err = ErrResaleListed
+		}
+		return quote, err
+	}
+
+	paid := payment.ToCents(booking.CreditApplied)
+	var p models.Payment
+	err := s.Db.Where("booking_id = ? AND status = ?", booking.ID, models.PaymentSucceeded).First(&p).Error
+	switch {
+	case err == nil:
+		// resale payouts may have given part of it back already
+		paid += payment.ToCents(p.Amount) - payment.ToCents(p.Refunded)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return quote, err
+	}
+	// a resold seat leaves its resale fee paid, the booking is worth its seats left
+	paid = min(paid, payment.ToCents(booking.TotalPrice))
+	if paid == 0 {
+		return quote, nil
+	}
+
+	var show models.Show
+	if err := s.Db.First(&show, booking.ShowID).Error; err != nil {
+		return quote, err
+	}
+
+	quote = calculateRefund(show.CancellationPolicy, payment.FromCents(paid), show.StartsAt, now)
+	quote.BookingID = booking.ID
+	quote.Credit = min(quote.Amount, booking.CreditApplied)
+	return quote, nil
+}
+
+// The steps of the refund saga, run by temporal activities. Each step is idempotent
+// since activities may be retried.
+
+// BeginRefund marks a confirmed booking as refunding and voids its tickets. Its seats stay
+// held until the refund succeeds, they are still the fan's if it fails.
+// amount goes back to the payment method and credit to the account credit.
+func (s Service) BeginRefund(bookingID uint, amount, credit float64) error {
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		var booking models.Booking
+		if err := tx.First(&booking, bookingID).Error; err != nil {
+			return err
+		}
+		if booking.Status == "refunding" && booking.RefundStatus == RefundStatusPending {
+			return nil
+		}
+		if booking.Status != "confirmed" {
+			return ErrNotRefundable
+		}
+
+		if err := tx.Model(&booking).Updates(map[string]any{
+			"status":        "refunding",
+			"refund_amount": payment.FromCents(payment.ToCents(amount) + payment.ToCents(credit)),
+			"refund_credit": credit,
+			"refund_status": RefundStatusPending,
+		}).Error; err != nil {
+			return err
+		}
+		return voidTickets(tx, booking.ID)
+	})
+}
+
+// cancelBooking releases the seats of a cancelled booking and voids its tickets
+func cancelBooking(tx *gorm.DB, booking models.Booking) error {
+	if err := BookingSeats(tx, booking).
+		Update("available_seats", gorm.Expr("available_seats + ?", booking.TicketCount)).Error; err != nil {
+		return err
+	}
+	return voidTickets(tx, booking.ID)
+}
+
+func voidTickets(tx *gorm.DB, bookingID uint) error {
+	return tx.Model(&models.Ticket{}).
+		Where("booking_id = ? AND status = ?", bookingID, models.TicketValid).
+		Update("status", models.TicketVoid).Error
+}
+
+// RefundToCredit cancels a confirmed booking and returns amount to the account credit of
+// the fan, without going through the payment provider.
+func (s Service) RefundToCredit(bookingID uint, amount float64) error {
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		var booking models.Booking
+		if err := tx.First(&booking, bookingID).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&booking).Where("status = ?", "confirmed").Updates(map[string]any{
+			"status":        "cancelled",
+			"refund_amount": amount,
+			"refund_credit": amount,
+			"refund_status": RefundStatusRefunded,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotRefundable
+		}
+		if err := cancelBooking(tx, booking); err != nil {
+			return err
+		}
+		return AddCredit(tx, &models.CreditEntry{
+			UserID:    booking.UserID,
+			Amount:    amount,
+			Kind:      models.CreditRefund,
+			BookingID: &booking.ID,
+		})
+	})
+}
+
+// IssueRefund sends the refund to the payment provider and returns its ID.
+func (s Service) IssueRefund(bookingID uint, amount float64) (string, error) {
+	var p models.Payment
+	if err := s.Db.Where("booking_id = ? AND status IN ?", bookingID,
+		[]string{models.PaymentSucceeded, models.PaymentRefunded}).First(&p).Error; err != nil {
+		return "", ErrNotRefundable
+	}
+	if p.RefundID != "" {
+		return p.RefundID, nil
+	}
+
+	refundID, err := s.Payments.Refund(context.Background(), p.IntentID, payment.ToCents(amount), fmt.Sprintf("refund-booking-%d", bookingID))
+	if err != nil {
+		return "", err
+	}
+	// saved to skip the provider on a retry, the key makes a retry after a crash before
+	// this save get the same refund back
+	if err := s.Db.Model(&p).Update("refund_id", refundID).Error; err != nil {
+		return "", err
+	}
+	return refundID, nil
+}
+
+// CompleteRefund records the refund on the payment and the booking, cancels the booking,
+// releasing its seats, and credits the account with the part refunded as credit.
+func (s Service) CompleteRefund(bookingID uint, refundID string) error {
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		var booking models.Booking
+		if err := tx.First(&booking, bookingID).Error; err != nil {
+			return err
+		}
+		if booking.RefundStatus == RefundStatusRefunded {
+			return nil
+		}
+		if booking.Status != "refunding" {
+			return ErrNotRefundable
+		}
+		if err := tx.Model(&booking).Update("status", "cancelled").Error; err != nil {
+			return err
+		}
+		if err := cancelBooking(tx, booking); err != nil {
+			return err
+		}
+		refunded := payment.ToCents(booking.RefundAmount) - payment.ToCents(booking.RefundCredit)
+		if err := tx.Model(&models.Payment{}).
+			Where("booking_id = ? AND status = ?", bookingID, models.PaymentSucceeded).
+			Updates(map[string]any{
+				"status":    models.PaymentRefunded,
+				"refund_id": refundID,
+				"refunded":  gorm.Expr("refunded + ?", payment.FromCents(refunded)),
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&booking).Update("refund_status", RefundStatusRefunded).Error; err != nil {
+			return err
+		}
+		if err := recordRefund(tx, booking, fmt.Sprintf("refund:booking:%d", booking.ID), refunded); err != nil {
+			return err
+		}
+		return AddCredit(tx, &models.CreditEntry{
+			UserID:    booking.UserID,
+			Amount:    booking.RefundCredit,
+			Kind:      models.CreditRefund,
+			BookingID: &booking.ID,
+		})
+	})
+}
+
+// AbortRefund compensates BeginRefund when the money could not be returned:
+// the booking is confirmed again with new tickets, and the refund marked as failed.
+func (s Service) AbortRefund(bookingID uint) error {
+	var booking models.Booking
+	var resold bool
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&booking, bookingID).Error; err != nil {
+			return err
+		}
+		if booking.RefundStatus != RefundStatusPending {
+			return nil
+		}
+		switch booking.Status {
+		case "refunding":
+			// the seats were kept
+		case "cancelled":
+			// a refund begun before the seats were held until now released them, they are
+			// taken back only if nobody bought them in the meantime
+			seats := BookingSeats(tx, booking)
+			var shows, free int64
+			if err := seats.Count(&shows).Error; err != nil {
+				return err
+			}
+			if err := BookingSeats(tx, booking).Where("available_seats >= ?", booking.TicketCount).Count(&free).Error; err != nil {
+				return err
+			}
+			if free < shows {
+				// the booking stays cancelled, its failed refund is left to the support
+				resold = true
+				return tx.Model(&booking).Update("refund_status", RefundStatusFailed).Error
+			}
+			if err := BookingSeats(tx, booking).
+				Update("available_seats", gorm.Expr("available_seats - ?", booking.TicketCount)).Error; err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+		if err := tx.Model(&booking).Updates(map[string]any{
+			"status":        "confirmed",
+			"refund_status": RefundStatusFailed,
+		}).Error; err != nil {
+			return err
+		}
+		booking.Status = "confirmed"
+		return nil
+	})
+	if err == nil && resold {
+		err = ErrSeatsResold
+	}
+	if err != nil || booking.Status != "confirmed" {
+		return err
+	}
+	// the old codes stay void, the fan gets new ones
+	_, err = s.IssueTickets(booking)
+	return err
+}
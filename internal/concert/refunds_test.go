@@ -0,0 +1,178 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/payment"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateRefund(t *testing.T) {
+	policy := models.CancellationPolicy{FullRefundDays: 7, PartialRefundPercent: 50, NoRefundHours: 24, Fee: 2}
+	show := time.Date(2026, 6, 20, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		rule   string
+		amount float64
+	}{
+		{"weeks before", show.AddDate(0, 0, -10), RefundFull, 98},
+		{"exactly the full refund limit", show.AddDate(0, 0, -7), RefundFull, 98},
+		{"a few days before", show.AddDate(0, 0, -3), RefundPartial, 48},
+		{"the day of the show", show.Add(-5 * time.Hour), RefundNone, 0},
+		{"after the show", show.Add(time.Hour), RefundNone, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := calculateRefund(policy, 100, show, tt.now)
+			assert.Equal(t, tt.rule, quote.Rule)
+			assert.Equal(t, tt.amount, quote.Amount)
+		})
+	}
+
+	// a fee bigger than the refund leaves nothing
+	quote := calculateRefund(models.CancellationPolicy{Fee: 5}, 4, show, show.AddDate(0, 0, -1))
+	assert.Equal(t, RefundNone, quote.Rule)
+	assert.Zero(t, quote.Amount)
+}
+
+func createPaidBooking(t *testing.T, service *Service) (models.Show, models.Booking) {
+	show, booking := createPendingBooking(service, 40)
+	service.Db.Model(&show).Updates(map[string]any{"starts_at": time.Now().AddDate(0, 1, 0), "cancel_no_refund_hours": 24})
+	_, _, err := service.StartPayment(booking)
+	assert.NoError(t, err)
+	booking, _, err = service.SimulatePayment(booking)
+	assert.NoError(t, err)
+	assert.Equal(t, "confirmed", booking.Status)
+	return show, booking
+}
+
+func TestRefundSagaSteps(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	show, booking := createPaidBooking(t, service)
+
+	quote, err := service.QuoteRefund(booking, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, RefundFull, quote.Rule)
+	assert.Equal(t, 80.0, quote.Amount)
+
+	// a refund completes only once it began
+	assert.ErrorIs(t, service.CompleteRefund(booking.ID, "re_early"), ErrNotRefundable)
+	assert.NoError(t, service.BeginRefund(booking.ID, quote.Amount, 0))
+	// retried activities are harmless
+	assert.NoError(t, service.BeginRefund(booking.ID, quote.Amount, 0))
+	// the seats are held until the money is back
+	got, _ := service.GetShowByID(show.ID)
+	assert.Equal(t, 8, got.AvailableSeats)
+
+	refundID, err := service.IssueRefund(booking.ID, quote.Amount)
+	assert.NoError(t, err)
+	again, err := service.IssueRefund(booking.ID, quote.Amount)
+	assert.NoError(t, err)
+	assert.Equal(t, refundID, again)
+
+	assert.NoError(t, service.CompleteRefund(booking.ID, refundID))
+	refunded, _ := service.GetBookingById(booking.ID)
+	assert.Equal(t, "cancelled", refunded.Status)
+	assert.Equal(t, RefundStatusRefunded, refunded.RefundStatus)
+	assert.Equal(t, 80.0, refunded.RefundAmount)
+	got, _ = service.GetShowByID(show.ID)
+	assert.Equal(t, 10, got.AvailableSeats)
+	var stored models.Show
+	service.Db.First(&stored, show.ID)
+	assert.Equal(t, 10, stored.AvailableSeats)
+
+	payments, _ := service.GetPaymentsByBooking(booking.ID)
+	assert.Equal(t, models.PaymentRefunded, payments[0].Status)
+}
+
+func TestRefundSagaCompensation(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	show, booking := createPaidBooking(t, service)
+	service.Payments.(*payment.MockProvider).FailRefunds = true
+	tickets, _ := service.IssueTickets(booking)
+
+	assert.NoError(t, service.BeginRefund(booking.ID, 80, 0))
+	_, err := service.IssueRefund(booking.ID, 80)
+	assert.ErrorIs(t, err, payment.ErrDeclined)
+	assert.NoError(t, service.AbortRefund(booking.ID))
+
+	restored, _ := service.GetBookingById(booking.ID)
+	assert.Equal(t, "confirmed", restored.Status)
+	assert.Equal(t, RefundStatusFailed, restored.RefundStatus)
+	got, _ := service.GetShowByID(show.ID)
+	assert.Equal(t, 8, got.AvailableSeats)
+
+	// the old codes are void, new tickets were issued
+	_, err = service.CheckIn(tickets[0].Code, "A", show.ID, 1)
+	assert.ErrorIs(t, err, ErrTicketVoid)
+	newTickets, _ := service.IssueTickets(restored)
+	assert.Len(t, newTickets, 2)
+	assert.NotEqual(t, tickets[0].Code, newTickets[0].Code)
+}
+
+func TestAbortRefundBegunWithSeatsReleased(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	show, booking := createPaidBooking(t, service)
+
+	// refunds begun before the seats were held released them when they began
+	service.Db.Model(&booking).Updates(map[string]any{"status": "cancelled", "refund_status": RefundStatusPending, "refund_amount": 80})
+	service.Db.Model(&show).Update("available_seats", 1)
+	assert.ErrorIs(t, service.AbortRefund(booking.ID), ErrSeatsResold)
+	failed, _ := service.GetBookingById(booking.ID)
+	assert.Equal(t, "cancelled", failed.Status)
+	assert.Equal(t, RefundStatusFailed, failed.RefundStatus)
+
+	service.Db.Model(&booking).Update("refund_status", RefundStatusPending)
+	service.Db.Model(&show).Update("available_seats", 10)
+	assert.NoError(t, service.AbortRefund(booking.ID))
+	restored, _ := service.GetBookingById(booking.ID)
+	assert.Equal(t, "confirmed", restored.Status)
+	var stored models.Show
+	service.Db.First(&stored, show.ID)
+	assert.Equal(t, 8, stored.AvailableSeats)
+}
+
+func TestQuoteRefundAfterPartialResale(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	_, booking := createPaidBooking(t, service)
+	// one of the two seats resold: the seller got 36 of its 40 back
+	service.Db.Model(&booking).Update("total_price", 40)
+	service.Db.Model(&models.Payment{}).Where("booking_id = ?", booking.ID).Update("refunded", 36)
+	booking, _ = service.GetBookingById(booking.ID)
+
+	quote, err := service.QuoteRefund(booking, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 40.0, quote.Amount)
+}
+
+func TestMockRefundOfUnknownIntent(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	_, err := service.Payments.Refund(context.Background(), "pi_mock_unknown", 100, "refund-unknown")
+	assert.ErrorIs(t, err, payment.ErrUnknownIntent)
+}
+
+func TestMockRefundIdempotencyKey(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	intent, _ := service.Payments.CreateIntent(context.Background(), 1000, "eur", "booking-1")
+	assert.NoError(t, service.Payments.Capture(context.Background(), intent.ID))
+
+	first, err := service.Payments.Refund(context.Background(), intent.ID, 400, "refund-booking-1")
+	assert.NoError(t, err)
+	// the retry gets the same refund back, the money goes out once
+	again, err := service.Payments.Refund(context.Background(), intent.ID, 400, "refund-booking-1")
+	assert.NoError(t, err)
+	assert.Equal(t, first, again)
+	var stored payment.MockIntent
+	service.Db.First(&stored, "id = ?", intent.ID)
+	assert.Equal(t, int64(400), stored.Refunded)
+
+	_, err = service.Payments.Refund(context.Background(), intent.ID, 300, "refund-booking-1")
+	assert.Error(t, err)
+	_, err = service.Payments.Refund(context.Background(), intent.ID, 300, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,468 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/payment"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrResaleAboveCap     = errors.New("the price is above the resale cap of this show")
+	ErrResaleInvalidCount = errors.New("invalid number of tickets for this booking")
+	ErrResaleListed       = errors.New("this booking is listed for resale")
+	ErrResaleUnavailable  = errors.New("this listing is not available")
+	ErrResaleOwnListing   = errors.New("you can't buy your own listing")
+	ErrResaleShowOver     = errors.New("the show has already taken place")
+)
+
+const (
+	// PayoutProcessing marks a resale payout being sent, so that it is sent once
+	PayoutProcessing = "processing"
+	// resalePayoutRetryDelay leaves the payout of a sale to the checkout before the
+	// worker takes it over, a payout processing for longer is taken as interrupted
+	resalePayoutRetryDelay = 10 * time.Minute
+)
+
+// resalePriceCap is the highest price per ticket a booking can be resold at
+func resalePriceCap(show models.Show, booking models.Booking) float64 {
+	if show.ResalePriceCap > 0 {
+		return show.ResalePriceCap
+	}
+	if booking.TicketCount == 0 {
+		return 0
+	}
+	return booking.TotalPrice / float64(booking.TicketCount)
+}
+
+func hasOpenListing(tx *gorm.DB, bookingID uint) (bool, error) {
+	listed, err := listedTicketCount(tx, bookingID)
+	return listed > 0, err
+}
+
+// listedTicketCount is the number of tickets of a booking on sale or in a buyer's checkout
+func listedTicketCount(tx *gorm.DB, bookingID uint) (int, error) {
+	var count int64
+	err := tx.Model(&models.ResaleListing{}).
+		Select("COALESCE(SUM(ticket_count), 0)").
+		Where("booking_id = ? AND status IN ?", bookingID, []string{models.ResaleActive, models.ResaleReserved}).
+		Scan(&count).Error
+	return int(count), err
+}
+
+// checkResaleShow tells whether the tickets of a show can still change hands
+func checkResaleShow(show models.Show, now time.Time) error {
+	if err := checkShowStatus(show, now); err != nil {
+		return err
+	}
+	if !show.StartsAt.After(now) {
+		return ErrResaleShowOver
+	}
+	return nil
+}
+
+// CreateResaleListing puts count tickets of a confirmed booking of seller on sale. The
+// codes of the booking are voided, the seller gets tickets again for the seats not listed.
+func (s Service) CreateResaleListing(booking models.Booking, seller models.User, count int, price float64) (models.ResaleListing, error) {
+	if booking.Status != "confirmed" || booking.UserID != seller.ID {
+		return models.ResaleListing{}, ErrBookingNotConfirmed
+	}
+	if booking.FestivalID != nil {
+		return models.ResaleListing{}, ErrFestivalPass
+	}
+	if count <= 0 || count > booking.TicketCount {
+		return models.ResaleListing{}, ErrResaleInvalidCount
+	}
+	show, err := s.GetShowByID(booking.ShowID)
+	if err != nil {
+		return models.ResaleListing{}, err
+	}
+	if err := checkResaleShow(show, time.Now()); err != nil {
+		return models.ResaleListing{}, err
+	}
+	if price <= 0 || payment.ToCents(price) > payment.ToCents(resalePriceCap(show, booking)) {
+		return models.ResaleListing{}, ErrResaleAboveCap
+	}
+
+	listing := models.ResaleListing{
+		BookingID:      booking.ID,
+		SellerID:       seller.ID,
+		ShowID:         booking.ShowID,
+		TicketCount:    count,
+		PricePerTicket: price,
+		Status:         models.ResaleActive,
+	}
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		if open, err := hasOpenListing(tx, booking.ID); err != nil || open {
+			if err == nil {
+				err = ErrResaleListed
+			}
+			return err
+		}
+		var pending int64
+		if err := tx.Model(&models.Transfer{}).
+			Where("booking_id = ? AND status = ? AND expires_at > ?", booking.ID, models.TransferPending, time.Now()).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return ErrTransferPending
+		}
+		// the seats already scanned at the door can't be sold
+		var used int64
+		if err := tx.Model(&models.Ticket{}).
+			Where("booking_id = ? AND status = ?", booking.ID, models.TicketUsed).
+			Count(&used).Error; err != nil {
+			return err
+		}
+		if count > booking.TicketCount-int(used) {
+			return ErrResaleInvalidCount
+		}
+		if err := tx.Create(&listing).Error; err != nil {
+			return err
+		}
+		if err := voidTickets(tx, booking.ID); err != nil {
+			return err
+		}
+		return s.audit(tx, booking.ID, models.AuditResaleListed, &seller.ID,
+			fmt.Sprintf("%d tickets at %.2f", count, price))
+	})
+	if err != nil {
+		return models.ResaleListing{}, err
+	}
+	return listing, nil
+}
+
+// ListResaleListings lists the listings of a show, or of every show when showID is zero.
+func (s Service) ListResaleListings(showID uint, status string) ([]models.ResaleListing, error) {
+	query := s.Db.Order("price_per_ticket, id")
+	if showID != 0 {
+		query = query.Where("show_id = ?", showID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var listings []models.ResaleListing
+	if err := query.Find(&listings).Error; err != nil {
+		return nil, err
+	}
+	return listings, nil
+}
+
+func (s Service) GetResaleListing(id uint) (models.ResaleListing, error) {
+	var listing models.ResaleListing
+	if err := s.Db.First(&listing, id).Error; err != nil {
+		return models.ResaleListing{}, err
+	}
+	return listing, nil
+}
+
+// CancelResaleListing withdraws an active listing of seller.
+func (s Service) CancelResaleListing(id uint, seller models.User) error {
+	result := s.Db.Model(&models.ResaleListing{}).
+		Where("id = ? AND seller_id = ? AND status = ?", id, seller.ID, models.ResaleActive).
+		Update("status", models.ResaleCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrResaleUnavailable
+	}
+	return nil
+}
+
+// RemoveResaleListing takes an active listing down for moderation.
+func (s Service) RemoveResaleListing(id uint, note string) error {
+	result := s.Db.Model(&models.ResaleListing{}).
+		Where("id = ? AND status = ?", id, models.ResaleActive).
+		Updates(map[string]any{"status": models.ResaleRemoved, "moderation_note": note})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrResaleUnavailable
+	}
+	return nil
+}
+
+// BuyResaleListing reserves a listing for buyer and creates their pending booking,
+// paid through the same checkout as a regular booking.
+func (s Service) BuyResaleListing(id uint, buyer models.User) (models.Booking, error) {
+	listing, err := s.GetResaleListing(id)
+	if err != nil || listing.Status != models.ResaleActive {
+		return models.Booking{}, ErrResaleUnavailable
+	}
+	if listing.SellerID == buyer.ID {
+		return models.Booking{}, ErrResaleOwnListing
+	}
+	show, err := s.GetShowByID(listing.ShowID)
+	if err != nil {
+		return models.Booking{}, err
+	}
+	if err := checkResaleShow(show, time.Now()); err != nil {
+		return models.Booking{}, err
+	}
+	if err := s.CheckTicketLimits(show, buyer, listing.TicketCount); err != nil {
+		return models.Booking{}, err
+	}
+
+	total := listing.PricePerTicket * float64(listing.TicketCount)
+	booking := models.Booking{
+		UserID:          buyer.ID,
+		ShowID:          listing.ShowID,
+		TicketCount:     listing.TicketCount,
+		TotalPrice:      total,
+		Status:          "pending",
+		ResaleListingID: &listing.ID,
+		// the tax was paid with the first sale of the seats
+		PriceBreakdown: models.PriceBreakdown{NetAmount: total},
+	}
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&booking).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&listing).Where("status = ?", models.ResaleActive).
+			Updates(map[string]any{"status": models.ResaleReserved, "reserved_booking_id": booking.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrResaleUnavailable
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Booking{}, err
+	}
+	return booking, nil
+}
+
+// completeResale moves the seats of a paid resale booking from the seller's booking.
+// The seller's old codes are voided, tickets for what they keep are issued again on demand.
+func (s Service) completeResale(tx *gorm.DB, buyerBookingID uint) error {
+	var buyer models.Booking
+	if err := tx.First(&buyer, buyerBookingID).Error; err != nil {
+		return err
+	}
+	if buyer.ResaleListingID == nil {
+		return nil
+	}
+
+	var listing models.ResaleListing
+	if err := tx.First(&listing, *buyer.ResaleListingID).Error; err != nil {
+		return err
+	}
+	var seller models.Booking
+	if err := tx.First(&seller, listing.BookingID).Error; err != nil {
+		return err
+	}
+	var show models.Show
+	if err := tx.First(&show, listing.ShowID).Error; err != nil {
+		return err
+	}
+
+	// the sold seats leave the seller's booking at face value, the rounding stays with the seats kept
+	var faceValue int64
+	if seller.TicketCount > 0 {
+		faceValue = payment.ToCents(seller.TotalPrice) / int64(seller.TicketCount)
+	}
+	soldFaceValue := faceValue * int64(listing.TicketCount)
+	sold := payment.ToCents(listing.PricePerTicket) * int64(listing.TicketCount)
+	feeCents := sold * int64(show.ResaleFeePercent) / 100
+	// a refund can't exceed what the seller was charged for these seats
+	payoutCents := min(sold-feeCents, soldFaceValue)
+
+	now := time.Now()
+	if err := tx.Model(&listing).Updates(map[string]any{
+		"status":           models.ResaleSold,
+		"buyer_booking_id": buyer.ID,
+		"face_value":       payment.FromCents(faceValue),
+		"fee":              payment.FromCents(feeCents),
+		"payout":           payment.FromCents(payoutCents),
+		"payout_status":    RefundStatusPending,
+		"sold_at":          now,
+	}).Error; err != nil {
+		return err
+	}
+
+	remaining := seller.TicketCount - listing.TicketCount
+	// the fees and the tax of the seats kept stay in proportion
+	updates := shrinkBreakdown(seller, payment.ToCents(seller.TotalPrice)-soldFaceValue)
+	updates["ticket_count"] = remaining
+	updates["total_price"] = payment.FromCents(payment.ToCents(seller.TotalPrice) - soldFaceValue)
+	if remaining == 0 {
+		updates["status"] = "resold"
+	}
+	if err := tx.Model(&seller).Updates(updates).Error; err != nil {
+		return err
+	}
+	if err := s.audit(tx, seller.ID, models.AuditResaleSold, &buyer.UserID,
+		fmt.Sprintf("%d tickets to booking %d", listing.TicketCount, buyer.ID)); err != nil {
+		return err
+	}
+	return tx.Model(&models.Ticket{}).
+		Where("booking_id = ? AND status = ?", seller.ID, models.TicketValid).
+		Update("status", models.TicketVoid).Error
+}
+
+// settleResale issues the tickets the seller kept and refunds them for the sold ones.
+// Payout failures stay visible to the admins on the listing with a failed payout status.
+func (s Service) settleResale(buyerBookingID uint) {
+	var listing models.ResaleListing
+	if err := s.Db.Where("buyer_booking_id = ? AND payout_status = ?", buyerBookingID, RefundStatusPending).First(&listing).Error; err != nil {
+		return
+	}
+	claimed, err := s.claimPayout(&listing)
+	if err == nil && claimed {
+		err = s.payOutResale(listing)
+	}
+	if err != nil {
+		log.Printf("Error paying out resale listing %d: %v", listing.ID, err)
+	}
+}
+
+// claimPayout takes over the pending payout of a sold listing, unless another run did,
+// and sets the part of it paid back on the card before anything is sent.
+func (s Service) claimPayout(listing *models.ResaleListing) (bool, error) {
+	payout := payment.ToCents(listing.Payout)
+	var toCard int64
+	var p models.Payment
+	if err := s.Db.Where("booking_id = ? AND status = ?", listing.BookingID, models.PaymentSucceeded).First(&p).Error; err == nil {
+		toCard = min(payout, payment.ToCents(p.Amount)-payment.ToCents(p.Refunded))
+	}
+	claim := s.Db.Model(&models.ResaleListing{}).
+		Where("id = ? AND payout_status = ?", listing.ID, RefundStatusPending).
+		Updates(map[string]any{
+			"payout_status": PayoutProcessing,
+			"payout_ref":    fmt.Sprintf("resale-payout-%d", listing.ID),
+			"payout_card":   payment.FromCents(max(toCard, 0)),
+		})
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return false, claim.Error
+	}
+	return true, s.Db.First(listing, listing.ID).Error
+}
+
+// payOutResale sends the payout of a claimed listing. Each step is done once: a payout
+// interrupted is taken over again with the same refund key and amount.
+func (s Service) payOutResale(listing models.ResaleListing) error {
+	var seller models.Booking
+	if err := s.Db.First(&seller, listing.BookingID).Error; err == nil && seller.Status == "confirmed" {
+		if _, err := s.IssueTickets(seller); err != nil {
+			log.Printf("Error issuing tickets for booking %d: %v", seller.ID, err)
+		}
+	}
+
+	toCard := payment.ToCents(listing.PayoutCard)
+	if toCard > 0 && listing.PayoutRefundID == "" {
+		var p models.Payment
+		if err := s.Db.Where("booking_id = ? AND status = ?", listing.BookingID, models.PaymentSucceeded).First(&p).Error; err != nil {
+			return err
+		}
+		refundID, err := s.Payments.Refund(context.Background(), p.IntentID, toCard, listing.PayoutRef)
+		if err != nil {
+			log.Printf("Error paying out resale listing %d: %v", listing.ID, err)
+			return s.Db.Model(&listing).Where("payout_status = ?", PayoutProcessing).
+				Update("payout_status", RefundStatusFailed).Error
+		}
+		if err := s.Db.Transaction(func(tx *gorm.DB) error {
+			recorded := tx.Model(&listing).Where("payout_refund_id = ?", "").Update("payout_refund_id", refundID)
+			if recorded.Error != nil || recorded.RowsAffected == 0 {
+				return recorded.Error
+			}
+			if err := tx.Model(&p).Update("refunded", gorm.Expr("refunded + ?", payment.FromCents(toCard))).Error; err != nil {
+				return err
+			}
+			return recordRefund(tx, seller, fmt.Sprintf("payout:resale:%d", listing.ID), toCard)
+		}); err != nil {
+			// the refund is made, the listing stays processing to record it again
+			return fmt.Errorf("recording payout of resale listing %d: %w", listing.ID, err)
+		}
+	}
+
+	payout := payment.ToCents(listing.Payout)
+	status := RefundStatusRefunded
+	if payout == 0 {
+		// nothing was charged, e.g. a free show
+		status = ""
+	}
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		done := tx.Model(&listing).Where("payout_status = ?", PayoutProcessing).Update("payout_status", status)
+		if done.Error != nil || done.RowsAffected == 0 {
+			return done.Error
+		}
+		if payout <= toCard {
+			return nil
+		}
+		// what the payment can't take back, e.g. seats paid with credit, goes to the account credit
+		return AddCredit(tx, &models.CreditEntry{
+			UserID:    listing.SellerID,
+			Amount:    payment.FromCents(payout - toCard),
+			Kind:      models.CreditRefund,
+			BookingID: &listing.BookingID,
+			Note:      "resale payout",
+		})
+	})
+}
+
+// PayOutDueResales sends the payouts the checkout left pending, e.g. when the server
+// stopped right after a sale, and takes over those interrupted while processing.
+func (s Service) PayOutDueResales(now time.Time) (int, error) {
+	var due []models.ResaleListing
+	if err := s.Db.
+		Where("status = ? AND ((payout_status = ? AND sold_at < ?) OR (payout_status = ? AND updated_at < ?))",
+			models.ResaleSold, RefundStatusPending, now.Add(-resalePayoutRetryDelay),
+			PayoutProcessing, now.Add(-resalePayoutRetryDelay)).
+		Order("sold_at").
+		Limit(100).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+	paid := 0
+	for _, listing := range due {
+		var claimed bool
+		var err error
+		if listing.PayoutStatus == RefundStatusPending {
+			claimed, err = s.claimPayout(&listing)
+		} else {
+			// touched so that a single run takes an interrupted payout over
+			claim := s.Db.Model(&listing).
+				Where("payout_status = ? AND updated_at = ?", PayoutProcessing, listing.UpdatedAt).
+				Update("updated_at", now)
+			claimed, err = claim.RowsAffected == 1, claim.Error
+		}
+		if err == nil && claimed {
+			err = s.payOutResale(listing)
+		}
+		if err != nil {
+			return paid, err
+		}
+		if claimed {
+			paid++
+		}
+	}
+	return paid, nil
+}
+
+// RunResalePayouts polls for the payouts left pending until ctx is cancelled.
+func (s Service) RunResalePayouts(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n, err := s.PayOutDueResales(now); err != nil {
+				log.Printf("Error paying out resales: %v", err)
+			} else if n > 0 {
+				log.Printf("Paid out %d resale listings left pending", n)
+			}
+		}
+	}
+}
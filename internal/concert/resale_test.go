@@ -0,0 +1,229 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/payment"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestResaleListing(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	show, booking := createPaidBooking(t, service)
+	seller := models.User{Username: "seller", Email: "seller@example.com"}
+	seller.ID = booking.UserID
+	service.Db.Create(&seller)
+	buyer := models.User{Username: "buyer", Email: "buyer@example.com"}
+	service.Db.Create(&buyer)
+
+	// face value is the cap by default
+	_, err := service.CreateResaleListing(booking, seller, 1, 45)
+	assert.ErrorIs(t, err, ErrResaleAboveCap)
+	_, err = service.CreateResaleListing(booking, seller, 3, 40)
+	assert.ErrorIs(t, err, ErrResaleInvalidCount)
+
+	listing, err := service.CreateResaleListing(booking, seller, 1, 40)
+	assert.NoError(t, err)
+	_, err = service.CreateResaleListing(booking, seller, 1, 40)
+	assert.ErrorIs(t, err, ErrResaleListed)
+	_, err = service.QuoteRefund(booking, time.Now())
+	assert.ErrorIs(t, err, ErrResaleListed)
+
+	_, err = service.BuyResaleListing(listing.ID, seller)
+	assert.ErrorIs(t, err, ErrResaleOwnListing)
+
+	bought, err := service.BuyResaleListing(listing.ID, buyer)
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", bought.Status)
+	_, err = service.BuyResaleListing(listing.ID, buyer)
+	assert.ErrorIs(t, err, ErrResaleUnavailable)
+
+	// the seats stay with the seller during the checkout
+	got, _ := service.GetShowByID(show.ID)
+	assert.Equal(t, 8, got.AvailableSeats)
+
+	_, _, err = service.StartPayment(bought)
+	assert.NoError(t, err)
+	bought, _, err = service.SimulatePayment(bought)
+	assert.NoError(t, err)
+	assert.Equal(t, "confirmed", bought.Status)
+
+	sold, _ := service.GetResaleListing(listing.ID)
+	assert.Equal(t, models.ResaleSold, sold.Status)
+	assert.Equal(t, 4.0, sold.Fee)
+	assert.Equal(t, 36.0, sold.Payout)
+	assert.Equal(t, RefundStatusRefunded, sold.PayoutStatus)
+
+	kept, _ := service.GetBookingById(booking.ID)
+	assert.Equal(t, "confirmed", kept.Status)
+	assert.Equal(t, 1, kept.TicketCount)
+	assert.Equal(t, 40.0, kept.TotalPrice)
+	tickets, _ := service.GetTicketsByBooking(kept.ID)
+	valid := 0
+	for _, ticket := range tickets {
+		if ticket.Status == models.TicketValid {
+			valid++
+		}
+	}
+	assert.Equal(t, 1, valid)
+
+	got, _ = service.GetShowByID(show.ID)
+	assert.Equal(t, 8, got.AvailableSeats)
+}
+
+func TestResaleCheckoutFailureRelistsTickets(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	show, booking := createPaidBooking(t, service)
+	service.Db.Model(&show).Update("resale_price_cap", 50)
+	seller := models.User{Username: "seller", Email: "seller@example.com"}
+	seller.ID = booking.UserID
+	service.Db.Create(&seller)
+	buyer := models.User{Username: "buyer", Email: "buyer@example.com"}
+	service.Db.Create(&buyer)
+
+	// the show cap allows more than face value, a total ending in .02 is declined by the mock provider
+	listing, err := service.CreateResaleListing(booking, seller, 2, 45.01)
+	assert.NoError(t, err)
+	bought, err := service.BuyResaleListing(listing.ID, buyer)
+	assert.NoError(t, err)
+	_, _, err = service.StartPayment(bought)
+	assert.NoError(t, err)
+	bought, _, err = service.SimulatePayment(bought)
+	assert.NoError(t, err)
+	assert.Equal(t, "payment_failed", bought.Status)
+
+	relisted, _ := service.GetResaleListing(listing.ID)
+	assert.Equal(t, models.ResaleActive, relisted.Status)
+	kept, _ := service.GetBookingById(booking.ID)
+	assert.Equal(t, 2, kept.TicketCount)
+
+	assert.NoError(t, service.RemoveResaleListing(listing.ID, "spam"))
+	assert.ErrorIs(t, service.CancelResaleListing(listing.ID, seller), ErrResaleUnavailable)
+}
+
+func TestResaleListingLocksTickets(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	show, booking := createPaidBooking(t, service)
+	seller := models.User{Username: "seller", Email: "seller@example.com"}
+	seller.ID = booking.UserID
+	service.Db.Create(&seller)
+	issued, err := service.IssueTickets(booking)
+	assert.NoError(t, err)
+	assert.Len(t, issued, 2)
+
+	// the codes the seller holds stop working, the seat kept gets a new one
+	listing, err := service.CreateResaleListing(booking, seller, 1, 40)
+	assert.NoError(t, err)
+	_, err = service.CheckIn(issued[0].Code, "A", show.ID, 0)
+	assert.ErrorIs(t, err, ErrTicketVoid)
+	tickets, err := service.IssueTickets(booking)
+	assert.NoError(t, err)
+	assert.Len(t, tickets, 1)
+
+	assert.NoError(t, service.CancelResaleListing(listing.ID, seller))
+	tickets, _ = service.IssueTickets(booking)
+	assert.Len(t, tickets, 2)
+
+	// a scanned seat can't be sold
+	_, err = service.CheckIn(tickets[0].Code, "A", show.ID, 0)
+	assert.NoError(t, err)
+	_, err = service.CreateResaleListing(booking, seller, 2, 40)
+	assert.ErrorIs(t, err, ErrResaleInvalidCount)
+
+	listing, err = service.CreateResaleListing(booking, seller, 1, 40)
+	assert.NoError(t, err)
+	service.Db.Model(&show).Updates(map[string]any{"status": models.ShowCancelled, "status_reason": "Illness"})
+	buyer := models.User{Username: "buyer", Email: "buyer@example.com"}
+	service.Db.Create(&buyer)
+	_, err = service.BuyResaleListing(listing.ID, buyer)
+	assert.ErrorIs(t, err, ErrShowCancelled)
+
+	service.Db.Model(&show).Updates(map[string]any{"status": models.ShowPublished, "starts_at": time.Now().Add(-time.Hour)})
+	_, err = service.BuyResaleListing(listing.ID, buyer)
+	assert.ErrorIs(t, err, ErrResaleShowOver)
+}
+
+func TestPayOutDueResales(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	_, booking := createPaidBooking(t, service)
+	seller := models.User{Username: "seller", Email: "seller@example.com"}
+	seller.ID = booking.UserID
+	service.Db.Create(&seller)
+	buyer := models.User{Username: "buyer", Email: "buyer@example.com"}
+	service.Db.Create(&buyer)
+
+	listing, err := service.CreateResaleListing(booking, seller, 1, 40)
+	assert.NoError(t, err)
+	bought, err := service.BuyResaleListing(listing.ID, buyer)
+	assert.NoError(t, err)
+	// the server stopped between the sale and its payout
+	assert.NoError(t, service.Db.Transaction(func(tx *gorm.DB) error {
+		tx.Model(&bought).Update("status", "confirmed")
+		return service.completeResale(tx, bought.ID)
+	}))
+	sold, _ := service.GetResaleListing(listing.ID)
+	assert.Equal(t, RefundStatusPending, sold.PayoutStatus)
+
+	n, err := service.PayOutDueResales(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	n, err = service.PayOutDueResales(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	sold, _ = service.GetResaleListing(listing.ID)
+	assert.Equal(t, RefundStatusRefunded, sold.PayoutStatus)
+	var p models.Payment
+	service.Db.Where("booking_id = ?", booking.ID).First(&p)
+	assert.Equal(t, 36.0, p.Refunded)
+
+	// paid out once
+	n, _ = service.PayOutDueResales(time.Now().Add(time.Hour))
+	assert.Equal(t, 0, n)
+}
+
+func TestPayOutResumesInterruptedPayout(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	_, booking := createPaidBooking(t, service)
+	seller := models.User{Username: "seller", Email: "seller@example.com"}
+	seller.ID = booking.UserID
+	service.Db.Create(&seller)
+	buyer := models.User{Username: "buyer", Email: "buyer@example.com"}
+	service.Db.Create(&buyer)
+
+	listing, _ := service.CreateResaleListing(booking, seller, 1, 40)
+	bought, _ := service.BuyResaleListing(listing.ID, buyer)
+	assert.NoError(t, service.Db.Transaction(func(tx *gorm.DB) error {
+		tx.Model(&bought).Update("status", "confirmed")
+		return service.completeResale(tx, bought.ID)
+	}))
+	// the server stopped right after the provider paid the seller back
+	sold, _ := service.GetResaleListing(listing.ID)
+	claimed, err := service.claimPayout(&sold)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	var p models.Payment
+	service.Db.Where("booking_id = ?", booking.ID).First(&p)
+	_, err = service.Payments.Refund(context.Background(), p.IntentID, payment.ToCents(sold.PayoutCard), sold.PayoutRef)
+	assert.NoError(t, err)
+
+	n, err := service.PayOutDueResales(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	sold, _ = service.GetResaleListing(listing.ID)
+	assert.Equal(t, RefundStatusRefunded, sold.PayoutStatus)
+	assert.NotEmpty(t, sold.PayoutRefundID)
+	service.Db.First(&p, p.ID)
+	assert.Equal(t, 36.0, p.Refunded)
+	var intent payment.MockIntent
+	service.Db.First(&intent, "id = ?", p.IntentID)
+	assert.Equal(t, int64(3600), intent.Refunded)
+	entries, _ := service.GetCreditEntries(seller.ID)
+	assert.Empty(t, entries)
+
+	n, _ = service.PayOutDueResales(time.Now().Add(2 * time.Hour))
+	assert.Equal(t, 0, n)
+}
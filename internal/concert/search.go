@@ -0,0 +1,433 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"errors"
+	"html"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// kinds of search results
+const (
+	SearchArtist = "artist"
+	SearchShow   = "show"
+)
+
+// search backends, picked from the database at each search
+const (
+	searchPostgres = "postgres"
+	searchFTS5     = "fts5"
+	searchLike     = "like"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 50
+	maxSearchTerms     = 8
+)
+
+var ErrEmptySearch = errors.New("the search needs at least a word of two letters")
+
+// SearchResult is an artist or a show matching a search. Title and Snippet are HTML
+// escaped, their matched words wrapped in <mark> tags.
+type SearchResult struct {
+	Kind    string     `json:"kind"`
+	ID      uint       `json:"id"`
+	Title   string     `json:"title"`
+	Snippet string     `json:"snippet"`
+	Date    *time.Time `json:"date,omitempty"`
+	Rank    float64    `json:"rank"`
+}
+
+// SearchResults are the results of a search, best first. Corrected is the search that
+// ran instead of Query when Query had typos.
+type SearchResults struct {
+	Query     string         `json:"query"`
+	Corrected string         `json:"corrected,omitempty"`
+	Results   []SearchResult `json:"results"`
+}
+
+// searchBackend finds the documents matching any of the terms, each term being the
+// prefix of a word, and lists the indexed words starting with a letter.
+type searchBackend interface {
+	match(terms []string, since *time.Time, limit int) ([]SearchResult, error)
+	words(first string) ([]string, error)
+}
+
+// SetupSearch prepares the search index of the database: generated tsvector columns on
+// Postgres, an FTS5 table kept up to date by triggers on SQLite. SQLite builds without
+// FTS5 search with LIKE.
+func SetupSearch(db *gorm.DB) error {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return setupPostgresSearch(db)
+	case "sqlite":
+		return setupFTS5Search(db)
+	}
+	return nil
+}
+
+func (s Service) searchBackend() searchBackend {
+	switch s.Db.Dialector.Name() {
+	case "postgres":
+		return postgresSearch{s.Db}
+	case "sqlite":
+		if s.Db.Migrator().HasTable(fts5Table) {
+			return fts5Search{s.Db}
+		}
+	}
+	return likeSearch{s.Db}
+}
+
+// searchTerms splits text in lowercase words of two letters or more
+func searchTerms(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(word) >= 2 && len(terms) < maxSearchTerms {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// Search finds the artists and the upcoming shows, or all of them with includePast,
+// matching text. A show matches on its artist too. When nothing matches, the words
+// that match no indexed word are replaced by the closest one and the search runs again.
+func (s Service) Search(text string, includePast bool, limit int) (SearchResults, error) {
+	terms := searchTerms(text)
+	if len(terms) == 0 {
+		return SearchResults{}, ErrEmptySearch
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	limit = min(limit, MaxSearchLimit)
+	var since *time.Time
+	if !includePast {
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		since = &today
+	}
+
+	backend := s.searchBackend()
+	results := SearchResults{Query: text, Results: []SearchResult{}}
+	found, err := backend.match(terms, since, limit)
+	if err != nil {
+		return SearchResults{}, err
+	}
+	if len(found) == 0 {
+		corrected, changed, err := correctTerms(terms, backend.words)
+		if err != nil {
+			return SearchResults{}, err
+		}
+		if changed {
+			if found, err = backend.match(corrected, since, limit); err != nil {
+				return SearchResults{}, err
+			}
+			results.Corrected = strings.Join(corrected, " ")
+		}
+	}
+	results.Results = append(results.Results, found...)
+	return results, nil
+}
+
+// maxTypos is how many edits a word of n letters may have, short words are taken as typed
+func maxTypos(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	}
+	return 2
+}
+
+// correctTerms replaces each term that starts no indexed word by the closest word
+// sharing its first letter, within maxTypos edits of the word or of its prefix.
+func correctTerms(terms []string, words func(first string) ([]string, error)) ([]string, bool, error) {
+	corrected := make([]string, len(terms))
+	changed := false
+	for i, term := range terms {
+		corrected[i] = term
+		typos := maxTypos(utf8.RuneCountInString(term))
+		if typos == 0 {
+			continue
+		}
+		first, _ := utf8.DecodeRuneInString(term)
+		candidates, err := words(string(first))
+		if err != nil {
+			return nil, false, err
+		}
+
+		best, bestDistance := "", typos+1
+		for _, word := range candidates {
+			if strings.HasPrefix(word, term) {
+				best = ""
+				break
+			}
+			distance := editDistance(term, word)
+			if runes := []rune(word); len(runes) > len([]rune(term)) {
+				distance = min(distance, editDistance(term, string(runes[:len([]rune(term))])))
+			}
+			if distance < bestDistance || (distance == bestDistance && word < best) {
+				best, bestDistance = word, distance
+			}
+		}
+		if best != "" {
+			corrected[i] = best
+			changed = true
+		}
+	}
+	return corrected, changed, nil
+}
+
+// editDistance is the Damerau-Levenshtein distance of a and b: insertions, deletions,
+// substitutions and swaps of two neighbour letters.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	rows := make([][]int, len(ra)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(rb)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(ra)][len(rb)]
+}
+
+// likeSearch searches the tables with LIKE and ranks in Go, for the databases without
+// a full-text index. Title matches count three times a match elsewhere.
+type likeSearch struct {
+	db *gorm.DB
+}
+
+// likeDocument is an artist or a show as searched by likeSearch
+type likeDocument struct {
+	result SearchResult
+	body   string
+}
+
+func (l likeSearch) documents(terms []string, since *time.Time) ([]likeDocument, error) {
+	var artists []models.Artist
+	var shows []models.Show
+	artistQuery := l.db.Model(&models.Artist{})
+	showQuery := listedShows(l.db.Preload("Artist").Model(&models.Show{}), time.Now())
+	if since != nil {
+		showQuery = showQuery.Where("starts_at >= ?", *since)
+	}
+	if terms != nil {
+		var artistConds, showConds []string
+		var artistArgs, showArgs []any
+		for _, term := range terms {
+			pattern := likePattern(term)
+			artistConds = append(artistConds, `LOWER(name) LIKE ? ESCAPE '\' OR LOWER(genre) LIKE ? ESCAPE '\' OR LOWER(bio) LIKE ? ESCAPE '\'`)
+			artistArgs = append(artistArgs, pattern, pattern, pattern)
+			showConds = append(showConds, `LOWER(title) LIKE ? ESCAPE '\' OR LOWER(description) LIKE ? ESCAPE '\' OR LOWER(venue) LIKE ? ESCAPE '\' OR LOWER(city) LIKE ? ESCAPE '\' OR `+
+				`artist_id IN (SELECT id FROM artists WHERE deleted_at IS NULL AND (LOWER(name) LIKE ? ESCAPE '\' OR LOWER(genre) LIKE ? ESCAPE '\'))`)
+			showArgs = append(showArgs, pattern, pattern, pattern, pattern, pattern, pattern)
+		}
+		artistQuery = artistQuery.Where("("+strings.Join(artistConds, " OR ")+")", artistArgs...)
+		showQuery = showQuery.Where("("+strings.Join(showConds, " OR ")+")", showArgs...)
+	}
+	if err := artistQuery.Find(&artists).Error; err != nil {
+		return nil, err
+	}
+	if err := showQuery.Find(&shows).Error; err != nil {
+		return nil, err
+	}
+
+	var docs []likeDocument
+	for _, artist := range artists {
+		docs = append(docs, likeDocument{
+			result: SearchResult{Kind: SearchArtist, ID: artist.ID, Title: artist.Name},
+			body:   strings.Join([]string{artist.Genre, artist.Bio}, " "),
+		})
+	}
+	for _, show := range shows {
+		date := show.StartsAt
+		docs = append(docs, likeDocument{
+			result: SearchResult{Kind: SearchShow, ID: show.ID, Title: show.Title, Date: &date},
+			body:   strings.Join([]string{show.Venue, show.City, show.Artist.Name, show.Artist.Genre, show.Description}, " "),
+		})
+	}
+	return docs, nil
+}
+
+func (l likeSearch) match(terms []string, since *time.Time, limit int) ([]SearchResult, error) {
+	docs, err := l.documents(terms, since)
+	if err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	for _, doc := range docs {
+		title, body := doc.result.Title, doc.body
+		for _, term := range terms {
+			if hasWordPrefix(title, term) {
+				doc.result.Rank += 3
+			}
+			if hasWordPrefix(body, term) {
+				doc.result.Rank++
+			}
+		}
+		if doc.result.Rank == 0 {
+			continue
+		}
+		doc.result.Title = highlight(doc.result.Title, terms)
+		doc.result.Snippet = highlight(snippet(doc.body, terms), terms)
+		results = append(results, doc.result)
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Rank > results[j].Rank })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// maxWordDocuments bounds the artists and the shows read for the words of a letter
+const maxWordDocuments = 500
+
+// wordStartCondition matches the rows with a word starting with prefix in one of columns
+func wordStartCondition(prefix string, columns ...string) (string, []any) {
+	var conds []string
+	var args []any
+	for _, column := range columns {
+		conds = append(conds, "LOWER("+column+`) LIKE ? ESCAPE '\' OR LOWER(`+column+`) LIKE ? ESCAPE '\'`)
+		args = append(args, likePrefix(prefix), "% "+likePrefix(prefix))
+	}
+	return strings.Join(conds, " OR "), args
+}
+
+func (l likeSearch) words(first string) ([]string, error) {
+	var texts []string
+	var artists []models.Artist
+	cond, args := wordStartCondition(first, "name", "genre", "bio")
+	if err := l.db.Where(cond, args...).Limit(maxWordDocuments).Find(&artists).Error; err != nil {
+		return nil, err
+	}
+	for _, artist := range artists {
+		texts = append(texts, artist.Name, artist.Genre, artist.Bio)
+	}
+	var shows []models.Show
+	cond, args = wordStartCondition(first, "title", "description", "venue", "city")
+	if err := listedShows(l.db.Model(&models.Show{}), time.Now()).Where(cond, args...).
+		Limit(maxWordDocuments).Find(&shows).Error; err != nil {
+		return nil, err
+	}
+	for _, show := range shows {
+		texts = append(texts, show.Title, show.Description, show.Venue, show.City)
+	}
+
+	seen := map[string]bool{}
+	var words []string
+	for _, text := range texts {
+		for _, word := range searchTerms(text) {
+			if strings.HasPrefix(word, first) && !seen[word] {
+				seen[word] = true
+				words = append(words, word)
+			}
+		}
+	}
+	return words, nil
+}
+
+func hasWordPrefix(text, term string) bool {
+	for _, word := range searchTerms(text) {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// snippetLength is about how many letters of the body a snippet shows
+const snippetLength = 120
+
+// snippet cuts text around the first term it contains
+func snippet(text string, terms []string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= snippetLength {
+		return text
+	}
+	start := 0
+	lower := strings.ToLower(text)
+	for _, term := range terms {
+		if i := strings.Index(lower, term); i >= 0 {
+			start = max(utf8.RuneCountInString(lower[:i])-snippetLength/4, 0)
+			break
+		}
+	}
+	end := min(start+snippetLength, len(runes))
+	out := string(runes[start:end])
+	if start > 0 {
+		out = "…" + out
+	}
+	if end < len(runes) {
+		out += "…"
+	}
+	return out
+}
+
+// the databases mark the matches with these private use characters, markMatches turns
+// them into <mark> tags once the text is escaped
+const (
+	matchStart = "\ue000"
+	matchStop  = "\ue001"
+)
+
+// markMatches escapes text marked by the database for HTML and tags its matches
+func markMatches(text string) string {
+	return strings.NewReplacer(matchStart, "<mark>", matchStop, "</mark>").Replace(html.EscapeString(text))
+}
+
+// highlight escapes text for HTML and wraps its words starting with a term in <mark> tags
+func highlight(text string, terms []string) string {
+	var out strings.Builder
+	word := []rune{}
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		lower := strings.ToLower(string(word))
+		for _, term := range terms {
+			if strings.HasPrefix(lower, term) {
+				out.WriteString("<mark>" + html.EscapeString(string(word)) + "</mark>")
+				word = word[:0]
+				return
+			}
+		}
+		out.WriteString(html.EscapeString(string(word)))
+		word = word[:0]
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word = append(word, r)
+			continue
+		}
+		flush()
+		out.WriteString(html.EscapeString(string(r)))
+	}
+	flush()
+	return out.String()
+}
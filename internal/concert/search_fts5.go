@@ -0,0 +1,123 @@
+package concert
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	fts5Table      = "search_fts"
+	fts5VocabTable = "search_vocab"
+)
+
+// the rowid of a document is the id of its artist or show, doubled and odd for shows
+const (
+	fts5ArtistDocuments = `INSERT INTO search_fts(rowid, kind, ref_id, title, body)
+		SELECT id*2, 'artist', id, name, coalesce(genre, '') || ' ' || coalesce(bio, '')
+		FROM artists WHERE deleted_at IS NULL AND `
+	fts5ShowDocuments = `INSERT INTO search_fts(rowid, kind, ref_id, title, body)
+		SELECT s.id*2+1, 'show', s.id, s.title, coalesce(s.venue, '') || ' ' || coalesce(s.city, '') || ' ' ||
+			coalesce(a.name, '') || ' ' || coalesce(a.genre, '') || ' ' || coalesce(s.description, '')
+		FROM shows s LEFT JOIN artists a ON a.id = s.artist_id AND a.deleted_at IS NULL
+		WHERE s.deleted_at IS NULL AND `
+)
+
+// the triggers keep the index up to date, soft deletes are updates of deleted_at
+var fts5Triggers = map[string]string{
+	"search_artists_insert": `AFTER INSERT ON artists BEGIN ` + fts5ArtistDocuments + `id = new.id; END`,
+	"search_artists_update": `AFTER UPDATE OF name, genre, bio, deleted_at ON artists BEGIN
+		DELETE FROM search_fts WHERE rowid = old.id*2;
+		` + fts5ArtistDocuments + `id = new.id;
+		DELETE FROM search_fts WHERE rowid IN (SELECT id*2+1 FROM shows WHERE artist_id = new.id);
+		` + fts5ShowDocuments + `s.artist_id = new.id; END`,
+	"search_artists_delete": `AFTER DELETE ON artists BEGIN DELETE FROM search_fts WHERE rowid = old.id*2; END`,
+	"search_shows_insert":   `AFTER INSERT ON shows BEGIN ` + fts5ShowDocuments + `s.id = new.id; END`,
+	"search_shows_update": `AFTER UPDATE OF title, description, venue, city, artist_id, deleted_at ON shows BEGIN
+		DELETE FROM search_fts WHERE rowid = old.id*2+1;
+		` + fts5ShowDocuments + `s.id = new.id; END`,
+	"search_shows_delete": `AFTER DELETE ON shows BEGIN DELETE FROM search_fts WHERE rowid = old.id*2+1; END`,
+}
+
+// setupFTS5Search creates the FTS5 index and rebuilds it. Without the FTS5 module, e.g.
+// go-sqlite3 built without the sqlite_fts5 tag, the search falls back to LIKE.
+func setupFTS5Search(db *gorm.DB) error {
+	err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS search_fts USING fts5(
+		kind UNINDEXED, ref_id UNINDEXED, title, body, tokenize = 'unicode61 remove_diacritics 2')`).Error
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			log.Printf("SQLite has no FTS5, the search uses LIKE")
+			return nil
+		}
+		return err
+	}
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS search_vocab USING fts5vocab(search_fts, 'row')`,
+	}
+	for name, body := range fts5Triggers {
+		statements = append(statements,
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s", name),
+			fmt.Sprintf("CREATE TRIGGER %s %s", name, body))
+	}
+	statements = append(statements,
+		`DELETE FROM search_fts`,
+		fts5ArtistDocuments+`1 = 1`,
+		fts5ShowDocuments+`1 = 1`,
+	)
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// fts5Search ranks with bm25, title matches weigh ten times more than the body.
+type fts5Search struct {
+	db *gorm.DB
+}
+
+// fts5Query ors the terms as prefixes, quoted since they only hold letters and digits
+func fts5Query(terms []string) string {
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = `"` + term + `"*`
+	}
+	return strings.Join(prefixes, " OR ")
+}
+
+func (f fts5Search) match(terms []string, since *time.Time, limit int) ([]SearchResult, error) {
+	query := f.db.Table(fts5Table).
+		Select(`search_fts.kind, search_fts.ref_id AS id, shows.starts_at AS date,
+			-bm25(search_fts, 0, 0, 10.0, 1.0) AS rank,
+			highlight(search_fts, 2, '`+matchStart+`', '`+matchStop+`') AS title,
+			snippet(search_fts, 3, '`+matchStart+`', '`+matchStop+`', '…', 20) AS snippet`).
+		Joins(`LEFT JOIN shows ON search_fts.kind = 'show' AND shows.id = search_fts.ref_id`).
+		Where("search_fts MATCH ?", fts5Query(terms)).
+		Where("(search_fts.kind = 'artist' OR "+listedShowsCondition+")", time.Now()).
+		Order("rank DESC, search_fts.kind, search_fts.ref_id").
+		Limit(limit)
+	if since != nil {
+		query = query.Where("(search_fts.kind = 'artist' OR shows.starts_at >= ?)", *since)
+	}
+	var results []SearchResult
+	if err := query.Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Title = markMatches(results[i].Title)
+		results[i].Snippet = markMatches(results[i].Snippet)
+	}
+	return results, nil
+}
+
+func (f fts5Search) words(first string) ([]string, error) {
+	var words []string
+	err := f.db.Table(fts5VocabTable).Where("term LIKE ? ESCAPE '\\'", likePrefix(first)).Pluck("term", &words).Error
+	return words, err
+}
@@ -0,0 +1,104 @@
+package concert
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// the search vectors are generated columns, weighted by where the words come from
+var postgresSearchSetup = []string{
+	`ALTER TABLE artists ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+		setweight(to_tsvector('simple', coalesce(genre, '')), 'B') ||
+		setweight(to_tsvector('simple', coalesce(bio, '')), 'C')) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_artists_search_vector ON artists USING GIN (search_vector)`,
+	`ALTER TABLE shows ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('simple', coalesce(venue, '') || ' ' || coalesce(city, '')), 'B') ||
+		setweight(to_tsvector('simple', coalesce(description, '')), 'C')) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_shows_search_vector ON shows USING GIN (search_vector)`,
+}
+
+func setupPostgresSearch(db *gorm.DB) error {
+	for _, statement := range postgresSearchSetup {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postgresSearch ranks with ts_rank, a show also matches on its artist with a lower weight.
+type postgresSearch struct {
+	db *gorm.DB
+}
+
+// the matches are marked with matchStart and matchStop, see markMatches
+const (
+	postgresHeadline      = `'StartSel=` + matchStart + `, StopSel=` + matchStop + `, MaxWords=25, MinWords=8, HighlightAll=false'`
+	postgresTitleHeadline = `'StartSel=` + matchStart + `, StopSel=` + matchStop + `, HighlightAll=true'`
+)
+
+// postgresSearchQuery lists the matching artists and published shows, ? are the tsquery,
+// the earliest show date, the current time and the limit
+const postgresSearchQuery = `
+WITH q AS (SELECT to_tsquery('simple', ?) AS query)
+SELECT * FROM (
+	SELECT 'artist' AS kind, a.id, NULL::timestamptz AS date,
+		ts_rank(a.search_vector, q.query) AS rank,
+		ts_headline('simple', a.name, q.query, ` + postgresTitleHeadline + `) AS title,
+		ts_headline('simple', coalesce(a.genre, '') || ' ' || coalesce(a.bio, ''), q.query, ` + postgresHeadline + `) AS snippet
+	FROM artists a, q
+	WHERE a.deleted_at IS NULL AND a.search_vector @@ q.query
+	UNION ALL
+	SELECT 'show', s.id, s.starts_at,
+		ts_rank(s.search_vector || setweight(coalesce(a.search_vector, ''::tsvector), 'D'), q.query),
+		ts_headline('simple', s.title, q.query, ` + postgresTitleHeadline + `),
+		ts_headline('simple', concat_ws(' ', s.venue, s.city, a.name, a.genre, s.description), q.query, ` + postgresHeadline + `)
+	FROM shows s LEFT JOIN artists a ON a.id = s.artist_id AND a.deleted_at IS NULL, q
+	WHERE s.deleted_at IS NULL AND s.starts_at >= ?
+		AND (s.status NOT IN ('draft', 'scheduled') OR (s.status = 'scheduled' AND s.publish_at <= ?))
+		AND (s.search_vector || setweight(coalesce(a.search_vector, ''::tsvector), 'D')) @@ q.query
+) results
+ORDER BY rank DESC, kind, id
+LIMIT ?`
+
+// tsQuery ors the terms as prefixes, they only hold letters and digits
+func tsQuery(terms []string) string {
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	return strings.Join(prefixes, " | ")
+}
+
+func (p postgresSearch) match(terms []string, since *time.Time, limit int) ([]SearchResult, error) {
+	earliest := time.Time{}
+	if since != nil {
+		earliest = *since
+	}
+	var results []SearchResult
+	if err := p.db.Raw(postgresSearchQuery, tsQuery(terms), earliest, time.Now(), limit).Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Title = markMatches(results[i].Title)
+		results[i].Snippet = markMatches(results[i].Snippet)
+	}
+	return results, nil
+}
+
+func (p postgresSearch) words(first string) ([]string, error) {
+	var words []string
+	err := p.db.Raw(`SELECT word FROM ts_stat('SELECT search_vector FROM artists WHERE deleted_at IS NULL `+
+		`UNION ALL SELECT search_vector FROM shows WHERE deleted_at IS NULL') WHERE word LIKE ?`,
+		likePrefix(first)).Scan(&words).Error
+	return words, err
+}
+
+// likePrefix matches the words starting with prefix
+func likePrefix(prefix string) string {
+	return likeEscape(prefix) + "%"
+}
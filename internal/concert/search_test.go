@@ -0,0 +1,109 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func seedSearch(service *Service) (models.Artist, models.Show) {
+	trio := models.Artist{Name: "Blue Note Trio", Genre: "Jazz", Bio: "Hard bop from the Paris clubs"}
+	band := models.Artist{Name: "Thunder", Genre: "Rock"}
+	service.Db.Create(&trio)
+	service.Db.Create(&band)
+
+	day := time.Now().Truncate(24 * time.Hour)
+	jazzShow := models.Show{Title: "Late night session", ArtistID: trio.ID, Venue: "New Morning", City: "Paris", StartsAt: day.AddDate(0, 0, 3), Price: 30, TotalSeats: 10, AvailableSeats: 10}
+	service.Db.Create(&jazzShow)
+	service.Db.Create(&models.Show{Title: "Stadium tour", ArtistID: band.ID, Venue: "Accor Arena", City: "Paris", StartsAt: day.AddDate(0, 0, 5), Price: 60, TotalSeats: 10, AvailableSeats: 10})
+	service.Db.Create(&models.Show{Title: "Jazz à Lyon", ArtistID: trio.ID, Venue: "Le Périscope", City: "Lyon", StartsAt: day.AddDate(-1, 0, 0), Price: 20, TotalSeats: 10, AvailableSeats: 10})
+	return trio, jazzShow
+}
+
+func TestSearchRanksMixedResults(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	trio, jazzShow := seedSearch(service)
+
+	results, err := service.Search("jazz paris", false, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, results.Corrected)
+	// the jazz show in Paris matches both words, the rock show in Paris only one
+	assert.Len(t, results.Results, 3)
+	first := results.Results[0]
+	assert.Contains(t, []uint{jazzShow.ID, trio.ID}, first.ID)
+	last := results.Results[2]
+	assert.Equal(t, SearchShow, last.Kind)
+	assert.Contains(t, last.Title, "Stadium")
+	for _, result := range results.Results {
+		assert.Contains(t, result.Title+result.Snippet, "<mark>")
+		// the shows are dated, the artists aren't
+		if result.Kind == SearchShow && result.ID == jazzShow.ID && assert.NotNil(t, result.Date) {
+			assert.True(t, jazzShow.StartsAt.Equal(*result.Date))
+		} else if result.Kind == SearchArtist {
+			assert.Nil(t, result.Date)
+		}
+	}
+
+	// prefixes match, past shows only on demand
+	results, err = service.Search("Thund", false, 0)
+	assert.NoError(t, err)
+	assert.Len(t, results.Results, 2)
+	results, err = service.Search("lyon", false, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, results.Results)
+	results, err = service.Search("lyon", true, 0)
+	assert.NoError(t, err)
+	assert.Len(t, results.Results, 1)
+
+	_, err = service.Search("a !", false, 0)
+	assert.ErrorIs(t, err, ErrEmptySearch)
+}
+
+func TestSearchCorrectsTypos(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	seedSearch(service)
+
+	results, err := service.Search("thnuder", false, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "thunder", results.Corrected)
+	assert.Len(t, results.Results, 2)
+
+	// a typo in the prefix being typed
+	results, err = service.Search("stadim", false, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "stadium", results.Corrected)
+	assert.Len(t, results.Results, 1)
+
+	// short words are taken as typed
+	results, err = service.Search("jaz zzz", false, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, results.Corrected)
+}
+
+func TestSearchFollowsChanges(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	trio, jazzShow := seedSearch(service)
+
+	service.Db.Model(&trio).Update("name", "Red Note Quartet")
+	results, _ := service.Search("quartet", false, 0)
+	// the artist and their upcoming show
+	assert.Len(t, results.Results, 2)
+
+	service.Db.Delete(&jazzShow)
+	results, _ = service.Search("quartet", false, 0)
+	assert.Len(t, results.Results, 1)
+	assert.Equal(t, SearchArtist, results.Results[0].Kind)
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("jazz", "jazz"))
+	assert.Equal(t, 1, editDistance("jazz", "jaz"))
+	assert.Equal(t, 1, editDistance("thnuder", "thunder"))
+	assert.Equal(t, 2, editDistance("olympia", "olimpya"))
+	assert.Equal(t, "<mark>Jazz</mark> à <mark>Lyon</mark>", highlight("Jazz à Lyon", []string{"jaz", "lyon"}))
+	// the text is escaped before the tags are added
+	assert.Equal(t, "&lt;b&gt;<mark>Jazz</mark>&lt;/b&gt; &amp; co", highlight("<b>Jazz</b> & co", []string{"jazz"}))
+	assert.Equal(t, "&lt;img&gt; <mark>Jazz</mark>", markMatches("<img> "+matchStart+"Jazz"+matchStop))
+}
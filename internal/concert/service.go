@@ -1,6 +1,11 @@
 package concert
 
-import "concert/internal/models"
+import (
+	"concert/internal/models"
+	"concert/internal/payment"
+	"net/http"
+	"time"
+)
 
 type ConcertService interface {
 	GetFan(name string) ([]models.Booking, error)
@@ -24,4 +29,115 @@ type ConcertService interface {
 	GetAllBookings() ([]models.Booking, error)
 	GetBookingById(id uint) (models.Booking, error)
 	CountConfirmSeats(id uint) int64
+	ListWebhooks() ([]models.WebhookSubscription, error)
+	GetWebhookByID(id uint) (models.WebhookSubscription, error)
+	SetWebhook(sub models.WebhookSubscription) (models.WebhookSubscription, error)
+	DeleteWebhook(id uint) error
+	PublishWebhookEvent(event string, data any) error
+	ListWebhookDeliveries(subscriptionID uint) ([]models.WebhookDelivery, error)
+	ListDeadWebhookDeliveries() ([]models.WebhookDelivery, error)
+	RetryWebhookDelivery(id uint) (models.WebhookDelivery, error)
+	SendTestWebhook(subscriptionID uint) (models.WebhookDelivery, error)
+	JoinQueue(showID, userID uint) (QueueStatus, error)
+	GetQueueStatus(token string) (QueueStatus, error)
+	CheckQueueAdmission(show models.Show, userID uint, token string) (models.QueueTicket, error)
+	CheckSaleWindow(show models.Show, user models.User, accessCode string) error
+	ListPresales(showID uint) ([]models.Presale, error)
+	GetPresaleByID(id uint) (models.Presale, error)
+	SetPresale(presale models.Presale) (models.Presale, error)
+	DeletePresale(id uint) error
+	CheckTicketLimits(show models.Show, user models.User, count int) error
+	ListLimitOverrides(showID uint) ([]models.TicketLimitOverride, error)
+	SetLimitOverride(override models.TicketLimitOverride) (models.TicketLimitOverride, error)
+	DeleteLimitOverride(id uint) error
+	IssueTickets(booking models.Booking) ([]models.Ticket, error)
+	GetTicketsByBooking(bookingID uint) ([]models.Ticket, error)
+	VoidTickets(bookingID uint) error
+	CheckIn(code, gate string, showID, staffID uint) (CheckInResult, error)
+	GetCheckInManifest(showID uint) (SignedManifest, error)
+	SyncScans(showID, staffID uint, deviceID string, scans []OfflineScan) ([]ScanResult, error)
+	ListScanEvents(showID uint, result string) ([]models.ScanEvent, error)
+	GetInvoice(booking models.Booking) (models.Invoice, error)
+	TicketsPDF(booking models.Booking) ([]byte, error)
+	ReceiptPDF(booking models.Booking) ([]byte, error)
+	StartPayment(booking models.Booking) (models.Payment, payment.Intent, error)
+	GetPaymentsByBooking(bookingID uint) ([]models.Payment, error)
+	HandlePaymentWebhook(payload []byte, header http.Header) (models.Booking, bool, error)
+	SimulatePayment(booking models.Booking) (models.Booking, bool, error)
+	ReleaseBooking(bookingID uint, reason string) error
+	QuoteRefund(booking models.Booking, now time.Time) (RefundQuote, error)
+	CreateTransfer(booking models.Booking, from models.User, toEmail string) (models.Transfer, string, error)
+	GetTransferByToken(token string) (models.Transfer, error)
+	AcceptTransfer(token string, recipient models.User) (models.Booking, error)
+	CancelTransfer(id uint, user models.User) error
+	ListBookingTransfers(bookingID uint) ([]models.Transfer, error)
+	ListBookingAudit(bookingID uint) ([]models.BookingAudit, error)
+
+	CreateResaleListing(booking models.Booking, seller models.User, count int, price float64) (models.ResaleListing, error)
+	ListResaleListings(showID uint, status string) ([]models.ResaleListing, error)
+	GetResaleListing(id uint) (models.ResaleListing, error)
+	CancelResaleListing(id uint, seller models.User) error
+	RemoveResaleListing(id uint, note string) error
+	BuyResaleListing(id uint, buyer models.User) (models.Booking, error)
+
+	RefundToCredit(bookingID uint, amount float64) error
+	GetCreditEntries(userID uint) ([]models.CreditEntry, error)
+	GrantCredit(userID uint, amount float64, admin models.User, note string) (models.CreditEntry, error)
+	GetCreditLiabilities(now time.Time) (CreditLiabilities, error)
+	IssueGiftCard(amount float64, admin models.User, recipientEmail, message string, expiresAt *time.Time) (models.GiftCard, error)
+	BuyGiftCard(amount float64, purchaser models.User, recipientEmail, message string) (models.GiftCard, models.Payment, payment.Intent, error)
+	ListPurchasedGiftCards(userID uint) ([]models.GiftCard, error)
+	ListGiftCards(status string) ([]models.GiftCard, error)
+	SimulateGiftCardPayment(cardID uint, purchaser models.User) (models.GiftCard, error)
+	RedeemGiftCard(code string, user models.User, now time.Time) (models.CreditEntry, error)
+
+	TrialBalance() ([]AccountBalance, error)
+	GetLedgerReport(filter LedgerFilter) (LedgerReport, error)
+	CheckLedger() ([]LedgerCheck, error)
+	ListJournalEntries(bookingID uint, limit int) ([]models.JournalEntry, error)
+
+	SearchShows(query ShowQuery) (ShowPage, error)
+	ShowsNearby(query NearbyQuery) (NearbyPage, error)
+	Search(text string, includePast bool, limit int) (SearchResults, error)
+
+	PriceBooking(show models.Show, count int) (models.PriceBreakdown, error)
+	ListTaxRates() ([]models.TaxRate, error)
+	SetTaxRate(rate models.TaxRate) (models.TaxRate, error)
+	DeleteTaxRate(id uint) error
+
+	ApplyVenue(show *models.Show) error
+	ListVenues(city string) ([]models.Venue, error)
+	GetVenue(id uint) (models.Venue, error)
+	CreateVenue(venue models.Venue) (models.Venue, error)
+	UpdateVenue(venue models.Venue, now time.Time) (models.Venue, error)
+	DeleteVenue(id uint) error
+
+	SetLineup(showID uint, entries []LineupEntry) (models.Show, error)
+	GetArtistAppearances(artistID uint) ([]Appearance, error)
+	ListFestivals() ([]models.Festival, error)
+	GetFestival(id uint) (models.Festival, error)
+	CreateFestival(festival models.Festival) (models.Festival, error)
+	UpdateFestival(festival models.Festival) (models.Festival, error)
+	DeleteFestival(id uint) error
+	FestivalPassShow(festivalID uint) (models.Show, error)
+
+	ListTours(artistID uint) ([]models.Tour, error)
+	GetTour(id uint) (models.Tour, error)
+	CreateTour(tour models.Tour) (models.Tour, error)
+	UpdateTour(tour models.Tour) (models.Tour, error)
+	DeleteTour(id uint) error
+	CreateTourShows(tourID uint, template TourTemplate, dates []TourDate) ([]models.Show, error)
+	UpdateTourShows(tourID uint, changes TourShowChanges, now time.Time) ([]models.Show, error)
+
+	SetShowStatus(showID uint, change ShowStatusChange, now time.Time) (models.Show, error)
+
+	FollowArtist(userID, artistID uint) (models.Follow, error)
+	UnfollowArtist(userID, artistID uint) error
+	GetFollowingFeed(userID uint, limit int, now time.Time) (FollowingFeed, error)
+	SetShowAlerts(userID uint, enabled bool) error
+
+	GetSettlement(showID uint) (SettlementStatement, error)
+	ListSettlements(status string) ([]models.Settlement, error)
+	ApproveSettlement(id uint, admin models.User, now time.Time) (models.Settlement, error)
+	MarkSettlementPaid(id uint, reference string, now time.Time) (models.Settlement, error)
 }
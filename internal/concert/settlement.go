@@ -0,0 +1,280 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/payment"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrShowNotOver      = errors.New("the show hasn't taken place yet")
+	ErrSettlementStatus = errors.New("the settlement can't go to this status from its current one")
+)
+
+// SettlementStatement is a settlement with what identifies its show.
+type SettlementStatement struct {
+	models.Settlement
+	ShowTitle  string    `json:"showTitle"`
+	ShowDate   time.Time `json:"showDate"`
+	ArtistName string    `json:"artistName"`
+}
+
+// settlementFigures are the columns calculated again while a settlement is a draft
+var settlementFigures = []string{
+	"artist_id", "gross_sales", "refunds", "fees", "taxes", "net_receipts",
+	"split_percent", "guarantee", "artist_share", "promoter_net", "calculated_at",
+}
+
+// settlementReceipts are the figures of the ledger a settlement is calculated from, in cents
+type settlementReceipts struct {
+	gross, refunds, fees, taxes int64
+}
+
+func (r settlementReceipts) minus(o settlementReceipts) settlementReceipts {
+	return settlementReceipts{r.gross - o.gross, r.refunds - o.refunds, r.fees - o.fees, r.taxes - o.taxes}
+}
+
+func (r settlementReceipts) plus(o settlementReceipts) settlementReceipts {
+	return settlementReceipts{r.gross + o.gross, r.refunds + o.refunds, r.fees + o.fees, r.taxes + o.taxes}
+}
+
+// share is the part day of days of the receipts, the cents left over go to the first days
+func (r settlementReceipts) share(day, days int) settlementReceipts {
+	part := func(amount int64) int64 {
+		q, rest := amount/int64(days), amount%int64(days)
+		if rest > 0 && int64(day) < rest {
+			q++
+		} else if rest < 0 && int64(day) < -rest {
+			q--
+		}
+		return q
+	}
+	return settlementReceipts{part(r.gross), part(r.refunds), part(r.fees), part(r.taxes)}
+}
+
+// ledgerReceipts sums the ledger over filter, the booking fees kept without the ones
+// its refunds gave back
+func (s Service) ledgerReceipts(filter LedgerFilter) (settlementReceipts, error) {
+	report, err := s.GetLedgerReport(filter)
+	if err != nil {
+		return settlementReceipts{}, err
+	}
+
+	// the refunds gave back the booking fees in the proportion of the price refunded
+	query := s.Db.Select("total_price", "fee_amount", "refund_amount").
+		Where("refund_status = ?", RefundStatusRefunded)
+	if filter.ShowID != 0 {
+		query = query.Where("show_id = ?", filter.ShowID)
+	}
+	if filter.FestivalID != 0 {
+		query = query.Where("festival_id = ?", filter.FestivalID)
+	}
+	var refunded []models.Booking
+	if err := query.Find(&refunded).Error; err != nil {
+		return settlementReceipts{}, err
+	}
+	var refundedFees int64
+	for _, b := range refunded {
+		if total := payment.ToCents(b.TotalPrice); total > 0 {
+			refundedFees += min(payment.ToCents(b.RefundAmount), total) * payment.ToCents(b.FeeAmount) / total
+		}
+	}
+
+	return settlementReceipts{
+		gross:   payment.ToCents(report.GrossSales),
+		refunds: payment.ToCents(report.Refunds),
+		// only the fees kept, the refunded ones are in the refunds already
+		fees:  payment.ToCents(report.Fees) - refundedFees,
+		taxes: payment.ToCents(report.Taxes),
+	}, nil
+}
+
+// calculateSettlement fills the figures of settlement from the ledger of show. The booking
+// and resale fees stay with the promoter, the artist gets their split of the ticket
+// receipts or the guarantee. Taxes are reported but never part of the receipts. The passes
+// of a festival are booked on its opening show, each day gets an equal share of them.
+func (s Service) calculateSettlement(show models.Show, settlement *models.Settlement) error {
+	receipts, err := s.ledgerReceipts(LedgerFilter{ShowID: show.ID})
+	if err != nil {
+		return err
+	}
+	if show.FestivalID != nil {
+		booked, err := s.ledgerReceipts(LedgerFilter{ShowID: show.ID, FestivalID: *show.FestivalID})
+		if err != nil {
+			return err
+		}
+		passes, err := s.ledgerReceipts(LedgerFilter{FestivalID: *show.FestivalID})
+		if err != nil {
+			return err
+		}
+		// the days a pass gives a ticket for
+		var days []uint
+		if err := s.Db.Model(&models.Show{}).Where("festival_id = ?", *show.FestivalID).
+			Order("starts_at, id").Pluck("id", &days).Error; err != nil {
+			return err
+		}
+		receipts = receipts.minus(booked).plus(passes.share(slices.Index(days, show.ID), len(days)))
+	}
+
+	var fees float64
+	if err := s.Db.Model(&models.ResaleListing{}).
+		Where("show_id = ? AND status = ?", show.ID, models.ResaleSold).
+		Select("COALESCE(SUM(fee), 0)").Scan(&fees).Error; err != nil {
+		return err
+	}
+
+	gross := receipts.gross
+	refunds := receipts.refunds
+	feeCents := receipts.fees + payment.ToCents(fees)
+	taxes := receipts.taxes
+	net := gross - refunds - feeCents
+
+	contract := show.Artist.Contract
+	share := max(net*int64(contract.SplitPercent)/100, payment.ToCents(contract.Guarantee), 0)
+
+	settlement.ArtistID = show.ArtistID
+	settlement.GrossSales = payment.FromCents(gross)
+	settlement.Refunds = payment.FromCents(refunds)
+	settlement.Fees = payment.FromCents(feeCents)
+	settlement.Taxes = payment.FromCents(taxes)
+	settlement.NetReceipts = payment.FromCents(net)
+	settlement.SplitPercent = contract.SplitPercent
+	settlement.Guarantee = contract.Guarantee
+	settlement.ArtistShare = payment.FromCents(share)
+	settlement.PromoterNet = payment.FromCents(net - share)
+	settlement.CalculatedAt = time.Now()
+	return nil
+}
+
+// GetSettlement returns the settlement of a show, its draft calculated again from the ledger.
+func (s Service) GetSettlement(showID uint) (SettlementStatement, error) {
+	show, err := s.GetShowByID(showID)
+	if err != nil {
+		return SettlementStatement{}, err
+	}
+
+	settlement := models.Settlement{ShowID: show.ID}
+	if err := s.Db.Where(models.Settlement{ShowID: show.ID}).
+		Attrs(models.Settlement{ArtistID: show.ArtistID, Status: models.SettlementDraft}).
+		FirstOrCreate(&settlement).Error; err != nil {
+		return SettlementStatement{}, err
+	}
+	if settlement.Status == models.SettlementDraft {
+		if err := s.calculateSettlement(show, &settlement); err != nil {
+			return SettlementStatement{}, err
+		}
+		if err := s.Db.Model(&settlement).Where("status = ?", models.SettlementDraft).
+			Select(settlementFigures).Updates(&settlement).Error; err != nil {
+			return SettlementStatement{}, err
+		}
+	}
+
+	return SettlementStatement{
+		Settlement: settlement,
+		ShowTitle:  show.Title,
+		ShowDate:   show.StartsAt,
+		ArtistName: show.Artist.Name,
+	}, nil
+}
+
+// ListSettlements lists the settlements with a status, or all of them when status is empty.
+func (s Service) ListSettlements(status string) ([]models.Settlement, error) {
+	query := s.Db.Order("id DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var settlements []models.Settlement
+	if err := query.Find(&settlements).Error; err != nil {
+		return nil, err
+	}
+	return settlements, nil
+}
+
+// ApproveSettlement freezes the figures of a draft once its show took place, and records
+// what is owed to the artist in the ledger.
+func (s Service) ApproveSettlement(id uint, admin models.User, now time.Time) (models.Settlement, error) {
+	var settlement models.Settlement
+	if err := s.Db.First(&settlement, id).Error; err != nil {
+		return models.Settlement{}, err
+	}
+	if settlement.Status != models.SettlementDraft {
+		return models.Settlement{}, ErrSettlementStatus
+	}
+	show, err := s.GetShowByID(settlement.ShowID)
+	if err != nil {
+		return models.Settlement{}, err
+	}
+	if show.EndTime().After(now) {
+		return models.Settlement{}, ErrShowNotOver
+	}
+	if err := s.calculateSettlement(show, &settlement); err != nil {
+		return models.Settlement{}, err
+	}
+	settlement.Status = models.SettlementApproved
+	settlement.ApprovedByID = &admin.ID
+	settlement.ApprovedAt = &now
+
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&settlement).Where("status = ?", models.SettlementDraft).
+			Select(append(settlementFigures, "status", "approved_by_id", "approved_at")).Updates(&settlement)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSettlementStatus
+		}
+		share := payment.ToCents(settlement.ArtistShare)
+		return postJournal(tx, models.JournalEntry{
+			Kind:      models.JournalSettlement,
+			Reference: fmt.Sprintf("settlement:%d", settlement.ID),
+			ShowID:    &settlement.ShowID,
+			PostedAt:  now,
+		},
+			posting{AccountArtistFees, share},
+			posting{AccountArtistsPayable, -share},
+		)
+	})
+	if err != nil {
+		return models.Settlement{}, err
+	}
+	return settlement, nil
+}
+
+// MarkSettlementPaid records the payout of an approved settlement to the artist.
+func (s Service) MarkSettlementPaid(id uint, reference string, now time.Time) (models.Settlement, error) {
+	var settlement models.Settlement
+	if err := s.Db.First(&settlement, id).Error; err != nil {
+		return models.Settlement{}, err
+	}
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&settlement).Where("status = ?", models.SettlementApproved).
+			Updates(map[string]any{"status": models.SettlementPaid, "paid_at": now, "payment_ref": reference})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSettlementStatus
+		}
+		share := payment.ToCents(settlement.ArtistShare)
+		return postJournal(tx, models.JournalEntry{
+			Kind:      models.JournalSettlement,
+			Reference: fmt.Sprintf("payout:settlement:%d", settlement.ID),
+			ShowID:    &settlement.ShowID,
+			Memo:      reference,
+			PostedAt:  now,
+		},
+			posting{AccountArtistsPayable, share},
+			posting{AccountBank, -share},
+		)
+	})
+	if err != nil {
+		return models.Settlement{}, err
+	}
+	err = s.Db.First(&settlement, id).Error
+	return settlement, err
+}
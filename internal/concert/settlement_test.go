@@ -0,0 +1,163 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createSettledShow(t *testing.T, service *Service, contract models.ArtistContract) models.Show {
+	artist := models.Artist{Name: "Headliner", Contract: contract}
+	service.Db.Create(&artist)
+	show, _ := createPaidBooking(t, service)
+	service.Db.Model(&show).Update("artist_id", artist.ID)
+	return show
+}
+
+func TestSettlementLifecycle(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	show := createSettledShow(t, service, models.ArtistContract{SplitPercent: 70, Guarantee: 50})
+	admin := models.User{Username: "admin", Email: "admin@example.com", Role: "admin"}
+	service.Db.Create(&admin)
+
+	statement, err := service.GetSettlement(show.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SettlementDraft, statement.Status)
+	assert.Equal(t, "Headliner", statement.ArtistName)
+	assert.Equal(t, 80.0, statement.GrossSales)
+	assert.Equal(t, 80.0, statement.NetReceipts)
+	assert.Equal(t, 56.0, statement.ArtistShare)
+	assert.Equal(t, 24.0, statement.PromoterNet)
+
+	_, err = service.ApproveSettlement(statement.ID, admin, time.Now())
+	assert.ErrorIs(t, err, ErrShowNotOver)
+
+	_, err = service.MarkSettlementPaid(statement.ID, "wire-1", time.Now())
+	assert.ErrorIs(t, err, ErrSettlementStatus)
+
+	after := show.StartsAt.Add(24 * time.Hour)
+	approved, err := service.ApproveSettlement(statement.ID, admin, after)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SettlementApproved, approved.Status)
+	assert.Equal(t, admin.ID, *approved.ApprovedByID)
+	assertLedgerChecks(t, service)
+
+	_, err = service.ApproveSettlement(statement.ID, admin, after)
+	assert.ErrorIs(t, err, ErrSettlementStatus)
+
+	// an approved settlement isn't calculated again
+	service.Db.Model(&models.Artist{}).Where("id = ?", show.ArtistID).Update("contract_split_percent", 100)
+	statement, err = service.GetSettlement(show.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 56.0, statement.ArtistShare)
+
+	paid, err := service.MarkSettlementPaid(statement.ID, "wire-1", after)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SettlementPaid, paid.Status)
+	assert.Equal(t, "wire-1", paid.PaymentRef)
+	assertLedgerChecks(t, service)
+
+	balances, err := service.TrialBalance()
+	assert.NoError(t, err)
+	for _, b := range balances {
+		switch b.Code {
+		case AccountArtistFees:
+			assert.Equal(t, int64(5600), b.Balance)
+		case AccountArtistsPayable:
+			assert.Equal(t, int64(0), b.Balance)
+		}
+	}
+}
+
+func TestSettlementGuaranteeAndRefunds(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	// the fan of the paid booking, refunds to credit need their account
+	service.Db.Create(&models.User{Username: "fan", Email: "fan@example.com"})
+	show := createSettledShow(t, service, models.ArtistContract{SplitPercent: 80, Guarantee: 100})
+
+	var booking models.Booking
+	service.Db.Where("show_id = ?", show.ID).First(&booking)
+	assert.NoError(t, service.RefundToCredit(booking.ID, 30))
+
+	statement, err := service.GetSettlement(show.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 30.0, statement.Refunds)
+	assert.Equal(t, 50.0, statement.NetReceipts)
+	// the guarantee is paid whatever the sales
+	assert.Equal(t, 100.0, statement.ArtistShare)
+	assert.Equal(t, -50.0, statement.PromoterNet)
+
+	settlements, err := service.ListSettlements(models.SettlementDraft)
+	assert.NoError(t, err)
+	assert.Len(t, settlements, 1)
+}
+
+func TestSettlementRefundedFees(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	artist := models.Artist{Name: "Headliner", Contract: models.ArtistContract{SplitPercent: 50}}
+	service.Db.Create(&artist)
+	show := models.Show{Title: "Night", ArtistID: artist.ID, Venue: "Lille", StartsAt: time.Now(), Price: 50, TotalSeats: 10, AvailableSeats: 6}
+	service.Db.Create(&show)
+	var bookings []models.Booking
+	for _, name := range []string{"kept", "refunded"} {
+		fan := models.User{Username: name, Email: name + "@example.com"}
+		service.Db.Create(&fan)
+		booking := models.Booking{ShowID: show.ID, UserID: fan.ID, TicketCount: 2, TotalPrice: 110, Status: "confirmed",
+			PriceBreakdown: models.PriceBreakdown{NetAmount: 100, FeeAmount: 10}}
+		service.Db.Create(&booking)
+		assert.NoError(t, RecordSale(service.Db, booking))
+		bookings = append(bookings, booking)
+	}
+	assert.NoError(t, service.RefundToCredit(bookings[1].ID, 110))
+
+	statement, err := service.GetSettlement(show.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 220.0, statement.GrossSales)
+	assert.Equal(t, 110.0, statement.Refunds)
+	// the fee of the refunded booking went back to the fan, it isn't taken twice
+	assert.Equal(t, 10.0, statement.Fees)
+	assert.Equal(t, 100.0, statement.NetReceipts)
+	assert.Equal(t, 50.0, statement.ArtistShare)
+}
+
+func TestSettlementFestivalPasses(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	artist := models.Artist{Name: "Headliner", Contract: models.ArtistContract{SplitPercent: 50}}
+	service.Db.Create(&artist)
+	fan := models.User{Username: "fan", Email: "fan@example.com"}
+	service.Db.Create(&fan)
+	festival := models.Festival{Name: "Summer Fest", PassPrice: 165}
+	service.Db.Create(&festival)
+	var days []models.Show
+	for i := range 3 {
+		day := models.Show{Title: "Day", ArtistID: artist.ID, Venue: "Park", StartsAt: time.Now().AddDate(0, 0, i), Price: 60, TotalSeats: 10, AvailableSeats: 10, FestivalID: &festival.ID}
+		service.Db.Create(&day)
+		days = append(days, day)
+	}
+
+	for _, booking := range []models.Booking{
+		{ShowID: days[0].ID, FestivalID: &festival.ID, UserID: fan.ID, TicketCount: 2, TotalPrice: 330.01, Status: "confirmed",
+			PriceBreakdown: models.PriceBreakdown{NetAmount: 300.01, FeeAmount: 30}},
+		{ShowID: days[0].ID, UserID: fan.ID, TicketCount: 1, TotalPrice: 60, Status: "confirmed",
+			PriceBreakdown: models.PriceBreakdown{NetAmount: 60}},
+	} {
+		service.Db.Create(&booking)
+		assert.NoError(t, RecordSale(service.Db, booking))
+	}
+
+	// the passes booked on the opening show are shared by the days
+	statement, err := service.GetSettlement(days[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 170.01, statement.GrossSales)
+	assert.Equal(t, 10.0, statement.Fees)
+	assert.Equal(t, 160.01, statement.NetReceipts)
+	for _, day := range days[1:] {
+		statement, err = service.GetSettlement(day.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 110.0, statement.GrossSales)
+		assert.Equal(t, 10.0, statement.Fees)
+		assert.Equal(t, 50.0, statement.ArtistShare)
+	}
+}
@@ -0,0 +1,240 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// page sizes of the show listings
+const (
+	DefaultShowPageSize = 20
+	MaxShowPageSize     = 100
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort, use date, price or title, with a - for descending")
+)
+
+// showSortColumns are the orders of the listings, ties are broken by id
+var showSortColumns = map[string]string{
+	"date":  "shows.starts_at",
+	"price": "shows.price",
+	"title": "shows.title",
+}
+
+// ShowQuery filters, sorts and pages the shows the public sees, zero values don't filter.
+// Past shows are left out unless IncludePast. To is excluded.
+type ShowQuery struct {
+	Text          string
+	ArtistID      uint
+	Genre         string
+	VenueID       uint
+	FestivalID    uint
+	TourID        uint
+	Venue         string
+	City          string
+	From          time.Time
+	To            time.Time
+	MinPrice      *float64
+	MaxPrice      *float64
+	AvailableOnly bool
+	IncludePast   bool
+	// Sort is date, price or title, prefixed with - for descending, date by default
+	Sort   string
+	Cursor string
+	Limit  int
+}
+
+// ShowPage is a page of shows. Total counts every show matching the query, NextCursor
+// is empty on the last page.
+type ShowPage struct {
+	Items      []models.Show `json:"items"`
+	Total      int64         `json:"total"`
+	Limit      int           `json:"limit"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+// showCursor is the position after the last show of a page, for the sort of the query
+type showCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// likePattern matches text anywhere, its wildcards taken literally
+func likePattern(text string) string {
+	return "%" + likeEscape(strings.ToLower(text)) + "%"
+}
+
+// likeEscape makes the wildcards of text literal in a LIKE pattern with ESCAPE '\'
+func likeEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+}
+
+func (q ShowQuery) sort() (column string, desc bool, err error) {
+	sort := q.Sort
+	if sort == "" {
+		sort = "date"
+	}
+	desc = strings.HasPrefix(sort, "-")
+	column, ok := showSortColumns[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return "", false, ErrInvalidSort
+	}
+	return column, desc, nil
+}
+
+// filter applies the filters of q to a query on the shows
+func (q ShowQuery) filter(db *gorm.DB, now time.Time) *gorm.DB {
+	query := listedShows(db.Model(&models.Show{}), now)
+	if text := strings.TrimSpace(q.Text); text != "" {
+		pattern := likePattern(text)
+		query = query.Where(
+			`LOWER(shows.title) LIKE ? ESCAPE '\' OR LOWER(shows.description) LIKE ? ESCAPE '\' OR LOWER(shows.venue) LIKE ? ESCAPE '\' OR `+
+				`shows.artist_id IN (SELECT id FROM artists WHERE LOWER(name) LIKE ? ESCAPE '\' AND deleted_at IS NULL)`,
+			pattern, pattern, pattern, pattern)
+	}
+	if q.ArtistID != 0 {
+		// the artist headlines the show or is on its bill
+		query = query.Where("shows.artist_id = ? OR shows.id IN (SELECT show_id FROM lineup_slots WHERE artist_id = ? AND deleted_at IS NULL)", q.ArtistID, q.ArtistID)
+	}
+	if q.Genre != "" {
+		query = query.Where("shows.artist_id IN (SELECT id FROM artists WHERE LOWER(genre) = ? AND deleted_at IS NULL)", strings.ToLower(q.Genre))
+	}
+	if q.VenueID != 0 {
+		query = query.Where("shows.venue_id = ?", q.VenueID)
+	}
+	if q.FestivalID != 0 {
+		query = query.Where("shows.festival_id = ?", q.FestivalID)
+	}
+	if q.TourID != 0 {
+		query = query.Where("shows.tour_id = ?", q.TourID)
+	}
+	if q.Venue != "" {
+		query = query.Where("LOWER(shows.venue) = ?", strings.ToLower(q.Venue))
+	}
+	if q.City != "" {
+		query = query.Where("LOWER(shows.city) = ?", strings.ToLower(q.City))
+	}
+	if !q.IncludePast {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		query = query.Where("shows.starts_at >= ?", today)
+	}
+	if !q.From.IsZero() {
+		query = query.Where("shows.starts_at >= ?", q.From)
+	}
+	if !q.To.IsZero() {
+		query = query.Where("shows.starts_at < ?", q.To)
+	}
+	if q.MinPrice != nil {
+		query = query.Where("shows.price >= ?", *q.MinPrice)
+	}
+	if q.MaxPrice != nil {
+		query = query.Where("shows.price <= ?", *q.MaxPrice)
+	}
+	if q.AvailableOnly {
+		query = query.Where("shows.available_seats > 0")
+	}
+	return query
+}
+
+func encodeShowCursor(sort string, show models.Show) string {
+	cursor := showCursor{Sort: sort, ID: show.ID}
+	switch strings.TrimPrefix(sort, "-") {
+	case "price":
+		cursor.Value = strconv.FormatFloat(show.Price, 'f', -1, 64)
+	case "title":
+		cursor.Value = show.Title
+	default:
+		cursor.Value = show.StartsAt.Format(time.RFC3339Nano)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeShowCursor returns the sort value and id of a cursor made for sort
+func decodeShowCursor(raw, sort string) (any, uint, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	var cursor showCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort {
+		return nil, 0, ErrInvalidCursor
+	}
+	switch strings.TrimPrefix(sort, "-") {
+	case "price":
+		price, err := strconv.ParseFloat(cursor.Value, 64)
+		if err != nil {
+			return nil, 0, ErrInvalidCursor
+		}
+		return price, cursor.ID, nil
+	case "title":
+		return cursor.Value, cursor.ID, nil
+	default:
+		date, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, 0, ErrInvalidCursor
+		}
+		return date, cursor.ID, nil
+	}
+}
+
+// SearchShows returns a page of the shows matching q. Pages are keyed on the sort value and
+// the id of the last show, so they stay consistent while shows are added.
+func (s Service) SearchShows(q ShowQuery) (ShowPage, error) {
+	column, desc, err := q.sort()
+	if err != nil {
+		return ShowPage{}, err
+	}
+	if q.Sort == "" {
+		q.Sort = "date"
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultShowPageSize
+	}
+	q.Limit = min(q.Limit, MaxShowPageSize)
+	now := time.Now()
+
+	page := ShowPage{Limit: q.Limit, Items: []models.Show{}}
+	if err := q.filter(s.Db, now).Count(&page.Total).Error; err != nil {
+		return ShowPage{}, err
+	}
+
+	op, order := ">", "ASC"
+	if desc {
+		op, order = "<", "DESC"
+	}
+	query := preloadLineup(q.filter(s.Db, now)).Preload("Artist").Preload("Presales").Preload("Location").
+		Order(column + " " + order).Order("shows.id " + order).
+		Limit(q.Limit + 1)
+	if q.Cursor != "" {
+		value, id, err := decodeShowCursor(q.Cursor, q.Sort)
+		if err != nil {
+			return ShowPage{}, err
+		}
+		query = query.Where("("+column+" "+op+" ? OR ("+column+" = ? AND shows.id "+op+" ?))", value, value, id)
+	}
+
+	var shows []models.Show
+	if err := query.Find(&shows).Error; err != nil {
+		return ShowPage{}, err
+	}
+	if len(shows) > q.Limit {
+		shows = shows[:q.Limit]
+		page.NextCursor = encodeShowCursor(q.Sort, shows[len(shows)-1])
+	}
+	for i := range shows {
+		applySaleStatus(&shows[i], now)
+	}
+	page.Items = append(page.Items, shows...)
+	return page, nil
+}
@@ -0,0 +1,110 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func seedShowListing(service *Service) (models.Artist, models.Artist) {
+	jazz := models.Artist{Name: "Blue Note Trio", Genre: "Jazz"}
+	rock := models.Artist{Name: "Loud_Band", Genre: "Rock"}
+	service.Db.Create(&jazz)
+	service.Db.Create(&rock)
+
+	day := time.Now().Truncate(24 * time.Hour)
+	shows := []models.Show{
+		{Title: "Late night jazz", ArtistID: jazz.ID, Venue: "New Morning", City: "Paris", StartsAt: day.AddDate(0, 0, 3), Price: 30, TotalSeats: 10, AvailableSeats: 10},
+		{Title: "Jazz brunch", ArtistID: jazz.ID, Venue: "Le Bijou", City: "Toulouse", StartsAt: day.AddDate(0, 0, 10), Price: 20, TotalSeats: 10, AvailableSeats: 0},
+		{Title: "Arena tour", ArtistID: rock.ID, Venue: "Accor Arena", City: "Paris", StartsAt: day.AddDate(0, 0, 5), Price: 60, TotalSeats: 10, AvailableSeats: 4},
+		{Title: "Summer 100% live", ArtistID: rock.ID, Venue: "Zenith", City: "Lille", StartsAt: day.AddDate(0, 1, 0), Price: 45, TotalSeats: 10, AvailableSeats: 4},
+		{Title: "Last year", ArtistID: rock.ID, Venue: "Zenith", City: "Lille", StartsAt: day.AddDate(-1, 0, 0), Price: 45, TotalSeats: 10, AvailableSeats: 4},
+	}
+	for i := range shows {
+		service.Db.Create(&shows[i])
+	}
+	return jazz, rock
+}
+
+func titles(page ShowPage) []string {
+	var titles []string
+	for _, show := range page.Items {
+		titles = append(titles, show.Title)
+	}
+	return titles
+}
+
+func TestSearchShowsFilters(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	jazz, _ := seedShowListing(service)
+
+	page, err := service.SearchShows(ShowQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), page.Total)
+	assert.Equal(t, []string{"Late night jazz", "Arena tour", "Jazz brunch", "Summer 100% live"}, titles(page))
+
+	page, _ = service.SearchShows(ShowQuery{IncludePast: true})
+	assert.Equal(t, int64(5), page.Total)
+
+	// the text matches the titles, venues and artist names
+	page, _ = service.SearchShows(ShowQuery{Text: "JAZZ"})
+	assert.Equal(t, []string{"Late night jazz", "Jazz brunch"}, titles(page))
+	page, _ = service.SearchShows(ShowQuery{Text: "blue note"})
+	assert.Equal(t, int64(2), page.Total)
+	page, _ = service.SearchShows(ShowQuery{Text: "100%"})
+	assert.Equal(t, []string{"Summer 100% live"}, titles(page))
+	// wildcards are taken literally
+	page, _ = service.SearchShows(ShowQuery{Text: "d_b"})
+	assert.Equal(t, int64(2), page.Total)
+	page, _ = service.SearchShows(ShowQuery{Text: "o_d"})
+	assert.Equal(t, int64(0), page.Total)
+
+	page, _ = service.SearchShows(ShowQuery{Genre: "rock", City: "paris"})
+	assert.Equal(t, []string{"Arena tour"}, titles(page))
+	page, _ = service.SearchShows(ShowQuery{ArtistID: jazz.ID, AvailableOnly: true})
+	assert.Equal(t, []string{"Late night jazz"}, titles(page))
+
+	minPrice, maxPrice := 25.0, 50.0
+	page, _ = service.SearchShows(ShowQuery{MinPrice: &minPrice, MaxPrice: &maxPrice, Sort: "-price"})
+	assert.Equal(t, []string{"Summer 100% live", "Late night jazz"}, titles(page))
+
+	today := time.Now().Truncate(24 * time.Hour)
+	page, _ = service.SearchShows(ShowQuery{From: today.AddDate(0, 0, 4), To: today.AddDate(0, 0, 11)})
+	assert.Equal(t, []string{"Arena tour", "Jazz brunch"}, titles(page))
+
+	_, err = service.SearchShows(ShowQuery{Sort: "popularity"})
+	assert.ErrorIs(t, err, ErrInvalidSort)
+}
+
+func TestSearchShowsCursor(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	seedShowListing(service)
+
+	var seen []string
+	query := ShowQuery{Sort: "title", Limit: 3}
+	for {
+		page, err := service.SearchShows(query)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), page.Total)
+		seen = append(seen, titles(page)...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"Arena tour", "Jazz brunch", "Late night jazz", "Summer 100% live"}, seen)
+
+	first, _ := service.SearchShows(ShowQuery{Sort: "-date", Limit: 2})
+	next, err := service.SearchShows(ShowQuery{Sort: "-date", Limit: 2, Cursor: first.NextCursor})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Arena tour", "Late night jazz"}, titles(next))
+	assert.Empty(t, next.NextCursor)
+
+	// a cursor only goes with the sort it was made for
+	_, err = service.SearchShows(ShowQuery{Sort: "price", Cursor: first.NextCursor})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	_, err = service.SearchShows(ShowQuery{Cursor: "garbage"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
@@ -0,0 +1,157 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrShowNotPublished     = errors.New("this show is not published")
+	ErrShowCancelled        = errors.New("this show is cancelled")
+	ErrShowPostponed        = errors.New("this show is postponed, tickets go on sale again with its new date")
+	ErrInvalidShowStatus    = errors.New("invalid show status")
+	ErrShowStatusTransition = errors.New("the show can't change to this status")
+)
+
+// listedShowsCondition keeps the shows the public sees, like models.Show.Listed. Its
+// argument is the current time.
+const listedShowsCondition = "(shows.status NOT IN ('draft', 'scheduled') OR (shows.status = 'scheduled' AND shows.publish_at <= ?))"
+
+// showTransitions are the statuses a show can go to from each status, besides its own. A
+// cancelled show stays cancelled.
+var showTransitions = map[string][]string{
+	models.ShowDraft:     {models.ShowScheduled, models.ShowPublished, models.ShowCancelled},
+	models.ShowScheduled: {models.ShowDraft, models.ShowPublished, models.ShowCancelled},
+	models.ShowPublished: {models.ShowDraft, models.ShowScheduled, models.ShowPostponed, models.ShowCancelled},
+	models.ShowPostponed: {models.ShowPublished, models.ShowCancelled},
+}
+
+// ShowStatusChange moves a show to Status. Reason is required to cancel or postpone a
+// show, PublishAt to schedule it.
+type ShowStatusChange struct {
+	Status    string
+	Reason    string
+	PublishAt *time.Time
+}
+
+// listedShows scopes a query on the shows to those the public sees
+func listedShows(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Where(listedShowsCondition, now)
+}
+
+// applyShowStatus fills the computed statuses: a scheduled show past its publish time is
+// published, a published show without seats left is sold out.
+func applyShowStatus(show *models.Show, now time.Time) {
+	if show.Status == "" || show.Status == models.ShowScheduled && show.Listed(now) {
+		show.Status = models.ShowPublished
+	}
+	if show.Status == models.ShowPublished && show.AvailableSeats <= 0 {
+		show.Status = models.ShowSoldOut
+	}
+}
+
+// checkShowStatus tells whether a show can be booked for its status
+func checkShowStatus(show models.Show, now time.Time) error {
+	switch {
+	case !show.Listed(now):
+		return ErrShowNotPublished
+	case show.Status == models.ShowCancelled:
+		return ErrShowCancelled
+	case show.Status == models.ShowPostponed:
+		return ErrShowPostponed
+	}
+	return nil
+}
+
+// InitialShowStatus is the status of a new show: a draft, scheduled to be published at
+// publishAt or published. Without a status, a publishAt to come schedules the show.
+func InitialShowStatus(status string, publishAt *time.Time, now time.Time) (string, error) {
+	if status == "" {
+		status = models.ShowPublished
+		if publishAt != nil && publishAt.After(now) {
+			status = models.ShowScheduled
+		}
+	}
+	switch status {
+	case models.ShowDraft, models.ShowPublished:
+		return status, nil
+	case models.ShowScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return "", fmt.Errorf("%w: a scheduled show needs a publishAt to come", ErrInvalidShowStatus)
+		}
+		return status, nil
+	}
+	return "", fmt.Errorf("%w: a show is created as a draft, scheduled or published", ErrInvalidShowStatus)
+}
+
+// SetShowStatus moves a show through its lifecycle. A show with tickets sold can't be
+// hidden again as a draft or a scheduled show.
+func (s Service) SetShowStatus(showID uint, change ShowStatusChange, now time.Time) (models.Show, error) {
+	var show models.Show
+	if err := s.Db.First(&show, showID).Error; err != nil {
+		return models.Show{}, err
+	}
+	current := show.Status
+	if current == models.ShowScheduled && show.Listed(now) {
+		current = models.ShowPublished
+	}
+
+	reason := strings.TrimSpace(change.Reason)
+	updates := map[string]any{
+		"status":            change.Status,
+		"status_reason":     "",
+		"publish_at":        show.PublishAt,
+		"status_changed_at": now,
+	}
+	switch change.Status {
+	case models.ShowDraft:
+		updates["publish_at"] = nil
+	case models.ShowScheduled:
+		if change.PublishAt == nil || !change.PublishAt.After(now) {
+			return models.Show{}, fmt.Errorf("%w: a scheduled show needs a publishAt to come", ErrInvalidShowStatus)
+		}
+		updates["publish_at"] = *change.PublishAt
+	case models.ShowPublished:
+		if show.PublishAt == nil || show.PublishAt.After(now) {
+			updates["publish_at"] = now
+		}
+	case models.ShowCancelled, models.ShowPostponed:
+		if reason == "" {
+			return models.Show{}, fmt.Errorf("%w: a reason is required to cancel or postpone a show", ErrInvalidShowStatus)
+		}
+		updates["status_reason"] = reason
+	default:
+		return models.Show{}, fmt.Errorf("%w: use draft, scheduled, published, postponed or cancelled", ErrInvalidShowStatus)
+	}
+
+	if change.Status != current {
+		allowed := false
+		for _, next := range showTransitions[current] {
+			allowed = allowed || next == change.Status
+		}
+		if !allowed {
+			return models.Show{}, fmt.Errorf("%w: it is %s", ErrShowStatusTransition, current)
+		}
+	}
+	if change.Status == models.ShowDraft || change.Status == models.ShowScheduled {
+		var held int64
+		if err := s.Db.Model(&models.Booking{}).
+			Where("(show_id = ? OR festival_id = ?) AND status IN ?", show.ID, show.FestivalID, heldTicketStatuses).
+			Count(&held).Error; err != nil {
+			return models.Show{}, err
+		}
+		if held > 0 {
+			return models.Show{}, fmt.Errorf("%w: tickets are sold", ErrShowStatusTransition)
+		}
+	}
+
+	if err := s.Db.Model(&models.Show{}).Where("id = ?", show.ID).Updates(updates).Error; err != nil {
+		return models.Show{}, err
+	}
+	return s.GetShowByID(show.ID)
+}
@@ -0,0 +1,111 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShowStatusVisibility(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	artist, _ := service.SetArtist(models.Artist{Name: "Band"})
+	now := time.Now()
+	soon, past := now.Add(time.Hour), now.Add(-time.Hour)
+	show := func(title, status string, publishAt *time.Time) models.Show {
+		show, err := service.SetShow(models.Show{Title: title, ArtistID: artist.ID, Venue: "Olympia", StartsAt: now.AddDate(0, 1, 0), TotalSeats: 10, AvailableSeats: 10, Status: status, PublishAt: publishAt})
+		assert.NoError(t, err)
+		return show
+	}
+	show("Published", "", nil)
+	draft := show("Draft", models.ShowDraft, nil)
+	show("Later", models.ShowScheduled, &soon)
+	due := show("Due", models.ShowScheduled, &past)
+
+	page, err := service.SearchShows(ShowQuery{})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 2) {
+		assert.Equal(t, models.ShowPublished, page.Items[0].Status)
+		assert.Equal(t, models.ShowPublished, page.Items[1].Status)
+	}
+	appearances, err := service.GetArtistAppearances(artist.ID)
+	assert.NoError(t, err)
+	assert.Len(t, appearances, 2)
+	results, err := service.Search("olympia", false, 10)
+	assert.NoError(t, err)
+	assert.Len(t, results.Results, 2)
+
+	// drafts can't be booked, a scheduled show can once published
+	fan := models.User{Email: "fan@test.com"}
+	assert.ErrorIs(t, service.CheckSaleWindow(draft, fan, ""), ErrShowNotPublished)
+	assert.NoError(t, service.CheckSaleWindow(due, fan, ""))
+
+	got, _ := service.GetShowByID(draft.ID)
+	assert.Equal(t, models.SaleOffSale, got.SaleStatus)
+	assert.False(t, got.Listed(now))
+
+	// the status of a show without seats left reads sold out, it is stored as published
+	service.Db.Create(&models.Booking{UserID: 1, ShowID: due.ID, TicketCount: 10, Status: "confirmed"})
+	got, _ = service.GetShowByID(due.ID)
+	assert.Equal(t, models.ShowSoldOut, got.Status)
+	got, err = service.SetShow(got)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ShowPublished, got.Status)
+}
+
+func TestSetShowStatus(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	artist, _ := service.SetArtist(models.Artist{Name: "Band"})
+	now := time.Now()
+	show, _ := service.SetShow(models.Show{Title: "Night", ArtistID: artist.ID, Venue: "Olympia", StartsAt: now.AddDate(0, 1, 0), TotalSeats: 10, AvailableSeats: 10, Status: models.ShowDraft})
+
+	later := now.Add(24 * time.Hour)
+	got, err := service.SetShowStatus(show.ID, ShowStatusChange{Status: models.ShowScheduled, PublishAt: &later}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ShowScheduled, got.Status)
+	_, err = service.SetShowStatus(show.ID, ShowStatusChange{Status: models.ShowScheduled}, now)
+	assert.ErrorIs(t, err, ErrInvalidShowStatus)
+
+	got, err = service.SetShowStatus(show.ID, ShowStatusChange{Status: models.ShowPublished}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ShowPublished, got.Status)
+	assert.WithinDuration(t, now, *got.PublishAt, time.Second)
+
+	// a show with tickets sold stays public
+	service.Db.Create(&models.Booking{UserID: 1, ShowID: show.ID, TicketCount: 2, Status: "confirmed"})
+	_, err = service.SetShowStatus(show.ID, ShowStatusChange{Status: models.ShowDraft}, now)
+	assert.ErrorIs(t, err, ErrShowStatusTransition)
+
+	_, err = service.SetShowStatus(show.ID, ShowStatusChange{Status: models.ShowPostponed}, now)
+	assert.ErrorIs(t, err, ErrInvalidShowStatus)
+	got, err = service.SetShowStatus(show.ID, ShowStatusChange{Status: models.ShowPostponed, Reason: " Illness "}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "Illness", got.StatusReason)
+	assert.ErrorIs(t, service.CheckSaleWindow(got, models.User{}, ""), ErrShowPostponed)
+	page, _ := service.SearchShows(ShowQuery{})
+	assert.Len(t, page.Items, 1)
+
+	got, err = service.SetShowStatus(show.ID, ShowStatusChange{Status: models.ShowCancelled, Reason: "Illness"}, now)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, service.CheckSaleWindow(got, models.User{}, ""), ErrShowCancelled)
+	_, err = service.SetShowStatus(show.ID, ShowStatusChange{Status: models.ShowPublished}, now)
+	assert.ErrorIs(t, err, ErrShowStatusTransition)
+	_, err = service.SetShowStatus(show.ID, ShowStatusChange{Status: models.ShowSoldOut}, now)
+	assert.ErrorIs(t, err, ErrInvalidShowStatus)
+}
+
+func TestInitialShowStatus(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	status, err := InitialShowStatus("", nil, now)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ShowPublished, status)
+	status, err = InitialShowStatus("", &later, now)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ShowScheduled, status)
+	_, err = InitialShowStatus(models.ShowScheduled, nil, now)
+	assert.ErrorIs(t, err, ErrInvalidShowStatus)
+	_, err = InitialShowStatus(models.ShowCancelled, nil, now)
+	assert.ErrorIs(t, err, ErrInvalidShowStatus)
+}
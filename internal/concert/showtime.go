@@ -0,0 +1,214 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/utils"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// bounds of the doors and end times around the start of a show
+const (
+	maxDoorsBeforeStart = 12 * time.Hour
+	maxShowDuration     = 24 * time.Hour
+)
+
+// defaultShowClock is the start of a show given as a date only
+const defaultShowClock = "20:00"
+
+// localTimeLayouts are the wall clock formats accepted for the times of a show, in its timezone
+var localTimeLayouts = []string{"2006-01-02T15:04", "2006-01-02T15:04:05", "2006-01-02 15:04"}
+
+// DefaultTimezone is the zone of the shows whose venue has none, DEFAULT_TIMEZONE or UTC.
+func DefaultTimezone() string {
+	return utils.GetEnvOrDefault("DEFAULT_TIMEZONE", "UTC")
+}
+
+// ShowTimesInput are the times of a show as typed by an admin. StartsAt, DoorsAt and EndsAt
+// are wall clock times in the show timezone, e.g. 2025-06-21T20:30, or RFC 3339 instants.
+// Date (YYYY-MM-DD) and Time (HH:MM) set the start in two parts. Empty fields are unchanged.
+type ShowTimesInput struct {
+	StartsAt string
+	DoorsAt  string
+	EndsAt   string
+	Date     string
+	Time     string
+	Timezone string
+}
+
+func parseShowTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	for _, layout := range localTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, use YYYY-MM-DDTHH:MM in the show timezone", value)
+}
+
+// SetShowTimes sets the timezone and the times of show from input and validates them. The
+// timezone is the one of the venue when it has one, then the input one, then the default.
+// Call it once the venue is applied.
+func SetShowTimes(show *models.Show, input ShowTimesInput) error {
+	switch {
+	case show.Location != nil && show.Location.Timezone != "":
+		show.Timezone = show.Location.Timezone
+	case input.Timezone != "":
+		show.Timezone = input.Timezone
+	case show.Timezone == "":
+		show.Timezone = DefaultTimezone()
+	}
+	loc, err := time.LoadLocation(show.Timezone)
+	if err != nil {
+		return errors.New("timezone must be an IANA time zone name")
+	}
+
+	if input.StartsAt == "" && (input.Date != "" || input.Time != "") {
+		date, clock := input.Date, input.Time
+		if !show.StartsAt.IsZero() {
+			local := show.StartsAt.In(loc)
+			if date == "" {
+				date = local.Format("2006-01-02")
+			}
+			if clock == "" {
+				clock = local.Format("15:04")
+			}
+		}
+		if date == "" {
+			return errors.New("date is required")
+		}
+		if clock == "" {
+			clock = defaultShowClock
+		}
+		input.StartsAt = date + "T" + clock
+	}
+	if input.StartsAt != "" {
+		startsAt, err := parseShowTime(input.StartsAt, loc)
+		if err != nil {
+			return err
+		}
+		// the doors and the end move with the start unless they are given too
+		if !show.StartsAt.IsZero() {
+			shift := startsAt.Sub(show.StartsAt)
+			for _, t := range []*time.Time{show.DoorsAt, show.EndsAt} {
+				if t != nil {
+					*t = t.Add(shift)
+				}
+			}
+		}
+		show.StartsAt = startsAt
+	}
+	for _, field := range []struct {
+		value  string
+		target **time.Time
+	}{{input.DoorsAt, &show.DoorsAt}, {input.EndsAt, &show.EndsAt}} {
+		if field.value == "" {
+			continue
+		}
+		t, err := parseShowTime(field.value, loc)
+		if err != nil {
+			return err
+		}
+		*field.target = &t
+	}
+	return validateShowTimes(*show)
+}
+
+func validateShowTimes(show models.Show) error {
+	if show.StartsAt.IsZero() {
+		return errors.New("startsAt is required")
+	}
+	if show.DoorsAt != nil {
+		if show.DoorsAt.After(show.StartsAt) {
+			return errors.New("doorsAt must not be after startsAt")
+		}
+		if show.StartsAt.Sub(*show.DoorsAt) > maxDoorsBeforeStart {
+			return errors.New("doorsAt must be at most 12 hours before startsAt")
+		}
+	}
+	if show.EndsAt != nil {
+		if !show.EndsAt.After(show.StartsAt) {
+			return errors.New("endsAt must be after startsAt")
+		}
+		if show.EndsAt.Sub(show.StartsAt) > maxShowDuration {
+			return errors.New("endsAt must be at most 24 hours after startsAt")
+		}
+	}
+	return nil
+}
+
+// FormatShowDate is the start of a show in its timezone, for the documents and emails
+func FormatShowDate(show models.Show) string {
+	return show.In(show.StartsAt).Format("Monday 2 January 2006 at 15:04")
+}
+
+// MigrateShowTimes moves the shows from a date at UTC midnight and a separate "20:00" time
+// string to a start instant in the timezone of their venue, then drops the time column.
+// It runs once, on the first start without the column, after LinkShowVenues.
+func MigrateShowTimes(db *gorm.DB) (int, error) {
+	// HasColumn of SQLite looks for the name in the table definition, "datetime" has it
+	columns, err := db.Migrator().ColumnTypes(&models.Show{})
+	if err != nil {
+		return 0, err
+	}
+	if !slices.ContainsFunc(columns, func(column gorm.ColumnType) bool { return column.Name() == "time" }) {
+		return 0, nil
+	}
+	type legacyShow struct {
+		ID       uint
+		StartsAt time.Time
+		Time     string
+		VenueID  *uint
+	}
+	var shows []legacyShow
+	if err := db.Unscoped().Model(&models.Show{}).Select("id, starts_at, time, venue_id").Scan(&shows).Error; err != nil {
+		return 0, err
+	}
+	var venues []models.Venue
+	if err := db.Unscoped().Find(&venues).Error; err != nil {
+		return 0, err
+	}
+	zones := map[uint]string{}
+	for _, venue := range venues {
+		zones[venue.ID] = venue.Timezone
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, show := range shows {
+			zone := DefaultTimezone()
+			if show.VenueID != nil && zones[*show.VenueID] != "" {
+				zone = zones[*show.VenueID]
+			}
+			clock, err := time.Parse("15:04", show.Time)
+			if err != nil {
+				if show.Time != "" {
+					log.Printf("Show %d has an invalid time %q, it starts at %s", show.ID, show.Time, defaultShowClock)
+				}
+				clock, _ = time.Parse("15:04", defaultShowClock)
+			}
+			// the date was stored as midnight UTC of the day of the show
+			day := show.StartsAt.UTC()
+			startsAt := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, models.LoadLocation(zone))
+			if err := tx.Model(&models.Show{}).Unscoped().Where("id = ?", show.ID).UpdateColumns(map[string]any{
+				"starts_at": startsAt.UTC(),
+				"timezone":  zone,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		// the SQLite migrator copies the table to drop a column, which the search triggers don't survive
+		return tx.Exec(`ALTER TABLE shows DROP COLUMN "time"`).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("Moved %d shows to start times in their timezone", len(shows))
+	return len(shows), nil
+}
@@ -0,0 +1,100 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetShowTimes(t *testing.T) {
+	paris := models.Venue{Name: "Olympia", Timezone: "Europe/Paris"}
+	show := models.Show{Location: &paris}
+
+	err := SetShowTimes(&show, ShowTimesInput{StartsAt: "2025-06-21T20:30", DoorsAt: "2025-06-21T19:00", EndsAt: "2025-06-21T23:00", Timezone: "Asia/Tokyo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Europe/Paris", show.Timezone)
+	assert.Equal(t, time.Date(2025, 6, 21, 18, 30, 0, 0, time.UTC), show.StartsAt)
+	assert.Equal(t, time.Date(2025, 6, 21, 17, 0, 0, 0, time.UTC), *show.DoorsAt)
+	assert.Equal(t, "Saturday 21 June 2025 at 20:30", FormatShowDate(show))
+
+	// the date and the time are changed one at a time, in the local time of the show
+	assert.NoError(t, SetShowTimes(&show, ShowTimesInput{Time: "21:00"}))
+	assert.Equal(t, time.Date(2025, 6, 21, 19, 0, 0, 0, time.UTC), show.StartsAt)
+	assert.NoError(t, SetShowTimes(&show, ShowTimesInput{Date: "2025-12-20", EndsAt: "2025-12-20T23:30"}))
+	assert.Equal(t, time.Date(2025, 12, 20, 20, 0, 0, 0, time.UTC), show.StartsAt)
+	assert.Equal(t, time.Date(2025, 12, 20, 18, 30, 0, 0, time.UTC), *show.DoorsAt)
+	assert.Equal(t, time.Date(2025, 12, 20, 22, 30, 0, 0, time.UTC), *show.EndsAt)
+
+	assert.EqualError(t, SetShowTimes(&show, ShowTimesInput{DoorsAt: "2025-12-20T22:00"}), "doorsAt must not be after startsAt")
+	show.DoorsAt = nil
+	assert.EqualError(t, SetShowTimes(&show, ShowTimesInput{EndsAt: "2025-12-22T20:00"}), "endsAt must be at most 24 hours after startsAt")
+	assert.Error(t, SetShowTimes(&show, ShowTimesInput{StartsAt: "21/06/2025 20:30"}))
+
+	// without a venue timezone, the one given or the default applies
+	other := models.Show{}
+	assert.NoError(t, SetShowTimes(&other, ShowTimesInput{StartsAt: "2025-03-01T20:00", Timezone: "America/New_York"}))
+	assert.Equal(t, time.Date(2025, 3, 2, 1, 0, 0, 0, time.UTC), other.StartsAt)
+	assert.EqualError(t, SetShowTimes(&models.Show{}, ShowTimesInput{StartsAt: "2025-03-01T20:00", Timezone: "Mars/Olympus"}), "timezone must be an IANA time zone name")
+	assert.EqualError(t, SetShowTimes(&models.Show{}, ShowTimesInput{Time: "20:00"}), "date is required")
+
+	// instants keep their offset
+	instant := models.Show{}
+	assert.NoError(t, SetShowTimes(&instant, ShowTimesInput{StartsAt: "2025-03-01T20:00:00+09:00"}))
+	assert.Equal(t, time.Date(2025, 3, 1, 11, 0, 0, 0, time.UTC), instant.StartsAt)
+}
+
+func TestShowLocalTimes(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	doors := time.Date(2030, 1, 10, 18, 0, 0, 0, time.UTC)
+	show := models.Show{Title: "Local", Venue: "Lille", StartsAt: time.Date(2030, 1, 10, 19, 0, 0, 0, time.UTC), DoorsAt: &doors, Timezone: "Europe/Paris"}
+	show, err := service.SetShow(show)
+	assert.NoError(t, err)
+
+	got, err := service.GetShowByID(show.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, got.StartsAt.Location())
+	assert.Equal(t, "2030-01-10T20:00:00+01:00", got.Local.StartsAt.Format(time.RFC3339))
+	assert.Equal(t, "2030-01-10T19:00:00+01:00", got.Local.DoorsAt.Format(time.RFC3339))
+	assert.Nil(t, got.Local.EndsAt)
+	assert.Equal(t, models.SaleOnSale, got.SaleStatus)
+
+	// sales end with the show
+	service.Db.Model(&got).Update("starts_at", time.Now().Add(-time.Hour))
+	got, _ = service.GetShowByID(show.ID)
+	assert.Equal(t, models.SaleOffSale, got.SaleStatus)
+	assert.ErrorIs(t, service.CheckSaleWindow(got, models.User{}, ""), ErrSaleEnded)
+}
+
+func TestMigrateShowTimes(t *testing.T) {
+	db := SetupTestDB()
+	n, err := MigrateShowTimes(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	assert.NoError(t, db.Exec("ALTER TABLE shows ADD COLUMN `time` text").Error)
+	venue := models.Venue{Name: "Beacon", NameKey: "beacon", Timezone: "America/New_York"}
+	db.Create(&venue)
+	day := time.Date(2025, 7, 4, 0, 0, 0, 0, time.UTC)
+	ny := models.Show{Title: "NY", Venue: "Beacon", VenueID: &venue.ID, StartsAt: day}
+	local := models.Show{Title: "Default", Venue: "Nowhere", StartsAt: day}
+	db.Create(&ny)
+	db.Create(&local)
+	db.Exec("UPDATE shows SET time = ? WHERE id = ?", "21:30", ny.ID)
+
+	n, err = MigrateShowTimes(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = MigrateShowTimes(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	var got, other models.Show
+	db.First(&got, ny.ID)
+	assert.Equal(t, time.Date(2025, 7, 5, 1, 30, 0, 0, time.UTC), got.StartsAt)
+	assert.Equal(t, "America/New_York", got.Timezone)
+	db.First(&other, local.ID)
+	assert.Equal(t, time.Date(2025, 7, 4, 20, 0, 0, 0, time.UTC), other.StartsAt)
+	assert.Equal(t, "UTC", other.Timezone)
+}
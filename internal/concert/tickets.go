@@ -0,0 +1,191 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTicketInvalid       = errors.New("invalid ticket code")
+	ErrTicketVoid          = errors.New("this ticket has been invalidated")
+	ErrBookingCancelled    = errors.New("the booking of this ticket is cancelled")
+	ErrBookingNotConfirmed = errors.New("tickets are only issued for confirmed bookings")
+	ErrTicketWrongShow     = errors.New("this ticket is not for this show")
+)
+
+const issueTicketsAttempts = 3
+
+// AlreadyCheckedInError is returned when a ticket is scanned a second time.
+type AlreadyCheckedInError struct {
+	Ticket models.Ticket
+}
+
+func (e *AlreadyCheckedInError) Error() string {
+	return fmt.Sprintf("ticket already checked in at %s (gate %s)", e.Ticket.CheckedInAt.Format(time.RFC3339), e.Ticket.Gate)
+}
+
+type CheckInResult struct {
+	Ticket      models.Ticket `json:"ticket"`
+	ShowTitle   string        `json:"showTitle"`
+	TicketCount int           `json:"ticketCount"`
+}
+
+// newTicketCode signs the ticket identity, the nonce makes re-issued codes differ from old ones.
+func newTicketCode(showID, bookingID uint, seq int) string {
+	nonce := make([]byte, 4)
+	rand.Read(nonce)
+	return utils.Sign(fmt.Sprintf("t:%d:%d:%d:%s", showID, bookingID, seq, hex.EncodeToString(nonce)))
+}
+
+func verifyTicketCode(code string) error {
+	payload, err := utils.Verify(strings.TrimSpace(code))
+	if err != nil || !strings.HasPrefix(payload, "t:") {
+		return ErrTicketInvalid
+	}
+	return nil
+}
+
+// IssueTickets creates one ticket per seat of a confirmed booking, for each day of the
+// festival on a pass. It is idempotent, tickets already issued are returned as they are.
+// Concurrent calls can't issue a seat twice, the unique index rejects all but one and the
+// others retry, finding the tickets issued. The seats listed for resale get no tickets
+// until the listing is withdrawn.
+func (s Service) IssueTickets(booking models.Booking) ([]models.Ticket, error) {
+	if booking.Status != "confirmed" {
+		return nil, ErrBookingNotConfirmed
+	}
+	listed, err := listedTicketCount(s.Db, booking.ID)
+	if err != nil {
+		return nil, err
+	}
+	booking.TicketCount -= listed
+
+	showIDs := []uint{booking.ShowID}
+	if booking.FestivalID != nil {
+		if err := s.Db.Model(&models.Show{}).Where("festival_id = ?", *booking.FestivalID).
+			Order("starts_at, id").Pluck("id", &showIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var tickets []models.Ticket
+	for attempt := 0; attempt < issueTicketsAttempts; attempt++ {
+		tickets, err = issueTickets(s.Db, booking, showIDs)
+		if err == nil {
+			return tickets, nil
+		}
+	}
+	return nil, err
+}
+
+func issueTickets(db *gorm.DB, booking models.Booking, showIDs []uint) ([]models.Ticket, error) {
+	var tickets []models.Ticket
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var issued []models.Ticket
+		if err := tx.Where("booking_id = ? AND status <> ?", booking.ID, models.TicketVoid).Order("seq").Find(&issued).Error; err != nil {
+			return err
+		}
+		for _, showID := range showIDs {
+			count := 0
+			for _, ticket := range issued {
+				if ticket.ShowID == showID {
+					tickets = append(tickets, ticket)
+					count++
+				}
+			}
+			for seq := count + 1; seq <= booking.TicketCount; seq++ {
+				ticket := models.Ticket{
+					BookingID: booking.ID,
+					ShowID:    showID,
+					Seq:       seq,
+					Code:      newTicketCode(showID, booking.ID, seq),
+					Status:    models.TicketValid,
+				}
+				if err := tx.Create(&ticket).Error; err != nil {
+					return err
+				}
+				tickets = append(tickets, ticket)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tickets, nil
+}
+
+func (s Service) GetTicketsByBooking(bookingID uint) ([]models.Ticket, error) {
+	var tickets []models.Ticket
+	if err := s.Db.Where("booking_id = ?", bookingID).Order("seq, id").Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+	return tickets, nil
+}
+
+// VoidTickets invalidates every ticket of a booking, e.g. when it is cancelled.
+func (s Service) VoidTickets(bookingID uint) error {
+	return s.Db.Model(&models.Ticket{}).
+		Where("booking_id = ? AND status = ?", bookingID, models.TicketValid).
+		Update("status", models.TicketVoid).Error
+}
+
+// CheckIn admits the holder of a ticket code at a gate of a show. Each ticket gets in
+// once, at its show: a festival pass has a ticket for each day.
+func (s Service) CheckIn(code, gate string, showID, staffID uint) (CheckInResult, error) {
+	if err := verifyTicketCode(code); err != nil {
+		return CheckInResult{}, err
+	}
+
+	var ticket models.Ticket
+	if err := s.Db.Preload("Booking.Show").Where("code = ?", strings.TrimSpace(code)).First(&ticket).Error; err != nil {
+		return CheckInResult{}, ErrTicketInvalid
+	}
+	result := CheckInResult{Ticket: ticket, ShowTitle: ticket.Booking.Show.Title, TicketCount: ticket.Booking.TicketCount}
+	if ticket.ShowID != ticket.Booking.ShowID {
+		// a day of a festival pass, booked on the opening show
+		var day models.Show
+		if err := s.Db.Select("title").First(&day, ticket.ShowID).Error; err == nil {
+			result.ShowTitle = day.Title
+		}
+	}
+
+	switch {
+	case ticket.ShowID != showID:
+		return result, ErrTicketWrongShow
+	case ticket.Booking.Status == "cancelled":
+		return result, ErrBookingCancelled
+	case ticket.Status == models.TicketVoid:
+		return result, ErrTicketVoid
+	case ticket.Status == models.TicketUsed:
+		return result, &AlreadyCheckedInError{Ticket: ticket}
+	}
+
+	now := time.Now()
+	// the status condition makes concurrent scans of the same ticket admit only one person
+	update := s.Db.Model(&models.Ticket{}).
+		Where("id = ? AND status = ?", ticket.ID, models.TicketValid).
+		Updates(map[string]any{"status": models.TicketUsed, "checked_in_at": now, "gate": gate, "checked_in_by": staffID})
+	if update.Error != nil {
+		return result, update.Error
+	}
+	if update.RowsAffected == 0 {
+		s.Db.First(&ticket, ticket.ID)
+		return result, &AlreadyCheckedInError{Ticket: ticket}
+	}
+
+	ticket.Status = models.TicketUsed
+	ticket.CheckedInAt = &now
+	ticket.Gate = gate
+	ticket.CheckedInBy = &staffID
+	result.Ticket = ticket
+	return result, nil
+}
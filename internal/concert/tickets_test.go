@@ -0,0 +1,77 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssueTicketsAndCheckIn(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	show := models.Show{Title: "Doors", Venue: "Lyon", StartsAt: time.Now()}
+	db.Create(&show)
+	booking := models.Booking{ShowID: show.ID, TicketCount: 2, Status: "confirmed"}
+	db.Create(&booking)
+
+	tickets, err := service.IssueTickets(booking)
+	assert.NoError(t, err)
+	assert.Len(t, tickets, 2)
+	assert.NotEqual(t, tickets[0].Code, tickets[1].Code)
+
+	// issuing again returns the same tickets
+	again, err := service.IssueTickets(booking)
+	assert.NoError(t, err)
+	assert.Equal(t, tickets[0].Code, again[0].Code)
+	assert.Len(t, again, 2)
+
+	other := models.Show{Title: "Other", Venue: "Lyon", StartsAt: time.Now()}
+	db.Create(&other)
+	_, err = service.CheckIn(tickets[0].Code, "A", other.ID, 7)
+	assert.ErrorIs(t, err, ErrTicketWrongShow)
+
+	result, err := service.CheckIn(tickets[0].Code, "A", show.ID, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, "Doors", result.ShowTitle)
+	assert.Equal(t, models.TicketUsed, result.Ticket.Status)
+
+	var already *AlreadyCheckedInError
+	_, err = service.CheckIn(tickets[0].Code, "B", show.ID, 7)
+	assert.ErrorAs(t, err, &already)
+	assert.Equal(t, "A", already.Ticket.Gate)
+
+	_, err = service.CheckIn("forged.code", "A", show.ID, 7)
+	assert.ErrorIs(t, err, ErrTicketInvalid)
+
+	assert.NoError(t, service.VoidTickets(booking.ID))
+	_, err = service.CheckIn(tickets[1].Code, "A", show.ID, 7)
+	assert.ErrorIs(t, err, ErrTicketVoid)
+
+	_, err = service.IssueTickets(models.Booking{ShowID: show.ID, TicketCount: 1, Status: "cancelled"})
+	assert.ErrorIs(t, err, ErrBookingNotConfirmed)
+}
+
+func TestIssueTicketsOncePerSeat(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	show := models.Show{Title: "Doors", Venue: "Lyon", StartsAt: time.Now()}
+	db.Create(&show)
+	booking := models.Booking{ShowID: show.ID, TicketCount: 1, Status: "confirmed"}
+	db.Create(&booking)
+	tickets, err := service.IssueTickets(booking)
+	assert.NoError(t, err)
+
+	// a concurrent issue of the same seat is rejected
+	duplicate := models.Ticket{BookingID: booking.ID, ShowID: show.ID, Seq: 1, Code: "other", Status: models.TicketValid}
+	assert.Error(t, db.Create(&duplicate).Error)
+
+	// a void ticket gives its seat to a new one
+	assert.NoError(t, service.VoidTickets(booking.ID))
+	reissued, err := service.IssueTickets(booking)
+	assert.NoError(t, err)
+	assert.NotEqual(t, tickets[0].Code, reissued[0].Code)
+}
@@ -0,0 +1,385 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTourInUse       = errors.New("the tour still has shows")
+	ErrInvalidTourShow = errors.New("invalid tour show")
+)
+
+// MaxTourDates bounds the shows created at once
+const MaxTourDates = 366
+
+// maxRecurrenceSpan bounds how far a recurrence goes looking for its dates
+const maxRecurrenceSpan = 5 * 366 * 24 * time.Hour
+
+const (
+	RecurDaily  = "daily"
+	RecurWeekly = "weekly"
+)
+
+// TourDate is a date of a tour at a venue, given by id or by name like on a show.
+// StartsAt is a wall clock time in the venue timezone, or Date a day played at the
+// time of the template.
+type TourDate struct {
+	Date     string
+	StartsAt string
+	VenueID  *uint
+	Venue    string
+	City     string
+	Country  string
+}
+
+// TourTemplate is what the shows of a tour share. Time is the start, HH:MM, of the dates
+// given as a day, the doors open DoorsBefore the start and the show lasts Duration, zero
+// leaves the doors or the end time unset. Timezone is for the venues without one.
+type TourTemplate struct {
+	Show        models.Show
+	Time        string
+	Timezone    string
+	DoorsBefore time.Duration
+	Duration    time.Duration
+}
+
+// Recurrence gives the dates of a residency at a venue: every Interval days, or weeks on
+// Weekdays, from From until Until or Count dates. Weeks are counted from From, which
+// gives the weekday when there are none. Except are days skipped, e.g. bank holidays.
+type Recurrence struct {
+	Frequency string
+	Interval  int
+	Weekdays  []time.Weekday
+	From      string
+	Until     string
+	Count     int
+	Except    []string
+	VenueID   *uint
+	Venue     string
+	City      string
+	Country   string
+}
+
+// TourShowChanges are changed on every show of a tour, nil ones are left alone. Time
+// moves the start of each show to HH:MM in its timezone, the doors and end with it.
+type TourShowChanges struct {
+	Title       *string
+	Description *string
+	ImageURL    *string
+	Price       *float64
+	TotalSeats  *int
+	OnSaleAt    *time.Time
+	OffSaleAt   *time.Time
+	Time        string
+}
+
+// Dates lists the days of the recurrence, at the venue of the recurrence.
+func (r Recurrence) Dates() ([]TourDate, error) {
+	from, err := time.Parse("2006-01-02", r.From)
+	if err != nil {
+		return nil, errors.New("from must be a date, YYYY-MM-DD")
+	}
+	var until time.Time
+	if r.Until != "" {
+		if until, err = time.Parse("2006-01-02", r.Until); err != nil {
+			return nil, errors.New("until must be a date, YYYY-MM-DD")
+		}
+		if until.Before(from) {
+			return nil, errors.New("until must not be before from")
+		}
+	} else if r.Count <= 0 {
+		return nil, errors.New("until or count is required")
+	}
+	interval := max(r.Interval, 1)
+	weekdays := map[time.Weekday]bool{}
+	switch r.Frequency {
+	case RecurDaily:
+	case RecurWeekly:
+		for _, day := range r.Weekdays {
+			weekdays[day] = true
+		}
+		if len(weekdays) == 0 {
+			weekdays[from.Weekday()] = true
+		}
+	default:
+		return nil, errors.New("frequency must be daily or weekly")
+	}
+	except := map[string]bool{}
+	for _, day := range r.Except {
+		except[day] = true
+	}
+
+	var dates []TourDate
+	for day := from; until.IsZero() || !day.After(until); day = day.AddDate(0, 0, 1) {
+		if r.Count > 0 && len(dates) == r.Count {
+			break
+		}
+		if day.Sub(from) > maxRecurrenceSpan {
+			return nil, errors.New("the recurrence spans more than 5 years")
+		}
+		// the days are at UTC midnight, there is no daylight saving time in the count
+		n := int(day.Sub(from).Hours() / 24)
+		match := n%interval == 0
+		if r.Frequency == RecurWeekly {
+			match = weekdays[day.Weekday()] && (n/7)%interval == 0
+		}
+		if !match || except[day.Format("2006-01-02")] {
+			continue
+		}
+		if len(dates) == MaxTourDates {
+			return nil, fmt.Errorf("a recurrence gives at most %d dates", MaxTourDates)
+		}
+		dates = append(dates, TourDate{
+			Date:    day.Format("2006-01-02"),
+			VenueID: r.VenueID,
+			Venue:   r.Venue,
+			City:    r.City,
+			Country: r.Country,
+		})
+	}
+	return dates, nil
+}
+
+func (s Service) ListTours(artistID uint) ([]models.Tour, error) {
+	query := s.Db.Preload("Artist").Order("id")
+	if artistID != 0 {
+		query = query.Where("artist_id = ?", artistID)
+	}
+	var tours []models.Tour
+	if err := query.Find(&tours).Error; err != nil {
+		return nil, err
+	}
+	return tours, nil
+}
+
+// GetTour returns a tour with its published shows by start time.
+func (s Service) GetTour(id uint) (models.Tour, error) {
+	var tour models.Tour
+	if err := s.Db.Preload("Artist").First(&tour, id).Error; err != nil {
+		return models.Tour{}, err
+	}
+	now := time.Now()
+	if err := listedShows(preloadLineup(s.Db).Preload("Location").Preload("Presales"), now).
+		Where("tour_id = ?", tour.ID).
+		Order("starts_at, id").
+		Find(&tour.Shows).Error; err != nil {
+		return models.Tour{}, err
+	}
+	for i := range tour.Shows {
+		applySaleStatus(&tour.Shows[i], now)
+	}
+	return tour, nil
+}
+
+func (s Service) CreateTour(tour models.Tour) (models.Tour, error) {
+	tour.ID = 0
+	tour.Shows = nil
+	if err := tour.Validate(); err != nil {
+		return models.Tour{}, err
+	}
+	if err := s.Db.Omit(clause.Associations).Create(&tour).Error; err != nil {
+		return models.Tour{}, err
+	}
+	return s.GetTour(tour.ID)
+}
+
+func (s Service) UpdateTour(tour models.Tour) (models.Tour, error) {
+	if err := tour.Validate(); err != nil {
+		return models.Tour{}, err
+	}
+	if err := s.Db.Omit(clause.Associations).Save(&tour).Error; err != nil {
+		return models.Tour{}, err
+	}
+	return s.GetTour(tour.ID)
+}
+
+// DeleteTour deletes a tour without shows.
+func (s Service) DeleteTour(id uint) error {
+	var shows int64
+	if err := s.Db.Model(&models.Show{}).Where("tour_id = ?", id).Count(&shows).Error; err != nil {
+		return err
+	}
+	if shows > 0 {
+		return ErrTourInUse
+	}
+	result := s.Db.Delete(&models.Tour{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// tourShow is the show of a date, from the template
+func (s Service) tourShow(tour models.Tour, template TourTemplate, date TourDate) (models.Show, error) {
+	show := template.Show
+	show.ID = 0
+	show.TourID = &tour.ID
+	show.ArtistID = tour.ArtistID
+	show.AvailableSeats = show.TotalSeats
+	show.VenueID, show.Venue, show.City, show.Location = date.VenueID, date.Venue, date.City, nil
+	if date.Country != "" {
+		show.Country = date.Country
+	}
+	if show.VenueID == nil && show.Venue == "" {
+		return models.Show{}, errors.New("venue or venueId is required")
+	}
+	if err := s.ApplyVenue(&show); err != nil {
+		return models.Show{}, err
+	}
+
+	input := ShowTimesInput{StartsAt: date.StartsAt, Timezone: template.Timezone}
+	if date.StartsAt == "" {
+		input.Date, input.Time = date.Date, template.Time
+	}
+	if err := SetShowTimes(&show, input); err != nil {
+		return models.Show{}, err
+	}
+	if template.DoorsBefore > 0 {
+		doors := show.StartsAt.Add(-template.DoorsBefore)
+		show.DoorsAt = &doors
+	}
+	if template.Duration > 0 {
+		end := show.StartsAt.Add(template.Duration)
+		show.EndsAt = &end
+	}
+	return show, validateShowTimes(show)
+}
+
+// CreateTourShows creates a show of the tour for each date from the template, all or
+// none. A tour plays a venue once at a time.
+func (s Service) CreateTourShows(tourID uint, template TourTemplate, dates []TourDate) ([]models.Show, error) {
+	var tour models.Tour
+	if err := s.Db.First(&tour, tourID).Error; err != nil {
+		return nil, err
+	}
+	if len(dates) == 0 {
+		return nil, fmt.Errorf("%w: no dates", ErrInvalidTourShow)
+	}
+	if len(dates) > MaxTourDates {
+		return nil, fmt.Errorf("%w: at most %d dates at once", ErrInvalidTourShow, MaxTourDates)
+	}
+
+	type slot struct {
+		venueID  uint
+		startsAt int64
+	}
+	taken := map[slot]bool{}
+	var existing []models.Show
+	if err := s.Db.Select("venue_id, starts_at").Where("tour_id = ?", tour.ID).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	for _, show := range existing {
+		if show.VenueID != nil {
+			taken[slot{*show.VenueID, show.StartsAt.Unix()}] = true
+		}
+	}
+
+	shows := make([]models.Show, len(dates))
+	for i, date := range dates {
+		show, err := s.tourShow(tour, template, date)
+		if err != nil {
+			return nil, fmt.Errorf("%w: date %d: %w", ErrInvalidTourShow, i+1, err)
+		}
+		key := slot{*show.VenueID, show.StartsAt.Unix()}
+		if taken[key] {
+			return nil, fmt.Errorf("%w: date %d: the tour already plays %s at this time", ErrInvalidTourShow, i+1, show.Venue)
+		}
+		taken[key] = true
+		shows[i] = show
+	}
+
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		return tx.Omit(clause.Associations).Create(&shows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shows, nil
+}
+
+// UpdateTourShows applies changes to every show of a tour still to come, all or none.
+// The seats can't go below the seats held by bookings nor above the capacity of a venue.
+func (s Service) UpdateTourShows(tourID uint, changes TourShowChanges, now time.Time) ([]models.Show, error) {
+	if err := s.Db.First(&models.Tour{}, tourID).Error; err != nil {
+		return nil, err
+	}
+	var shows []models.Show
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Location").
+			Where("tour_id = ? AND starts_at >= ?", tourID, now).
+			Order("starts_at, id").
+			Find(&shows).Error; err != nil {
+			return err
+		}
+		for i := range shows {
+			if err := applyTourShowChanges(tx, &shows[i], changes); err != nil {
+				return err
+			}
+		}
+		for i := range shows {
+			if err := tx.Omit(clause.Associations, "alerted_at", "queue_admitted_at").Save(&shows[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shows, nil
+}
+
+// applyTourShowChanges applies changes to show, its available seats counted again from
+// the bookings rather than trusted from the stored column.
+func applyTourShowChanges(tx *gorm.DB, show *models.Show, changes TourShowChanges) error {
+	if changes.Title != nil {
+		show.Title = *changes.Title
+	}
+	if changes.Description != nil {
+		show.Description = *changes.Description
+	}
+	if changes.ImageURL != nil {
+		show.ImageURL = *changes.ImageURL
+	}
+	if changes.Price != nil {
+		show.Price = *changes.Price
+	}
+	if changes.OnSaleAt != nil {
+		show.OnSaleAt = changes.OnSaleAt
+	}
+	if changes.OffSaleAt != nil {
+		show.OffSaleAt = changes.OffSaleAt
+	}
+	if changes.TotalSeats != nil {
+		show.TotalSeats = *changes.TotalSeats
+	}
+	show.AvailableSeats = show.TotalSeats - heldSeats(tx, *show)
+
+	var err error
+	switch {
+	case show.Price < 0:
+		err = errors.New("price can't be negative")
+	case show.AvailableSeats < 0:
+		err = errors.New("more seats are sold than that")
+	case show.Location != nil && show.Location.Capacity > 0 && show.TotalSeats > show.Location.Capacity:
+		err = ErrVenueCapacity
+	case show.OnSaleAt != nil && show.OffSaleAt != nil && !show.OffSaleAt.After(*show.OnSaleAt):
+		err = errors.New("offSaleAt must be after onSaleAt")
+	case changes.Time != "":
+		err = SetShowTimes(show, ShowTimesInput{Time: changes.Time})
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s on %s: %w", ErrInvalidTourShow, show.Venue, show.In(show.StartsAt).Format("2006-01-02"), err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecurrenceDates(t *testing.T) {
+	// every Friday and Saturday of March 2030 but the 15th
+	dates, err := Recurrence{Frequency: RecurWeekly, Weekdays: []time.Weekday{time.Friday, time.Saturday}, From: "2030-03-01", Until: "2030-03-31", Except: []string{"2030-03-15"}, Venue: "Olympia"}.Dates()
+	assert.NoError(t, err)
+	var days []string
+	for _, date := range dates {
+		days = append(days, date.Date)
+		assert.Equal(t, "Olympia", date.Venue)
+	}
+	assert.Equal(t, []string{"2030-03-01", "2030-03-02", "2030-03-08", "2030-03-09", "2030-03-16", "2030-03-22", "2030-03-23", "2030-03-29", "2030-03-30"}, days)
+
+	// every other week on the weekday of from
+	dates, err = Recurrence{Frequency: RecurWeekly, Interval: 2, From: "2030-03-06", Count: 3}.Dates()
+	assert.NoError(t, err)
+	if assert.Len(t, dates, 3) {
+		assert.Equal(t, "2030-03-20", dates[1].Date)
+		assert.Equal(t, "2030-04-03", dates[2].Date)
+	}
+
+	dates, err = Recurrence{Frequency: RecurDaily, Interval: 3, From: "2030-03-30", Until: "2030-04-05"}.Dates()
+	assert.NoError(t, err)
+	assert.Len(t, dates, 3)
+
+	_, err = Recurrence{Frequency: RecurDaily, From: "2030-03-01"}.Dates()
+	assert.EqualError(t, err, "until or count is required")
+	_, err = Recurrence{Frequency: "monthly", From: "2030-03-01", Count: 2}.Dates()
+	assert.Error(t, err)
+	_, err = Recurrence{Frequency: RecurDaily, From: "2030-01-01", Until: "2031-12-31"}.Dates()
+	assert.Error(t, err)
+}
+
+func TestCreateTourShows(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	artist, _ := service.SetArtist(models.Artist{Name: "Band"})
+	paris, _ := service.CreateVenue(models.Venue{Name: "Olympia", City: "Paris", Timezone: "Europe/Paris", Capacity: 2000})
+	tour, err := service.CreateTour(models.Tour{Name: "World Tour", ArtistID: artist.ID})
+	assert.NoError(t, err)
+
+	template := TourTemplate{
+		Show:        models.Show{Title: "World Tour", Price: 45, TotalSeats: 1500},
+		Time:        "20:30",
+		DoorsBefore: 90 * time.Minute,
+		Timezone:    "America/New_York",
+	}
+	shows, err := service.CreateTourShows(tour.ID, template, []TourDate{
+		{Date: "2030-05-01", VenueID: &paris.ID},
+		{StartsAt: "2030-05-03T21:00", Venue: "Beacon Theatre", City: "New York", Country: "us"},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, shows, 2) {
+		assert.Equal(t, time.Date(2030, 5, 1, 18, 30, 0, 0, time.UTC), shows[0].StartsAt)
+		assert.Equal(t, time.Date(2030, 5, 1, 17, 0, 0, 0, time.UTC), *shows[0].DoorsAt)
+		assert.Equal(t, "Paris", shows[0].City)
+		assert.Equal(t, time.Date(2030, 5, 4, 1, 0, 0, 0, time.UTC), shows[1].StartsAt)
+		assert.Equal(t, "US", shows[1].Country)
+		assert.Equal(t, artist.ID, shows[1].ArtistID)
+		assert.Equal(t, 1500, shows[1].AvailableSeats)
+	}
+
+	// nothing is created when a date is wrong
+	_, err = service.CreateTourShows(tour.ID, template, []TourDate{{Date: "2030-05-05", Venue: "Somewhere"}, {Date: "2030-05-01", VenueID: &paris.ID}})
+	assert.ErrorIs(t, err, ErrInvalidTourShow)
+	template.Show.TotalSeats = 3000
+	_, err = service.CreateTourShows(tour.ID, template, []TourDate{{Date: "2030-05-02", VenueID: &paris.ID}})
+	assert.ErrorIs(t, err, ErrVenueCapacity)
+	_, err = service.CreateTourShows(tour.ID, template, []TourDate{{Date: "2030-05-02"}})
+	assert.ErrorIs(t, err, ErrInvalidTourShow)
+
+	got, err := service.GetTour(tour.ID)
+	assert.NoError(t, err)
+	assert.Len(t, got.Shows, 2)
+	assert.Equal(t, "Band", got.Artist.Name)
+	assert.ErrorIs(t, service.DeleteTour(tour.ID), ErrTourInUse)
+
+	page, err := service.SearchShows(ShowQuery{TourID: tour.ID})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+}
+
+func TestUpdateTourShows(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	artist, _ := service.SetArtist(models.Artist{Name: "Band"})
+	tour, _ := service.CreateTour(models.Tour{Name: "Residency", ArtistID: artist.ID})
+	dates, _ := Recurrence{Frequency: RecurWeekly, From: "2030-01-04", Count: 3, Venue: "Bataclan"}.Dates()
+	shows, err := service.CreateTourShows(tour.ID, TourTemplate{Show: models.Show{Title: "Residency", Price: 30, TotalSeats: 100}, Time: "20:00", Duration: 2 * time.Hour}, dates)
+	assert.NoError(t, err)
+	past := models.Show{Title: "Residency", ArtistID: artist.ID, TourID: &tour.ID, Venue: "Bataclan", StartsAt: time.Now().AddDate(0, 0, -7), TotalSeats: 100}
+	service.Db.Create(&past)
+	service.Db.Create(&models.Booking{ShowID: shows[0].ID, TicketCount: 60, Status: "confirmed"})
+	service.Db.Create(&models.Booking{ShowID: shows[0].ID, TicketCount: 5, Status: "cancelled"})
+
+	title := "Residency, extended"
+	seats := 80
+	updated, err := service.UpdateTourShows(tour.ID, TourShowChanges{Title: &title, TotalSeats: &seats, Time: "21:00"}, time.Now())
+	assert.NoError(t, err)
+	if assert.Len(t, updated, 3) {
+		assert.Equal(t, title, updated[2].Title)
+		assert.Equal(t, 20, updated[0].AvailableSeats)
+		assert.Equal(t, time.Date(2030, 1, 4, 21, 0, 0, 0, time.UTC), updated[0].StartsAt)
+		assert.Equal(t, time.Date(2030, 1, 4, 23, 0, 0, 0, time.UTC), *updated[0].EndsAt)
+	}
+	var old models.Show
+	service.Db.First(&old, past.ID)
+	assert.Equal(t, "Residency", old.Title)
+
+	// the seats sold stay, whatever the stored count says
+	service.Db.Model(&shows[0]).Update("available_seats", 80)
+	seats = 50
+	_, err = service.UpdateTourShows(tour.ID, TourShowChanges{TotalSeats: &seats}, time.Now())
+	assert.ErrorIs(t, err, ErrInvalidTourShow)
+	got, _ := service.GetShowByID(shows[1].ID)
+	assert.Equal(t, 80, got.TotalSeats)
+}
@@ -0,0 +1,230 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TransferInviteValidity is how long the recipient has to accept a transfer.
+const TransferInviteValidity = 7 * 24 * time.Hour
+
+var (
+	ErrTransfersDisabled     = errors.New("tickets of this show can't be transferred")
+	ErrTransferTooLate       = errors.New("it is too close to the show to transfer tickets")
+	ErrTransferLimit         = errors.New("this booking reached the transfer limit of the show")
+	ErrTransferPending       = errors.New("this booking already has a pending transfer")
+	ErrTransferToSelf        = errors.New("you can't transfer tickets to yourself")
+	ErrTransferInvalid       = errors.New("invalid or cancelled transfer invitation")
+	ErrTransferExpired       = errors.New("this transfer invitation has expired")
+	ErrTransferEmailMismatch = errors.New("this invitation was sent to another email address")
+)
+
+func transferToken(transfer models.Transfer) string {
+	return utils.Sign(fmt.Sprintf("tr:%d:%s", transfer.ID, transfer.Nonce))
+}
+
+func (s Service) audit(tx *gorm.DB, bookingID uint, action string, actorID *uint, detail string) error {
+	return tx.Create(&models.BookingAudit{BookingID: bookingID, Action: action, ActorID: actorID, Detail: detail}).Error
+}
+
+// CreateTransfer invites toEmail to take over a booking of from and returns the invitation token.
+func (s Service) CreateTransfer(booking models.Booking, from models.User, toEmail string) (models.Transfer, string, error) {
+	toEmail = strings.ToLower(strings.TrimSpace(toEmail))
+	if booking.Status != "confirmed" {
+		return models.Transfer{}, "", ErrBookingNotConfirmed
+	}
+	if strings.EqualFold(toEmail, from.Email) {
+		return models.Transfer{}, "", ErrTransferToSelf
+	}
+
+	show, err := s.GetShowByID(booking.ShowID)
+	if err != nil {
+		return models.Transfer{}, "", err
+	}
+	now := time.Now()
+	switch {
+	case show.TransfersDisabled:
+		return models.Transfer{}, "", ErrTransfersDisabled
+	case show.TransferCutoffHours > 0 && show.DoorsTime().Sub(now) < time.Duration(show.TransferCutoffHours)*time.Hour:
+		return models.Transfer{}, "", ErrTransferTooLate
+	}
+
+	nonce := make([]byte, 12)
+	rand.Read(nonce)
+	transfer := models.Transfer{
+		BookingID:  booking.ID,
+		FromUserID: from.ID,
+		ToEmail:    toEmail,
+		Status:     models.TransferPending,
+		Nonce:      hex.EncodeToString(nonce),
+		ExpiresAt:  now.Add(TransferInviteValidity),
+	}
+
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		if listed, err := hasOpenListing(tx, booking.ID); err != nil || listed {
+			if err == nil {
+				err = ErrResaleListed
+			}
+			return err
+		}
+		var accepted, pending int64
+		if err := tx.Model(&models.Transfer{}).Where("booking_id = ? AND status = ?", booking.ID, models.TransferAccepted).Count(&accepted).Error; err != nil {
+			return err
+		}
+		if show.MaxTransfersPerBooking > 0 && int(accepted) >= show.MaxTransfersPerBooking {
+			return ErrTransferLimit
+		}
+		if err := tx.Model(&models.Transfer{}).
+			Where("booking_id = ? AND status = ? AND expires_at > ?", booking.ID, models.TransferPending, now).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return ErrTransferPending
+		}
+
+		if err := tx.Create(&transfer).Error; err != nil {
+			return err
+		}
+		return s.audit(tx, booking.ID, models.AuditTransferInvited, &from.ID, "to "+toEmail)
+	})
+	if err != nil {
+		return models.Transfer{}, "", err
+	}
+	return transfer, transferToken(transfer), nil
+}
+
+// GetTransferByToken returns the pending transfer of an invitation token.
+func (s Service) GetTransferByToken(token string) (models.Transfer, error) {
+	payload, err := utils.Verify(token)
+	if err != nil {
+		return models.Transfer{}, ErrTransferInvalid
+	}
+	parts := strings.Split(payload, ":")
+	if len(parts) != 3 || parts[0] != "tr" {
+		return models.Transfer{}, ErrTransferInvalid
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return models.Transfer{}, ErrTransferInvalid
+	}
+
+	var transfer models.Transfer
+	if err := s.Db.First(&transfer, id).Error; err != nil || transfer.Nonce != parts[2] {
+		return models.Transfer{}, ErrTransferInvalid
+	}
+	if transfer.Status != models.TransferPending {
+		return transfer, ErrTransferInvalid
+	}
+	if time.Now().After(transfer.ExpiresAt) {
+		return transfer, ErrTransferExpired
+	}
+	return transfer, nil
+}
+
+// AcceptTransfer moves the booking of an invitation to recipient. The old ticket codes
+// are voided and new ones issued, so the previous holder can't use them anymore.
+func (s Service) AcceptTransfer(token string, recipient models.User) (models.Booking, error) {
+	transfer, err := s.GetTransferByToken(token)
+	if err != nil {
+		return models.Booking{}, err
+	}
+	if !strings.EqualFold(recipient.Email, transfer.ToEmail) {
+		return models.Booking{}, ErrTransferEmailMismatch
+	}
+	if recipient.ID == transfer.FromUserID {
+		return models.Booking{}, ErrTransferToSelf
+	}
+
+	booking, err := s.GetBookingById(transfer.BookingID)
+	if err != nil {
+		return models.Booking{}, err
+	}
+	if booking.Status != "confirmed" || booking.UserID != transfer.FromUserID {
+		return models.Booking{}, ErrTransferInvalid
+	}
+	if err := s.CheckTicketLimits(booking.Show, recipient, booking.TicketCount); err != nil {
+		return models.Booking{}, err
+	}
+
+	now := time.Now()
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&transfer).Where("status = ?", models.TransferPending).
+			Updates(map[string]any{"status": models.TransferAccepted, "to_user_id": recipient.ID, "accepted_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTransferInvalid
+		}
+		// the booking may have been cancelled or transferred since it was read
+		result = tx.Model(&models.Booking{}).
+			Where("id = ? AND status = ? AND user_id = ?", booking.ID, "confirmed", transfer.FromUserID).
+			Update("user_id", recipient.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTransferInvalid
+		}
+		if err := tx.Model(&models.Ticket{}).
+			Where("booking_id = ? AND status = ?", booking.ID, models.TicketValid).
+			Update("status", models.TicketVoid).Error; err != nil {
+			return err
+		}
+		detail := fmt.Sprintf("from user %d to user %d", transfer.FromUserID, recipient.ID)
+		return s.audit(tx, booking.ID, models.AuditTransferAccepted, &recipient.ID, detail)
+	})
+	if err != nil {
+		return models.Booking{}, err
+	}
+
+	booking.UserID = recipient.ID
+	if _, err := s.IssueTickets(booking); err != nil {
+		return booking, err
+	}
+	return booking, nil
+}
+
+// CancelTransfer withdraws a pending invitation of user.
+func (s Service) CancelTransfer(id uint, user models.User) error {
+	var transfer models.Transfer
+	if err := s.Db.First(&transfer, id).Error; err != nil || transfer.FromUserID != user.ID {
+		return ErrTransferInvalid
+	}
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&transfer).Where("status = ?", models.TransferPending).Update("status", models.TransferCancelled)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTransferInvalid
+		}
+		return s.audit(tx, transfer.BookingID, models.AuditTransferCancelled, &user.ID, "to "+transfer.ToEmail)
+	})
+}
+
+func (s Service) ListBookingTransfers(bookingID uint) ([]models.Transfer, error) {
+	var transfers []models.Transfer
+	if err := s.Db.Where("booking_id = ?", bookingID).Order("created_at DESC").Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+func (s Service) ListBookingAudit(bookingID uint) ([]models.BookingAudit, error) {
+	var entries []models.BookingAudit
+	if err := s.Db.Where("booking_id = ?", bookingID).Order("id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,118 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTransferBooking(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	show := models.Show{Title: "Gift", Venue: "Rennes", StartsAt: time.Now().AddDate(0, 0, 10), MaxTransfersPerBooking: 1}
+	db.Create(&show)
+	alice := models.User{Username: "alice", Email: "alice@example.com"}
+	bob := models.User{Username: "bob", Email: "bob@example.com"}
+	carol := models.User{Username: "carol", Email: "carol@example.com"}
+	db.Create(&alice)
+	db.Create(&bob)
+	db.Create(&carol)
+	booking := models.Booking{UserID: alice.ID, ShowID: show.ID, TicketCount: 2, Status: "confirmed"}
+	db.Create(&booking)
+	oldTickets, _ := service.IssueTickets(booking)
+
+	_, _, err := service.CreateTransfer(booking, alice, "ALICE@example.com")
+	assert.ErrorIs(t, err, ErrTransferToSelf)
+
+	transfer, token, err := service.CreateTransfer(booking, alice, "Bob@Example.com ")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob@example.com", transfer.ToEmail)
+
+	_, _, err = service.CreateTransfer(booking, alice, "carol@example.com")
+	assert.ErrorIs(t, err, ErrTransferPending)
+
+	_, err = service.AcceptTransfer(token, carol)
+	assert.ErrorIs(t, err, ErrTransferEmailMismatch)
+	_, err = service.AcceptTransfer(token+"x", bob)
+	assert.ErrorIs(t, err, ErrTransferInvalid)
+
+	moved, err := service.AcceptTransfer(token, bob)
+	assert.NoError(t, err)
+	assert.Equal(t, bob.ID, moved.UserID)
+
+	// the invitation works once
+	_, err = service.AcceptTransfer(token, bob)
+	assert.ErrorIs(t, err, ErrTransferInvalid)
+
+	_, err = service.CheckIn(oldTickets[0].Code, "A", oldTickets[0].ShowID, 1)
+	assert.ErrorIs(t, err, ErrTicketVoid)
+	newTickets, _ := service.IssueTickets(moved)
+	assert.Len(t, newTickets, 2)
+
+	// the show allows a single transfer per booking
+	_, _, err = service.CreateTransfer(moved, bob, "carol@example.com")
+	assert.ErrorIs(t, err, ErrTransferLimit)
+
+	audit, err := service.ListBookingAudit(booking.ID)
+	assert.NoError(t, err)
+	assert.Len(t, audit, 2)
+	assert.Equal(t, models.AuditTransferAccepted, audit[1].Action)
+}
+
+func TestCancelTransfer(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	show := models.Show{Title: "Gift", Venue: "Rennes", StartsAt: time.Now().Add(2 * time.Hour), TransferCutoffHours: 1}
+	db.Create(&show)
+	alice := models.User{Username: "alice", Email: "alice@example.com"}
+	db.Create(&alice)
+	booking := models.Booking{UserID: alice.ID, ShowID: show.ID, TicketCount: 1, Status: "confirmed"}
+	db.Create(&booking)
+
+	transfer, token, err := service.CreateTransfer(booking, alice, "bob@example.com")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, service.CancelTransfer(transfer.ID, models.User{}), ErrTransferInvalid)
+	assert.NoError(t, service.CancelTransfer(transfer.ID, alice))
+	_, err = service.GetTransferByToken(token)
+	assert.ErrorIs(t, err, ErrTransferInvalid)
+
+	db.Model(&show).Update("transfer_cutoff_hours", 3)
+	_, _, err = service.CreateTransfer(booking, alice, "bob@example.com")
+	assert.ErrorIs(t, err, ErrTransferTooLate)
+}
+
+func TestAcceptTransferCancelledMeanwhile(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	show := models.Show{Title: "Gift", Venue: "Rennes", StartsAt: time.Now().AddDate(0, 0, 10)}
+	db.Create(&show)
+	alice := models.User{Username: "alice", Email: "alice@example.com"}
+	bob := models.User{Username: "bob", Email: "bob@example.com"}
+	db.Create(&alice)
+	db.Create(&bob)
+	booking := models.Booking{UserID: alice.ID, ShowID: show.ID, TicketCount: 1, Status: "confirmed"}
+	db.Create(&booking)
+	_, token, err := service.CreateTransfer(booking, alice, "bob@example.com")
+	assert.NoError(t, err)
+
+	// the booking is cancelled after it was checked, while the invitation is accepted
+	db.Callback().Update().Before("gorm:update").Register("test:cancel_booking", func(tx *gorm.DB) {
+		if tx.Statement.Table == "transfers" {
+			tx.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Model(&models.Booking{}).
+				Where("id = ?", booking.ID).Update("status", "cancelled")
+		}
+	})
+	_, err = service.AcceptTransfer(token, bob)
+	assert.ErrorIs(t, err, ErrTransferInvalid)
+	db.Callback().Update().Remove("test:cancel_booking")
+
+	var stored models.Booking
+	db.First(&stored, booking.ID)
+	assert.Equal(t, alice.ID, stored.UserID)
+}
@@ -0,0 +1,295 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrVenueCapacity = errors.New("the seats of the show exceed the capacity of the venue")
+	ErrVenueInUse    = errors.New("the venue still has shows")
+	ErrVenueExists   = errors.New("a venue with this name already exists in this city")
+)
+
+// venueArticles are left out of the venue keys, "L'Olympia" is the Olympia
+var venueArticles = []string{"l'", "le ", "la ", "les ", "the ", "el ", "il ", "de ", "het "}
+
+// VenueKey folds a venue or city name for comparison: without case, accents, leading
+// article and punctuation, "L'Olympia" and "olympia" both give "olympia".
+func VenueKey(name string) string {
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn))), name)
+	if err != nil {
+		folded = name
+	}
+	folded = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(folded, "’", "'")))
+	for _, article := range venueArticles {
+		if rest, ok := strings.CutPrefix(folded, article); ok && strings.TrimSpace(rest) != "" {
+			folded = rest
+			break
+		}
+	}
+	var key strings.Builder
+	for _, r := range folded {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			key.WriteRune(r)
+		}
+	}
+	return key.String()
+}
+
+// findVenue looks a venue up by name in city. Venues of the same name in other cities
+// don't match, an empty city only matches a venue without a city.
+func findVenue(tx *gorm.DB, name, city string) (models.Venue, bool, error) {
+	var venues []models.Venue
+	if err := tx.Where("name_key = ?", VenueKey(name)).Order("id").Find(&venues).Error; err != nil {
+		return models.Venue{}, false, err
+	}
+	cityKey := VenueKey(city)
+	for _, venue := range venues {
+		if VenueKey(venue.City) == cityKey {
+			return venue, true, nil
+		}
+	}
+	return models.Venue{}, false, nil
+}
+
+// resolveVenue returns the venue of a name typed by hand in city, created when it is new.
+func resolveVenue(tx *gorm.DB, name, city, country string) (models.Venue, error) {
+	venue, found, err := findVenue(tx, name, city)
+	if err != nil {
+		return models.Venue{}, err
+	}
+	if !found {
+		venue = models.Venue{
+			Name:    strings.TrimSpace(name),
+			NameKey: VenueKey(name),
+			City:    city,
+			Country: strings.ToUpper(country),
+		}
+		err = tx.Create(&venue).Error
+	}
+	return venue, err
+}
+
+// linkVenue copies the venue to the fields of show it backs
+func linkVenue(show *models.Show, venue models.Venue) {
+	show.VenueID = &venue.ID
+	show.Location = &venue
+	show.Venue = venue.Name
+	if venue.City != "" {
+		show.City = venue.City
+	}
+	if venue.Country != "" {
+		show.Country = venue.Country
+	}
+}
+
+// ApplyVenue links show to its venue before it is saved. A show without VenueID gets the
+// venue of its Venue name, created when it is new. The seats can't exceed the capacity.
+func (s Service) ApplyVenue(show *models.Show) error {
+	var venue models.Venue
+	if show.VenueID != nil {
+		if err := s.Db.First(&venue, *show.VenueID).Error; err != nil {
+			return err
+		}
+	} else {
+		if strings.TrimSpace(show.Venue) == "" {
+			return nil
+		}
+		var err error
+		if venue, err = resolveVenue(s.Db, show.Venue, show.City, show.Country); err != nil {
+			return err
+		}
+	}
+	if venue.Capacity > 0 && show.TotalSeats > venue.Capacity {
+		return ErrVenueCapacity
+	}
+	linkVenue(show, venue)
+	return nil
+}
+
+// ListVenues lists the venues by name, of a city when city is not empty.
+func (s Service) ListVenues(city string) ([]models.Venue, error) {
+	query := s.Db.Order("name, id")
+	if city != "" {
+		query = query.Where("LOWER(city) = ?", strings.ToLower(city))
+	}
+	var venues []models.Venue
+	if err := query.Find(&venues).Error; err != nil {
+		return nil, err
+	}
+	return venues, nil
+}
+
+func (s Service) GetVenue(id uint) (models.Venue, error) {
+	var venue models.Venue
+	if err := s.Db.First(&venue, id).Error; err != nil {
+		return models.Venue{}, err
+	}
+	return venue, nil
+}
+
+// checkVenue validates venue and checks no other venue has its name in its city
+func (s Service) checkVenue(venue *models.Venue) error {
+	venue.Name = strings.TrimSpace(venue.Name)
+	venue.Country = strings.ToUpper(venue.Country)
+	venue.NameKey = VenueKey(venue.Name)
+	if err := venue.Validate(); err != nil {
+		return err
+	}
+	var others []models.Venue
+	if err := s.Db.Where("name_key = ? AND id <> ?", venue.NameKey, venue.ID).Find(&others).Error; err != nil {
+		return err
+	}
+	for _, other := range others {
+		if VenueKey(other.City) == VenueKey(venue.City) {
+			return ErrVenueExists
+		}
+	}
+	return nil
+}
+
+func (s Service) CreateVenue(venue models.Venue) (models.Venue, error) {
+	venue.ID = 0
+	if err := s.checkVenue(&venue); err != nil {
+		return models.Venue{}, err
+	}
+	if err := s.Db.Create(&venue).Error; err != nil {
+		return models.Venue{}, err
+	}
+	return venue, nil
+}
+
+// UpdateVenue saves venue and copies its name, city, country and timezone to its shows.
+// The capacity can't go below the seats of a show to come.
+func (s Service) UpdateVenue(venue models.Venue, now time.Time) (models.Venue, error) {
+	if err := s.checkVenue(&venue); err != nil {
+		return models.Venue{}, err
+	}
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		if venue.Capacity > 0 {
+			var over int64
+			if err := tx.Model(&models.Show{}).
+				Where("venue_id = ? AND starts_at >= ? AND total_seats > ?", venue.ID, now, venue.Capacity).
+				Count(&over).Error; err != nil {
+				return err
+			}
+			if over > 0 {
+				return ErrVenueCapacity
+			}
+		}
+		if err := tx.Save(&venue).Error; err != nil {
+			return err
+		}
+		updates := map[string]any{"venue": venue.Name}
+		if venue.City != "" {
+			updates["city"] = venue.City
+		}
+		if venue.Country != "" {
+			updates["country"] = venue.Country
+		}
+		if err := tx.Model(&models.Show{}).Where("venue_id = ?", venue.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if venue.Timezone == "" {
+			return nil
+		}
+		return moveShowsTimezone(tx, venue.ID, venue.Timezone)
+	})
+	if err != nil {
+		return models.Venue{}, err
+	}
+	return venue, nil
+}
+
+// moveShowsTimezone gives the shows of a venue the zone. A show keeps the clock time it
+// was announced at, its instants move with the zone.
+func moveShowsTimezone(tx *gorm.DB, venueID uint, zone string) error {
+	var shows []models.Show
+	if err := tx.Where("venue_id = ? AND timezone <> ?", venueID, zone).Find(&shows).Error; err != nil {
+		return err
+	}
+	loc := models.LoadLocation(zone)
+	move := func(show models.Show, t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		wall := show.In(*t)
+		moved := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), loc).UTC()
+		return &moved
+	}
+	for _, show := range shows {
+		if err := tx.Model(&models.Show{}).Where("id = ?", show.ID).UpdateColumns(map[string]any{
+			"timezone":  zone,
+			"starts_at": *move(show, &show.StartsAt),
+			"doors_at":  move(show, show.DoorsAt),
+			"ends_at":   move(show, show.EndsAt),
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteVenue deletes a venue no show is played at.
+func (s Service) DeleteVenue(id uint) error {
+	var shows int64
+	if err := s.Db.Model(&models.Show{}).Where("venue_id = ?", id).Count(&shows).Error; err != nil {
+		return err
+	}
+	if shows > 0 {
+		return ErrVenueInUse
+	}
+	result := s.Db.Delete(&models.Venue{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// LinkShowVenues links the shows created before the venues to the venue of their venue
+// name, the spellings of one name share a venue, e.g. "Olympia" and "L'Olympia". The
+// first spelling met names the venue. It only touches unlinked shows and runs on every start.
+func LinkShowVenues(db *gorm.DB) (int, error) {
+	var shows []models.Show
+	if err := db.Unscoped().Where("venue_id IS NULL AND venue <> ''").Order("id").Find(&shows).Error; err != nil {
+		return 0, err
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, show := range shows {
+			venue, err := resolveVenue(tx, show.Venue, show.City, show.Country)
+			if err != nil {
+				return err
+			}
+			linkVenue(&show, venue)
+			if err := tx.Model(&models.Show{}).Unscoped().Where("id = ?", show.ID).UpdateColumns(map[string]any{
+				"venue_id": venue.ID,
+				"venue":    show.Venue,
+				"city":     show.City,
+				"country":  show.Country,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(shows) > 0 {
+		log.Printf("Linked %d shows to their venue", len(shows))
+	}
+	return len(shows), nil
+}
@@ -0,0 +1,128 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVenueKey(t *testing.T) {
+	assert.Equal(t, "olympia", VenueKey("L'Olympia"))
+	assert.Equal(t, "olympia", VenueKey("  l’olympia "))
+	assert.Equal(t, "olympia", VenueKey("OLYMPIA"))
+	assert.Equal(t, "bataclan", VenueKey("Le Bataclan"))
+	assert.Equal(t, "zenithdenantes", VenueKey("Zénith de Nantes"))
+	assert.Equal(t, "the", VenueKey("The"))
+}
+
+func TestLinkShowVenues(t *testing.T) {
+	db := SetupTestDB()
+	date := time.Now().AddDate(0, 1, 0)
+	shows := []models.Show{
+		{Title: "A", Venue: "Olympia", StartsAt: date, TotalSeats: 10},
+		{Title: "B", Venue: "L'Olympia", City: "Paris", Country: "fr", StartsAt: date, TotalSeats: 10},
+		{Title: "C", Venue: "olympia ", City: "paris", StartsAt: date, TotalSeats: 10},
+		{Title: "D", Venue: "Le Bataclan", City: "Paris", StartsAt: date, TotalSeats: 10},
+		{Title: "E", Venue: "Olympia", City: "Dublin", StartsAt: date, TotalSeats: 10},
+	}
+	db.Create(&shows)
+
+	n, err := LinkShowVenues(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+
+	// a show without a city can't tell which Olympia it plays, it gets its own venue
+	var venues []models.Venue
+	db.Order("id").Find(&venues)
+	assert.Len(t, venues, 4)
+	assert.Equal(t, "Olympia", venues[0].Name)
+	assert.Empty(t, venues[0].City)
+	assert.Equal(t, "L'Olympia", venues[1].Name)
+	assert.Equal(t, "Paris", venues[1].City)
+	assert.Equal(t, "FR", venues[1].Country)
+	assert.Equal(t, "Dublin", venues[3].City)
+
+	var linked []models.Show
+	db.Order("id").Find(&linked)
+	assert.Equal(t, venues[0].ID, *linked[0].VenueID)
+	for _, i := range []int{1, 2} {
+		assert.Equal(t, venues[1].ID, *linked[i].VenueID)
+		assert.Equal(t, "L'Olympia", linked[i].Venue)
+	}
+	assert.Equal(t, venues[2].ID, *linked[3].VenueID)
+	assert.Equal(t, venues[3].ID, *linked[4].VenueID)
+
+	// linked shows are left alone
+	n, err = LinkShowVenues(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestVenueCapacity(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	venue, err := service.CreateVenue(models.Venue{Name: "La Cigale", City: "Paris", Country: "fr", Capacity: 100, Timezone: "Europe/Paris"})
+	assert.NoError(t, err)
+	assert.Equal(t, "FR", venue.Country)
+
+	_, err = service.CreateVenue(models.Venue{Name: "Cigale", City: "paris"})
+	assert.ErrorIs(t, err, ErrVenueExists)
+	_, err = service.CreateVenue(models.Venue{Name: "Cigale", Timezone: "Paris"})
+	assert.Error(t, err)
+
+	show := models.Show{Title: "Big", StartsAt: time.Now().AddDate(0, 1, 0), VenueID: &venue.ID, TotalSeats: 120}
+	assert.ErrorIs(t, service.ApplyVenue(&show), ErrVenueCapacity)
+
+	show.TotalSeats = 80
+	assert.NoError(t, service.ApplyVenue(&show))
+	assert.Equal(t, "La Cigale", show.Venue)
+	assert.Equal(t, "Paris", show.City)
+	show, err = service.SetShow(show)
+	assert.NoError(t, err)
+	assert.Equal(t, "Europe/Paris", show.Location.Timezone)
+
+	// a show typed by hand finds the venue of its city
+	typed := models.Show{Title: "Typed", Venue: "cigale", City: "Paris", StartsAt: time.Now().AddDate(0, 1, 0), TotalSeats: 50}
+	assert.NoError(t, service.ApplyVenue(&typed))
+	assert.Equal(t, venue.ID, *typed.VenueID)
+	elsewhere := models.Show{Title: "Elsewhere", Venue: "cigale", StartsAt: time.Now().AddDate(0, 1, 0), TotalSeats: 50}
+	assert.NoError(t, service.ApplyVenue(&elsewhere))
+	assert.NotEqual(t, venue.ID, *elsewhere.VenueID)
+
+	venue.Capacity = 60
+	_, err = service.UpdateVenue(venue, time.Now())
+	assert.ErrorIs(t, err, ErrVenueCapacity)
+
+	venue.Capacity = 90
+	venue.Name = "La Cigale Paris"
+	_, err = service.UpdateVenue(venue, time.Now())
+	assert.NoError(t, err)
+	got, _ := service.GetShowByID(show.ID)
+	assert.Equal(t, "La Cigale Paris", got.Venue)
+
+	assert.ErrorIs(t, service.DeleteVenue(venue.ID), ErrVenueInUse)
+	service.DeleteShow(show.ID)
+	assert.NoError(t, service.DeleteVenue(venue.ID))
+}
+
+func TestUpdateVenueTimezone(t *testing.T) {
+	service := NewConcert(SetupTestDB())
+	venue, err := service.CreateVenue(models.Venue{Name: "Forum", City: "Montreal", Timezone: "Europe/Paris"})
+	assert.NoError(t, err)
+	show := models.Show{Title: "Night", VenueID: &venue.ID, TotalSeats: 10}
+	assert.NoError(t, service.ApplyVenue(&show))
+	assert.NoError(t, SetShowTimes(&show, ShowTimesInput{Date: "2030-06-01", Time: "20:00"}))
+	show, err = service.SetShow(show)
+	assert.NoError(t, err)
+	assert.Equal(t, 18, show.StartsAt.Hour())
+
+	// the zone was wrong, the show still starts at 20:00 in the right one
+	venue.Timezone = "America/Toronto"
+	_, err = service.UpdateVenue(venue, time.Now())
+	assert.NoError(t, err)
+	got, _ := service.GetShowByID(show.ID)
+	assert.Equal(t, "America/Toronto", got.Timezone)
+	assert.Equal(t, 20, got.Local.StartsAt.Hour())
+	assert.Equal(t, 0, got.StartsAt.Hour())
+}
@@ -0,0 +1,259 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"concert/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	QueueWaiting  = "waiting"
+	QueueAdmitted = "admitted"
+	QueueExpired  = "expired"
+	QueueUsed     = "used"
+
+	// how long an admitted client has to complete its booking
+	QueueAdmissionWindow  = 10 * time.Minute
+	defaultAdmitPerMinute = 60
+	queueJoinAttempts     = 10
+)
+
+var (
+	ErrQueueDisabled       = errors.New("this show has no waiting room")
+	ErrQueueTicketRequired = errors.New("a waiting room ticket is required to book this show")
+	ErrQueueTicketInvalid  = errors.New("invalid waiting room ticket")
+	ErrQueueNotAdmitted    = errors.New("you have not been admitted from the waiting room yet")
+	ErrQueueTicketExpired  = errors.New("your waiting room admission has expired")
+	ErrQueueTicketUsed     = errors.New("this waiting room ticket has already been used")
+)
+
+type QueueStatus struct {
+	Ticket               string     `json:"ticket"`
+	ShowID               uint       `json:"showId"`
+	Status               string     `json:"status"`
+	Position             int        `json:"position"`
+	EstimatedWaitSeconds int        `json:"estimatedWaitSeconds"`
+	AdmittedUntil        *time.Time `json:"admittedUntil,omitempty"`
+}
+
+func queueToken(ticket models.QueueTicket) string {
+	return utils.Sign(fmt.Sprintf("q:%d:%d:%d", ticket.ID, ticket.ShowID, ticket.Position))
+}
+
+func parseQueueToken(token string) (uint, error) {
+	payload, err := utils.Verify(token)
+	if err != nil {
+		return 0, ErrQueueTicketInvalid
+	}
+	parts := strings.Split(payload, ":")
+	if len(parts) != 4 || parts[0] != "q" {
+		return 0, ErrQueueTicketInvalid
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, ErrQueueTicketInvalid
+	}
+	return uint(id), nil
+}
+
+func admitRate(show models.Show) int {
+	if show.QueueAdmitPerMinute > 0 {
+		return show.QueueAdmitPerMinute
+	}
+	return defaultAdmitPerMinute
+}
+
+// JoinQueue hands out the next position in the waiting room of a show to a user, or the
+// one they already hold.
+func (s Service) JoinQueue(showID, userID uint) (QueueStatus, error) {
+	var show models.Show
+	if err := s.Db.First(&show, showID).Error; err != nil {
+		return QueueStatus{}, err
+	}
+	// the public doesn't know of the shows not published yet
+	if !show.Listed(time.Now()) {
+		return QueueStatus{}, gorm.ErrRecordNotFound
+	}
+	if !show.QueueEnabled {
+		return QueueStatus{}, ErrQueueDisabled
+	}
+
+	// clients joining together may take the same position, the unique index rejects all
+	// but one and the others retry with the next one
+	var ticket models.QueueTicket
+	var err error
+	for attempt := 0; attempt < queueJoinAttempts; attempt++ {
+		err = s.Db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Where("show_id = ? AND user_id = ?", showID, userID).First(&ticket).Error
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			var last int
+			if err := tx.Model(&models.QueueTicket{}).
+				Where("show_id = ?", showID).
+				Select("COALESCE(MAX(position), 0)").
+				Scan(&last).Error; err != nil {
+				return err
+			}
+			ticket = models.QueueTicket{ShowID: showID, UserID: userID, Position: last + 1}
+			return tx.Create(&ticket).Error
+		})
+		if err == nil {
+			return s.queueStatus(ticket, show, time.Now())
+		}
+		if !s.isDuplicatedKey(err) {
+			break
+		}
+	}
+	return QueueStatus{}, err
+}
+
+// isDuplicatedKey tells whether err is a violation of a unique index
+func (s Service) isDuplicatedKey(err error) bool {
+	if translator, ok := s.Db.Dialector.(gorm.ErrorTranslator); ok {
+		err = translator.Translate(err)
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
+
+// GetQueueStatus is what waiting clients poll with their signed ticket.
+func (s Service) GetQueueStatus(token string) (QueueStatus, error) {
+	id, err := parseQueueToken(token)
+	if err != nil {
+		return QueueStatus{}, err
+	}
+	var ticket models.QueueTicket
+	if err := s.Db.First(&ticket, id).Error; err != nil {
+		return QueueStatus{}, ErrQueueTicketInvalid
+	}
+	var show models.Show
+	if err := s.Db.First(&show, ticket.ShowID).Error; err != nil {
+		return QueueStatus{}, err
+	}
+	return s.queueStatus(ticket, show, time.Now())
+}
+
+func (s Service) queueStatus(ticket models.QueueTicket, show models.Show, now time.Time) (QueueStatus, error) {
+	status := QueueStatus{
+		Ticket: queueToken(ticket),
+		ShowID: ticket.ShowID,
+	}
+	switch {
+	case ticket.UsedAt != nil:
+		status.Status = QueueUsed
+	case ticket.AdmittedAt != nil && ticket.ExpiresAt.Before(now):
+		status.Status = QueueExpired
+	case ticket.AdmittedAt != nil:
+		status.Status = QueueAdmitted
+		status.AdmittedUntil = ticket.ExpiresAt
+	default:
+		var ahead int64
+		if err := s.Db.Model(&models.QueueTicket{}).
+			Where("show_id = ? AND position < ? AND admitted_at IS NULL", ticket.ShowID, ticket.Position).
+			Count(&ahead).Error; err != nil {
+			return QueueStatus{}, err
+		}
+		status.Status = QueueWaiting
+		status.Position = int(ahead) + 1
+		status.EstimatedWaitSeconds = int(math.Ceil(float64(status.Position) / float64(admitRate(show)) * 60))
+	}
+	return status, nil
+}
+
+// AdmitFromQueues lets the next clients of every waiting room in, at the rate configured on the show.
+// Every server runs it, the one that moves QueueAdmittedAt of a show admits for the time
+// elapsed since, the others skip it until interval has passed.
+func (s Service) AdmitFromQueues(now time.Time, interval time.Duration) error {
+	var shows []models.Show
+	if err := s.Db.Where("queue_enabled = ?", true).Find(&shows).Error; err != nil {
+		return err
+	}
+	for _, show := range shows {
+		elapsed := interval
+		claim := s.Db.Model(&models.Show{}).Where("id = ? AND queue_admitted_at IS NULL", show.ID)
+		if show.QueueAdmittedAt != nil {
+			elapsed = min(now.Sub(*show.QueueAdmittedAt), 2*interval)
+			claim = s.Db.Model(&models.Show{}).
+				Where("id = ? AND queue_admitted_at = ? AND queue_admitted_at <= ?", show.ID, *show.QueueAdmittedAt, now.Add(-interval))
+		}
+		result := claim.UpdateColumn("queue_admitted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		count := int(math.Ceil(float64(admitRate(show)) * elapsed.Minutes()))
+		var ids []uint
+		if err := s.Db.Model(&models.QueueTicket{}).
+			Where("show_id = ? AND admitted_at IS NULL", show.ID).
+			Order("position").
+			Limit(count).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		if err := s.Db.Model(&models.QueueTicket{}).
+			Where("id IN ?", ids).
+			Updates(map[string]any{"admitted_at": now, "expires_at": now.Add(QueueAdmissionWindow)}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s Service) RunQueueAdmitter(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.AdmitFromQueues(now, interval); err != nil {
+				log.Printf("Error admitting from waiting rooms: %v", err)
+			}
+		}
+	}
+}
+
+// CheckQueueAdmission returns the queue ticket allowing a booking for the show by the
+// user who holds it. Shows without a waiting room need no ticket and get a zero
+// QueueTicket back.
+func (s Service) CheckQueueAdmission(show models.Show, userID uint, token string) (models.QueueTicket, error) {
+	if !show.QueueEnabled {
+		return models.QueueTicket{}, nil
+	}
+	if token == "" {
+		return models.QueueTicket{}, ErrQueueTicketRequired
+	}
+	id, err := parseQueueToken(token)
+	if err != nil {
+		return models.QueueTicket{}, err
+	}
+	var ticket models.QueueTicket
+	if err := s.Db.First(&ticket, id).Error; err != nil || ticket.ShowID != show.ID || ticket.UserID != userID {
+		return models.QueueTicket{}, ErrQueueTicketInvalid
+	}
+	switch {
+	case ticket.UsedAt != nil:
+		return ticket, ErrQueueTicketUsed
+	case ticket.AdmittedAt == nil:
+		return ticket, ErrQueueNotAdmitted
+	case ticket.ExpiresAt.Before(time.Now()):
+		return ticket, ErrQueueTicketExpired
+	}
+	return ticket, nil
+}
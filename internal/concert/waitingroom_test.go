@@ -0,0 +1,84 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestWaitingRoomAdmission(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	show := models.Show{Title: "On sale rush", Venue: "Paris", StartsAt: time.Now(), QueueEnabled: true, QueueAdmitPerMinute: 12}
+	db.Create(&show)
+
+	first, err := service.JoinQueue(show.ID, 1)
+	assert.NoError(t, err)
+	second, err := service.JoinQueue(show.ID, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, QueueWaiting, second.Status)
+	assert.Equal(t, 2, second.Position)
+	assert.Equal(t, 10, second.EstimatedWaitSeconds)
+	// a user holds one place
+	again, err := service.JoinQueue(show.ID, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, second.Ticket, again.Ticket)
+
+	_, err = service.CheckQueueAdmission(show, 1, "")
+	assert.ErrorIs(t, err, ErrQueueTicketRequired)
+	_, err = service.CheckQueueAdmission(show, 1, first.Ticket)
+	assert.ErrorIs(t, err, ErrQueueNotAdmitted)
+
+	// 12 per minute over 5 seconds lets one client in
+	now := time.Now()
+	assert.NoError(t, service.AdmitFromQueues(now, 5*time.Second))
+	// another server polling at the same time lets nobody else in
+	assert.NoError(t, service.AdmitFromQueues(now.Add(time.Second), 5*time.Second))
+
+	status, err := service.GetQueueStatus(first.Ticket)
+	assert.NoError(t, err)
+	assert.Equal(t, QueueAdmitted, status.Status)
+	status, _ = service.GetQueueStatus(second.Ticket)
+	assert.Equal(t, 1, status.Position)
+
+	ticket, err := service.CheckQueueAdmission(show, 1, first.Ticket)
+	assert.NoError(t, err)
+	assert.NotZero(t, ticket.ID)
+	// the place is not handed to someone else
+	_, err = service.CheckQueueAdmission(show, 2, first.Ticket)
+	assert.ErrorIs(t, err, ErrQueueTicketInvalid)
+	_, err = service.CheckQueueAdmission(show, 2, second.Ticket)
+	assert.ErrorIs(t, err, ErrQueueNotAdmitted)
+
+	assert.NoError(t, service.AdmitFromQueues(now.Add(5*time.Second), 5*time.Second))
+	status, _ = service.GetQueueStatus(second.Ticket)
+	assert.Equal(t, QueueAdmitted, status.Status)
+}
+
+func TestWaitingRoomRejectsForgedTicket(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	show := models.Show{Title: "On sale rush", Venue: "Paris", StartsAt: time.Now(), QueueEnabled: true}
+	db.Create(&show)
+	status, _ := service.JoinQueue(show.ID, 1)
+
+	_, err := service.GetQueueStatus(status.Ticket + "x")
+	assert.ErrorIs(t, err, ErrQueueTicketInvalid)
+
+	open := models.Show{Title: "Quiet night", Venue: "Lyon", StartsAt: time.Now()}
+	db.Create(&open)
+	_, err = service.JoinQueue(open.ID, 1)
+	assert.ErrorIs(t, err, ErrQueueDisabled)
+	_, err = service.CheckQueueAdmission(open, 1, "")
+	assert.NoError(t, err)
+	// a show not published yet has no waiting room to join
+	draft := models.Show{Title: "Secret", Venue: "Lyon", StartsAt: time.Now(), QueueEnabled: true, Status: models.ShowDraft}
+	db.Create(&draft)
+	_, err = service.JoinQueue(draft.ID, 1)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
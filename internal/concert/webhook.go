@@ -0,0 +1,388 @@
+package concert
+
+import (
+	"bytes"
+	"concert/internal/models"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	WebhookSignatureHeader = "X-Concert-Signature"
+	WebhookEventHeader     = "X-Concert-Event"
+	WebhookDeliveryHeader  = "X-Concert-Delivery"
+
+	// after this many failed attempts a delivery goes to the dead-letter list
+	WebhookMaxAttempts = 6
+	// webhookDeliveryLease is how long an attempt keeps its delivery from the other
+	// servers, longer than the request may take
+	webhookDeliveryLease = time.Minute
+)
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+var (
+	ErrWebhookDeleted = errors.New("the webhook subscription was deleted")
+	ErrWebhookNotDead = errors.New("only a dead delivery can be retried")
+)
+
+type WebhookEnvelope struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+func NewWebhookSecret() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+// SignWebhookPayload returns the value of the signature header, in the form t=<unix>,v1=<hex hmac>.
+// The HMAC-SHA256 is computed over "<unix>.<body>" with the subscription secret.
+func SignWebhookPayload(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature is what a receiver runs to check a payload came from us.
+func VerifyWebhookSignature(secret, header string, body []byte, tolerance time.Duration) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return errors.New("malformed signature header")
+	}
+	signedAt := time.Unix(unix, 0)
+	if tolerance > 0 && time.Since(signedAt).Abs() > tolerance {
+		return errors.New("signature timestamp outside tolerance")
+	}
+	expected := SignWebhookPayload(secret, signedAt, body)
+	if !hmac.Equal([]byte(expected), []byte("t="+ts+",v1="+sig)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// webhookBackoff gives the wait before the next attempt: 30s, 2m, 8m, 32m, ... capped at 6h.
+func webhookBackoff(attempt int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempt; i++ {
+		delay *= 4
+		if delay >= 6*time.Hour {
+			return 6 * time.Hour
+		}
+	}
+	return delay
+}
+
+func (s Service) ListWebhooks() ([]models.WebhookSubscription, error) {
+	var subs []models.WebhookSubscription
+	if err := s.Db.Order("id").Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (s Service) GetWebhookByID(id uint) (models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	if err := s.Db.First(&sub, id).Error; err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	return sub, nil
+}
+
+func (s Service) SetWebhook(sub models.WebhookSubscription) (models.WebhookSubscription, error) {
+	if sub.Secret == "" {
+		sub.Secret = NewWebhookSecret()
+	}
+	// the column default wins over a false Active on create, so write it explicitly
+	active := sub.Active
+	if err := s.Db.Save(&sub).Error; err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	if err := s.Db.Model(&sub).Update("active", active).Error; err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	return sub, nil
+}
+
+// DeleteWebhook deletes a subscription and cancels its pending deliveries.
+func (s Service) DeleteWebhook(id uint) error {
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.WebhookSubscription{}, id).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("subscription_id = ? AND status = ?", id, models.DeliveryPending).
+			Updates(map[string]any{"status": models.DeliveryCancelled, "last_error": "the subscription was deleted"}).Error
+	})
+}
+
+// PublishWebhookEvent queues one delivery per active subscription listening to event.
+// Deliveries are sent by the webhook worker, so this never blocks on partners.
+func (s Service) PublishWebhookEvent(event string, data any) error {
+	var subs []models.WebhookSubscription
+	if err := s.Db.Where("active = ?", true).Find(&subs).Error; err != nil {
+		return err
+	}
+	envelope := WebhookEnvelope{
+		ID:        uuid.NewString(),
+		Type:      event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		if !sub.Events.Contains(event) && !sub.Events.Contains("*") {
+			continue
+		}
+		delivery := models.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        envelope.ID,
+			Event:          event,
+			Payload:        string(payload),
+			Status:         models.DeliveryPending,
+			NextAttemptAt:  envelope.CreatedAt,
+		}
+		if err := s.Db.Create(&delivery).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeliverWebhook makes one HTTP attempt for the delivery and records the outcome.
+// Failed attempts are rescheduled with exponential back-off until WebhookMaxAttempts.
+// The delivery is cancelled when its subscription was deleted.
+func (s Service) DeliverWebhook(delivery *models.WebhookDelivery) error {
+	sub := delivery.Subscription
+	if sub.ID == 0 {
+		err := s.Db.First(&sub, delivery.SubscriptionID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			delivery.Status = models.DeliveryCancelled
+			delivery.LastError = "the subscription was deleted"
+			if err := s.Db.Omit("Subscription", "Logs").Save(delivery).Error; err != nil {
+				return err
+			}
+			return ErrWebhookDeleted
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+	statusCode, respBody, sendErr := sendWebhook(sub, delivery, now)
+	attempt := models.WebhookAttempt{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts,
+		StatusCode: statusCode,
+		DurationMs: time.Since(now).Milliseconds(),
+		Response:   respBody,
+	}
+	delivery.LastStatusCode = statusCode
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+		delivery.LastError = sendErr.Error()
+		if delivery.Attempts >= WebhookMaxAttempts {
+			delivery.Status = models.DeliveryDead
+		} else {
+			delivery.Status = models.DeliveryPending
+			delivery.NextAttemptAt = now.Add(webhookBackoff(delivery.Attempts))
+		}
+	} else {
+		delivery.Status = models.DeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	}
+
+	if err := s.Db.Create(&attempt).Error; err != nil {
+		return err
+	}
+	if err := s.Db.Omit("Subscription", "Logs").Save(delivery).Error; err != nil {
+		return err
+	}
+	return sendErr
+}
+
+func sendWebhook(sub models.WebhookSubscription, delivery *models.WebhookDelivery, now time.Time) (int, string, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "concert-webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.EventID)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(sub.Secret, now, body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(respBody), fmt.Errorf("receiver answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(respBody), nil
+}
+
+// DeliverDueWebhooks sends every pending delivery whose next attempt time has passed.
+func (s Service) DeliverDueWebhooks(now time.Time) (int, error) {
+	var due []models.WebhookDelivery
+	if err := s.Db.
+		Preload("Subscription").
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("next_attempt_at").
+		Limit(100).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+	sent := 0
+	for i := range due {
+		// each server polls, the one that moves the next attempt forward makes it
+		claim := s.Db.Model(&models.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at <= ?", due[i].ID, models.DeliveryPending, now).
+			Update("next_attempt_at", now.Add(webhookDeliveryLease))
+		if claim.Error != nil {
+			return sent, claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+		sent++
+		if err := s.DeliverWebhook(&due[i]); err != nil {
+			log.Printf("Webhook delivery %d to %s failed (attempt %d): %v", due[i].ID, due[i].Subscription.URL, due[i].Attempts, err)
+		}
+	}
+	return sent, nil
+}
+
+// RunWebhookWorker polls for due deliveries until ctx is cancelled.
+func (s Service) RunWebhookWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := s.DeliverDueWebhooks(now); err != nil {
+				log.Printf("Error delivering webhooks: %v", err)
+			}
+		}
+	}
+}
+
+func (s Service) ListWebhookDeliveries(subscriptionID uint) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	if err := s.Db.
+		Preload("Logs").
+		Where("subscription_id = ?", subscriptionID).
+		Order("created_at DESC").
+		Limit(200).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (s Service) ListDeadWebhookDeliveries() ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	if err := s.Db.
+		Preload("Logs").
+		Where("status = ?", models.DeliveryDead).
+		Order("updated_at DESC").
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RetryWebhookDelivery puts a dead delivery back in the queue with a fresh attempt budget.
+// Delivered and cancelled deliveries are not sent again.
+func (s Service) RetryWebhookDelivery(id uint) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := s.Db.First(&delivery, id).Error; err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	result := s.Db.Model(&delivery).Where("status = ?", models.DeliveryDead).Updates(map[string]any{
+		"status":          models.DeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	})
+	if result.Error != nil {
+		return models.WebhookDelivery{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.WebhookDelivery{}, ErrWebhookNotDead
+	}
+	return delivery, s.Db.First(&delivery, id).Error
+}
+
+// SendTestWebhook delivers a webhook.test event right away so admins can check their endpoint.
+func (s Service) SendTestWebhook(subscriptionID uint) (models.WebhookDelivery, error) {
+	sub, err := s.GetWebhookByID(subscriptionID)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	envelope := WebhookEnvelope{
+		ID:        uuid.NewString(),
+		Type:      models.EventWebhookTest,
+		CreatedAt: time.Now().UTC(),
+		Data:      map[string]any{"subscriptionId": sub.ID, "message": "This is a test event"},
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	delivery := models.WebhookDelivery{
+		SubscriptionID: sub.ID,
+		Subscription:   sub,
+		EventID:        envelope.ID,
+		Event:          envelope.Type,
+		Payload:        string(payload),
+		Status:         models.DeliveryPending,
+		// leased to this call, the poller leaves it alone
+		NextAttemptAt: envelope.CreatedAt.Add(webhookDeliveryLease),
+	}
+	if err := s.Db.Omit("Subscription").Create(&delivery).Error; err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	// a failed test is reported to the caller, it is retried like any other delivery
+	return delivery, s.DeliverWebhook(&delivery)
+}
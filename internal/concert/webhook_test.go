@@ -0,0 +1,151 @@
+package concert
+
+import (
+	"concert/internal/models"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishWebhookEventSignedDelivery(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	var received WebhookEnvelope
+	var verifyErr error
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = VerifyWebhookSignature("s3cret", r.Header.Get(WebhookSignatureHeader), body, time.Minute)
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	sub, err := service.SetWebhook(models.WebhookSubscription{
+		URL:    receiver.URL,
+		Events: models.StringList{models.EventBookingCreated},
+		Secret: "s3cret",
+		Active: true,
+	})
+	assert.NoError(t, err)
+
+	// not subscribed to this one
+	assert.NoError(t, service.PublishWebhookEvent(models.EventShowDeleted, map[string]uint{"id": 1}))
+	assert.NoError(t, service.PublishWebhookEvent(models.EventBookingCreated, models.Booking{TicketCount: 2}))
+
+	sent, err := service.DeliverDueWebhooks(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.NoError(t, verifyErr)
+	assert.Equal(t, models.EventBookingCreated, received.Type)
+
+	deliveries, err := service.ListWebhookDeliveries(sub.ID)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, models.DeliveryDelivered, deliveries[0].Status)
+	assert.Len(t, deliveries[0].Logs, 1)
+	assert.Equal(t, http.StatusNoContent, deliveries[0].Logs[0].StatusCode)
+}
+
+func TestWebhookRetriesThenDeadLetter(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	sub, _ := service.SetWebhook(models.WebhookSubscription{
+		URL:    receiver.URL,
+		Events: models.StringList{"*"},
+		Active: true,
+	})
+	assert.NotEmpty(t, sub.Secret)
+	assert.NoError(t, service.PublishWebhookEvent(models.EventShowCreated, models.Show{Title: "Live"}))
+
+	// first failure is retried after the back-off, not straight away
+	now := time.Now()
+	sent, _ := service.DeliverDueWebhooks(now)
+	assert.Equal(t, 1, sent)
+	sent, _ = service.DeliverDueWebhooks(now)
+	assert.Equal(t, 0, sent)
+
+	for i := 1; i < WebhookMaxAttempts; i++ {
+		now = now.Add(webhookBackoff(i) + time.Second)
+		sent, _ = service.DeliverDueWebhooks(now)
+		assert.Equal(t, 1, sent)
+	}
+	assert.Equal(t, int32(WebhookMaxAttempts), atomic.LoadInt32(&calls))
+
+	dead, err := service.ListDeadWebhookDeliveries()
+	assert.NoError(t, err)
+	assert.Len(t, dead, 1)
+	assert.Len(t, dead[0].Logs, WebhookMaxAttempts)
+	assert.Equal(t, http.StatusInternalServerError, dead[0].LastStatusCode)
+
+	retried, err := service.RetryWebhookDelivery(dead[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DeliveryPending, retried.Status)
+	assert.Equal(t, 0, retried.Attempts)
+	_, err = service.RetryWebhookDelivery(dead[0].ID)
+	assert.ErrorIs(t, err, ErrWebhookNotDead)
+}
+
+func TestSendTestWebhook(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	var event string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event = r.Header.Get(WebhookEventHeader)
+	}))
+	defer receiver.Close()
+
+	sub, _ := service.SetWebhook(models.WebhookSubscription{URL: receiver.URL, Events: models.StringList{models.EventShowCreated}})
+	delivery, err := service.SendTestWebhook(sub.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DeliveryDelivered, delivery.Status)
+	assert.Equal(t, models.EventWebhookTest, event)
+	// delivered once, by this call only
+	_, err = service.RetryWebhookDelivery(delivery.ID)
+	assert.ErrorIs(t, err, ErrWebhookNotDead)
+	sent, _ := service.DeliverDueWebhooks(time.Now())
+	assert.Equal(t, 0, sent)
+
+	// SetWebhook keeps an explicitly disabled subscription disabled
+	assert.False(t, sub.Active)
+}
+
+func TestDeleteWebhookCancelsDeliveries(t *testing.T) {
+	db := SetupTestDB()
+	service := NewConcert(db)
+
+	kept, _ := service.SetWebhook(models.WebhookSubscription{URL: "http://127.0.0.1:1", Events: models.StringList{"*"}, Active: true})
+	deleted, _ := service.SetWebhook(models.WebhookSubscription{URL: "http://127.0.0.1:1", Events: models.StringList{"*"}, Active: true})
+	assert.NoError(t, service.PublishWebhookEvent(models.EventShowCreated, models.Show{Title: "Live"}))
+
+	// a delivery picked up before the subscription was deleted is cancelled too
+	var racing models.WebhookDelivery
+	db.Where("subscription_id = ?", deleted.ID).First(&racing)
+	assert.NoError(t, service.DeleteWebhook(deleted.ID))
+	racing.Status = models.DeliveryPending
+	assert.ErrorIs(t, service.DeliverWebhook(&racing), ErrWebhookDeleted)
+
+	var cancelled int64
+	db.Model(&models.WebhookDelivery{}).Where("subscription_id = ? AND status = ?", deleted.ID, models.DeliveryCancelled).Count(&cancelled)
+	assert.Equal(t, int64(1), cancelled)
+	var pending []models.WebhookDelivery
+	db.Where("status = ?", models.DeliveryPending).Find(&pending)
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, kept.ID, pending[0].SubscriptionID)
+	}
+}
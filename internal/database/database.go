@@ -2,10 +2,12 @@ package database
 
 import (
 	"concert/internal/models"
+	"concert/internal/payment"
 	"concert/internal/utils"
 	"fmt"
 	"log"
 	"path/filepath"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
@@ -59,15 +61,87 @@ func DbSetup() (*gorm.DB, error) {
 }
 
 func Migrate(db *gorm.DB) error {
+	// the show date became its start instant, concert.MigrateShowTimes adds the time of day to it
+	if db.Migrator().HasTable(&models.Show{}) && db.Migrator().HasColumn(&models.Show{}, "date") {
+		if err := db.Migrator().RenameColumn(&models.Show{}, "date", "starts_at"); err != nil {
+			return fmt.Errorf("failed to rename the show date: %w", err)
+		}
+	}
+	// concurrent issues could create two tickets for a seat, all but the first are voided
+	// before the index preventing it is created
+	if db.Migrator().HasTable(&models.Ticket{}) && !db.Migrator().HasIndex(&models.Ticket{}, "idx_tickets_seat") {
+		if err := db.Exec(`UPDATE tickets SET status = 'void' WHERE status <> 'void' AND id NOT IN (
+			SELECT MIN(id) FROM tickets WHERE status <> 'void' GROUP BY booking_id, show_id, seq)`).Error; err != nil {
+			return fmt.Errorf("failed to void the duplicate tickets: %w", err)
+		}
+	}
+	// waiting room places became bound to a user, the anonymous ones can't be kept
+	if db.Migrator().HasTable(&models.QueueTicket{}) && !db.Migrator().HasColumn(&models.QueueTicket{}, "user_id") {
+		if err := db.Exec("DELETE FROM queue_tickets").Error; err != nil {
+			return fmt.Errorf("failed to clear the anonymous queue tickets: %w", err)
+		}
+	}
+	// the shows were announced once through the id of their alert workflow, those the
+	// public already saw are marked as announced
+	// the invoices printed the current holder of their booking, the purchaser is kept now
+	billed := db.Migrator().HasTable(&models.Invoice{}) && !db.Migrator().HasColumn(&models.Invoice{}, "billed_email")
+	alerted := db.Migrator().HasTable(&models.Show{}) && !db.Migrator().HasColumn(&models.Show{}, "alerted_at")
 	err := db.AutoMigrate(
 		&models.User{},
 		&models.Artist{},
 		&models.Show{},
-		&models.Booking{}, 
+		&models.Booking{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.WebhookAttempt{},
+		&models.QueueTicket{},
+		&models.Presale{},
+		&models.TicketLimitOverride{},
+		&models.Ticket{},
+		&models.ScanEvent{},
+		&models.Invoice{},
+		&models.Payment{},
+		&models.Transfer{},
+		&models.BookingAudit{},
+		&models.ResaleListing{},
+		&models.CreditEntry{},
+		&models.GiftCard{},
+		&models.LedgerAccount{},
+		&models.JournalEntry{},
+		&models.Posting{},
+		&models.Settlement{},
+		&models.TaxRate{},
+		&models.Venue{},
+		&models.LineupSlot{},
+		&models.Festival{},
+		&models.Tour{},
+		&models.Follow{},
+		&payment.MockIntent{},
+		&payment.MockRefund{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
+	if alerted {
+		if err := db.Model(&models.Show{}).
+			Where("status NOT IN ? OR (status = ? AND publish_at <= ?)", []string{models.ShowDraft, models.ShowScheduled}, models.ShowScheduled, time.Now()).
+			UpdateColumn("alerted_at", gorm.Expr("COALESCE(status_changed_at, created_at)")).Error; err != nil {
+			return fmt.Errorf("failed to mark the announced shows: %w", err)
+		}
+	}
+	if billed {
+		// the holder when the invoice was issued: the sender of the first transfer accepted since, or the holder now
+		purchaser := `COALESCE((SELECT transfers.from_user_id FROM transfers WHERE transfers.booking_id = invoices.booking_id
+			AND transfers.status = ? AND transfers.accepted_at > invoices.issued_at ORDER BY transfers.accepted_at LIMIT 1),
+			(SELECT bookings.user_id FROM bookings WHERE bookings.id = invoices.booking_id))`
+		if err := db.Exec(`UPDATE invoices SET
+			billed_name = COALESCE((SELECT username FROM users WHERE users.id = `+purchaser+`), ''),
+			billed_email = COALESCE((SELECT email FROM users WHERE users.id = `+purchaser+`), ''),
+			credit_applied = COALESCE((SELECT credit_applied FROM bookings WHERE bookings.id = invoices.booking_id), 0)`,
+			models.TransferAccepted, models.TransferAccepted).Error; err != nil {
+			return fmt.Errorf("failed to fill the purchasers of the invoices: %w", err)
+		}
+	}
 	log.Println("Database migration completed")
 	return nil
 }
@@ -1,11 +1,14 @@
 package http
 
 import (
+	"concert/internal/concert"
 	"concert/internal/models"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -15,12 +18,155 @@ type CreateShowRequest struct {
 	Title       string  `json:"title"`
 	Date        string  `json:"date"` 
 	Time        string  `json:"time"` 
+	StartsAt    string  `json:"startsAt"`
+	DoorsAt     string  `json:"doorsAt"`
+	EndsAt      string  `json:"endsAt"`
+	Timezone    string  `json:"timezone"`
 	ArtistID    uint    `json:"artistId"`
+	VenueID     *uint   `json:"venueId"`
 	Venue       string  `json:"venue"`
+	City        string  `json:"city"`
 	Price       float64 `json:"price"`
 	TotalSeats  int     `json:"totalSeats"`
 	Description string  `json:"description"`
 	ImageURL    string  `json:"imageUrl"`
+
+	// FestivalID makes the show a day of a festival, zero takes it out of its festival
+	FestivalID *uint `json:"festivalId"`
+
+	// Status is draft, scheduled or published, a publishAt to come schedules the show. Both
+	// are only read on creation, later the status changes through its own endpoint
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publishAt"`
+
+	OnSaleAt  *time.Time `json:"onSaleAt"`
+	OffSaleAt *time.Time `json:"offSaleAt"`
+
+	MaxTicketsPerUser        *int `json:"maxTicketsPerUser"`
+	MaxTicketsPerOrder       *int `json:"maxTicketsPerOrder"`
+	MaxTicketsPerEmailDomain *int `json:"maxTicketsPerEmailDomain"`
+	MaxTicketsPerInstrument  *int `json:"maxTicketsPerInstrument"`
+
+	QueueEnabled        *bool `json:"queueEnabled"`
+	QueueAdmitPerMinute int   `json:"queueAdmitPerMinute"`
+
+	CancellationPolicy *models.CancellationPolicy `json:"cancellationPolicy"`
+
+	TransfersDisabled      *bool `json:"transfersDisabled"`
+	MaxTransfersPerBooking *int  `json:"maxTransfersPerBooking"`
+	TransferCutoffHours    *int  `json:"transferCutoffHours"`
+
+	ResalePriceCap   *float64 `json:"resalePriceCap"`
+	ResaleFeePercent *int     `json:"resaleFeePercent"`
+
+	Country   *string  `json:"country"`
+	TicketFee *float64 `json:"ticketFee"`
+	OrderFee  *float64 `json:"orderFee"`
+}
+
+// showTimes are the times of the request, date and time are the older way to give the start
+func (req CreateShowRequest) showTimes() concert.ShowTimesInput {
+	return concert.ShowTimesInput{
+		StartsAt: req.StartsAt,
+		DoorsAt:  req.DoorsAt,
+		EndsAt:   req.EndsAt,
+		Date:     req.Date,
+		Time:     req.Time,
+		Timezone: req.Timezone,
+	}
+}
+
+// applyTicketLimits copies the limits present in the request, zero lifts a limit
+func (req CreateShowRequest) applyTicketLimits(show *models.Show) {
+	if req.MaxTicketsPerUser != nil {
+		show.MaxTicketsPerUser = max(*req.MaxTicketsPerUser, 0)
+	}
+	if req.MaxTicketsPerOrder != nil {
+		show.MaxTicketsPerOrder = max(*req.MaxTicketsPerOrder, 0)
+	}
+	if req.MaxTicketsPerEmailDomain != nil {
+		show.MaxTicketsPerEmailDomain = max(*req.MaxTicketsPerEmailDomain, 0)
+	}
+	if req.MaxTicketsPerInstrument != nil {
+		show.MaxTicketsPerInstrument = max(*req.MaxTicketsPerInstrument, 0)
+	}
+	if req.TransfersDisabled != nil {
+		show.TransfersDisabled = *req.TransfersDisabled
+	}
+	if req.MaxTransfersPerBooking != nil {
+		show.MaxTransfersPerBooking = max(*req.MaxTransfersPerBooking, 0)
+	}
+	if req.TransferCutoffHours != nil {
+		show.TransferCutoffHours = max(*req.TransferCutoffHours, 0)
+	}
+	if req.ResalePriceCap != nil {
+		show.ResalePriceCap = max(*req.ResalePriceCap, 0)
+	}
+	if req.ResaleFeePercent != nil {
+		show.ResaleFeePercent = min(max(*req.ResaleFeePercent, 0), 100)
+	}
+}
+
+// applyPricing copies the country and the fees present in the request
+func (req CreateShowRequest) applyPricing(show *models.Show) {
+	if req.Country != nil {
+		show.Country = strings.ToUpper(*req.Country)
+	}
+	if req.TicketFee != nil {
+		show.TicketFee = max(*req.TicketFee, 0)
+	}
+	if req.OrderFee != nil {
+		show.OrderFee = max(*req.OrderFee, 0)
+	}
+}
+
+// newShow is the show of a creation request, with the default seats and price. The
+// venue and the times are left to the caller.
+func (req CreateShowRequest) newShow() (models.Show, error) {
+	if req.TotalSeats == 0 {
+		req.TotalSeats = 100
+	}
+	if req.OnSaleAt != nil && req.OffSaleAt != nil && !req.OffSaleAt.After(*req.OnSaleAt) {
+		return models.Show{}, errors.New("offSaleAt must be after onSaleAt")
+	}
+	if req.Price == 0 {
+		req.Price = 50.0
+	}
+	if req.CancellationPolicy != nil {
+		if err := req.CancellationPolicy.Validate(); err != nil {
+			return models.Show{}, err
+		}
+	}
+	status, err := concert.InitialShowStatus(req.Status, req.PublishAt, time.Now())
+	if err != nil {
+		return models.Show{}, err
+	}
+
+	show := models.Show{
+		Title:          req.Title,
+		ArtistID:       req.ArtistID,
+		Venue:          req.Venue,
+		City:           req.City,
+		Price:          req.Price,
+		TotalSeats:     req.TotalSeats,
+		AvailableSeats: req.TotalSeats,
+		Description:    req.Description,
+		ImageURL:       req.ImageURL,
+		OnSaleAt:       req.OnSaleAt,
+		OffSaleAt:      req.OffSaleAt,
+
+		QueueEnabled:        req.QueueEnabled != nil && *req.QueueEnabled,
+		QueueAdmitPerMinute: req.QueueAdmitPerMinute,
+
+		Status:    status,
+		PublishAt: req.PublishAt,
+	}
+	req.applyTicketLimits(&show)
+	req.applyPricing(&show)
+	if req.CancellationPolicy != nil {
+		show.CancellationPolicy = *req.CancellationPolicy
+	}
+	return show, nil
 }
 
 type CreateArtistRequest struct {
@@ -28,6 +174,8 @@ type CreateArtistRequest struct {
 	Genre    string `json:"genre"`
 	Bio      string `json:"bio"`
 	ImageURL string `json:"imageUrl"`
+
+	Contract *models.ArtistContract `json:"contract"`
 }
 
 type AdminBookingResponse struct {
@@ -42,6 +190,9 @@ type AdminBookingResponse struct {
 	TotalPrice  float64 `json:"totalPrice"`
 	Status      string  `json:"status"`
 	CreatedAt   string  `json:"createdAt"`
+
+	RefundAmount float64 `json:"refundAmount"`
+	RefundStatus string  `json:"refundStatus,omitempty"`
 }
 
 type Stats struct {
@@ -112,33 +263,23 @@ func (h *Handler) CreateShow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	date, err := time.Parse("2006-01-02", req.Date)
+	show, err := req.newShow()
 	if err != nil {
-		http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	if req.Time == "" {
-		req.Time = "20:00"
-	}
-	if req.TotalSeats == 0 {
-		req.TotalSeats = 100
+	show.VenueID = req.VenueID
+	if err := h.Service.ApplyVenue(&show); err != nil {
+		writeVenueError(w, err)
+		return
 	}
-	if req.Price == 0 {
-		req.Price = 50.0
+	if err := h.applyFestival(req.FestivalID, &show); err != nil {
+		writeFestivalError(w, err)
+		return
 	}
-
-	show := models.Show{
-		Title:          req.Title,
-		Date:           date,
-		Time:           req.Time,
-		ArtistID:       req.ArtistID,
-		Venue:          req.Venue,
-		Price:          req.Price,
-		TotalSeats:     req.TotalSeats,
-		AvailableSeats: req.TotalSeats,
-		Description:    req.Description,
-		ImageURL:       req.ImageURL,
+	if err := concert.SetShowTimes(&show, req.showTimes()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	show, err = h.Service.SetShow(show)
@@ -148,7 +289,9 @@ func (h *Handler) CreateShow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("Show created: %s by %s on %s", show.Title, artist.Name, show.Date.Format("2006-01-02"))
+	log.Printf("Show created: %s by %s on %s", show.Title, artist.Name, concert.FormatShowDate(show))
+	h.publishEvent(models.EventShowCreated, show)
+	h.sendShowAlert(show)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(show)
@@ -180,17 +323,6 @@ func (h *Handler) UpdateShow(w http.ResponseWriter, r *http.Request) {
 	if req.Title != "" {
 		show.Title = req.Title
 	}
-	if req.Date != "" {
-		date, err := time.Parse("2006-01-02", req.Date)
-		if err != nil {
-			http.Error(w, "Invalid date format", http.StatusBadRequest)
-			return
-		}
-		show.Date = date
-	}
-	if req.Time != "" {
-		show.Time = req.Time
-	}
 	if req.ArtistID != 0 {
 		h.Service.GetArtistByID(req.ArtistID)
 		_, err := h.Service.GetArtistByID(req.ArtistID)
@@ -200,8 +332,15 @@ func (h *Handler) UpdateShow(w http.ResponseWriter, r *http.Request) {
 		}
 		show.ArtistID = req.ArtistID
 	}
-	if req.Venue != "" {
+	if req.VenueID != nil {
+		show.VenueID = req.VenueID
+	} else if req.Venue != "" {
+		// a venue typed by hand is looked up again
 		show.Venue = req.Venue
+		show.VenueID = nil
+	}
+	if req.City != "" {
+		show.City = req.City
 	}
 	if req.Price > 0 {
 		show.Price = req.Price
@@ -215,6 +354,43 @@ func (h *Handler) UpdateShow(w http.ResponseWriter, r *http.Request) {
 	if req.ImageURL != "" {
 		show.ImageURL = req.ImageURL
 	}
+	if req.OnSaleAt != nil {
+		show.OnSaleAt = req.OnSaleAt
+	}
+	if req.OffSaleAt != nil {
+		show.OffSaleAt = req.OffSaleAt
+	}
+	if show.OnSaleAt != nil && show.OffSaleAt != nil && !show.OffSaleAt.After(*show.OnSaleAt) {
+		http.Error(w, "offSaleAt must be after onSaleAt", http.StatusBadRequest)
+		return
+	}
+	req.applyTicketLimits(&show)
+	req.applyPricing(&show)
+	if req.QueueEnabled != nil {
+		show.QueueEnabled = *req.QueueEnabled
+	}
+	if req.QueueAdmitPerMinute > 0 {
+		show.QueueAdmitPerMinute = req.QueueAdmitPerMinute
+	}
+	if req.CancellationPolicy != nil {
+		if err := req.CancellationPolicy.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		show.CancellationPolicy = *req.CancellationPolicy
+	}
+	if err := h.Service.ApplyVenue(&show); err != nil {
+		writeVenueError(w, err)
+		return
+	}
+	if err := h.applyFestival(req.FestivalID, &show); err != nil {
+		writeFestivalError(w, err)
+		return
+	}
+	if err := concert.SetShowTimes(&show, req.showTimes()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	show, err = h.Service.SetShow(show)
 
@@ -225,6 +401,7 @@ func (h *Handler) UpdateShow(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("Show updated: %s (ID: %d)", show.Title, show.ID)
+	h.publishEvent(models.EventShowUpdated, show)
 
 	json.NewEncoder(w).Encode(show)
 }
@@ -259,6 +436,9 @@ func (h *Handler) DeleteShow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.publishEvent(models.EventShowDeleted, map[string]uint{"id": uint(id)})
+	h.Hub.Publish(concert.AvailabilityEvent{Type: concert.ShowCancelled, ShowID: uint(id)})
+
 	json.NewEncoder(w).Encode(map[string]string{"message": "Show deleted successfully"})
 }
 
@@ -293,6 +473,13 @@ func (h *Handler) CreateArtist(w http.ResponseWriter, r *http.Request) {
 		Bio:      req.Bio,
 		ImageURL: req.ImageURL,
 	}
+	if req.Contract != nil {
+		if err := req.Contract.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		artist.Contract = *req.Contract
+	}
 
 	_, err := h.Service.SetArtist(artist)
 
@@ -318,8 +505,7 @@ func (h *Handler) UpdateArtist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var artist models.Artist
-	_, err = h.Service.GetArtistByID(uint(id))
+	artist, err := h.Service.GetArtistByID(uint(id))
 	if err != nil {
 		http.Error(w, "Artist not found", http.StatusNotFound)
 		return
@@ -343,6 +529,13 @@ func (h *Handler) UpdateArtist(w http.ResponseWriter, r *http.Request) {
 	if req.ImageURL != "" {
 		artist.ImageURL = req.ImageURL
 	}
+	if req.Contract != nil {
+		if err := req.Contract.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		artist.Contract = *req.Contract
+	}
 
 	_, err = h.Service.SetArtist(artist)
 	if err != nil {
@@ -412,6 +605,9 @@ func (h *Handler) ListBookings(w http.ResponseWriter, r *http.Request) {
 			TotalPrice:  booking.TotalPrice,
 			Status:      booking.Status,
 			CreatedAt:   booking.CreatedAt.Format("2006-01-02 15:04"),
+
+			RefundAmount: booking.RefundAmount,
+			RefundStatus: booking.RefundStatus,
 		}
 	}
 
@@ -429,10 +625,12 @@ func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
 	h.Db.Model(&models.Booking{}).Count(&stats.TotalBookings)
 	h.Db.Model(&models.User{}).Count(&stats.TotalUsers)
 
-	h.Db.Model(&models.Booking{}).
-		Where("status = ?", "confirmed").
-		Select("COALESCE(SUM(total_price), 0)").
-		Scan(&stats.TotalRevenue)
+	// revenue comes from the ledger so that refunds and payouts are taken out
+	if report, err := h.Service.GetLedgerReport(concert.LedgerFilter{}); err != nil {
+		log.Printf("Error computing revenue: %v", err)
+	} else {
+		stats.TotalRevenue = report.NetRevenue
+	}
 
 	json.NewEncoder(w).Encode(stats)
 }
@@ -473,6 +671,8 @@ func (h *Handler) AdminRoutes(r chi.Router) {
 	r.Post("/api/admin/shows", h.CreateShow)
 	r.Put("/api/admin/shows/{id}", h.UpdateShow)
 	r.Delete("/api/admin/shows/{id}", h.DeleteShow)
+	r.Put("/api/admin/shows/{id}/lineup", h.SetShowLineup)
+	r.Put("/api/admin/shows/{id}/status", h.SetShowStatus)
 
 	// Artists
 	r.Get("/api/admin/artists", h.ListArtists)
@@ -480,11 +680,80 @@ func (h *Handler) AdminRoutes(r chi.Router) {
 	r.Put("/api/admin/artists/{id}", h.UpdateArtist)
 	r.Delete("/api/admin/artists/{id}", h.DeleteArtist)
 
+	// Venues
+	r.Get("/api/admin/venues", h.ListVenues)
+	r.Post("/api/admin/venues", h.CreateVenue)
+	r.Put("/api/admin/venues/{id}", h.UpdateVenue)
+	r.Delete("/api/admin/venues/{id}", h.DeleteVenue)
+
+	// Festivals
+	r.Get("/api/admin/festivals", h.ListFestivals)
+	r.Post("/api/admin/festivals", h.CreateFestival)
+	r.Put("/api/admin/festivals/{id}", h.UpdateFestival)
+	r.Delete("/api/admin/festivals/{id}", h.DeleteFestival)
+
+	// Tours
+	r.Get("/api/admin/tours", h.ListTours)
+	r.Post("/api/admin/tours", h.CreateTour)
+	r.Put("/api/admin/tours/{id}", h.UpdateTour)
+	r.Delete("/api/admin/tours/{id}", h.DeleteTour)
+	r.Post("/api/admin/tours/{id}/shows", h.CreateTourShows)
+	r.Put("/api/admin/tours/{id}/shows", h.UpdateTourShows)
+
 	// Bookings
 	r.Get("/api/admin/bookings", h.ListBookings)
+	r.Get("/api/admin/bookings/{id}/audit", h.ListBookingAudit)
+
+	// Resale
+	r.Get("/api/admin/resale", h.ListAllResaleListings)
+	r.Delete("/api/admin/resale/{id}", h.RemoveResaleListing)
+
+	// Gift cards and credit
+	r.Get("/api/admin/gift-cards", h.ListGiftCards)
+	r.Post("/api/admin/gift-cards", h.IssueGiftCard)
+	r.Post("/api/admin/users/{id}/credit", h.GrantCredit)
+	r.Get("/api/admin/credit/liabilities", h.GetCreditLiabilities)
+
+	// Ledger
+	r.Get("/api/admin/ledger/accounts", h.GetTrialBalance)
+	r.Get("/api/admin/ledger/entries", h.ListJournalEntries)
+	r.Get("/api/admin/ledger/report", h.GetLedgerReport)
+	r.Get("/api/admin/ledger/check", h.CheckLedger)
+
+	// Taxes
+	r.Get("/api/admin/tax-rates", h.ListTaxRates)
+	r.Post("/api/admin/tax-rates", h.SetTaxRate)
+	r.Delete("/api/admin/tax-rates/{id}", h.DeleteTaxRate)
+
+	// Settlements
+	r.Get("/api/admin/settlements", h.ListSettlements)
+	r.Get("/api/admin/shows/{id}/settlement", h.GetSettlement)
+	r.Post("/api/admin/settlements/{id}/approve", h.ApproveSettlement)
+	r.Post("/api/admin/settlements/{id}/paid", h.MarkSettlementPaid)
 
 	// Stats
 	r.Get("/api/admin/stats", h.GetStats)
+
+	// Presales
+	r.Get("/api/admin/shows/{id}/presales", h.ListPresales)
+	r.Post("/api/admin/shows/{id}/presales", h.CreatePresale)
+	r.Put("/api/admin/presales/{id}", h.UpdatePresale)
+	r.Delete("/api/admin/presales/{id}", h.DeletePresale)
+
+	// Ticket limit overrides
+	r.Get("/api/admin/shows/{id}/limit-overrides", h.ListLimitOverrides)
+	r.Put("/api/admin/shows/{id}/limit-overrides", h.SetLimitOverride)
+	r.Delete("/api/admin/limit-overrides/{id}", h.DeleteLimitOverride)
+
+	// Webhooks
+	r.Get("/api/admin/webhooks", h.ListWebhooks)
+	r.Post("/api/admin/webhooks", h.CreateWebhook)
+	r.Get("/api/admin/webhooks/dead-letters", h.ListDeadLetters)
+	r.Put("/api/admin/webhooks/{id}", h.UpdateWebhook)
+	r.Delete("/api/admin/webhooks/{id}", h.DeleteWebhook)
+	r.Get("/api/admin/webhooks/{id}/deliveries", h.ListWebhookDeliveries)
+	r.Post("/api/admin/webhooks/{id}/test", h.TestWebhook)
+	r.Post("/api/admin/webhook-deliveries/{id}/retry", h.RetryWebhookDelivery)
 }
 
 // func (h *Handler) DeleteShow(w http.ResponseWriter, r *http.Request) {
@@ -1,6 +1,8 @@
 package http
 
 import (
+	"concert/internal/concert"
+	"concert/internal/models"
 	"encoding/json"
 	"log"
 	"net/http"
@@ -22,6 +24,12 @@ func (h *Handler) ListAllArtists(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(artists)
 }
 
+// ArtistPage is an artist with every show it plays, headlining or on the bill
+type ArtistPage struct {
+	models.Artist
+	Appearances []concert.Appearance `json:"appearances"`
+}
+
 func (h *Handler) GetArtistPublic(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -32,11 +40,18 @@ func (h *Handler) GetArtistPublic(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.Service.GetArtistByID(uint(id))
+	artist, err := h.Service.GetArtistByID(uint(id))
 	if err != nil {
 		http.Error(w, "Artist not found", http.StatusNotFound)
 		return
 	}
 
-	json.NewEncoder(w).Encode(response)
+	appearances, err := h.Service.GetArtistAppearances(artist.ID)
+	if err != nil {
+		log.Printf("Error listing the shows of artist %d: %v", artist.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(ArtistPage{Artist: artist, Appearances: appearances})
 }
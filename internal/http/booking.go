@@ -1,20 +1,38 @@
 package http
 
 import (
+	"concert/internal/concert"
 	"concert/internal/models"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 )
 
 
 
 type CreateBookingRequest struct {
-	ShowID      uint `json:"showId"`
-	TicketCount int  `json:"ticketCount"`
+	ShowID      uint   `json:"showId"`
+	TicketCount int    `json:"ticketCount"`
+	QueueTicket string `json:"queueTicket,omitempty"`
+	AccessCode  string `json:"accessCode,omitempty"`
+
+	// FestivalID books passes of a festival, for each of its days, instead of a show
+	FestivalID uint `json:"festivalId,omitempty"`
+
+	// UseCredit spends account credit on the booking, at most CreditAmount when set
+	UseCredit    bool    `json:"useCredit,omitempty"`
+	CreditAmount float64 `json:"creditAmount,omitempty"`
+}
+
+type CreateBookingResponse struct {
+	models.Booking
+	Payment *PaymentResponse `json:"payment,omitempty"`
 }
 
 type BookingResponse struct {
@@ -42,14 +60,52 @@ func (h *Handler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.ShowID == 0 || req.TicketCount <= 0 {
+	if (req.ShowID == 0 && req.FestivalID == 0) || req.TicketCount <= 0 {
 		http.Error(w, "Invalid show ID or ticket count", http.StatusBadRequest)
 		return
 	}
 
-	show, err := h.Service.GetShowByID(req.ShowID)
+	var show models.Show
+	var festivalID *uint
+	if req.FestivalID != 0 {
+		// a pass is booked on the opening show of the festival
+		show, err = h.Service.FestivalPassShow(req.FestivalID)
+		if err != nil {
+			writeFestivalError(w, err)
+			return
+		}
+		festivalID = &req.FestivalID
+	} else {
+		show, err = h.Service.GetShowByID(req.ShowID)
+		if err != nil {
+			http.Error(w, "Show not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	if err := h.Service.CheckSaleWindow(show, *user, req.AccessCode); err != nil {
+		if errors.Is(err, concert.ErrShowNotPublished) {
+			http.Error(w, "Show not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	queueTicket, err := h.Service.CheckQueueAdmission(show, user.ID, req.QueueTicket)
 	if err != nil {
-		http.Error(w, "Show not found", http.StatusNotFound)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	if err := h.Service.CheckTicketLimits(show, *user, req.TicketCount); err != nil {
+		var limitErr *concert.TicketLimitError
+		if errors.As(err, &limitErr) {
+			writeTicketLimitError(w, limitErr)
+			return
+		}
+		log.Printf("Error checking ticket limits: %v", err)
+		http.Error(w, "Failed to create booking", http.StatusInternalServerError)
 		return
 	}
 
@@ -58,18 +114,78 @@ func (h *Handler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	totalPrice := show.Price * float64(req.TicketCount)
+	breakdown, err := h.Service.PriceBooking(show, req.TicketCount)
+	if err != nil {
+		log.Printf("Error pricing booking: %v", err)
+		http.Error(w, "Failed to create booking", http.StatusInternalServerError)
+		return
+	}
+	totalPrice := concert.GrossPrice(breakdown)
+
+	var credit float64
+	if req.UseCredit {
+		credit = concert.CreditToApply(*user, totalPrice, req.CreditAmount)
+	}
+
+	// seats are held while the booking waits for its payment, free shows and bookings
+	// fully paid with credit are confirmed right away
+	status := "pending"
+	var confirmedAt *time.Time
+	if totalPrice == 0 || credit >= totalPrice {
+		status = "confirmed"
+		now := time.Now()
+		confirmedAt = &now
+	}
 
 	booking := models.Booking{
-		UserID:      user.ID,
-		ShowID:      req.ShowID,
-		TicketCount: req.TicketCount,
-		TotalPrice:  totalPrice,
-		Status:      "confirmed",
+		UserID:        user.ID,
+		ShowID:        show.ID,
+		FestivalID:    festivalID,
+		TicketCount:   req.TicketCount,
+		TotalPrice:    totalPrice,
+		Status:        status,
+		ConfirmedAt:   confirmedAt,
+		CreditApplied: credit,
+
+		PriceBreakdown: breakdown,
 	}
 
 	tx := h.Db.Begin()
 
+	// checked again holding the show, concurrent orders could pass the check above together
+	locked, err := concert.LockTicketLimits(tx, show, *user, req.TicketCount)
+	if err != nil {
+		tx.Rollback()
+		var limitErr *concert.TicketLimitError
+		if errors.As(err, &limitErr) {
+			writeTicketLimitError(w, limitErr)
+			return
+		}
+		if errors.Is(err, concert.ErrNotEnoughSeats) {
+			http.Error(w, "Not enough seats available", http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error checking ticket limits: %v", err)
+		http.Error(w, "Failed to create booking", http.StatusInternalServerError)
+		return
+	}
+
+	var festivalDays int
+	if festivalID != nil {
+		// and the passes left, holding every day of the festival
+		festivalDays, err = concert.LockFestivalPasses(tx, *festivalID, req.TicketCount)
+		if err != nil {
+			tx.Rollback()
+			if errors.Is(err, concert.ErrNotEnoughSeats) {
+				http.Error(w, "Not enough seats available", http.StatusBadRequest)
+				return
+			}
+			log.Printf("Error checking festival passes: %v", err)
+			http.Error(w, "Failed to create booking", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	if err := tx.Create(&booking).Error; err != nil {
 		tx.Rollback()
 		log.Printf("Error creating booking: %v", err)
@@ -77,12 +193,58 @@ func (h *Handler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := tx.Model(&show).Update("available_seats", show.AvailableSeats-req.TicketCount).Error; err != nil {
+	if err := concert.SpendCredit(tx, booking); err != nil {
+		tx.Rollback()
+		if errors.Is(err, concert.ErrInsufficientCredit) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("Error spending credit: %v", err)
+		http.Error(w, "Failed to create booking", http.StatusInternalServerError)
+		return
+	}
+
+	if booking.Status == "confirmed" {
+		if err := concert.RecordSale(tx, booking); err != nil {
+			tx.Rollback()
+			log.Printf("Error recording sale: %v", err)
+			http.Error(w, "Failed to create booking", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var seats *gorm.DB
+	if festivalID != nil {
+		// a pass takes a seat on each day of the festival
+		seats = concert.BookingSeats(tx, booking).
+			Where("available_seats >= ?", req.TicketCount).
+			Update("available_seats", gorm.Expr("available_seats - ?", req.TicketCount))
+	} else {
+		seats = tx.Model(&models.Show{}).
+			Where("id = ? AND available_seats >= ?", show.ID, req.TicketCount).
+			Update("available_seats", gorm.Expr("available_seats - ?", req.TicketCount))
+	}
+	if err := seats.Error; err != nil {
 		tx.Rollback()
 		log.Printf("Error updating seats: %v", err)
 		http.Error(w, "Failed to update seats", http.StatusInternalServerError)
 		return
 	}
+	if seats.RowsAffected == 0 || festivalID != nil && seats.RowsAffected != int64(festivalDays) {
+		tx.Rollback()
+		http.Error(w, "Not enough seats available", http.StatusBadRequest)
+		return
+	}
+
+	if queueTicket.ID != 0 {
+		// a queue ticket admits a single booking
+		result := tx.Model(&queueTicket).Where("used_at IS NULL").Update("used_at", time.Now())
+		if result.Error != nil || result.RowsAffected == 0 {
+			tx.Rollback()
+			http.Error(w, "This waiting room ticket has already been used", http.StatusForbidden)
+			return
+		}
+	}
 
 	if err := tx.Commit().Error; err != nil {
 		log.Printf("Error committing transaction: %v", err)
@@ -90,6 +252,25 @@ func (h *Handler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.publishAvailability(show, locked.AvailableSeats-req.TicketCount)
+
+	var paymentResponse *PaymentResponse
+	if booking.Status == "pending" {
+		payment, intent, err := h.Service.StartPayment(booking)
+		if err != nil {
+			log.Printf("Error starting payment of booking %d: %v", booking.ID, err)
+			if err := h.Service.ReleaseBooking(booking.ID, err.Error()); err != nil {
+				log.Printf("Error releasing booking %d: %v", booking.ID, err)
+			}
+			h.publishAvailability(show, locked.AvailableSeats)
+			http.Error(w, "Failed to start the payment", http.StatusBadGateway)
+			return
+		}
+		paymentResponse = &PaymentResponse{Payment: payment, ClientSecret: intent.ClientSecret}
+	} else {
+		h.onBookingConfirmed(booking, *user)
+	}
+
 	var fullBooking models.Booking
 	if err := h.Db.Preload("Show.Artist").First(&fullBooking, booking.ID).Error; err != nil {
 		log.Printf("Error reloading booking: %v", err)
@@ -108,7 +289,7 @@ func (h *Handler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(fullBooking)
+	json.NewEncoder(w).Encode(CreateBookingResponse{Booking: fullBooking, Payment: paymentResponse})
 }
 
 func (h *Handler) GetMyBookings(w http.ResponseWriter, r *http.Request) {
@@ -162,6 +343,52 @@ func (h *Handler) CancelBooking(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Booking already cancelled", http.StatusBadRequest)
 		return
 	}
+	if booking.Status == "refunding" {
+		http.Error(w, "This booking is already being refunded", http.StatusBadRequest)
+		return
+	}
+	if booking.Status == "payment_failed" {
+		http.Error(w, "The payment of this booking failed, its seats are already released", http.StatusBadRequest)
+		return
+	}
+
+	quote, err := h.Service.QuoteRefund(booking, time.Now())
+	if errors.Is(err, concert.ErrResaleListed) {
+		http.Error(w, "This booking is listed for resale, withdraw the listing first", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error quoting refund of booking %d: %v", booking.ID, err)
+		http.Error(w, "Failed to cancel booking", http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("refundTo") == "credit" {
+		quote.Credit = quote.Amount
+	}
+	if quote.Amount > 0 && quote.Credit >= quote.Amount {
+		// nothing goes back through the payment provider, no need for the saga
+		if err := h.Service.RefundToCredit(booking.ID, quote.Amount); err != nil {
+			log.Printf("Error refunding booking %d to credit: %v", booking.ID, err)
+			http.Error(w, "Failed to cancel booking", http.StatusInternalServerError)
+			return
+		}
+		booking.Status = "cancelled"
+		h.publishEvent(models.EventBookingCancelled, booking)
+		h.publishAvailability(booking.Show, booking.Show.AvailableSeats+booking.TicketCount)
+		json.NewEncoder(w).Encode(map[string]any{"message": "Booking cancelled, the refund was added to your credit", "refund": quote})
+		return
+	}
+	if quote.Amount > 0 {
+		// paid bookings are cancelled by the refund saga
+		if err := h.startRefund(booking, quote); err != nil {
+			log.Printf("Error starting refund of booking %d: %v", booking.ID, err)
+			http.Error(w, "Refunds are unavailable, please try again later", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{"message": "Booking cancellation and refund in progress", "refund": quote})
+		return
+	}
 
 	tx := h.Db.Begin()
 
@@ -171,13 +398,49 @@ func (h *Handler) CancelBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := tx.Model(&booking.Show).Update("available_seats", booking.Show.AvailableSeats+booking.TicketCount).Error; err != nil {
+	var seats *gorm.DB
+	if booking.FestivalID != nil {
+		seats = concert.BookingSeats(tx, booking).Update("available_seats", gorm.Expr("available_seats + ?", booking.TicketCount))
+	} else {
+		seats = tx.Model(&models.Show{}).Where("id = ?", booking.ShowID).
+			Update("available_seats", gorm.Expr("available_seats + ?", booking.TicketCount))
+	}
+	if err := seats.Error; err != nil {
 		tx.Rollback()
 		http.Error(w, "Failed to update seats", http.StatusInternalServerError)
 		return
 	}
 
+	if err := tx.Model(&models.Ticket{}).
+		Where("booking_id = ? AND status = ?", booking.ID, models.TicketValid).
+		Update("status", models.TicketVoid).Error; err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to invalidate tickets", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Model(&models.Payment{}).
+		Where("booking_id = ? AND status = ?", booking.ID, models.PaymentPending).
+		Updates(map[string]any{"status": models.PaymentFailed, "failure_reason": "booking cancelled"}).Error; err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to cancel booking", http.StatusInternalServerError)
+		return
+	}
+
+	if booking.Status == "pending" {
+		// nothing was charged yet, the credit spent on the booking comes back
+		if err := concert.ReturnBookingCredit(tx, booking); err != nil {
+			tx.Rollback()
+			http.Error(w, "Failed to cancel booking", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	tx.Commit()
 
+	booking.Status = "cancelled"
+	h.publishEvent(models.EventBookingCancelled, booking)
+	h.publishAvailability(booking.Show, booking.Show.AvailableSeats+booking.TicketCount)
+
 	json.NewEncoder(w).Encode(map[string]string{"message": "Booking cancelled successfully"})
 }
@@ -0,0 +1,269 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"concert/internal/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type CreditResponse struct {
+	Balance float64              `json:"balance"`
+	Entries []models.CreditEntry `json:"entries"`
+}
+
+type GiftCardRequest struct {
+	Amount         float64    `json:"amount"`
+	RecipientEmail string     `json:"recipientEmail"`
+	Message        string     `json:"message"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+}
+
+type GiftCardPurchaseResponse struct {
+	GiftCard models.GiftCard `json:"giftCard"`
+	Payment  PaymentResponse `json:"payment"`
+}
+
+type RedeemGiftCardRequest struct {
+	Code string `json:"code"`
+}
+
+type GrantCreditRequest struct {
+	Amount float64 `json:"amount"`
+	Note   string  `json:"note"`
+}
+
+func writeCreditError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, concert.ErrGiftCardInvalid):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, concert.ErrGiftCardExpired):
+		http.Error(w, err.Error(), http.StatusGone)
+	case errors.Is(err, concert.ErrGiftCardRedeemed), errors.Is(err, concert.ErrInsufficientCredit),
+		errors.Is(err, concert.ErrNoPendingPayment):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, concert.ErrInvalidAmount):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, concert.ErrNotMockProvider):
+		http.Error(w, "Not found", http.StatusNotFound)
+	default:
+		log.Printf("Error handling credit: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) GetMyCredit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	entries, err := h.Service.GetCreditEntries(user.ID)
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(CreditResponse{Balance: user.CreditBalance, Entries: entries})
+}
+
+func (h *Handler) BuyGiftCard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req GiftCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.RecipientEmail != "" && !strings.Contains(req.RecipientEmail, "@") {
+		http.Error(w, "Invalid recipient email", http.StatusBadRequest)
+		return
+	}
+
+	card, payment, intent, err := h.Service.BuyGiftCard(req.Amount, *user, req.RecipientEmail, req.Message)
+	if errors.Is(err, concert.ErrInvalidAmount) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error starting gift card payment: %v", err)
+		http.Error(w, "Failed to start the payment", http.StatusBadGateway)
+		return
+	}
+	// the code is only shown once the card is paid
+	card.Code = ""
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(GiftCardPurchaseResponse{
+		GiftCard: card,
+		Payment:  PaymentResponse{Payment: payment, ClientSecret: intent.ClientSecret},
+	})
+}
+
+func (h *Handler) ListMyGiftCards(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	cards, err := h.Service.ListPurchasedGiftCards(user.ID)
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(cards)
+}
+
+// SimulateGiftCardPayment completes a gift card payment with the mock provider, only available in development.
+func (h *Handler) SimulateGiftCardPayment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	card, err := h.Service.SimulateGiftCardPayment(uint(id), *user)
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(card)
+}
+
+func (h *Handler) RedeemGiftCard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req RedeemGiftCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.Service.RedeemGiftCard(req.Code, *user, time.Now())
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(entry)
+}
+
+func (h *Handler) ListGiftCards(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	cards, err := h.Service.ListGiftCards(r.URL.Query().Get("status"))
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(cards)
+}
+
+func (h *Handler) IssueGiftCard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	admin, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req GiftCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	card, err := h.Service.IssueGiftCard(req.Amount, *admin, req.RecipientEmail, req.Message, req.ExpiresAt)
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(card)
+}
+
+// GrantCredit credits a user account, e.g. as a goodwill gesture for a postponed show
+func (h *Handler) GrantCredit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	admin, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	if err := h.Db.First(&models.User{}, id).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	var req GrantCreditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.Service.GrantCredit(uint(id), req.Amount, *admin, req.Note)
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+func (h *Handler) GetCreditLiabilities(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	liabilities, err := h.Service.GetCreditLiabilities(time.Now())
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(liabilities)
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"concert/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"go.temporal.io/sdk/client"
+)
+
+func writePDF(w http.ResponseWriter, filename string, data []byte) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(data)
+}
+
+func (h *Handler) GetTicketsPDF(w http.ResponseWriter, r *http.Request) {
+	booking, ok := h.ownConfirmedBooking(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.Service.TicketsPDF(booking)
+	if err != nil {
+		log.Printf("Error rendering tickets of booking %d: %v", booking.ID, err)
+		http.Error(w, "Failed to render tickets", http.StatusInternalServerError)
+		return
+	}
+
+	writePDF(w, fmt.Sprintf("tickets-%d.pdf", booking.ID), data)
+}
+
+func (h *Handler) GetReceiptPDF(w http.ResponseWriter, r *http.Request) {
+	booking, ok := h.ownBooking(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.Service.ReceiptPDF(booking)
+	if errors.Is(err, concert.ErrBookingNotConfirmed) {
+		http.Error(w, "No receipt for this booking", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error rendering receipt of booking %d: %v", booking.ID, err)
+		http.Error(w, "Failed to render receipt", http.StatusInternalServerError)
+		return
+	}
+
+	writePDF(w, fmt.Sprintf("receipt-%d.pdf", booking.ID), data)
+}
+
+// sendBookingConfirmation starts the temporal workflow emailing the tickets and the receipt.
+// A failure is only logged, the documents stay downloadable from the bookings API.
+func (h *Handler) sendBookingConfirmation(booking models.Booking, user models.User) {
+	if h.TemporalClient == nil {
+		return
+	}
+
+	tickets, err := h.Service.TicketsPDF(booking)
+	if err != nil {
+		log.Printf("Error rendering tickets of booking %d: %v", booking.ID, err)
+		return
+	}
+	receipt, err := h.Service.ReceiptPDF(booking)
+	if err != nil {
+		log.Printf("Error rendering receipt of booking %d: %v", booking.ID, err)
+		return
+	}
+	invoice, err := h.Service.GetInvoice(booking)
+	if err != nil {
+		log.Printf("Error getting invoice of booking %d: %v", booking.ID, err)
+		return
+	}
+	show, err := h.Service.GetShowByID(booking.ShowID)
+	if err != nil {
+		log.Printf("Error getting show of booking %d: %v", booking.ID, err)
+		return
+	}
+
+	input := TemporalBookingInput{
+		Email:         user.Email,
+		Username:      user.Username,
+		BookingID:     booking.ID,
+		ShowTitle:     show.Title,
+		Venue:         show.Venue,
+		Date:          concert.FormatShowDate(show),
+		TicketCount:   booking.TicketCount,
+		TotalPrice:    booking.TotalPrice,
+		InvoiceNumber: invoice.Number,
+		Attachments: []TemporalAttachment{
+			{Filename: fmt.Sprintf("tickets-%d.pdf", booking.ID), Content: tickets},
+			{Filename: fmt.Sprintf("receipt-%s.pdf", invoice.Number), Content: receipt},
+		},
+	}
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        fmt.Sprintf("booking-confirmation-%d", booking.ID),
+		TaskQueue: "email-task-queue",
+	}
+	if _, err := h.TemporalClient.ExecuteWorkflow(context.Background(), workflowOptions, "BookingConfirmationWorkflow", input); err != nil {
+		log.Printf("Error executing booking confirmation workflow: %v", err)
+	}
+}
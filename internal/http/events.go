@@ -0,0 +1,121 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"concert/internal/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const sseHeartbeat = 15 * time.Second
+
+// publishAvailability pushes the new seat count of a show to the clients streaming it
+func (h *Handler) publishAvailability(show models.Show, available int) {
+	eventType := concert.AvailabilityChanged
+	if available <= 0 {
+		eventType = concert.AvailabilitySoldOut
+	}
+	h.Hub.Publish(concert.AvailabilityEvent{
+		Type:           eventType,
+		ShowID:         show.ID,
+		AvailableSeats: available,
+		TotalSeats:     show.TotalSeats,
+	})
+}
+
+func writeSSE(w http.ResponseWriter, id int, event concert.AvailabilityEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event.Type, data)
+	return err
+}
+
+// ShowEvents streams availability changes of a show as Server-Sent Events
+func (h *Handler) ShowEvents(w http.ResponseWriter, r *http.Request) {
+	idString := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idString)
+	if err != nil {
+		http.Error(w, "Invalid show ID", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	show, err := h.Service.GetShowByID(uint(id))
+	if err != nil || !show.Listed(time.Now()) {
+		http.Error(w, "Show not found", http.StatusNotFound)
+		return
+	}
+
+	sub, err := h.Hub.Subscribe(show.ID)
+	if err != nil {
+		w.Header().Set("Retry-After", "10")
+		http.Error(w, "Too many clients, try again later", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.Hub.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// tell the browser how long to wait before reconnecting, then send the current state
+	fmt.Fprintf(w, "retry: 5000\n\n")
+	eventID := 1
+	initial := concert.AvailabilityEvent{
+		Type:           concert.AvailabilityChanged,
+		ShowID:         show.ID,
+		AvailableSeats: show.AvailableSeats,
+		TotalSeats:     show.TotalSeats,
+		At:             time.Now().UTC(),
+	}
+	if show.AvailableSeats <= 0 {
+		initial.Type = concert.AvailabilitySoldOut
+	}
+	if err := writeSSE(w, eventID, initial); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// hub closed, the server is shutting down
+				fmt.Fprintf(w, "event: shutdown\ndata: {}\n\n")
+				flusher.Flush()
+				return
+			}
+			eventID++
+			if err := writeSSE(w, eventID, event); err != nil {
+				log.Printf("Error writing event to show %d stream: %v", show.ID, err)
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprintf(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
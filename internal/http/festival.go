@@ -0,0 +1,183 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"concert/internal/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type FestivalRequest struct {
+	Name        string   `json:"name"`
+	Description *string  `json:"description"`
+	ImageURL    *string  `json:"imageUrl"`
+	PassPrice   *float64 `json:"passPrice"`
+	PassSeats   *int     `json:"passSeats"`
+}
+
+// apply copies the fields present in the request, an empty name is left unchanged
+func (req FestivalRequest) apply(festival *models.Festival) {
+	if req.Name != "" {
+		festival.Name = req.Name
+	}
+	if req.Description != nil {
+		festival.Description = *req.Description
+	}
+	if req.ImageURL != nil {
+		festival.ImageURL = *req.ImageURL
+	}
+	if req.PassPrice != nil {
+		festival.PassPrice = *req.PassPrice
+	}
+	if req.PassSeats != nil {
+		festival.PassSeats = *req.PassSeats
+	}
+}
+
+func writeFestivalError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Festival not found", http.StatusNotFound)
+	case errors.Is(err, concert.ErrFestivalInUse), errors.Is(err, concert.ErrFestivalNoShows):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Error handling festival: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// applyFestival makes show a day of a festival, zero takes it out of its festival
+func (h *Handler) applyFestival(festivalID *uint, show *models.Show) error {
+	if festivalID == nil {
+		return nil
+	}
+	// the festival loaded with the show would win over the new id when it is saved
+	show.Festival = nil
+	if *festivalID == 0 {
+		show.FestivalID = nil
+		return nil
+	}
+	if _, err := h.Service.GetFestival(*festivalID); err != nil {
+		return err
+	}
+	show.FestivalID = festivalID
+	return nil
+}
+
+func (h *Handler) ListFestivals(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	festivals, err := h.Service.ListFestivals()
+	if err != nil {
+		log.Printf("Error listing festivals: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(festivals)
+}
+
+// GetFestival returns a festival with its days, their lineups and the passes left
+func (h *Handler) GetFestival(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	festival, err := h.Service.GetFestival(uint(id))
+	if err != nil {
+		writeFestivalError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(festival)
+}
+
+func (h *Handler) CreateFestival(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req FestivalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var festival models.Festival
+	req.apply(&festival)
+	if err := festival.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	festival, err := h.Service.CreateFestival(festival)
+	if err != nil {
+		writeFestivalError(w, err)
+		return
+	}
+
+	log.Printf("Festival created: %s (ID: %d)", festival.Name, festival.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(festival)
+}
+
+func (h *Handler) UpdateFestival(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	festival, err := h.Service.GetFestival(uint(id))
+	if err != nil {
+		writeFestivalError(w, err)
+		return
+	}
+
+	var req FestivalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.apply(&festival)
+	if err := festival.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	festival, err = h.Service.UpdateFestival(festival)
+	if err != nil {
+		writeFestivalError(w, err)
+		return
+	}
+
+	log.Printf("Festival updated: %s (ID: %d)", festival.Name, festival.ID)
+	json.NewEncoder(w).Encode(festival)
+}
+
+func (h *Handler) DeleteFestival(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteFestival(uint(id)); err != nil {
+		writeFestivalError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Festival deleted successfully"})
+}
@@ -0,0 +1,153 @@
+package http
+
+import (
+	"concert/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"gorm.io/gorm"
+)
+
+type ShowAlertsRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+func (h *Handler) FollowArtist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	follow, err := h.Service.FollowArtist(user.ID, uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Artist not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error following artist %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(follow)
+}
+
+func (h *Handler) UnfollowArtist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.Service.UnfollowArtist(user.ID, uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "You don't follow this artist", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error unfollowing artist %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Artist unfollowed successfully"})
+}
+
+// GetFollowing is the feed of the fan: the artists they follow and their upcoming shows,
+// ?limit= of them
+func (h *Handler) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	feed, err := h.Service.GetFollowingFeed(user.ID, limit, time.Now())
+	if err != nil {
+		log.Printf("Error getting the following feed of user %d: %v", user.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(feed)
+}
+
+// SetShowAlerts turns on or off the emails about the new shows of the followed artists
+func (h *Handler) SetShowAlerts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req ShowAlertsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		http.Error(w, "Invalid request body, enabled is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.SetShowAlerts(user.ID, *req.Enabled); err != nil {
+		log.Printf("Error setting the show alerts of user %d: %v", user.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]bool{"enabled": *req.Enabled})
+}
+
+// sendShowAlert starts the emails to the followers of the artists of a new show through
+// temporal, at its publish time when it is scheduled. Drafts are announced once published.
+// A show is announced once, as told by its AlertedAt, an alert still waiting for its
+// publish time is replaced.
+func (h *Handler) sendShowAlert(show models.Show) {
+	if h.TemporalClient == nil || show.Status == models.ShowDraft || show.AlertedAt != nil {
+		return
+	}
+	input := TemporalShowAlertInput{ShowID: show.ID}
+	if show.Status == models.ShowScheduled {
+		input.PublishAt = show.PublishAt
+	}
+	workflowOptions := client.StartWorkflowOptions{
+		ID:                       fmt.Sprintf("show-alert-%d", show.ID),
+		TaskQueue:                "email-task-queue",
+		WorkflowIDReusePolicy:    enumspb.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE,
+		WorkflowIDConflictPolicy: enumspb.WORKFLOW_ID_CONFLICT_POLICY_TERMINATE_EXISTING,
+	}
+	if _, err := h.TemporalClient.ExecuteWorkflow(context.Background(), workflowOptions, "NewShowAlertWorkflow", input); err != nil {
+		log.Printf("Error executing new show alert workflow: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxJournalEntries bounds a page of the journal
+const maxJournalEntries = 500
+
+func (h *Handler) GetTrialBalance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	balances, err := h.Service.TrialBalance()
+	if err != nil {
+		log.Printf("Error computing trial balance: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(balances)
+}
+
+func (h *Handler) ListJournalEntries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	var bookingID, limit int
+	var err error
+	if v := query.Get("bookingId"); v != "" {
+		if bookingID, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+			return
+		}
+	}
+	limit = 100
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := h.Service.ListJournalEntries(uint(bookingID), min(limit, maxJournalEntries))
+	if err != nil {
+		log.Printf("Error listing journal entries: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(entries)
+}
+
+// GetLedgerReport sums the ledger per show, artist, festival passes and period (from and to as YYYY-MM-DD, to excluded)
+func (h *Handler) GetLedgerReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	var filter concert.LedgerFilter
+	for key, target := range map[string]*uint{"showId": &filter.ShowID, "artistId": &filter.ArtistID, "festivalId": &filter.FestivalID} {
+		if v := query.Get(key); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, "Invalid "+key, http.StatusBadRequest)
+				return
+			}
+			*target = uint(id)
+		}
+	}
+	for key, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := query.Get(key); v != "" {
+			date, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			*target = date
+		}
+	}
+
+	report, err := h.Service.GetLedgerReport(filter)
+	if err != nil {
+		log.Printf("Error computing ledger report: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *Handler) CheckLedger(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	checks, err := h.Service.CheckLedger()
+	if err != nil {
+		log.Printf("Error checking ledger: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(checks)
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"concert/internal/models"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type LimitOverrideRequest struct {
+	UserID     uint   `json:"userId"`
+	MaxTickets int    `json:"maxTickets"`
+	Reason     string `json:"reason"`
+}
+
+func (h *Handler) ListLimitOverrides(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	overrides, err := h.Service.ListLimitOverrides(uint(id))
+	if err != nil {
+		log.Printf("Error listing limit overrides: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(overrides)
+}
+
+func (h *Handler) SetLimitOverride(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.Service.GetShowByID(uint(id)); err != nil {
+		http.Error(w, "Show not found", http.StatusNotFound)
+		return
+	}
+
+	var req LimitOverrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.UserID == 0 || req.MaxTickets <= 0 {
+		http.Error(w, "userId and a positive maxTickets are required", http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	if err := h.Db.First(&user, req.UserID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	override, err := h.Service.SetLimitOverride(models.TicketLimitOverride{
+		ShowID:     uint(id),
+		UserID:     user.ID,
+		MaxTickets: req.MaxTickets,
+		Reason:     req.Reason,
+	})
+	if err != nil {
+		log.Printf("Error saving limit override: %v", err)
+		http.Error(w, "Failed to save limit override", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Ticket limit override for user %s on show %d: %d", user.Username, id, req.MaxTickets)
+
+	json.NewEncoder(w).Encode(override)
+}
+
+func (h *Handler) DeleteLimitOverride(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteLimitOverride(uint(id)); err != nil {
+		log.Printf("Error deleting limit override: %v", err)
+		http.Error(w, "Failed to delete limit override", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Limit override deleted successfully"})
+}
+
+func writeTicketLimitError(w http.ResponseWriter, limitErr *concert.TicketLimitError) {
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error":     limitErr.Error(),
+		"scope":     limitErr.Scope,
+		"limit":     limitErr.Limit,
+		"remaining": limitErr.Remaining,
+	})
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"concert/internal/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type LineupSlotRequest struct {
+	ArtistID    uint   `json:"artistId"`
+	Role        string `json:"role"`
+	SetStartsAt string `json:"setStartsAt"`
+	SetEndsAt   string `json:"setEndsAt"`
+}
+
+type LineupRequest struct {
+	Lineup []LineupSlotRequest `json:"lineup"`
+}
+
+// SetShowLineup replaces the bill of a show, in order. The role defaults to headliner
+// for the first artist and support for the others.
+func (h *Handler) SetShowLineup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req LineupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entries := make([]concert.LineupEntry, len(req.Lineup))
+	for i, slot := range req.Lineup {
+		entries[i] = concert.LineupEntry{
+			ArtistID:    slot.ArtistID,
+			Role:        slot.Role,
+			SetStartsAt: slot.SetStartsAt,
+			SetEndsAt:   slot.SetEndsAt,
+		}
+	}
+
+	show, err := h.Service.SetLineup(uint(id), entries)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Show not found", http.StatusNotFound)
+		return
+	case errors.Is(err, concert.ErrInvalidLineup):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("Error setting the lineup of show %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Lineup of show %d set: %d artists", show.ID, len(show.Lineup))
+	h.publishEvent(models.EventShowUpdated, show)
+
+	json.NewEncoder(w).Encode(show)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -98,7 +99,8 @@ func NeedsAuth(db *gorm.DB) func(http.Handler) http.Handler {
 	}
 }
 
-func NeedsRole(db *gorm.DB, role string) func(http.Handler) http.Handler {
+// NeedsRole lets the request through when the user has one of the roles
+func NeedsRole(db *gorm.DB, roles ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			user, err := GetUserFromCookie(db, r)
@@ -106,7 +108,7 @@ func NeedsRole(db *gorm.DB, role string) func(http.Handler) http.Handler {
 				http.Error(w, "Forbidden", http.StatusForbidden)
 				return
 			}
-			if user.Role != role {
+			if !slices.Contains(roles, user.Role) {
 				http.Error(w, "Forbidden", http.StatusForbidden)
 				return
 			}
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"concert/internal/models"
+	"concert/internal/payment"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+)
+
+// maxWebhookBody bounds the payload read from the payment provider
+const maxWebhookBody = 64 << 10
+
+type PaymentResponse struct {
+	models.Payment
+	ClientSecret string `json:"clientSecret"`
+}
+
+// onBookingConfirmed runs what follows a confirmed booking: tickets, email and webhooks.
+func (h *Handler) onBookingConfirmed(booking models.Booking, user models.User) {
+	if _, err := h.Service.IssueTickets(booking); err != nil {
+		// tickets are issued again on the first GET of the booking tickets
+		log.Printf("Error issuing tickets for booking %d: %v", booking.ID, err)
+	}
+
+	h.sendBookingConfirmation(booking, user)
+	h.publishEvent(models.EventBookingCreated, booking)
+}
+
+// onPaymentResult reacts to a booking whose payment just succeeded or failed.
+func (h *Handler) onPaymentResult(booking models.Booking) {
+	switch booking.Status {
+	case "confirmed":
+		var user models.User
+		if err := h.Db.First(&user, booking.UserID).Error; err != nil {
+			log.Printf("Error loading user of booking %d: %v", booking.ID, err)
+			return
+		}
+		h.onBookingConfirmed(booking, user)
+	case "payment_failed":
+		show, err := h.Service.GetShowByID(booking.ShowID)
+		if err != nil {
+			log.Printf("Error loading show of booking %d: %v", booking.ID, err)
+			return
+		}
+		h.publishAvailability(show, show.AvailableSeats)
+	}
+}
+
+func (h *Handler) PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	booking, changed, err := h.Service.HandlePaymentWebhook(payload, r.Header)
+	switch {
+	case errors.Is(err, payment.ErrInvalidSignature):
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	case errors.Is(err, payment.ErrUnknownIntent):
+		// not ours to retry, acknowledge it
+		log.Printf("Payment webhook for an unknown intent")
+		json.NewEncoder(w).Encode(map[string]string{"message": "ignored"})
+		return
+	case err != nil:
+		// the provider retries on errors
+		log.Printf("Error handling payment webhook: %v", err)
+		http.Error(w, "Failed to handle webhook", http.StatusInternalServerError)
+		return
+	}
+
+	if changed {
+		h.onPaymentResult(booking)
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "ok"})
+}
+
+func (h *Handler) GetBookingPayments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	booking, ok := h.ownBooking(w, r)
+	if !ok {
+		return
+	}
+
+	payments, err := h.Service.GetPaymentsByBooking(booking.ID)
+	if err != nil {
+		log.Printf("Error listing payments of booking %d: %v", booking.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(payments)
+}
+
+// SimulatePayment completes a payment with the mock provider, only available in development.
+func (h *Handler) SimulatePayment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	booking, ok := h.ownBooking(w, r)
+	if !ok {
+		return
+	}
+
+	booking, changed, err := h.Service.SimulatePayment(booking)
+	switch {
+	case errors.Is(err, concert.ErrNotMockProvider):
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	case errors.Is(err, concert.ErrNoPendingPayment):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("Error simulating payment of booking %d: %v", booking.ID, err)
+		http.Error(w, "Failed to complete payment", http.StatusInternalServerError)
+		return
+	}
+
+	if changed {
+		h.onPaymentResult(booking)
+	}
+
+	json.NewEncoder(w).Encode(booking)
+}
+
+// startRefund starts the temporal saga cancelling and refunding a paid booking.
+// The workflow ID is per booking so a second cancel request doesn't refund twice.
+func (h *Handler) startRefund(booking models.Booking, quote concert.RefundQuote) error {
+	if h.TemporalClient == nil {
+		return errors.New("temporal client not configured")
+	}
+	workflowOptions := client.StartWorkflowOptions{
+		ID:                    fmt.Sprintf("refund-booking-%d", booking.ID),
+		TaskQueue:             "booking-task-queue",
+		WorkflowIDReusePolicy: enums.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE_FAILED_ONLY,
+	}
+	input := TemporalRefundInput{
+		BookingID: booking.ID,
+		Amount:    payment.FromCents(payment.ToCents(quote.Amount) - payment.ToCents(quote.Credit)),
+		Credit:    quote.Credit,
+	}
+	_, err := h.TemporalClient.ExecuteWorkflow(context.Background(), workflowOptions, "RefundWorkflow", input)
+	return err
+}
+
+func (h *Handler) GetRefundQuote(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	booking, ok := h.ownBooking(w, r)
+	if !ok {
+		return
+	}
+
+	quote, err := h.Service.QuoteRefund(booking, time.Now())
+	if errors.Is(err, concert.ErrResaleListed) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error quoting refund of booking %d: %v", booking.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(quote)
+}
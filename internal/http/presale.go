@@ -0,0 +1,172 @@
+package http
+
+import (
+	"concert/internal/models"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type PresaleRequest struct {
+	Name          string     `json:"name"`
+	StartsAt      *time.Time `json:"startsAt"`
+	EndsAt        *time.Time `json:"endsAt"`
+	AccessCode    string     `json:"accessCode"`
+	AllowedEmails []string   `json:"allowedEmails"`
+}
+
+func normalizeEmails(emails []string) models.StringList {
+	var list models.StringList
+	for _, email := range emails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			list = append(list, email)
+		}
+	}
+	return list
+}
+
+func (h *Handler) ListPresales(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	presales, err := h.Service.ListPresales(uint(id))
+	if err != nil {
+		log.Printf("Error listing presales: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(presales)
+}
+
+func (h *Handler) CreatePresale(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.Service.GetShowByID(uint(id)); err != nil {
+		http.Error(w, "Show not found", http.StatusNotFound)
+		return
+	}
+
+	var req PresaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" || req.StartsAt == nil || req.EndsAt == nil {
+		http.Error(w, "Name, startsAt and endsAt are required", http.StatusBadRequest)
+		return
+	}
+	if !req.EndsAt.After(*req.StartsAt) {
+		http.Error(w, "endsAt must be after startsAt", http.StatusBadRequest)
+		return
+	}
+	if req.AccessCode == "" && len(req.AllowedEmails) == 0 {
+		http.Error(w, "A presale needs an access code or an allowlist of emails", http.StatusBadRequest)
+		return
+	}
+
+	presale := models.Presale{
+		ShowID:        uint(id),
+		Name:          req.Name,
+		StartsAt:      *req.StartsAt,
+		EndsAt:        *req.EndsAt,
+		AccessCode:    req.AccessCode,
+		AllowedEmails: normalizeEmails(req.AllowedEmails),
+	}
+
+	presale, err = h.Service.SetPresale(presale)
+	if err != nil {
+		log.Printf("Error creating presale: %v", err)
+		http.Error(w, "Failed to create presale", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(presale)
+}
+
+func (h *Handler) UpdatePresale(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	presale, err := h.Service.GetPresaleByID(uint(id))
+	if err != nil {
+		http.Error(w, "Presale not found", http.StatusNotFound)
+		return
+	}
+
+	var req PresaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name != "" {
+		presale.Name = req.Name
+	}
+	if req.StartsAt != nil {
+		presale.StartsAt = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		presale.EndsAt = *req.EndsAt
+	}
+	if req.AccessCode != "" {
+		presale.AccessCode = req.AccessCode
+	}
+	if req.AllowedEmails != nil {
+		presale.AllowedEmails = normalizeEmails(req.AllowedEmails)
+	}
+	if !presale.EndsAt.After(presale.StartsAt) {
+		http.Error(w, "endsAt must be after startsAt", http.StatusBadRequest)
+		return
+	}
+
+	presale, err = h.Service.SetPresale(presale)
+	if err != nil {
+		log.Printf("Error updating presale: %v", err)
+		http.Error(w, "Failed to update presale", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(presale)
+}
+
+func (h *Handler) DeletePresale(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeletePresale(uint(id)); err != nil {
+		log.Printf("Error deleting presale: %v", err)
+		http.Error(w, "Failed to delete presale", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Presale deleted successfully"})
+}
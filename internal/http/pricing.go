@@ -0,0 +1,133 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"concert/internal/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type PriceQuoteResponse struct {
+	models.PriceBreakdown
+	TicketCount int     `json:"ticketCount"`
+	TotalPrice  float64 `json:"totalPrice"`
+}
+
+type TaxRateRequest struct {
+	Country string  `json:"country"`
+	Venue   string  `json:"venue"`
+	Name    string  `json:"name"`
+	Percent float64 `json:"percent"`
+}
+
+// GetShowPrice previews the price of ?tickets= tickets of a show with its fees and tax
+func (h *Handler) GetShowPrice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	count := 1
+	if v := r.URL.Query().Get("tickets"); v != "" {
+		if count, err = strconv.Atoi(v); err != nil || count <= 0 {
+			http.Error(w, "Invalid ticket count", http.StatusBadRequest)
+			return
+		}
+	}
+
+	show, err := h.Service.GetShowByID(uint(id))
+	if err != nil || !show.Listed(time.Now()) {
+		http.Error(w, "Show not found", http.StatusNotFound)
+		return
+	}
+
+	breakdown, err := h.Service.PriceBooking(show, count)
+	if err != nil {
+		log.Printf("Error pricing show %d: %v", show.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(PriceQuoteResponse{
+		PriceBreakdown: breakdown,
+		TicketCount:    count,
+		TotalPrice:     concert.GrossPrice(breakdown),
+	})
+}
+
+func (h *Handler) ListTaxRates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rates, err := h.Service.ListTaxRates()
+	if err != nil {
+		log.Printf("Error listing tax rates: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(rates)
+}
+
+// SetTaxRate creates the rate of a country or venue, or replaces it
+func (h *Handler) SetTaxRate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req TaxRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rate := models.TaxRate{
+		Country: strings.ToUpper(req.Country),
+		Venue:   req.Venue,
+		Name:    req.Name,
+		Percent: req.Percent,
+	}
+	if err := rate.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rate, err := h.Service.SetTaxRate(rate)
+	if err != nil {
+		log.Printf("Error saving tax rate: %v", err)
+		http.Error(w, "Failed to save tax rate", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(rate)
+}
+
+func (h *Handler) DeleteTaxRate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.Service.DeleteTaxRate(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Tax rate not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting tax rate %d: %v", id, err)
+		http.Error(w, "Failed to delete tax rate", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Tax rate deleted successfully"})
+}
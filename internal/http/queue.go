@@ -0,0 +1,66 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+func (h *Handler) JoinQueue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid show ID", http.StatusBadRequest)
+		return
+	}
+
+	status, err := h.Service.JoinQueue(uint(id), user.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Show not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, concert.ErrQueueDisabled) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error joining waiting room of show %d: %v", id, err)
+		http.Error(w, "Failed to join the waiting room", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(status)
+}
+
+func (h *Handler) GetQueueStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	status, err := h.Service.GetQueueStatus(chi.URLParam(r, "ticket"))
+	if errors.Is(err, concert.ErrQueueTicketInvalid) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting waiting room status: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// tell pollers not to come back faster than the queue moves
+	w.Header().Set("Retry-After", "5")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,196 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"concert/internal/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ResaleListingRequest struct {
+	TicketCount    int     `json:"ticketCount"`
+	PricePerTicket float64 `json:"pricePerTicket"`
+}
+
+type RemoveResaleListingRequest struct {
+	Note string `json:"note"`
+}
+
+func writeResaleError(w http.ResponseWriter, err error) {
+	var limitErr *concert.TicketLimitError
+	switch {
+	case errors.As(err, &limitErr):
+		writeTicketLimitError(w, limitErr)
+	case errors.Is(err, concert.ErrResaleUnavailable):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, concert.ErrResaleListed), errors.Is(err, concert.ErrTransferPending),
+		errors.Is(err, concert.ErrBookingNotConfirmed), errors.Is(err, concert.ErrResaleShowOver),
+		errors.Is(err, concert.ErrShowNotPublished), errors.Is(err, concert.ErrShowCancelled),
+		errors.Is(err, concert.ErrShowPostponed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, concert.ErrResaleAboveCap), errors.Is(err, concert.ErrResaleInvalidCount),
+		errors.Is(err, concert.ErrResaleOwnListing), errors.Is(err, concert.ErrFestivalPass):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Error reselling tickets: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) CreateResaleListing(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	booking, ok := h.ownBooking(w, r)
+	if !ok {
+		return
+	}
+
+	var req ResaleListingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	listing, err := h.Service.CreateResaleListing(booking, *user, req.TicketCount, req.PricePerTicket)
+	if err != nil {
+		writeResaleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(listing)
+}
+
+func (h *Handler) ListShowResaleListings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid show ID", http.StatusBadRequest)
+		return
+	}
+
+	listings, err := h.Service.ListResaleListings(uint(id), models.ResaleActive)
+	if err != nil {
+		log.Printf("Error listing resale listings of show %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(listings)
+}
+
+func (h *Handler) CancelResaleListing(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.CancelResaleListing(uint(id), *user); err != nil {
+		writeResaleError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Listing deleted successfully"})
+}
+
+// BuyResaleListing books the tickets of a listing, paid through the regular checkout
+func (h *Handler) BuyResaleListing(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	booking, err := h.Service.BuyResaleListing(uint(id), *user)
+	if err != nil {
+		writeResaleError(w, err)
+		return
+	}
+
+	payment, intent, err := h.Service.StartPayment(booking)
+	if err != nil {
+		log.Printf("Error starting payment of booking %d: %v", booking.ID, err)
+		if err := h.Service.ReleaseBooking(booking.ID, err.Error()); err != nil {
+			log.Printf("Error releasing booking %d: %v", booking.ID, err)
+		}
+		http.Error(w, "Failed to start the payment", http.StatusBadGateway)
+		return
+	}
+
+	if err := h.Db.Preload("Show.Artist").First(&booking, booking.ID).Error; err != nil {
+		log.Printf("Error reloading booking: %v", err)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateBookingResponse{
+		Booking: booking,
+		Payment: &PaymentResponse{Payment: payment, ClientSecret: intent.ClientSecret},
+	})
+}
+
+func (h *Handler) ListAllResaleListings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	listings, err := h.Service.ListResaleListings(0, r.URL.Query().Get("status"))
+	if err != nil {
+		log.Printf("Error listing resale listings: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(listings)
+}
+
+// RemoveResaleListing takes down a listing, the reason is kept on it for the seller
+func (h *Handler) RemoveResaleListing(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req RemoveResaleListingRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.Service.RemoveResaleListing(uint(id), req.Note); err != nil {
+		writeResaleError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Listing deleted successfully"})
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"go.temporal.io/sdk/client"
@@ -19,14 +20,20 @@ func NewRouter(service concert.ConcertService, db *gorm.DB) (*Handler, error) {
 	if err != nil {
 		return nil, err
 	}
+	maxPerShow, _ := strconv.Atoi(utils.GetEnvOrDefault("SSE_MAX_CLIENTS_PER_SHOW", "500"))
+	maxTotal, _ := strconv.Atoi(utils.GetEnvOrDefault("SSE_MAX_CLIENTS", "5000"))
 	return &Handler{
 		Service:        service,
 		Db:             db,
 		TemporalClient: tmpClient,
+		Hub:            concert.NewHub(maxPerShow, maxTotal),
 	}, nil
 }
 
 func (h *Handler) Close() {
+	if h.Hub != nil {
+		h.Hub.Close()
+	}
 	if h.TemporalClient != nil {
 		h.TemporalClient.Close()
 	}
@@ -38,23 +45,77 @@ func (h *Handler) ChiSetRoutes() {
 	h.Route.Group(func(r chi.Router) {
 		r.Use(RateLimit)
 		r.Get("/api/health", h.HealthCheck)
+		// authenticated by their signature, the provider retries what is rate limited
+		r.Post("/api/public/payments/webhook", h.PaymentWebhook)
 		r.Post("/api/public/register", h.RegisterAPI)
 		r.Post("/api/public/login", h.LoginAPI)
 
 		r.Post("/api/public/forget", h.ForgetPassword)
 		r.Get("/api/public/shows", h.ListAllShow)
+		r.Get("/api/public/shows/nearby", h.ShowsNearby)
+		r.Get("/api/public/search", h.Search)
 		r.Get("/api/public/shows/{id}", h.GetShowPublic)
+		r.Get("/api/public/shows/{id}/events", h.ShowEvents)
+		r.Get("/api/public/queue/{ticket}", h.GetQueueStatus)
+		r.Get("/api/public/transfers/{token}", h.GetTransferInvite)
+		r.Post("/api/public/transfers/accept", h.AcceptTransfer)
+		r.Get("/api/public/shows/{id}/resale", h.ListShowResaleListings)
+		r.Get("/api/public/shows/{id}/price", h.GetShowPrice)
 		r.Get("/api/public/artists/{id}", h.GetArtistPublic)
 		r.Get("/api/public/artists", h.ListAllArtists)
+		r.Get("/api/public/venues", h.ListVenues)
+		r.Get("/api/public/venues/{id}", h.GetVenue)
+		r.Get("/api/public/festivals", h.ListFestivals)
+		r.Get("/api/public/festivals/{id}", h.GetFestival)
+		r.Get("/api/public/tours", h.ListTours)
+		r.Get("/api/public/tours/{id}", h.GetTour)
 
 	})
 
 	h.Route.Group(func(r chi.Router) {
 		r.Use(NeedsAuth(h.Db))
 
+		r.Post("/api/shows/{id}/queue", h.JoinQueue)
 		r.Post("/api/bookings", h.CreateBooking)
 		r.Get("/api/bookings", h.GetMyBookings)
 		r.Delete("/api/bookings/{id}", h.CancelBooking)
+		r.Get("/api/bookings/{id}/tickets", h.GetBookingTickets)
+		r.Get("/api/bookings/{id}/tickets/{ticketId}/qr.png", h.GetTicketQRCode)
+		r.Get("/api/bookings/{id}/tickets.pdf", h.GetTicketsPDF)
+		r.Get("/api/bookings/{id}/receipt.pdf", h.GetReceiptPDF)
+		r.Get("/api/bookings/{id}/payments", h.GetBookingPayments)
+		r.Get("/api/bookings/{id}/refund-quote", h.GetRefundQuote)
+		r.Post("/api/bookings/{id}/transfer", h.CreateTransfer)
+		r.Get("/api/bookings/{id}/transfers", h.ListBookingTransfers)
+		r.Delete("/api/transfers/{id}", h.CancelTransfer)
+		r.Post("/api/bookings/{id}/resale", h.CreateResaleListing)
+		r.Delete("/api/resale/{id}", h.CancelResaleListing)
+		r.Post("/api/resale/{id}/buy", h.BuyResaleListing)
+		r.Get("/api/credit", h.GetMyCredit)
+		r.Get("/api/gift-cards", h.ListMyGiftCards)
+		r.Post("/api/gift-cards", h.BuyGiftCard)
+		r.Post("/api/gift-cards/redeem", h.RedeemGiftCard)
+		r.Post("/api/artists/{id}/follow", h.FollowArtist)
+		r.Delete("/api/artists/{id}/follow", h.UnfollowArtist)
+		r.Get("/api/me/following", h.GetFollowing)
+		r.Put("/api/me/show-alerts", h.SetShowAlerts)
+
+		if utils.DevMode() {
+			// anyone could pay for free with these, they only exist in development
+			r.Post("/api/bookings/{id}/pay/mock", h.SimulatePayment)
+			r.Post("/api/gift-cards/{id}/pay/mock", h.SimulateGiftCardPayment)
+		}
+	})
+
+	h.Route.Group(func(r chi.Router) {
+		r.Use(NeedsAuth(h.Db))
+		r.Use(NeedsRole(h.Db, "staff", "admin"))
+
+		r.Post("/api/checkin", h.CheckIn)
+		r.Get("/api/checkin/manifest-key", h.GetManifestKey)
+		r.Get("/api/checkin/shows/{id}/manifest", h.GetCheckInManifest)
+		r.Post("/api/checkin/shows/{id}/sync", h.SyncScans)
+		r.Get("/api/checkin/shows/{id}/scans", h.ListScanEvents)
 	})
 
 	h.Route.Group(func(r chi.Router) {
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"concert/internal/concert"
 	"concert/internal/models"
 	"concert/test/mocks"
 	"fmt"
@@ -24,6 +25,14 @@ func SetupTestDB() *gorm.DB {
 	return db
 }
 
+// newTestHandler routes the requests to service without Temporal, which the tests don't
+// run: the workflows a handler starts are skipped.
+func newTestHandler(service concert.ConcertService, db *gorm.DB) *Handler {
+	handler := &Handler{Service: service, Db: db, Hub: concert.NewHub(500, 5000)}
+	handler.ChiSetRoutes()
+	return handler
+}
+
 func TestHandler_GetFan(t *testing.T) {
 	mockService := mocks.MockConcertService{
 		GetFanFunc: func(name string) ([]models.Booking, error) {
@@ -35,9 +44,7 @@ func TestHandler_GetFan(t *testing.T) {
 		},
 	}
 
-	handler, err := NewRouter(&mockService, nil)
-	assert.Nil(t, err)
-	handler.ChiSetRoutes()
+	handler := newTestHandler(&mockService, nil)
 
 	request := httptest.NewRequest("GET", "/fan/Abdou", nil)
 	response := httptest.NewRecorder()
@@ -46,23 +53,40 @@ func TestHandler_GetFan(t *testing.T) {
 }
 
 func TestHandler_ListAllShow(t *testing.T) {
+	var got concert.ShowQuery
 	mockService := mocks.MockConcertService{
-		ListAllShowFunc: func() ([]models.Show, error) {
-			return []models.Show{
-				{Venue: "Paris", Date: time.Now()},
-				{Venue: "Marseille", Date: time.Now()},
+		SearchShowsFunc: func(query concert.ShowQuery) (concert.ShowPage, error) {
+			got = query
+			return concert.ShowPage{
+				Items: []models.Show{
+					{Venue: "Paris", StartsAt: time.Now()},
+					{Venue: "Marseille", StartsAt: time.Now()},
+				},
+				Total: 2,
+				Limit: 20,
 			}, nil
 		},
 	}
-	handler, err := NewRouter(&mockService, nil)
-	assert.Nil(t, err)
-	handler.ChiSetRoutes()
-	request := httptest.NewRequest("GET", "/api/public/shows", nil)
+	handler := newTestHandler(&mockService, nil)
+	request := httptest.NewRequest("GET", "/api/public/shows?q=jazz&genre=Jazz&to=2026-06-30&maxPrice=40&available=true&sort=-price", nil)
 	response := httptest.NewRecorder()
 	handler.Route.ServeHTTP(response, request)
 	assert.Equal(t, http.StatusOK, response.Code)
 	assert.True(t, strings.Contains(response.Body.String(), "Paris"))
 	assert.True(t, strings.Contains(response.Body.String(), "Marseille"))
+	assert.True(t, strings.Contains(response.Body.String(), `"total":2`))
+
+	assert.Equal(t, "jazz", got.Text)
+	assert.Equal(t, "Jazz", got.Genre)
+	assert.Equal(t, "2026-07-01", got.To.Format("2006-01-02"))
+	assert.Equal(t, 40.0, *got.MaxPrice)
+	assert.True(t, got.AvailableOnly)
+	assert.Equal(t, "-price", got.Sort)
+
+	request = httptest.NewRequest("GET", "/api/public/shows?minPrice=cheap", nil)
+	response = httptest.NewRecorder()
+	handler.Route.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
 }
 
 func TestHandler_GetMyBookings(t *testing.T) {
@@ -78,9 +102,7 @@ func TestHandler_GetMyBookings(t *testing.T) {
 			}, nil
 		},
 	}
-	handler, err := NewRouter(&mockService, db)
-	assert.Nil(t, err)
-	handler.ChiSetRoutes()
+	handler := newTestHandler(&mockService, db)
 	request := httptest.NewRequest("GET", "/bookings", nil)
 	response := httptest.NewRecorder()
 	handler.Route.ServeHTTP(response, request)
@@ -97,9 +119,7 @@ func TestHandler_SetArtist(t *testing.T) {
 			return models.Artist{Name: "Drake", Genre: "Rock", ImageURL: "https://example.com/photo.jpg", AlbumURL: "https://example.com/album.jpg"}, nil
 		},
 	}
-	handler, err := NewRouter(&mockService, db)
-	assert.Nil(t, err)
-	handler.ChiSetRoutes()
+	handler := newTestHandler(&mockService, db)
 
 	request := httptest.NewRequest("POST", "/api/admin/artists", nil)
 	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", admin.ID)})
@@ -115,12 +135,10 @@ func TestHandler_CreateShow(t *testing.T) {
 
 	mockService := mocks.MockConcertService{
 		SetShowFunc: func(show models.Show) (models.Show, error) {
-			return models.Show{Venue: "Paris", Date: time.Now()}, nil
+			return models.Show{Venue: "Paris", StartsAt: time.Now()}, nil
 		},
 	}
-	handler, err := NewRouter(&mockService, db)
-	assert.Nil(t, err)
-	handler.ChiSetRoutes()
+	handler := newTestHandler(&mockService, db)
 
 	request := httptest.NewRequest("POST", "/api/admin/shows", nil)
 	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", admin.ID)})
@@ -136,15 +154,13 @@ func TestHandler_UpdateShow(t *testing.T) {
 
 	mockService := mocks.MockConcertService{
 		GetShowByIDFunc: func(id uint) (models.Show, error) {
-			return models.Show{Venue: "Paris", Date: time.Now()}, nil
+			return models.Show{Venue: "Paris", StartsAt: time.Now()}, nil
 		},
 		SetShowFunc: func(show models.Show) (models.Show, error) {
-			return models.Show{Venue: "Paris", Date: time.Now()}, nil
+			return models.Show{Venue: "Paris", StartsAt: time.Now()}, nil
 		},
 	}
-	handler, err := NewRouter(&mockService, db)
-	assert.Nil(t, err)
-	handler.ChiSetRoutes()
+	handler := newTestHandler(&mockService, db)
 
 	request := httptest.NewRequest("PUT", "/api/admin/shows/1", nil)
 	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", admin.ID)})
@@ -160,7 +176,7 @@ func TestHandler_DeleteShow(t *testing.T) {
 
 	mockService := mocks.MockConcertService{
 		GetShowByIDFunc: func(id uint) (models.Show, error) {
-			return models.Show{Venue: "Paris", Date: time.Now()}, nil
+			return models.Show{Venue: "Paris", StartsAt: time.Now()}, nil
 		},
 		CountConfirmSeatsFunc: func(id uint) int64 {
 			return 0
@@ -169,9 +185,7 @@ func TestHandler_DeleteShow(t *testing.T) {
 			return nil
 		},
 	}
-	handler, err := NewRouter(&mockService, db)
-	assert.Nil(t, err)
-	handler.ChiSetRoutes()
+	handler := newTestHandler(&mockService, db)
 
 	request := httptest.NewRequest("DELETE", "/api/admin/shows/1", nil)
 	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", admin.ID)})
@@ -190,9 +204,7 @@ func TestHandler_CreateArtist(t *testing.T) {
 			return models.Artist{Name: "Drake", Genre: "Rock", ImageURL: "https://example.com/photo.jpg", AlbumURL: "https://example.com/album.jpg"}, nil
 		},
 	}
-	handler, err := NewRouter(&mockService, db)
-	assert.Nil(t, err)
-	handler.ChiSetRoutes()
+	handler := newTestHandler(&mockService, db)
 
 	request := httptest.NewRequest("POST", "/api/admin/artists", nil)
 	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", admin.ID)})
@@ -200,3 +212,218 @@ func TestHandler_CreateArtist(t *testing.T) {
 	handler.Route.ServeHTTP(response, request)
 	assert.Equal(t, http.StatusBadRequest, response.Code)
 }
+
+func TestHandler_CreateVenue(t *testing.T) {
+	admin := models.User{Email: "admin@test.com", Username: "admin", PasswordHash: "x", Role: "admin"}
+	db := SetupTestDB()
+	db.Create(&admin)
+
+	mockService := mocks.MockConcertService{
+		CreateVenueFunc: func(venue models.Venue) (models.Venue, error) {
+			venue.ID = 1
+			return venue, nil
+		},
+	}
+	handler := newTestHandler(&mockService, db)
+
+	request := httptest.NewRequest("POST", "/api/admin/venues", strings.NewReader(`{"name":"Olympia","timezone":"Paris"}`))
+	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", admin.ID)})
+	response := httptest.NewRecorder()
+	handler.Route.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	request = httptest.NewRequest("POST", "/api/admin/venues", strings.NewReader(`{"name":"Olympia","city":"Paris","timezone":"Europe/Paris","capacity":2000}`))
+	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", admin.ID)})
+	response = httptest.NewRecorder()
+	handler.Route.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusCreated, response.Code)
+}
+
+func TestHandler_DeleteVenue(t *testing.T) {
+	admin := models.User{Email: "admin@test.com", Username: "admin", PasswordHash: "x", Role: "admin"}
+	db := SetupTestDB()
+	db.Create(&admin)
+
+	mockService := mocks.MockConcertService{
+		DeleteVenueFunc: func(id uint) error {
+			return concert.ErrVenueInUse
+		},
+	}
+	handler := newTestHandler(&mockService, db)
+
+	request := httptest.NewRequest("DELETE", "/api/admin/venues/1", nil)
+	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", admin.ID)})
+	response := httptest.NewRecorder()
+	handler.Route.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusConflict, response.Code)
+}
+
+func TestHandler_GetArtistPublic(t *testing.T) {
+	mockService := mocks.MockConcertService{
+		GetArtistByIDFunc: func(id uint) (models.Artist, error) {
+			artist := models.Artist{Name: "Support"}
+			artist.ID = id
+			return artist, nil
+		},
+		GetArtistAppearancesFunc: func(artistID uint) ([]concert.Appearance, error) {
+			return []concert.Appearance{{Show: models.Show{Title: "Headline night"}, Role: models.LineupSupport, Position: 2}}, nil
+		},
+	}
+	handler := newTestHandler(&mockService, nil)
+
+	request := httptest.NewRequest("GET", "/api/public/artists/3", nil)
+	response := httptest.NewRecorder()
+	handler.Route.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"role":"support"`)
+}
+
+func TestHandler_SetShowLineup(t *testing.T) {
+	admin := models.User{Email: "admin@test.com", Username: "admin", PasswordHash: "x", Role: "admin"}
+	db := SetupTestDB()
+	db.Create(&admin)
+
+	var got []concert.LineupEntry
+	mockService := mocks.MockConcertService{
+		SetLineupFunc: func(showID uint, entries []concert.LineupEntry) (models.Show, error) {
+			got = entries
+			if len(entries) == 1 {
+				return models.Show{}, fmt.Errorf("%w: a show needs a headliner", concert.ErrInvalidLineup)
+			}
+			return models.Show{}, nil
+		},
+	}
+	handler := newTestHandler(&mockService, db)
+
+	request := httptest.NewRequest("PUT", "/api/admin/shows/1/lineup", strings.NewReader(`{"lineup":[{"artistId":1,"role":"support"}]}`))
+	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", admin.ID)})
+	response := httptest.NewRecorder()
+	handler.Route.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	request = httptest.NewRequest("PUT", "/api/admin/shows/1/lineup", strings.NewReader(`{"lineup":[{"artistId":1},{"artistId":2,"setStartsAt":"2030-06-21T19:00"}]}`))
+	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", admin.ID)})
+	response = httptest.NewRecorder()
+	handler.Route.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "2030-06-21T19:00", got[1].SetStartsAt)
+}
+
+func TestHandler_CreateTourShows(t *testing.T) {
+	admin := models.User{Email: "admin@test.com", Username: "admin", PasswordHash: "x", Role: "admin"}
+	db := SetupTestDB()
+	db.Create(&admin)
+
+	var gotTemplate concert.TourTemplate
+	var gotDates []concert.TourDate
+	mockService := mocks.MockConcertService{
+		CreateTourShowsFunc: func(tourID uint, template concert.TourTemplate, dates []concert.TourDate) ([]models.Show, error) {
+			gotTemplate, gotDates = template, dates
+			return make([]models.Show, len(dates)), nil
+		},
+	}
+	handler := newTestHandler(&mockService, db)
+
+	body := `{"template":{"title":"Residency","time":"21:00","totalSeats":300},"doorsBeforeMinutes":60,
+		"dates":[{"date":"2030-01-02","venue":"Olympia"}],
+		"recurrence":{"frequency":"weekly","weekdays":["fri","Saturday"],"from":"2030-01-03","count":2,"venue":"Bataclan"}}`
+	request := httptest.NewRequest("POST", "/api/admin/tours/1/shows", strings.NewReader(body))
+	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", admin.ID)})
+	response := httptest.NewRecorder()
+	handler.Route.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusCreated, response.Code)
+	assert.Equal(t, 300, gotTemplate.Show.TotalSeats)
+	assert.Equal(t, time.Hour, gotTemplate.DoorsBefore)
+	if assert.Len(t, gotDates, 3) {
+		assert.Equal(t, "2030-01-04", gotDates[1].Date)
+		assert.Equal(t, "Bataclan", gotDates[2].Venue)
+	}
+
+	request = httptest.NewRequest("POST", "/api/admin/tours/1/shows", strings.NewReader(`{"recurrence":{"frequency":"weekly","weekdays":["someday"],"from":"2030-01-03","count":2}}`))
+	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", admin.ID)})
+	response = httptest.NewRecorder()
+	handler.Route.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestHandler_SetShowStatus(t *testing.T) {
+	admin := models.User{Email: "admin@test.com", Username: "admin", PasswordHash: "x", Role: "admin"}
+	db := SetupTestDB()
+	db.Create(&admin)
+
+	var got concert.ShowStatusChange
+	mockService := mocks.MockConcertService{
+		SetShowStatusFunc: func(showID uint, change concert.ShowStatusChange, now time.Time) (models.Show, error) {
+			got = change
+			if change.Status == models.ShowPublished {
+				return models.Show{}, fmt.Errorf("%w: it is cancelled", concert.ErrShowStatusTransition)
+			}
+			return models.Show{Status: change.Status, StatusReason: change.Reason}, nil
+		},
+		GetShowByIDFunc: func(id uint) (models.Show, error) {
+			return models.Show{Title: "Secret", Status: models.ShowDraft}, nil
+		},
+	}
+	handler := newTestHandler(&mockService, db)
+
+	request := httptest.NewRequest("PUT", "/api/admin/shows/1/status", strings.NewReader(`{"status":"cancelled","reason":"Illness"}`))
+	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", admin.ID)})
+	response := httptest.NewRecorder()
+	handler.Route.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "Illness", got.Reason)
+
+	request = httptest.NewRequest("PUT", "/api/admin/shows/1/status", strings.NewReader(`{"status":"published"}`))
+	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", admin.ID)})
+	response = httptest.NewRecorder()
+	handler.Route.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusConflict, response.Code)
+
+	// drafts are not public
+	for _, path := range []string{"/api/public/shows/1", "/api/public/shows/1/price", "/api/public/shows/1/events"} {
+		response = httptest.NewRecorder()
+		handler.Route.ServeHTTP(response, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusNotFound, response.Code, path)
+	}
+}
+
+func TestHandler_FollowArtist(t *testing.T) {
+	fan := models.User{Email: "fan@test.com", Username: "fan", PasswordHash: "x"}
+	db := SetupTestDB()
+	db.Create(&fan)
+
+	var limit int
+	mockService := mocks.MockConcertService{
+		FollowArtistFunc: func(userID, artistID uint) (models.Follow, error) {
+			if artistID != 1 {
+				return models.Follow{}, gorm.ErrRecordNotFound
+			}
+			return models.Follow{UserID: userID, ArtistID: artistID}, nil
+		},
+		GetFollowingFeedFunc: func(userID uint, l int, now time.Time) (concert.FollowingFeed, error) {
+			limit = l
+			return concert.FollowingFeed{Artists: []models.Artist{{Name: "Band"}}}, nil
+		},
+	}
+	handler := newTestHandler(&mockService, db)
+
+	request := httptest.NewRequest("POST", "/api/artists/2/follow", nil)
+	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", fan.ID)})
+	response := httptest.NewRecorder()
+	handler.Route.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	request = httptest.NewRequest("POST", "/api/artists/1/follow", nil)
+	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", fan.ID)})
+	response = httptest.NewRecorder()
+	handler.Route.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	request = httptest.NewRequest("GET", "/api/me/following?limit=5", nil)
+	request.AddCookie(&http.Cookie{Name: "session_concert", Value: fmt.Sprintf("user_%d", fan.ID)})
+	response = httptest.NewRecorder()
+	handler.Route.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, 5, limit)
+	assert.Contains(t, response.Body.String(), `"name":"Band"`)
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// Search finds artists and shows for ?q=, upcoming shows only unless ?past=true
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := h.Service.Search(query.Get("q"), query.Get("past") == "true", limit)
+	if errors.Is(err, concert.ErrEmptySearch) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error searching %q: %v", query.Get("q"), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(results)
+}
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type SettlementPaidRequest struct {
+	Reference string `json:"reference"`
+}
+
+func writeSettlementError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Settlement not found", http.StatusNotFound)
+	case errors.Is(err, concert.ErrSettlementStatus), errors.Is(err, concert.ErrShowNotOver):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Error handling settlement: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// writeSettlementCSV writes the statement as item,value lines for the accounting tools
+func writeSettlementCSV(w http.ResponseWriter, statement concert.SettlementStatement) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=settlement-show-%d.csv", statement.ShowID))
+
+	writer := csv.NewWriter(w)
+	writer.WriteAll([][]string{
+		{"item", "value"},
+		{"show", statement.ShowTitle},
+		{"date", statement.ShowDate.Format("2006-01-02")},
+		{"artist", statement.ArtistName},
+		{"status", statement.Status},
+		{"gross sales", formatAmount(statement.GrossSales)},
+		{"refunds", formatAmount(statement.Refunds)},
+		{"fees", formatAmount(statement.Fees)},
+		{"taxes", formatAmount(statement.Taxes)},
+		{"net receipts", formatAmount(statement.NetReceipts)},
+		{"split percent", strconv.Itoa(statement.SplitPercent)},
+		{"guarantee", formatAmount(statement.Guarantee)},
+		{"artist share", formatAmount(statement.ArtistShare)},
+		{"promoter net", formatAmount(statement.PromoterNet)},
+		{"approved at", formatTime(statement.ApprovedAt)},
+		{"paid at", formatTime(statement.PaidAt)},
+		{"payment reference", statement.PaymentRef},
+	})
+	if err := writer.Error(); err != nil {
+		log.Printf("Error writing settlement of show %d: %v", statement.ShowID, err)
+	}
+}
+
+// GetSettlement produces the settlement statement of a show, as CSV with ?format=csv
+func (h *Handler) GetSettlement(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	statement, err := h.Service.GetSettlement(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Show not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeSettlementError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		writeSettlementCSV(w, statement)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statement)
+}
+
+func (h *Handler) ListSettlements(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	settlements, err := h.Service.ListSettlements(r.URL.Query().Get("status"))
+	if err != nil {
+		writeSettlementError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(settlements)
+}
+
+func (h *Handler) ApproveSettlement(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	admin, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	settlement, err := h.Service.ApproveSettlement(uint(id), *admin, time.Now())
+	if err != nil {
+		writeSettlementError(w, err)
+		return
+	}
+
+	log.Printf("Settlement %d approved by user %d", settlement.ID, admin.ID)
+	json.NewEncoder(w).Encode(settlement)
+}
+
+func (h *Handler) MarkSettlementPaid(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req SettlementPaidRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settlement, err := h.Service.MarkSettlementPaid(uint(id), req.Reference, time.Now())
+	if err != nil {
+		writeSettlementError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(settlement)
+}
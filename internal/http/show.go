@@ -1,9 +1,11 @@
 package http
 
 import (
+	"concert/internal/concert"
 	"concert/internal/models"
 
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -16,16 +18,49 @@ func (h *Handler) ListAllShow(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	shows, err := h.Service.ListAllShow()
+	query, err := parseShowQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.Service.SearchShows(query)
+	if errors.Is(err, concert.ErrInvalidCursor) || errors.Is(err, concert.ErrInvalidSort) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		log.Printf("Error listing shows: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
+	json.NewEncoder(w).Encode(page)
+
+}
 
-	json.NewEncoder(w).Encode(shows)
+// ShowsNearby lists the shows around ?lat=&lng= within ?radius= km, nearest first
+func (h *Handler) ShowsNearby(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query, err := parseNearbyQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.Service.ShowsNearby(query)
+	if errors.Is(err, concert.ErrInvalidLocation) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error listing shows nearby: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
 
+	json.NewEncoder(w).Encode(page)
 }
 
 func (h *Handler) GetShowPublic(w http.ResponseWriter, r *http.Request) {
@@ -44,6 +79,10 @@ func (h *Handler) GetShowPublic(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Could not get the show by ID", http.StatusBadRequest)
 		return
 	}
+	if !show.Listed(time.Now()) {
+		http.Error(w, "Show not found", http.StatusNotFound)
+		return
+	}
 	// Calculate available seats
 
 	json.NewEncoder(w).Encode(show)
@@ -89,13 +128,6 @@ func (h *Handler) SetShow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	date, err := time.Parse("2006-01-02T15:04", dateStr)
-	if err != nil {
-		log.Printf("Error parsing date '%s': %v", dateStr, err)
-		http.Error(w, "Invalid date or time format. Please select both date and time.", http.StatusBadRequest)
-		return
-	}
-
 	Venue := r.FormValue("Venue")
 	if Venue == "" {
 		http.Error(w, "Venue is required", http.StatusBadRequest)
@@ -103,10 +135,19 @@ func (h *Handler) SetShow(w http.ResponseWriter, r *http.Request) {
 	}
 
 	show := models.Show{
-		Date:     date,
 		ArtistID: uint(artistID),
 		Venue:    Venue,
 	}
+	if err := h.Service.ApplyVenue(&show); err != nil {
+		writeVenueError(w, err)
+		return
+	}
+	// the form sends the wall clock time of the venue
+	if err := concert.SetShowTimes(&show, concert.ShowTimesInput{StartsAt: dateStr}); err != nil {
+		log.Printf("Error parsing date '%s': %v", dateStr, err)
+		http.Error(w, "Invalid date or time format. Please select both date and time.", http.StatusBadRequest)
+		return
+	}
 
 	_, err = h.Service.SetShow(show)
 	if err != nil {
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// parseShowQuery reads the filters of the show listing from the query string.
+// Dates are YYYY-MM-DD and both ends of the range are included.
+func parseShowQuery(r *http.Request) (concert.ShowQuery, error) {
+	values := r.URL.Query()
+	q := concert.ShowQuery{
+		Text:          values.Get("q"),
+		Genre:         values.Get("genre"),
+		Venue:         values.Get("venue"),
+		City:          values.Get("city"),
+		AvailableOnly: values.Get("available") == "true",
+		IncludePast:   values.Get("past") == "true",
+		Sort:          values.Get("sort"),
+		Cursor:        values.Get("cursor"),
+	}
+	if v := values.Get("artistId"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return q, errors.New("invalid artistId")
+		}
+		q.ArtistID = uint(id)
+	}
+	if v := values.Get("venueId"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return q, errors.New("invalid venueId")
+		}
+		q.VenueID = uint(id)
+	}
+	if v := values.Get("festivalId"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return q, errors.New("invalid festivalId")
+		}
+		q.FestivalID = uint(id)
+	}
+	if v := values.Get("tourId"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return q, errors.New("invalid tourId")
+		}
+		q.TourID = uint(id)
+	}
+	if v := values.Get("from"); v != "" {
+		date, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return q, errors.New("invalid date format. Use YYYY-MM-DD")
+		}
+		q.From = date
+	}
+	if v := values.Get("to"); v != "" {
+		date, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return q, errors.New("invalid date format. Use YYYY-MM-DD")
+		}
+		q.To = date.AddDate(0, 0, 1)
+	}
+	for key, target := range map[string]**float64{"minPrice": &q.MinPrice, "maxPrice": &q.MaxPrice} {
+		if v := values.Get(key); v != "" {
+			price, err := strconv.ParseFloat(v, 64)
+			if err != nil || price < 0 {
+				return q, errors.New("invalid " + key)
+			}
+			*target = &price
+		}
+	}
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return q, errors.New("invalid limit")
+		}
+		q.Limit = limit
+	}
+	return q, nil
+}
+
+// parseNearbyQuery reads the point and radius of a nearby search on top of the filters of
+// the show listing. lat and lng are required, radius is in km.
+func parseNearbyQuery(r *http.Request) (concert.NearbyQuery, error) {
+	showQuery, err := parseShowQuery(r)
+	if err != nil {
+		return concert.NearbyQuery{}, err
+	}
+	q := concert.NearbyQuery{ShowQuery: showQuery}
+	values := r.URL.Query()
+	for key, target := range map[string]*float64{"lat": &q.Latitude, "lng": &q.Longitude, "radius": &q.RadiusKm} {
+		v := values.Get(key)
+		if v == "" {
+			if key == "radius" {
+				continue
+			}
+			return q, errors.New(key + " is required")
+		}
+		value, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return q, errors.New("invalid " + key)
+		}
+		*target = value
+	}
+	return q, nil
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"concert/internal/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type ShowStatusRequest struct {
+	Status    string     `json:"status"`
+	Reason    string     `json:"reason"`
+	PublishAt *time.Time `json:"publishAt"`
+}
+
+// SetShowStatus publishes, schedules, postpones or cancels a show. Cancelled and postponed
+// shows need a reason, shown to the fans. A show is announced to the followers of its
+// artists when it is first published.
+func (h *Handler) SetShowStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ShowStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.Service.GetShowByID(uint(id))
+	if err != nil {
+		http.Error(w, "Show not found", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	show, err := h.Service.SetShowStatus(uint(id), concert.ShowStatusChange(req), now)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Show not found", http.StatusNotFound)
+		return
+	case errors.Is(err, concert.ErrInvalidShowStatus):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, concert.ErrShowStatusTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("Error setting the status of show %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Show %d is now %s", show.ID, show.Status)
+	h.publishEvent(models.EventShowUpdated, show)
+	if !before.Listed(now) && show.Status != models.ShowCancelled {
+		// a show the public didn't see yet is announced, when it is published
+		h.sendShowAlert(show)
+	}
+	if show.Status == models.ShowCancelled {
+		h.Hub.Publish(concert.AvailabilityEvent{Type: concert.ShowCancelled, ShowID: show.ID})
+	}
+
+	json.NewEncoder(w).Encode(show)
+}
@@ -0,0 +1,297 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"concert/internal/models"
+	"concert/internal/qrcode"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type TicketResponse struct {
+	models.Ticket
+	QRCode string `json:"qrCode"`
+}
+
+type CheckInRequest struct {
+	Code   string `json:"code"`
+	Gate   string `json:"gate"`
+	ShowID uint   `json:"showId"`
+}
+
+// ownBooking loads a booking of the current user, writing the error response itself
+func (h *Handler) ownBooking(w http.ResponseWriter, r *http.Request) (models.Booking, bool) {
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return models.Booking{}, false
+	}
+
+	bookingID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+		return models.Booking{}, false
+	}
+
+	booking, err := h.Service.GetBookingById(uint(bookingID))
+	if err != nil || booking.ID == 0 {
+		http.Error(w, "Booking not found", http.StatusNotFound)
+		return models.Booking{}, false
+	}
+	if booking.UserID != user.ID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return models.Booking{}, false
+	}
+	return booking, true
+}
+
+func (h *Handler) ownConfirmedBooking(w http.ResponseWriter, r *http.Request) (models.Booking, bool) {
+	booking, ok := h.ownBooking(w, r)
+	if !ok {
+		return booking, false
+	}
+	if booking.Status != "confirmed" {
+		http.Error(w, "Tickets are only available for confirmed bookings", http.StatusConflict)
+		return models.Booking{}, false
+	}
+	return booking, true
+}
+
+func (h *Handler) GetBookingTickets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	booking, ok := h.ownConfirmedBooking(w, r)
+	if !ok {
+		return
+	}
+
+	tickets, err := h.Service.IssueTickets(booking)
+	if err != nil {
+		log.Printf("Error issuing tickets for booking %d: %v", booking.ID, err)
+		http.Error(w, "Failed to get tickets", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]TicketResponse, len(tickets))
+	for i, ticket := range tickets {
+		q, err := qrcode.Encode(ticket.Code)
+		if err != nil {
+			log.Printf("Error encoding QR code of ticket %d: %v", ticket.ID, err)
+			http.Error(w, "Failed to render tickets", http.StatusInternalServerError)
+			return
+		}
+		png, err := q.PNG(6)
+		if err != nil {
+			http.Error(w, "Failed to render tickets", http.StatusInternalServerError)
+			return
+		}
+		responses[i] = TicketResponse{
+			Ticket: ticket,
+			QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+		}
+	}
+
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *Handler) GetTicketQRCode(w http.ResponseWriter, r *http.Request) {
+	booking, ok := h.ownConfirmedBooking(w, r)
+	if !ok {
+		return
+	}
+
+	ticketID, err := strconv.Atoi(chi.URLParam(r, "ticketId"))
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	tickets, err := h.Service.IssueTickets(booking)
+	if err != nil {
+		log.Printf("Error issuing tickets for booking %d: %v", booking.ID, err)
+		http.Error(w, "Failed to get tickets", http.StatusInternalServerError)
+		return
+	}
+
+	for _, ticket := range tickets {
+		if ticket.ID != uint(ticketID) {
+			continue
+		}
+		q, err := qrcode.Encode(ticket.Code)
+		if err != nil {
+			http.Error(w, "Failed to render ticket", http.StatusInternalServerError)
+			return
+		}
+		png, err := q.PNG(8)
+		if err != nil {
+			http.Error(w, "Failed to render ticket", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.Write(png)
+		return
+	}
+
+	http.Error(w, "Ticket not found", http.StatusNotFound)
+}
+
+func (h *Handler) CheckIn(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	staff, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CheckInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Code == "" || req.Gate == "" || req.ShowID == 0 {
+		http.Error(w, "Code, gate and show ID are required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.Service.CheckIn(req.Code, req.Gate, req.ShowID, staff.ID)
+	var already *concert.AlreadyCheckedInError
+	switch {
+	case errors.Is(err, concert.ErrTicketInvalid):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"result": "rejected", "error": err.Error()})
+		return
+	case errors.As(err, &already):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]any{"result": "duplicate", "error": err.Error(), "ticket": already.Ticket})
+		return
+	case errors.Is(err, concert.ErrTicketVoid), errors.Is(err, concert.ErrBookingCancelled), errors.Is(err, concert.ErrTicketWrongShow):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]any{"result": "rejected", "error": err.Error(), "ticket": result.Ticket})
+		return
+	case err != nil:
+		log.Printf("Error checking in ticket: %v", err)
+		http.Error(w, "Failed to check in ticket", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Ticket %d checked in at gate %s by %s", result.Ticket.ID, req.Gate, staff.Username)
+
+	json.NewEncoder(w).Encode(map[string]any{"result": "admitted", "checkIn": result})
+}
+
+type SyncScansRequest struct {
+	DeviceID string                `json:"deviceId"`
+	Scans    []concert.OfflineScan `json:"scans"`
+}
+
+// maxScansPerSync bounds the work done in a single reconciliation transaction
+const maxScansPerSync = 5000
+
+func (h *Handler) GetCheckInManifest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid show ID", http.StatusBadRequest)
+		return
+	}
+
+	manifest, err := h.Service.GetCheckInManifest(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Show not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error building check-in manifest of show %d: %v", id, err)
+		http.Error(w, "Failed to build manifest", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, no-store")
+	json.NewEncoder(w).Encode(manifest)
+}
+
+// GetManifestKey returns the public key of the manifest signatures, devices fetch it when
+// they are set up and check every manifest they download with it
+func (h *Handler) GetManifestKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(concert.ManifestPublicKey())
+}
+
+func (h *Handler) SyncScans(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	staff, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid show ID", http.StatusBadRequest)
+		return
+	}
+
+	var req SyncScansRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.DeviceID == "" {
+		http.Error(w, "deviceId is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Scans) > maxScansPerSync {
+		http.Error(w, "Too many scans in one batch", http.StatusRequestEntityTooLarge)
+		return
+	}
+	for _, scan := range req.Scans {
+		if scan.Code == "" || scan.Gate == "" || scan.ScannedAt.IsZero() {
+			http.Error(w, "Every scan needs a code, a gate and scannedAt", http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := h.Service.SyncScans(uint(id), staff.ID, req.DeviceID, req.Scans)
+	if err != nil {
+		log.Printf("Error syncing scans of show %d: %v", id, err)
+		http.Error(w, "Failed to sync scans", http.StatusInternalServerError)
+		return
+	}
+
+	summary := map[string]int{}
+	for _, result := range results {
+		summary[result.Result]++
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"results": results, "summary": summary})
+}
+
+func (h *Handler) ListScanEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid show ID", http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.Service.ListScanEvents(uint(id), r.URL.Query().Get("result"))
+	if err != nil {
+		log.Printf("Error listing scans of show %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(events)
+}
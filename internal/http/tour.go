@@ -0,0 +1,349 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"concert/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type TourRequest struct {
+	Name        string  `json:"name"`
+	ArtistID    uint    `json:"artistId"`
+	Description *string `json:"description"`
+	ImageURL    *string `json:"imageUrl"`
+}
+
+// apply copies the fields present in the request, an empty name is left unchanged
+func (req TourRequest) apply(tour *models.Tour) {
+	if req.Name != "" {
+		tour.Name = req.Name
+	}
+	if req.Description != nil {
+		tour.Description = *req.Description
+	}
+	if req.ImageURL != nil {
+		tour.ImageURL = *req.ImageURL
+	}
+}
+
+type TourDateRequest struct {
+	Date     string `json:"date"`
+	StartsAt string `json:"startsAt"`
+	VenueID  *uint  `json:"venueId"`
+	Venue    string `json:"venue"`
+	City     string `json:"city"`
+	Country  string `json:"country"`
+}
+
+// RecurrenceRequest is a residency, weekdays are names like "friday" or "fri"
+type RecurrenceRequest struct {
+	Frequency string   `json:"frequency"`
+	Interval  int      `json:"interval"`
+	Weekdays  []string `json:"weekdays"`
+	From      string   `json:"from"`
+	Until     string   `json:"until"`
+	Count     int      `json:"count"`
+	Except    []string `json:"except"`
+	VenueID   *uint    `json:"venueId"`
+	Venue     string   `json:"venue"`
+	City      string   `json:"city"`
+	Country   string   `json:"country"`
+}
+
+// TourShowsRequest creates the shows of a tour from a template, for each of the dates
+// and of the dates of the recurrence. The time of the template starts the dates given as
+// a day, its other times are ignored.
+type TourShowsRequest struct {
+	Template           CreateShowRequest  `json:"template"`
+	DoorsBeforeMinutes int                `json:"doorsBeforeMinutes"`
+	DurationMinutes    int                `json:"durationMinutes"`
+	Dates              []TourDateRequest  `json:"dates"`
+	Recurrence         *RecurrenceRequest `json:"recurrence"`
+}
+
+type TourShowsUpdateRequest struct {
+	Title       *string    `json:"title"`
+	Description *string    `json:"description"`
+	ImageURL    *string    `json:"imageUrl"`
+	Price       *float64   `json:"price"`
+	TotalSeats  *int       `json:"totalSeats"`
+	OnSaleAt    *time.Time `json:"onSaleAt"`
+	OffSaleAt   *time.Time `json:"offSaleAt"`
+	Time        string     `json:"time"`
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || (len(name) >= 3 && strings.HasPrefix(full, name)) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", name)
+}
+
+// dates lists the dates of the request and of its recurrence
+func (req TourShowsRequest) dates() ([]concert.TourDate, error) {
+	var dates []concert.TourDate
+	for _, date := range req.Dates {
+		dates = append(dates, concert.TourDate(date))
+	}
+	if rec := req.Recurrence; rec != nil {
+		recurrence := concert.Recurrence{
+			Frequency: rec.Frequency,
+			Interval:  rec.Interval,
+			From:      rec.From,
+			Until:     rec.Until,
+			Count:     rec.Count,
+			Except:    rec.Except,
+			VenueID:   rec.VenueID,
+			Venue:     rec.Venue,
+			City:      rec.City,
+			Country:   rec.Country,
+		}
+		for _, name := range rec.Weekdays {
+			day, err := parseWeekday(name)
+			if err != nil {
+				return nil, err
+			}
+			recurrence.Weekdays = append(recurrence.Weekdays, day)
+		}
+		recurring, err := recurrence.Dates()
+		if err != nil {
+			return nil, err
+		}
+		dates = append(dates, recurring...)
+	}
+	return dates, nil
+}
+
+func writeTourError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Tour not found", http.StatusNotFound)
+	case errors.Is(err, concert.ErrTourInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, concert.ErrInvalidTourShow):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Error handling tour: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// ListTours lists the tours, of an artist with ?artistId=
+func (h *Handler) ListTours(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var artistID int
+	if v := r.URL.Query().Get("artistId"); v != "" {
+		var err error
+		if artistID, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "invalid artistId", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tours, err := h.Service.ListTours(uint(artistID))
+	if err != nil {
+		log.Printf("Error listing tours: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(tours)
+}
+
+// GetTour is the tour page, the tour with its artist and its shows by date
+func (h *Handler) GetTour(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	tour, err := h.Service.GetTour(uint(id))
+	if err != nil {
+		writeTourError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(tour)
+}
+
+func (h *Handler) CreateTour(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req TourRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tour := models.Tour{ArtistID: req.ArtistID}
+	req.apply(&tour)
+	if err := tour.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := h.Service.GetArtistByID(req.ArtistID); err != nil {
+		http.Error(w, "Artist not found", http.StatusNotFound)
+		return
+	}
+
+	tour, err := h.Service.CreateTour(tour)
+	if err != nil {
+		writeTourError(w, err)
+		return
+	}
+
+	log.Printf("Tour created: %s (ID: %d)", tour.Name, tour.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tour)
+}
+
+func (h *Handler) UpdateTour(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	tour, err := h.Service.GetTour(uint(id))
+	if err != nil {
+		writeTourError(w, err)
+		return
+	}
+
+	var req TourRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ArtistID != 0 && req.ArtistID != tour.ArtistID {
+		http.Error(w, "The artist of a tour can't change", http.StatusBadRequest)
+		return
+	}
+	req.apply(&tour)
+
+	tour, err = h.Service.UpdateTour(tour)
+	if err != nil {
+		writeTourError(w, err)
+		return
+	}
+
+	log.Printf("Tour updated: %s (ID: %d)", tour.Name, tour.ID)
+	json.NewEncoder(w).Encode(tour)
+}
+
+func (h *Handler) DeleteTour(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteTour(uint(id)); err != nil {
+		writeTourError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Tour deleted successfully"})
+}
+
+// CreateTourShows creates the dates of a tour in one go
+func (h *Handler) CreateTourShows(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req TourShowsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	show, err := req.Template.newShow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dates, err := req.dates()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	template := concert.TourTemplate{
+		Show:        show,
+		Time:        req.Template.Time,
+		Timezone:    req.Template.Timezone,
+		DoorsBefore: time.Duration(req.DoorsBeforeMinutes) * time.Minute,
+		Duration:    time.Duration(req.DurationMinutes) * time.Minute,
+	}
+
+	shows, err := h.Service.CreateTourShows(uint(id), template, dates)
+	if err != nil {
+		writeTourError(w, err)
+		return
+	}
+
+	log.Printf("Tour %d: %d shows created", id, len(shows))
+	for _, show := range shows {
+		h.publishEvent(models.EventShowCreated, show)
+		h.sendShowAlert(show)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(shows)
+}
+
+// UpdateTourShows changes every show of a tour still to come
+func (h *Handler) UpdateTourShows(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req TourShowsUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	shows, err := h.Service.UpdateTourShows(uint(id), concert.TourShowChanges(req), time.Now())
+	if err != nil {
+		writeTourError(w, err)
+		return
+	}
+
+	log.Printf("Tour %d: %d shows updated", id, len(shows))
+	for _, show := range shows {
+		h.publishEvent(models.EventShowUpdated, show)
+	}
+
+	json.NewEncoder(w).Encode(shows)
+}
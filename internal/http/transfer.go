@@ -0,0 +1,261 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"concert/internal/models"
+	"concert/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.temporal.io/sdk/client"
+)
+
+type TransferRequest struct {
+	Email string `json:"email"`
+}
+
+// AcceptTransferRequest carries the account to create when the recipient has none yet
+type AcceptTransferRequest struct {
+	Token     string `json:"token"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+type TransferInviteResponse struct {
+	ShowTitle   string `json:"showTitle"`
+	ShowDate    string `json:"showDate"`
+	Venue       string `json:"venue"`
+	TicketCount int    `json:"ticketCount"`
+	ToEmail     string `json:"toEmail"`
+	ExpiresAt   string `json:"expiresAt"`
+}
+
+func writeTransferError(w http.ResponseWriter, err error) {
+	var limitErr *concert.TicketLimitError
+	switch {
+	case errors.As(err, &limitErr):
+		writeTicketLimitError(w, limitErr)
+	case errors.Is(err, concert.ErrTransferInvalid):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, concert.ErrTransferExpired):
+		http.Error(w, err.Error(), http.StatusGone)
+	case errors.Is(err, concert.ErrTransferEmailMismatch):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, concert.ErrTransferPending), errors.Is(err, concert.ErrTransferLimit),
+		errors.Is(err, concert.ErrBookingNotConfirmed), errors.Is(err, concert.ErrResaleListed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, concert.ErrTransfersDisabled), errors.Is(err, concert.ErrTransferTooLate),
+		errors.Is(err, concert.ErrTransferToSelf):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Error transferring booking: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	booking, ok := h.ownBooking(w, r)
+	if !ok {
+		return
+	}
+
+	var req TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !strings.Contains(req.Email, "@") {
+		http.Error(w, "A valid email is required", http.StatusBadRequest)
+		return
+	}
+
+	transfer, token, err := h.Service.CreateTransfer(booking, *user, req.Email)
+	if err != nil {
+		writeTransferError(w, err)
+		return
+	}
+
+	h.sendTransferInvite(transfer, token, booking, *user)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// sendTransferInvite emails the invitation link through temporal, the token is only sent there
+func (h *Handler) sendTransferInvite(transfer models.Transfer, token string, booking models.Booking, from models.User) {
+	if h.TemporalClient == nil {
+		return
+	}
+	appURL := utils.GetEnvOrDefault("APP_URL", "http://localhost:8080")
+	input := TemporalTransferInput{
+		Email:        transfer.ToEmail,
+		FromUsername: from.Username,
+		ShowTitle:    booking.Show.Title,
+		Date:         concert.FormatShowDate(booking.Show),
+		TicketCount:  booking.TicketCount,
+		AcceptURL:    appURL + "/transfer/accept?token=" + url.QueryEscape(token),
+		ExpiresAt:    transfer.ExpiresAt.Format("2006-01-02 15:04"),
+	}
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        fmt.Sprintf("transfer-invite-%d", transfer.ID),
+		TaskQueue: "email-task-queue",
+	}
+	if _, err := h.TemporalClient.ExecuteWorkflow(context.Background(), workflowOptions, "TransferInviteWorkflow", input); err != nil {
+		log.Printf("Error executing transfer invite workflow: %v", err)
+	}
+}
+
+func (h *Handler) ListBookingTransfers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	booking, ok := h.ownBooking(w, r)
+	if !ok {
+		return
+	}
+
+	transfers, err := h.Service.ListBookingTransfers(booking.ID)
+	if err != nil {
+		log.Printf("Error listing transfers of booking %d: %v", booking.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(transfers)
+}
+
+func (h *Handler) CancelTransfer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.CancelTransfer(uint(id), *user); err != nil {
+		writeTransferError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Transfer cancelled successfully"})
+}
+
+func (h *Handler) GetTransferInvite(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	transfer, err := h.Service.GetTransferByToken(chi.URLParam(r, "token"))
+	if err != nil {
+		writeTransferError(w, err)
+		return
+	}
+
+	booking, err := h.Service.GetBookingById(transfer.BookingID)
+	if err != nil || booking.ID == 0 {
+		http.Error(w, "Booking not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(TransferInviteResponse{
+		ShowTitle:   booking.Show.Title,
+		ShowDate:    booking.Show.In(booking.Show.StartsAt).Format("2006-01-02"),
+		Venue:       booking.Show.Venue,
+		TicketCount: booking.TicketCount,
+		ToEmail:     transfer.ToEmail,
+		ExpiresAt:   transfer.ExpiresAt.Format("2006-01-02 15:04"),
+	})
+}
+
+// AcceptTransfer gives the booking to the logged in user, or to a new account created with
+// the invited email. An existing account of that email has to log in first.
+func (h *Handler) AcceptTransfer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req AcceptTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.Service.GetTransferByToken(req.Token)
+	if err != nil {
+		writeTransferError(w, err)
+		return
+	}
+
+	recipient, err := GetUserFromCookie(h.Db, r)
+	if err != nil {
+		exists, err := UserExists(h.Db, transfer.ToEmail, req.Username)
+		if err != nil {
+			log.Printf("Error checking user: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if exists {
+			http.Error(w, "An account already uses this email or username, log in to accept the tickets", http.StatusUnauthorized)
+			return
+		}
+		if req.Username == "" || req.Password == "" {
+			http.Error(w, "Username and password are required to create your account", http.StatusBadRequest)
+			return
+		}
+		recipient, err = InsertUser(h.Db, transfer.ToEmail, req.Username, req.Password, req.FirstName, req.LastName, "user")
+		if err != nil {
+			log.Printf("Error creating user: %v", err)
+			http.Error(w, "Failed to create user", http.StatusInternalServerError)
+			return
+		}
+		SetCookie(w, recipient.ID)
+	}
+
+	booking, err := h.Service.AcceptTransfer(req.Token, *recipient)
+	if err != nil {
+		writeTransferError(w, err)
+		return
+	}
+
+	log.Printf("Booking %d transferred to user %d", booking.ID, recipient.ID)
+
+	json.NewEncoder(w).Encode(booking)
+}
+
+func (h *Handler) ListBookingAudit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.Service.ListBookingAudit(uint(id))
+	if err != nil {
+		log.Printf("Error listing audit of booking %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(entries)
+}
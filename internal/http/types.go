@@ -3,6 +3,7 @@ package http
 import (
 	"concert/internal/concert"
 	"concert/internal/models"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"go.temporal.io/sdk/client"
@@ -14,6 +15,7 @@ type Handler struct {
 	Service        concert.ConcertService
 	Db             *gorm.DB
 	TemporalClient client.Client
+	Hub            *concert.Hub
 }
 
 type PageData struct {
@@ -41,3 +43,43 @@ type TemporalUserInput struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
+
+type TemporalRefundInput struct {
+	BookingID uint    `json:"bookingId"`
+	Amount    float64 `json:"amount"`
+	Credit    float64 `json:"credit"`
+}
+
+type TemporalTransferInput struct {
+	Email        string `json:"email"`
+	FromUsername string `json:"fromUsername"`
+	ShowTitle    string `json:"showTitle"`
+	Date         string `json:"date"`
+	TicketCount  int    `json:"ticketCount"`
+	AcceptURL    string `json:"acceptUrl"`
+	ExpiresAt    string `json:"expiresAt"`
+}
+
+type TemporalShowAlertInput struct {
+	ShowID      uint       `json:"showId"`
+	PublishAt   *time.Time `json:"publishAt,omitempty"`
+	AfterUserID uint       `json:"afterUserId"`
+}
+
+type TemporalAttachment struct {
+	Filename string `json:"filename"`
+	Content  []byte `json:"content"`
+}
+
+type TemporalBookingInput struct {
+	Email         string               `json:"email"`
+	Username      string               `json:"username"`
+	BookingID     uint                 `json:"bookingId"`
+	ShowTitle     string               `json:"showTitle"`
+	Venue         string               `json:"venue"`
+	Date          string               `json:"date"`
+	TicketCount   int                  `json:"ticketCount"`
+	TotalPrice    float64              `json:"totalPrice"`
+	InvoiceNumber string               `json:"invoiceNumber"`
+	Attachments   []TemporalAttachment `json:"attachments"`
+}
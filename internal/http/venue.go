@@ -0,0 +1,190 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"concert/internal/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type VenueRequest struct {
+	Name       string   `json:"name"`
+	Address    string   `json:"address"`
+	PostalCode string   `json:"postalCode"`
+	City       string   `json:"city"`
+	Country    string   `json:"country"`
+	Timezone   string   `json:"timezone"`
+	Latitude   *float64 `json:"latitude"`
+	Longitude  *float64 `json:"longitude"`
+	Capacity   *int     `json:"capacity"`
+
+	WheelchairAccessible *bool   `json:"wheelchairAccessible"`
+	AccessibilityNotes   *string `json:"accessibilityNotes"`
+}
+
+// apply copies the fields present in the request, empty strings are left unchanged
+func (req VenueRequest) apply(venue *models.Venue) {
+	if req.Name != "" {
+		venue.Name = req.Name
+	}
+	if req.Address != "" {
+		venue.Address = req.Address
+	}
+	if req.PostalCode != "" {
+		venue.PostalCode = req.PostalCode
+	}
+	if req.City != "" {
+		venue.City = req.City
+	}
+	if req.Country != "" {
+		venue.Country = req.Country
+	}
+	if req.Timezone != "" {
+		venue.Timezone = req.Timezone
+	}
+	if req.Latitude != nil || req.Longitude != nil {
+		venue.Latitude = req.Latitude
+		venue.Longitude = req.Longitude
+	}
+	if req.Capacity != nil {
+		venue.Capacity = *req.Capacity
+	}
+	if req.WheelchairAccessible != nil {
+		venue.WheelchairAccessible = *req.WheelchairAccessible
+	}
+	if req.AccessibilityNotes != nil {
+		venue.AccessibilityNotes = *req.AccessibilityNotes
+	}
+}
+
+func writeVenueError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Venue not found", http.StatusNotFound)
+	case errors.Is(err, concert.ErrVenueCapacity), errors.Is(err, concert.ErrVenueInUse), errors.Is(err, concert.ErrVenueExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Error handling venue: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// ListVenues lists the venues, of a city with ?city=
+func (h *Handler) ListVenues(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	venues, err := h.Service.ListVenues(r.URL.Query().Get("city"))
+	if err != nil {
+		log.Printf("Error listing venues: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(venues)
+}
+
+func (h *Handler) GetVenue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	venue, err := h.Service.GetVenue(uint(id))
+	if err != nil {
+		writeVenueError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(venue)
+}
+
+func (h *Handler) CreateVenue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req VenueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var venue models.Venue
+	req.apply(&venue)
+	if err := venue.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	venue, err := h.Service.CreateVenue(venue)
+	if err != nil {
+		writeVenueError(w, err)
+		return
+	}
+
+	log.Printf("Venue created: %s (ID: %d)", venue.Name, venue.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(venue)
+}
+
+// UpdateVenue changes a venue, its shows follow its name and city
+func (h *Handler) UpdateVenue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	venue, err := h.Service.GetVenue(uint(id))
+	if err != nil {
+		writeVenueError(w, err)
+		return
+	}
+
+	var req VenueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.apply(&venue)
+	if err := venue.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	venue, err = h.Service.UpdateVenue(venue, time.Now())
+	if err != nil {
+		writeVenueError(w, err)
+		return
+	}
+
+	log.Printf("Venue updated: %s (ID: %d)", venue.Name, venue.ID)
+	json.NewEncoder(w).Encode(venue)
+}
+
+func (h *Handler) DeleteVenue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteVenue(uint(id)); err != nil {
+		writeVenueError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Venue deleted successfully"})
+}
@@ -0,0 +1,267 @@
+package http
+
+import (
+	"concert/internal/concert"
+	"concert/internal/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// WebhookCreatedResponse is the only response holding the secret of a subscription
+type WebhookCreatedResponse struct {
+	models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+type WebhookRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Secret      string   `json:"secret"`
+	Description string   `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+func validWebhookEvents(events []string) bool {
+	if len(events) == 0 {
+		return false
+	}
+	for _, event := range events {
+		known := event == "*"
+		for _, e := range models.WebhookEvents {
+			if e == event {
+				known = true
+			}
+		}
+		if !known {
+			return false
+		}
+	}
+	return true
+}
+
+func validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// publishEvent queues a webhook event, a failure must never break the request that caused it
+func (h *Handler) publishEvent(event string, data any) {
+	if err := h.Service.PublishWebhookEvent(event, data); err != nil {
+		log.Printf("Error publishing %s webhook event: %v", event, err)
+	}
+}
+
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	subs, err := h.Service.ListWebhooks()
+	if err != nil {
+		log.Printf("Error listing webhooks: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(subs)
+}
+
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !validWebhookURL(req.URL) {
+		http.Error(w, "A valid http(s) URL is required", http.StatusBadRequest)
+		return
+	}
+	if !validWebhookEvents(req.Events) {
+		http.Error(w, "Unknown or missing event types", http.StatusBadRequest)
+		return
+	}
+
+	sub := models.WebhookSubscription{
+		URL:         req.URL,
+		Events:      req.Events,
+		Secret:      req.Secret,
+		Description: req.Description,
+		Active:      req.Active == nil || *req.Active,
+	}
+
+	sub, err := h.Service.SetWebhook(sub)
+	if err != nil {
+		log.Printf("Error creating webhook: %v", err)
+		http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(WebhookCreatedResponse{WebhookSubscription: sub, Secret: sub.Secret})
+}
+
+func (h *Handler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.Service.GetWebhookByID(uint(id))
+	if err != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.URL != "" {
+		if !validWebhookURL(req.URL) {
+			http.Error(w, "A valid http(s) URL is required", http.StatusBadRequest)
+			return
+		}
+		sub.URL = req.URL
+	}
+	if req.Events != nil {
+		if !validWebhookEvents(req.Events) {
+			http.Error(w, "Unknown or missing event types", http.StatusBadRequest)
+			return
+		}
+		sub.Events = req.Events
+	}
+	if req.Secret != "" {
+		sub.Secret = req.Secret
+	}
+	if req.Description != "" {
+		sub.Description = req.Description
+	}
+	if req.Active != nil {
+		sub.Active = *req.Active
+	}
+
+	sub, err = h.Service.SetWebhook(sub)
+	if err != nil {
+		log.Printf("Error updating webhook: %v", err)
+		http.Error(w, "Failed to update webhook", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(sub)
+}
+
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteWebhook(uint(id)); err != nil {
+		log.Printf("Error deleting webhook: %v", err)
+		http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Webhook deleted successfully"})
+}
+
+func (h *Handler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := h.Service.ListWebhookDeliveries(uint(id))
+	if err != nil {
+		log.Printf("Error listing webhook deliveries: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+func (h *Handler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	deliveries, err := h.Service.ListDeadWebhookDeliveries()
+	if err != nil {
+		log.Printf("Error listing dead webhook deliveries: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+func (h *Handler) RetryWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := h.Service.RetryWebhookDelivery(uint(id))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	case errors.Is(err, concert.ErrWebhookNotDead):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("Error retrying webhook delivery %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(delivery)
+}
+
+func (h *Handler) TestWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := h.Service.SendTestWebhook(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	if err != nil && delivery.ID == 0 {
+		log.Printf("Error testing webhook %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		// the receiver failed, the delivery tells how
+		w.WriteHeader(http.StatusBadGateway)
+	}
+
+	json.NewEncoder(w).Encode(delivery)
+}
@@ -1,13 +1,35 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
 
 type Artist struct {
 	gorm.Model
-	Name     string `gorm:"not null" json:"name"`
-	Genre    string `json:"genre"`
-	Bio      string `json:"bio,omitempty"`
-	ImageURL string `json:"imageUrl,omitempty"`
-	AlbumURL string `json:"albumUrl,omitempty"`
-	Shows    []Show `gorm:"foreignKey:ArtistID" json:"shows,omitempty"`
+	Name     string         `gorm:"not null" json:"name"`
+	Genre    string         `json:"genre"`
+	Bio      string         `json:"bio,omitempty"`
+	ImageURL string         `json:"imageUrl,omitempty"`
+	AlbumURL string         `json:"albumUrl,omitempty"`
+	Shows    []Show         `gorm:"foreignKey:ArtistID" json:"shows,omitempty"`
+	Contract ArtistContract `gorm:"embedded;embeddedPrefix:contract_" json:"contract"`
+}
+
+// ArtistContract is the deal of the artist for each show: SplitPercent of the net receipts,
+// and at least Guarantee whatever the sales.
+type ArtistContract struct {
+	SplitPercent int     `json:"splitPercent"`
+	Guarantee    float64 `json:"guarantee"`
+}
+
+func (c ArtistContract) Validate() error {
+	if c.SplitPercent < 0 || c.SplitPercent > 100 {
+		return errors.New("splitPercent must be between 0 and 100")
+	}
+	if c.Guarantee < 0 {
+		return errors.New("guarantee can't be negative")
+	}
+	return nil
 }
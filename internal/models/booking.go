@@ -1,6 +1,10 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Booking struct {
 	gorm.Model
@@ -11,4 +15,24 @@ type Booking struct {
 	TicketCount int     `gorm:"not null" json:"ticketCount"`
 	TotalPrice  float64 `gorm:"not null" json:"totalPrice"`
 	Status      string  `gorm:"default:'confirmed'" json:"status"`
+	// ConfirmedAt is set when the booking is paid, it stays set once cancelled
+	ConfirmedAt *time.Time `json:"confirmedAt,omitempty"`
+
+	// set when a paid booking is cancelled, RefundStatus is pending, refunded or failed
+	RefundAmount float64 `json:"refundAmount,omitempty"`
+	RefundStatus string  `json:"refundStatus,omitempty"`
+	// part of the refund returned to the account credit instead of the payment method
+	RefundCredit float64 `json:"refundCredit,omitempty"`
+
+	// account credit spent on the booking, the payment covers the rest of TotalPrice
+	CreditApplied float64 `json:"creditApplied,omitempty"`
+
+	// what TotalPrice is made of, bookings made before the breakdown only have TotalPrice
+	PriceBreakdown `gorm:"embedded"`
+
+	// set on the booking of a buyer on the resale marketplace
+	ResaleListingID *uint `json:"resaleListingId,omitempty"`
+
+	// set on a festival pass, booked on the opening show and holding a seat on each day
+	FestivalID *uint `gorm:"index" json:"festivalId,omitempty"`
 }
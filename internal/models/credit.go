@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// kinds of credit entries, positive amounts add to the balance
+const (
+	CreditGiftCard        = "gift_card"
+	CreditGoodwill        = "goodwill"
+	CreditRefund          = "refund"
+	CreditBooking         = "booking"
+	CreditBookingReleased = "booking_released"
+)
+
+// CreditEntry is a line of the credit ledger of a user. The balance cached on the user
+// is the sum of their entries, both are written in the same transaction.
+type CreditEntry struct {
+	gorm.Model
+	UserID     uint    `gorm:"not null;index" json:"userId"`
+	Amount     float64 `gorm:"not null" json:"amount"`
+	Kind       string  `gorm:"not null" json:"kind"`
+	BookingID  *uint   `gorm:"index" json:"bookingId,omitempty"`
+	GiftCardID *uint   `json:"giftCardId,omitempty"`
+	ActorID    *uint   `json:"actorId,omitempty"`
+	Note       string  `json:"note,omitempty"`
+}
+
+const (
+	GiftCardPending  = "pending"
+	GiftCardActive   = "active"
+	GiftCardRedeemed = "redeemed"
+	GiftCardVoid     = "void"
+)
+
+// GiftCard is a code worth Amount of credit once redeemed. Cards sold online are pending
+// until their payment succeeds, cards issued by an admin are active right away.
+type GiftCard struct {
+	gorm.Model
+	Code           string     `gorm:"not null;uniqueIndex" json:"code,omitempty"`
+	Amount         float64    `gorm:"not null" json:"amount"`
+	Status         string     `gorm:"default:'pending';index" json:"status"`
+	PurchaserID    *uint      `gorm:"index" json:"purchaserId,omitempty"`
+	IssuedByID     *uint      `json:"issuedById,omitempty"`
+	RecipientEmail string     `json:"recipientEmail,omitempty"`
+	Message        string     `json:"message,omitempty"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	RedeemedByID   *uint      `json:"redeemedById,omitempty"`
+	RedeemedAt     *time.Time `json:"redeemedAt,omitempty"`
+}
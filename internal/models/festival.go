@@ -0,0 +1,34 @@
+package models
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Festival groups the shows of several days. A pass admits to each of them, PassSeats
+// bounds the passes sold, zero means only the seats of the days do.
+type Festival struct {
+	gorm.Model
+	Name        string  `gorm:"not null" json:"name"`
+	Description string  `json:"description,omitempty"`
+	ImageURL    string  `json:"imageUrl,omitempty"`
+	PassPrice   float64 `json:"passPrice"`
+	PassSeats   int     `json:"passSeats"`
+	Shows       []Show  `gorm:"foreignKey:FestivalID" json:"shows,omitempty"`
+
+	PassesAvailable int `gorm:"-" json:"passesAvailable"`
+}
+
+func (f Festival) Validate() error {
+	if f.Name == "" {
+		return errors.New("name is required")
+	}
+	if f.PassPrice < 0 {
+		return errors.New("passPrice can't be negative")
+	}
+	if f.PassSeats < 0 {
+		return errors.New("passSeats can't be negative")
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package models
+
+import "gorm.io/gorm"
+
+// Follow is a fan following an artist, they are emailed when the artist announces a show
+// unless they opted out of the alerts.
+type Follow struct {
+	gorm.Model
+	UserID   uint   `gorm:"not null;uniqueIndex:idx_follows_user_artist" json:"userId"`
+	ArtistID uint   `gorm:"not null;uniqueIndex:idx_follows_user_artist;index" json:"artistId"`
+	Artist   Artist `gorm:"foreignKey:ArtistID;references:ID" json:"artist"`
+}
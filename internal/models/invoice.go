@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invoice is the receipt of a booking, Seq is a gapless sequence and Number its printed form.
+type Invoice struct {
+	gorm.Model
+	BookingID uint      `gorm:"not null;uniqueIndex" json:"bookingId"`
+	Booking   Booking   `gorm:"foreignKey:BookingID;references:ID" json:"-"`
+	Seq       int       `gorm:"not null;uniqueIndex" json:"seq"`
+	Number    string    `gorm:"not null;uniqueIndex" json:"number"`
+	Amount    float64   `gorm:"not null" json:"amount"`
+	IssuedAt  time.Time `gorm:"not null" json:"issuedAt"`
+
+	// the purchaser and the credit spent when the invoice was issued, a transfer moves the booking only
+	BilledName    string  `json:"billedName"`
+	BilledEmail   string  `json:"billedEmail"`
+	CreditApplied float64 `json:"creditApplied,omitempty"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// types of ledger accounts
+const (
+	AccountAsset     = "asset"
+	AccountLiability = "liability"
+	AccountRevenue   = "revenue"
+	AccountExpense   = "expense"
+)
+
+type LedgerAccount struct {
+	gorm.Model
+	Code string `gorm:"not null;uniqueIndex" json:"code"`
+	Name string `gorm:"not null" json:"name"`
+	Type string `gorm:"not null" json:"type"`
+}
+
+// kinds of journal entries
+const (
+	JournalSale          = "sale"
+	JournalRefund        = "refund"
+	JournalCredit        = "credit"
+	JournalGiftCardSale  = "gift_card_sale"
+	JournalGiftCardIssue = "gift_card_issue"
+	JournalSettlement    = "settlement"
+)
+
+// JournalEntry is one balanced movement of money in the ledger. Entries are never updated
+// or deleted, a mistake is fixed by a new entry. Reference makes recording idempotent.
+type JournalEntry struct {
+	gorm.Model
+	Kind      string    `gorm:"not null;index" json:"kind"`
+	Reference string    `gorm:"not null;uniqueIndex" json:"reference"`
+	BookingID *uint     `gorm:"index" json:"bookingId,omitempty"`
+	ShowID    *uint     `gorm:"index" json:"showId,omitempty"`
+	Memo      string    `json:"memo,omitempty"`
+	PostedAt  time.Time `gorm:"not null;index" json:"postedAt"`
+	Postings  []Posting `gorm:"foreignKey:JournalEntryID" json:"postings"`
+}
+
+// Posting is a line of a journal entry, in cents: debits are positive and credits negative,
+// the postings of an entry sum to zero.
+type Posting struct {
+	ID             uint          `gorm:"primarykey" json:"id"`
+	JournalEntryID uint          `gorm:"not null;index" json:"journalEntryId"`
+	AccountID      uint          `gorm:"not null;index" json:"accountId"`
+	Account        LedgerAccount `gorm:"foreignKey:AccountID" json:"account"`
+	Amount         int64         `gorm:"not null" json:"amount"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+// TicketLimitOverride lets an admin allow one user more tickets than the show limits.
+type TicketLimitOverride struct {
+	gorm.Model
+	ShowID     uint   `gorm:"not null;uniqueIndex:idx_limit_override" json:"showId"`
+	UserID     uint   `gorm:"not null;uniqueIndex:idx_limit_override" json:"userId"`
+	User       User   `gorm:"foreignKey:UserID;references:ID" json:"user,omitempty"`
+	MaxTickets int    `gorm:"not null" json:"maxTickets"`
+	Reason     string `json:"reason,omitempty"`
+}
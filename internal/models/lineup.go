@@ -0,0 +1,42 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	LineupHeadliner = "headliner"
+	LineupSupport   = "support"
+	LineupGuest     = "guest"
+)
+
+// LineupSlot is an artist playing a show, in the order of the bill. Show.ArtistID is
+// the first headliner of the lineup.
+type LineupSlot struct {
+	gorm.Model
+	ShowID      uint       `gorm:"not null;index" json:"showId"`
+	ArtistID    uint       `gorm:"not null;index" json:"artistId"`
+	Artist      Artist     `gorm:"foreignKey:ArtistID;references:ID" json:"artist"`
+	Position    int        `gorm:"not null" json:"position"`
+	Role        string     `gorm:"not null" json:"role"`
+	SetStartsAt *time.Time `json:"setStartsAt,omitempty"`
+	SetEndsAt   *time.Time `json:"setEndsAt,omitempty"`
+}
+
+func (l LineupSlot) Validate() error {
+	switch l.Role {
+	case LineupHeadliner, LineupSupport, LineupGuest:
+	default:
+		return errors.New("role must be headliner, support or guest")
+	}
+	if l.ArtistID == 0 {
+		return errors.New("artistId is required")
+	}
+	if l.SetStartsAt != nil && l.SetEndsAt != nil && !l.SetEndsAt.After(*l.SetStartsAt) {
+		return errors.New("setEndsAt must be after setStartsAt")
+	}
+	return nil
+}
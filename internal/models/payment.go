@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PaymentPending   = "pending"
+	PaymentSucceeded = "succeeded"
+	PaymentFailed    = "failed"
+	PaymentRefunded  = "refunded"
+)
+
+// Payment is the charge of a booking, or of a gift card, with the payment provider.
+type Payment struct {
+	gorm.Model
+	BookingID     uint       `gorm:"not null;index" json:"bookingId"`
+	GiftCardID    *uint      `gorm:"index" json:"giftCardId,omitempty"`
+	Provider      string     `gorm:"not null" json:"provider"`
+	IntentID      string     `gorm:"not null;uniqueIndex" json:"intentId"`
+	Amount        float64    `gorm:"not null" json:"amount"`
+	Currency      string     `gorm:"not null" json:"currency"`
+	Status        string     `gorm:"default:'pending';index" json:"status"`
+	FailureReason string     `json:"failureReason,omitempty"`
+	CapturedAt    *time.Time `json:"capturedAt,omitempty"`
+	RefundID      string     `json:"refundId,omitempty"`
+	Refunded      float64    `json:"refunded,omitempty"`
+
+	// Instrument is the fingerprint of the card or account that paid, reported by the provider
+	Instrument string `gorm:"index" json:"-"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "errors"
+
+// CancellationPolicy decides how much of a booking is refunded when a fan cancels it.
+// Until FullRefundDays before the show everything is refunded, then PartialRefundPercent,
+// and nothing within NoRefundHours of the show. Fee is kept on every refund.
+type CancellationPolicy struct {
+	FullRefundDays       int     `json:"fullRefundDays"`
+	PartialRefundPercent int     `json:"partialRefundPercent"`
+	NoRefundHours        int     `gorm:"default:24" json:"noRefundHours"`
+	Fee                  float64 `json:"fee"`
+}
+
+func (p CancellationPolicy) Validate() error {
+	if p.FullRefundDays < 0 || p.NoRefundHours < 0 || p.Fee < 0 {
+		return errors.New("cancellation policy values can't be negative")
+	}
+	if p.PartialRefundPercent < 0 || p.PartialRefundPercent > 100 {
+		return errors.New("partialRefundPercent must be between 0 and 100")
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	SaleOnSale     = "on_sale"
+	SaleOnSaleSoon = "on_sale_soon"
+	SalePresale    = "presale"
+	SaleOffSale    = "off_sale"
+)
+
+// Presale opens a show early to fans holding the access code or listed by email.
+type Presale struct {
+	gorm.Model
+	ShowID        uint       `gorm:"not null;index" json:"showId"`
+	Name          string     `gorm:"not null" json:"name"`
+	StartsAt      time.Time  `gorm:"not null" json:"startsAt"`
+	EndsAt        time.Time  `gorm:"not null" json:"endsAt"`
+	AccessCode    string     `json:"accessCode,omitempty"`
+	AllowedEmails StringList `gorm:"type:text" json:"allowedEmails"`
+}
+
+func (p Presale) ActiveAt(t time.Time) bool {
+	return !t.Before(p.StartsAt) && t.Before(p.EndsAt)
+}
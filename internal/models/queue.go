@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// QueueTicket is the place of a user in the waiting room of a show, positions are handed
+// out in arrival order and a user holds one per show.
+type QueueTicket struct {
+	gorm.Model
+	ShowID     uint       `gorm:"not null;uniqueIndex:idx_queue_show_position;uniqueIndex:idx_queue_show_user" json:"showId"`
+	UserID     uint       `gorm:"not null;uniqueIndex:idx_queue_show_user" json:"userId"`
+	Position   int        `gorm:"not null;uniqueIndex:idx_queue_show_position" json:"position"`
+	AdmittedAt *time.Time `gorm:"index" json:"admittedAt,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	UsedAt     *time.Time `json:"usedAt,omitempty"`
+}
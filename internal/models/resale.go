@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ResaleActive    = "active"
+	ResaleReserved  = "reserved"
+	ResaleSold      = "sold"
+	ResaleCancelled = "cancelled"
+	ResaleRemoved   = "removed"
+)
+
+// ResaleListing offers some tickets of a booking to other fans. While a buyer pays,
+// the listing is reserved for their pending booking.
+type ResaleListing struct {
+	gorm.Model
+	BookingID         uint       `gorm:"not null;index" json:"bookingId"`
+	SellerID          uint       `gorm:"not null;index" json:"sellerId"`
+	ShowID            uint       `gorm:"not null;index" json:"showId"`
+	TicketCount       int        `gorm:"not null" json:"ticketCount"`
+	PricePerTicket    float64    `gorm:"not null" json:"pricePerTicket"`
+	Status            string     `gorm:"default:'active';index" json:"status"`
+	ReservedBookingID *uint      `json:"-"`
+	BuyerBookingID    *uint      `json:"buyerBookingId,omitempty"`
+	FaceValue         float64    `json:"faceValue,omitempty"`
+	Fee               float64    `json:"fee,omitempty"`
+	Payout            float64    `json:"payout,omitempty"`
+	PayoutStatus      string     `json:"payoutStatus,omitempty"`
+	ModerationNote    string     `json:"moderationNote,omitempty"`
+	SoldAt            *time.Time `json:"soldAt,omitempty"`
+
+	// PayoutRef is the idempotency key of the refund paying PayoutCard of the payout back
+	// on the card, set before the provider is called; PayoutRefundID once it is recorded
+	PayoutRef      string  `json:"-"`
+	PayoutCard     float64 `json:"-"`
+	PayoutRefundID string  `json:"payoutRefundId,omitempty"`
+}
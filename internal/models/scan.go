@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ScanAdmitted    = "admitted"
+	ScanDuplicate   = "duplicate"
+	ScanDoubleEntry = "double_entry"
+	ScanConflict    = "conflict"
+	ScanInvalid     = "invalid"
+)
+
+// ScanEvent is a scan uploaded by a door device, with the outcome of its reconciliation.
+type ScanEvent struct {
+	gorm.Model
+	ShowID    uint      `gorm:"not null;index" json:"showId"`
+	TicketID  *uint     `gorm:"index" json:"ticketId,omitempty"`
+	Code      string    `gorm:"not null" json:"code"`
+	Gate      string    `json:"gate"`
+	DeviceID  string    `gorm:"index" json:"deviceId"`
+	ScannedAt time.Time `gorm:"not null" json:"scannedAt"`
+	ScannedBy uint      `json:"scannedBy"`
+	Result    string    `gorm:"index" json:"result"`
+	Detail    string    `json:"detail,omitempty"`
+}
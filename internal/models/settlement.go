@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// settlement statuses
+const (
+	SettlementDraft    = "draft"
+	SettlementApproved = "approved"
+	SettlementPaid     = "paid"
+)
+
+// Settlement is what is owed to the artist of a show. A draft is calculated again on
+// each read, the figures are frozen once an admin approves it.
+type Settlement struct {
+	gorm.Model
+	ShowID       uint       `gorm:"not null;uniqueIndex" json:"showId"`
+	ArtistID     uint       `gorm:"not null;index" json:"artistId"`
+	Status       string     `gorm:"not null;default:draft;index" json:"status"`
+	GrossSales   float64    `json:"grossSales"`
+	Refunds      float64    `json:"refunds"`
+	Fees         float64    `json:"fees"`
+	Taxes        float64    `json:"taxes"`
+	NetReceipts  float64    `json:"netReceipts"`
+	SplitPercent int        `json:"splitPercent"`
+	Guarantee    float64    `json:"guarantee"`
+	ArtistShare  float64    `json:"artistShare"`
+	PromoterNet  float64    `json:"promoterNet"`
+	CalculatedAt time.Time  `json:"calculatedAt"`
+	ApprovedByID *uint      `json:"approvedById,omitempty"`
+	ApprovedAt   *time.Time `json:"approvedAt,omitempty"`
+	PaidAt       *time.Time `json:"paidAt,omitempty"`
+	PaymentRef   string     `json:"paymentRef,omitempty"`
+}
@@ -1,23 +1,191 @@
 package models
 
 import (
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// show statuses. Drafts and shows scheduled before their publish time are only seen by
+// admins, sold out is never stored: a published show reads sold out without seats left
+const (
+	ShowDraft     = "draft"
+	ShowScheduled = "scheduled"
+	ShowPublished = "published"
+	ShowSoldOut   = "sold_out"
+	ShowCancelled = "cancelled"
+	ShowPostponed = "postponed"
+)
+
 type Show struct {
 	gorm.Model
 	Title          string    `gorm:"not null" json:"title"`
-	Date           time.Time `gorm:"not null" json:"date"`
-	Time           string    `json:"time"`
 	ArtistID       uint      `gorm:"not null" json:"artistId"`
 	Artist         Artist    `gorm:"foreignKey:ArtistID;references:ID" json:"artist"`
 	Venue          string    `gorm:"not null" json:"venue"`
+	City           string    `gorm:"index" json:"city,omitempty"`
+	VenueID        *uint     `gorm:"index" json:"venueId,omitempty"`
+	Location       *Venue    `gorm:"foreignKey:VenueID" json:"location,omitempty"`
 	Price          float64   `gorm:"not null" json:"price"`
 	TotalSeats     int       `gorm:"not null" json:"totalSeats"`
 	AvailableSeats int       `gorm:"not null" json:"availableSeats"`
 	Description    string    `json:"description,omitempty"`
 	ImageURL       string    `json:"imageUrl,omitempty"`
 	Bookings       []Booking `gorm:"foreignKey:ShowID" json:"-"`
+
+	// the artists on the bill in order, the festival the show is a day of and the tour it is a date of
+	Lineup     []LineupSlot `gorm:"foreignKey:ShowID" json:"lineup,omitempty"`
+	FestivalID *uint        `gorm:"index" json:"festivalId,omitempty"`
+	Festival   *Festival    `gorm:"foreignKey:FestivalID" json:"festival,omitempty"`
+	TourID     *uint        `gorm:"index" json:"tourId,omitempty"`
+	Tour       *Tour        `gorm:"foreignKey:TourID" json:"tour,omitempty"`
+
+	// lifecycle, StatusReason tells the fans why a show is cancelled or postponed
+	Status          string     `gorm:"default:'published';index" json:"status"`
+	StatusReason    string     `json:"statusReason,omitempty"`
+	PublishAt       *time.Time `json:"publishAt,omitempty"`
+	StatusChangedAt *time.Time `json:"statusChangedAt,omitempty"`
+	// AlertedAt is when the followers of its artists started being told about the show,
+	// they are once
+	AlertedAt *time.Time `json:"alertedAt,omitempty"`
+
+	// start, doors and end instants, in UTC, of a show played in Timezone, the IANA zone of
+	// its venue. Local has them in that zone, it is filled when the show is loaded or saved
+	StartsAt time.Time  `gorm:"not null;index" json:"startsAt"`
+	DoorsAt  *time.Time `json:"doorsAt,omitempty"`
+	EndsAt   *time.Time `json:"endsAt,omitempty"`
+	Timezone string     `json:"timezone"`
+	Local    *ShowTimes `gorm:"-" json:"local,omitempty"`
+
+	// sale window, a nil OnSaleAt means the show is bookable as soon as it is created
+	OnSaleAt   *time.Time `json:"onSaleAt,omitempty"`
+	OffSaleAt  *time.Time `json:"offSaleAt,omitempty"`
+	Presales   []Presale  `gorm:"foreignKey:ShowID" json:"-"`
+	SaleStatus string     `gorm:"-" json:"saleStatus,omitempty"`
+	NextSaleAt *time.Time `gorm:"-" json:"nextSaleAt,omitempty"`
+
+	// anti-scalping limits, zero means unlimited
+	MaxTicketsPerUser        int `json:"maxTicketsPerUser,omitempty"`
+	MaxTicketsPerOrder       int `json:"maxTicketsPerOrder,omitempty"`
+	MaxTicketsPerEmailDomain int `json:"maxTicketsPerEmailDomain,omitempty"`
+	MaxTicketsPerInstrument  int `json:"maxTicketsPerInstrument,omitempty"`
+
+	// waiting room, when enabled bookings need an admitted queue ticket. QueueAdmittedAt
+	// is when clients were last let in, by any server
+	QueueEnabled        bool       `json:"queueEnabled"`
+	QueueAdmitPerMinute int        `json:"queueAdmitPerMinute,omitempty"`
+	QueueAdmittedAt     *time.Time `json:"-"`
+
+	CancellationPolicy CancellationPolicy `gorm:"embedded;embeddedPrefix:cancel_" json:"cancellationPolicy"`
+
+	// ticket transfers between fans, zero values mean no limit
+	TransfersDisabled      bool `json:"transfersDisabled"`
+	MaxTransfersPerBooking int  `json:"maxTransfersPerBooking,omitempty"`
+	TransferCutoffHours    int  `json:"transferCutoffHours,omitempty"`
+
+	// resale marketplace, a zero cap means face value
+	ResalePriceCap   float64 `json:"resalePriceCap,omitempty"`
+	ResaleFeePercent int     `gorm:"default:10" json:"resaleFeePercent"`
+
+	// checkout pricing, Price is before tax and the fees are added to it. Country picks the tax rate
+	Country   string  `json:"country,omitempty"`
+	TicketFee float64 `json:"ticketFee,omitempty"`
+	OrderFee  float64 `json:"orderFee,omitempty"`
+}
+
+// ShowTimes are the times of a show in its timezone
+type ShowTimes struct {
+	StartsAt time.Time  `json:"startsAt"`
+	DoorsAt  *time.Time `json:"doorsAt,omitempty"`
+	EndsAt   *time.Time `json:"endsAt,omitempty"`
+}
+
+// locations caches the loaded timezones, time.LoadLocation reads the zone file on every call
+var locations sync.Map
+
+// LoadLocation returns the IANA zone name, UTC for an empty or unknown name.
+func LoadLocation(name string) *time.Location {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		loc = time.UTC
+	}
+	locations.Store(name, loc)
+	return loc
+}
+
+// Listed tells whether the public sees the show at now: it is not a draft, nor scheduled
+// before its publish time.
+func (s Show) Listed(now time.Time) bool {
+	switch s.Status {
+	case ShowDraft:
+		return false
+	case ShowScheduled:
+		return s.PublishAt != nil && !now.Before(*s.PublishAt)
+	}
+	return true
+}
+
+// In returns t in the timezone of the show
+func (s Show) In(t time.Time) time.Time {
+	return t.In(LoadLocation(s.Timezone))
+}
+
+// DoorsTime is when the doors open, the start of the show when it has no doors time.
+func (s Show) DoorsTime() time.Time {
+	if s.DoorsAt != nil {
+		return *s.DoorsAt
+	}
+	return s.StartsAt
+}
+
+// EndTime is when the show ends, its start when it has no end time.
+func (s Show) EndTime() time.Time {
+	if s.EndsAt != nil {
+		return *s.EndsAt
+	}
+	return s.StartsAt
+}
+
+// setTimes keeps the instants in UTC and fills Local
+func (s *Show) setTimes() {
+	utc := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		u := t.UTC()
+		return &u
+	}
+	local := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		l := s.In(*t)
+		return &l
+	}
+	s.StartsAt = s.StartsAt.UTC()
+	s.DoorsAt = utc(s.DoorsAt)
+	s.EndsAt = utc(s.EndsAt)
+	s.Local = &ShowTimes{StartsAt: s.In(s.StartsAt), DoorsAt: local(s.DoorsAt), EndsAt: local(s.EndsAt)}
+}
+
+func (s *Show) AfterFind(tx *gorm.DB) error {
+	s.setTimes()
+	return nil
+}
+
+// BeforeSave stores the computed sold out status as published
+func (s *Show) BeforeSave(tx *gorm.DB) error {
+	if s.Status == "" || s.Status == ShowSoldOut {
+		s.Status = ShowPublished
+	}
+	return nil
+}
+
+func (s *Show) AfterSave(tx *gorm.DB) error {
+	s.setTimes()
+	return nil
 }
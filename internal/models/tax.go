@@ -0,0 +1,41 @@
+package models
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// TaxRate is the sales tax applied to the tickets and fees of the shows of a country.
+// A rate with a Venue only applies to the shows of that venue in its country and wins over
+// the country rate.
+type TaxRate struct {
+	gorm.Model
+	Country string  `gorm:"not null;index" json:"country"`
+	Venue   string  `gorm:"index" json:"venue,omitempty"`
+	Name    string  `gorm:"not null" json:"name"`
+	Percent float64 `gorm:"not null" json:"percent"`
+}
+
+func (r TaxRate) Validate() error {
+	if len(r.Country) != 2 {
+		return errors.New("country must be an ISO 3166 two letter code")
+	}
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if r.Percent < 0 || r.Percent > 100 {
+		return errors.New("percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// PriceBreakdown splits the gross price of a booking, TotalPrice, into the tickets,
+// the booking fees and the tax on both.
+type PriceBreakdown struct {
+	NetAmount  float64 `json:"netAmount"`
+	FeeAmount  float64 `json:"feeAmount"`
+	TaxAmount  float64 `json:"taxAmount"`
+	TaxName    string  `json:"taxName,omitempty"`
+	TaxPercent float64 `json:"taxPercent,omitempty"`
+}
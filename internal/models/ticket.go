@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TicketValid = "valid"
+	TicketUsed  = "used"
+	TicketVoid  = "void"
+)
+
+// Ticket is one admission of a booking, Code is the signed value encoded in its QR code.
+// A seat of a booking has a single ticket that isn't void, for each show of the booking.
+type Ticket struct {
+	gorm.Model
+	BookingID   uint       `gorm:"not null;index;uniqueIndex:idx_tickets_seat,where:status <> 'void'" json:"bookingId"`
+	Booking     Booking    `gorm:"foreignKey:BookingID;references:ID" json:"-"`
+	ShowID      uint       `gorm:"not null;index;uniqueIndex:idx_tickets_seat" json:"showId"`
+	Seq         int        `gorm:"not null;uniqueIndex:idx_tickets_seat" json:"seq"`
+	Code        string     `gorm:"not null;uniqueIndex" json:"code"`
+	Status      string     `gorm:"default:'valid'" json:"status"`
+	CheckedInAt *time.Time `json:"checkedInAt,omitempty"`
+	Gate        string     `json:"gate,omitempty"`
+	CheckedInBy *uint      `json:"checkedInBy,omitempty"`
+}
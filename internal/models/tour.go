@@ -0,0 +1,28 @@
+package models
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Tour is a series of shows of an artist, a tour across cities or a residency at a venue.
+type Tour struct {
+	gorm.Model
+	Name        string `gorm:"not null" json:"name"`
+	ArtistID    uint   `gorm:"not null;index" json:"artistId"`
+	Artist      Artist `gorm:"foreignKey:ArtistID;references:ID" json:"artist"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"imageUrl,omitempty"`
+	Shows       []Show `gorm:"foreignKey:TourID" json:"shows,omitempty"`
+}
+
+func (t Tour) Validate() error {
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+	if t.ArtistID == 0 {
+		return errors.New("artistId is required")
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TransferPending   = "pending"
+	TransferAccepted  = "accepted"
+	TransferCancelled = "cancelled"
+)
+
+// Transfer is an invitation to take over a booking, sent to an email address.
+type Transfer struct {
+	gorm.Model
+	BookingID  uint       `gorm:"not null;index" json:"bookingId"`
+	FromUserID uint       `gorm:"not null" json:"fromUserId"`
+	ToEmail    string     `gorm:"not null" json:"toEmail"`
+	ToUserID   *uint      `json:"toUserId,omitempty"`
+	Status     string     `gorm:"default:'pending';index" json:"status"`
+	Nonce      string     `gorm:"not null" json:"-"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expiresAt"`
+	AcceptedAt *time.Time `json:"acceptedAt,omitempty"`
+}
+
+// audit actions of a booking
+const (
+	AuditTransferInvited   = "transfer_invited"
+	AuditTransferAccepted  = "transfer_accepted"
+	AuditTransferCancelled = "transfer_cancelled"
+	AuditResaleListed      = "resale_listed"
+	AuditResaleSold        = "resale_sold"
+)
+
+// BookingAudit records who did what to a booking, e.g. the steps of a transfer.
+type BookingAudit struct {
+	gorm.Model
+	BookingID uint   `gorm:"not null;index" json:"bookingId"`
+	Action    string `gorm:"not null" json:"action"`
+	ActorID   *uint  `json:"actorId,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+}
@@ -11,6 +11,12 @@ type User struct {
 	LastName     string    `json:"lastName,omitempty"`
 	Role         string    `gorm:"default:user" json:"role"`
 	Bookings     []Booking `gorm:"foreignKey:UserID" json:"-"`
+
+	// CreditBalance is the sum of the credit entries of the user
+	CreditBalance float64 `gorm:"default:0" json:"creditBalance"`
+
+	// ShowAlertsOff stops the emails about the new shows of the followed artists
+	ShowAlertsOff bool `json:"showAlertsOff"`
 }
 
 type UserRole string
@@ -18,5 +24,6 @@ type UserRole string
 const (
 	RoleUser      UserRole = "user"
 	RoleModerator UserRole = "moderator"
+	RoleStaff     UserRole = "staff"
 	RoleAdmin     UserRole = "admin"
 )
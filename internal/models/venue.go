@@ -0,0 +1,58 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Venue is a place shows are played at. The shows keep a copy of its name and city
+// in Show.Venue and Show.City, updated with the venue.
+type Venue struct {
+	gorm.Model
+	Name string `gorm:"not null" json:"name"`
+	// NameKey is the name without case, accents, articles and punctuation, "L'Olympia" and
+	// "Olympia" share theirs. It finds the venue of a name typed by hand.
+	NameKey    string `gorm:"not null;index" json:"-"`
+	Address    string `json:"address,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+	City       string `gorm:"index" json:"city,omitempty"`
+	Country    string `json:"country,omitempty"`
+	// Timezone is an IANA name, e.g. Europe/Paris
+	Timezone  string   `json:"timezone,omitempty"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	// Capacity bounds the seats of its shows, zero means unknown
+	Capacity int `json:"capacity"`
+
+	WheelchairAccessible bool   `json:"wheelchairAccessible"`
+	AccessibilityNotes   string `json:"accessibilityNotes,omitempty"`
+}
+
+func (v Venue) Validate() error {
+	if v.Name == "" {
+		return errors.New("name is required")
+	}
+	if v.Country != "" && len(v.Country) != 2 {
+		return errors.New("country must be an ISO 3166 two letter code")
+	}
+	if v.Timezone != "" {
+		if _, err := time.LoadLocation(v.Timezone); err != nil {
+			return errors.New("timezone must be an IANA time zone name")
+		}
+	}
+	if (v.Latitude == nil) != (v.Longitude == nil) {
+		return errors.New("latitude and longitude go together")
+	}
+	if v.Latitude != nil && (*v.Latitude < -90 || *v.Latitude > 90) {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	if v.Longitude != nil && (*v.Longitude < -180 || *v.Longitude > 180) {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	if v.Capacity < 0 {
+		return errors.New("capacity can't be negative")
+	}
+	return nil
+}
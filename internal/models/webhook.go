@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	EventBookingCreated   = "booking.created"
+	EventBookingCancelled = "booking.cancelled"
+	EventShowCreated      = "show.created"
+	EventShowUpdated      = "show.updated"
+	EventShowDeleted      = "show.deleted"
+	EventWebhookTest      = "webhook.test"
+)
+
+// WebhookEvents lists the event types partners can subscribe to.
+var WebhookEvents = []string{
+	EventBookingCreated,
+	EventBookingCancelled,
+	EventShowCreated,
+	EventShowUpdated,
+	EventShowDeleted,
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+	DeliveryCancelled = "cancelled"
+)
+
+type WebhookSubscription struct {
+	gorm.Model
+	URL         string     `gorm:"not null" json:"url"`
+	Events      StringList `gorm:"type:text;not null" json:"events"`
+	Secret      string     `gorm:"not null" json:"-"`
+	Description string     `json:"description,omitempty"`
+	Active      bool       `gorm:"default:true" json:"active"`
+}
+
+type WebhookDelivery struct {
+	gorm.Model
+	SubscriptionID uint                `gorm:"not null;index" json:"subscriptionId"`
+	Subscription   WebhookSubscription `gorm:"foreignKey:SubscriptionID;references:ID" json:"-"`
+	EventID        string              `gorm:"not null;index" json:"eventId"`
+	Event          string              `gorm:"not null" json:"event"`
+	Payload        string              `gorm:"type:text;not null" json:"payload"`
+	Status         string              `gorm:"default:'pending';index" json:"status"`
+	Attempts       int                 `json:"attempts"`
+	NextAttemptAt  time.Time           `gorm:"index" json:"nextAttemptAt"`
+	LastStatusCode int                 `json:"lastStatusCode,omitempty"`
+	LastError      string              `json:"lastError,omitempty"`
+	DeliveredAt    *time.Time          `json:"deliveredAt,omitempty"`
+	Logs           []WebhookAttempt    `gorm:"foreignKey:DeliveryID" json:"logs,omitempty"`
+}
+
+// WebhookAttempt is the log line written for every HTTP call made for a delivery.
+type WebhookAttempt struct {
+	gorm.Model
+	DeliveryID uint   `gorm:"not null;index" json:"deliveryId"`
+	Attempt    int    `json:"attempt"`
+	StatusCode int    `json:"statusCode"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+	Response   string `gorm:"type:text" json:"response,omitempty"`
+}
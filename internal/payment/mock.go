@@ -0,0 +1,191 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const MockSignatureHeader = "X-Mock-Signature"
+
+// DeclinedAmountCents makes the mock provider decline any amount ending with these cents,
+// e.g. 10.02, to exercise the failure paths.
+const DeclinedAmountCents = 2
+
+// MockIntent is an intent of the mock provider. Intents are kept in the database: they
+// survive restarts and the temporal worker refunds the intents created by the server.
+type MockIntent struct {
+	ID        string `gorm:"primaryKey"`
+	Amount    int64  `gorm:"not null"`
+	Currency  string `gorm:"not null"`
+	Captured  bool
+	Refunded  int64
+	Refunds   int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (MockIntent) TableName() string {
+	return "mock_payment_intents"
+}
+
+// MockRefund is a refund of the mock provider, found again by its idempotency key.
+type MockRefund struct {
+	Key       string `gorm:"primaryKey"`
+	IntentID  string `gorm:"not null"`
+	Amount    int64  `gorm:"not null"`
+	RefundID  string `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (MockRefund) TableName() string {
+	return "mock_payment_refunds"
+}
+
+// MockProvider is a deterministic provider for development and tests: intent IDs derive
+// from the reference and webhooks are signed with Secret.
+type MockProvider struct {
+	Secret string
+	// FailRefunds makes every refund fail, to exercise the compensation of the refund saga
+	FailRefunds bool
+	// Instrument is the card fingerprint of the payments completed with the mock
+	Instrument string
+
+	db *gorm.DB
+}
+
+func NewMockProvider(secret string, db *gorm.DB) *MockProvider {
+	return &MockProvider{Secret: secret, Instrument: "card_mock_4242", db: db}
+}
+
+func (m *MockProvider) intent(db *gorm.DB, id string) (MockIntent, error) {
+	var intent MockIntent
+	err := db.First(&intent, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return intent, ErrUnknownIntent
+	}
+	return intent, err
+}
+
+func (m *MockProvider) Name() string {
+	return "mock"
+}
+
+func (m *MockProvider) CreateIntent(ctx context.Context, amount int64, currency, reference string) (Intent, error) {
+	intent := MockIntent{ID: "pi_mock_" + reference, Amount: amount, Currency: currency}
+	// an existing intent of the reference is returned as it is
+	if err := m.db.WithContext(ctx).Where(MockIntent{ID: intent.ID}).FirstOrCreate(&intent).Error; err != nil {
+		return Intent{}, err
+	}
+	return Intent{ID: intent.ID, ClientSecret: intent.ID + "_secret", Amount: intent.Amount, Currency: intent.Currency}, nil
+}
+
+func (m *MockProvider) Capture(ctx context.Context, intentID string) error {
+	result := m.db.WithContext(ctx).Model(&MockIntent{}).Where("id = ?", intentID).Update("captured", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUnknownIntent
+	}
+	return nil
+}
+
+func (m *MockProvider) Refund(ctx context.Context, intentID string, amount int64, key string) (string, error) {
+	if m.FailRefunds {
+		return "", ErrDeclined
+	}
+	if key == "" {
+		return "", errors.New("payment: a refund needs an idempotency key")
+	}
+	var refundID string
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var done MockRefund
+		err := tx.First(&done, "key = ?", key).Error
+		if err == nil {
+			if done.IntentID != intentID || done.Amount != amount {
+				return fmt.Errorf("payment: refund key %s was used for %d of intent %s", key, done.Amount, done.IntentID)
+			}
+			refundID = done.RefundID
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// the conditions make concurrent refunds unable to give back more than was paid
+		result := tx.Model(&MockIntent{}).
+			Where("id = ? AND captured = ? AND refunded + ? <= amount", intentID, true, amount).
+			Updates(map[string]any{"refunded": gorm.Expr("refunded + ?", amount), "refunds": gorm.Expr("refunds + 1")})
+		if result.Error != nil {
+			return result.Error
+		}
+		intent, err := m.intent(tx, intentID)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("payment: cannot refund %d of intent %s", amount, intentID)
+		}
+		refundID = fmt.Sprintf("re_mock_%s_%d", intentID, intent.Refunds)
+		// a concurrent refund with the same key fails on it and rolls back
+		return tx.Create(&MockRefund{Key: key, IntentID: intentID, Amount: amount, RefundID: refundID}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return refundID, nil
+}
+
+// Complete simulates the customer finishing the payment, it returns the webhook the
+// provider would send: declined when the amount ends with DeclinedAmountCents.
+func (m *MockProvider) Complete(intentID string) ([]byte, http.Header, error) {
+	intent, err := m.intent(m.db, intentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	event := Event{ID: "evt_" + intentID, Type: EventAuthorized, IntentID: intentID, Amount: intent.Amount, Instrument: m.Instrument}
+	if intent.Amount%100 == DeclinedAmountCents {
+		event.Type = EventFailed
+		event.ID += "_failed"
+		event.FailureReason = ErrDeclined.Error()
+	}
+	return m.SignEvent(event)
+}
+
+// SignEvent encodes and signs an event the way the mock provider webhooks are.
+func (m *MockProvider) SignEvent(event Event) ([]byte, http.Header, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set(MockSignatureHeader, m.signature(payload))
+	return payload, header, nil
+}
+
+func (m *MockProvider) signature(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(m.Secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (m *MockProvider) VerifyWebhook(payload []byte, header http.Header) (Event, error) {
+	if !hmac.Equal([]byte(header.Get(MockSignatureHeader)), []byte(m.signature(payload))) {
+		return Event{}, ErrInvalidSignature
+	}
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return Event{}, err
+	}
+	return event, nil
+}
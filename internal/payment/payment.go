@@ -0,0 +1,89 @@
+// Package payment abstracts the payment service provider charging the bookings.
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// webhook event types, providers translate their own events to these
+const (
+	EventAuthorized      = "payment.authorized"
+	EventFailed          = "payment.failed"
+	EventRefundSucceeded = "refund.succeeded"
+	EventRefundFailed    = "refund.failed"
+)
+
+var (
+	ErrUnknownIntent    = errors.New("payment: unknown payment intent")
+	ErrInvalidSignature = errors.New("payment: invalid webhook signature")
+	ErrDeclined         = errors.New("payment: declined")
+	ErrNoWebhookSecret  = errors.New("payment: PAYMENT_WEBHOOK_SECRET is not set")
+)
+
+// Intent is a payment started with the provider, the client completes it with ClientSecret.
+// Amounts are in the smallest currency unit.
+type Intent struct {
+	ID           string `json:"id"`
+	ClientSecret string `json:"clientSecret"`
+	Amount       int64  `json:"amount"`
+	Currency     string `json:"currency"`
+}
+
+// Event is a verified webhook notification of the provider.
+type Event struct {
+	ID            string `json:"id"`
+	Type          string `json:"type"`
+	IntentID      string `json:"intentId"`
+	RefundID      string `json:"refundId,omitempty"`
+	Amount        int64  `json:"amount"`
+	FailureReason string `json:"failureReason,omitempty"`
+	// Instrument fingerprints the card or account of an authorized payment, the same card
+	// gives the same fingerprint
+	Instrument string `json:"instrument,omitempty"`
+}
+
+type PaymentProvider interface {
+	Name() string
+	// CreateIntent starts a payment of amount, reference identifies it on our side and
+	// makes the call idempotent.
+	CreateIntent(ctx context.Context, amount int64, currency, reference string) (Intent, error)
+	// Capture collects an authorized payment.
+	Capture(ctx context.Context, intentID string) error
+	// Refund gives back amount of a captured payment and returns the refund ID. key makes
+	// the call idempotent: a refund retried with the key of one already made returns it
+	// rather than refunding again.
+	Refund(ctx context.Context, intentID string, amount int64, key string) (string, error)
+	// VerifyWebhook authenticates a webhook request and decodes its event.
+	VerifyWebhook(payload []byte, header http.Header) (Event, error)
+}
+
+// NewProvider returns the provider called name, its webhooks are signed with secret. The
+// mock provider keeps its intents in db.
+func NewProvider(name, secret string, db *gorm.DB) (PaymentProvider, error) {
+	if secret == "" {
+		return nil, ErrNoWebhookSecret
+	}
+	switch name {
+	case "mock":
+		return NewMockProvider(secret, db), nil
+	}
+	return nil, fmt.Errorf("payment: unknown provider %q", name)
+}
+
+// ToCents converts an amount of the models to the smallest currency unit.
+func ToCents(amount float64) int64 {
+	if amount < 0 {
+		return -int64(-amount*100 + 0.5)
+	}
+	return int64(amount*100 + 0.5)
+}
+
+// FromCents converts an amount in the smallest currency unit back to the models.
+func FromCents(amount int64) float64 {
+	return float64(amount) / 100
+}
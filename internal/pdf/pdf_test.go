@@ -0,0 +1,36 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentStructure(t *testing.T) {
+	doc := New("Ticket (1)")
+	doc.Text(40, 60, 18, true, "Café (live) €5")
+	doc.Rect(40, 80, 10, 10, true)
+	doc.AddPage()
+	doc.Line(40, 40, 200, 40, 1)
+	data := doc.Bytes()
+
+	assert.True(t, bytes.HasPrefix(data, []byte("%PDF-1.4")))
+	assert.Contains(t, string(data), "/Count 2")
+	assert.Contains(t, string(data), `(Caf\351 \(live\) \2005)`)
+
+	// every xref entry points at the start of its object
+	m := regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(data)
+	assert.NotNil(t, m)
+	xref, _ := strconv.Atoi(string(m[1]))
+	assert.True(t, bytes.HasPrefix(data[xref:], []byte("xref\n0 10\n")))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(data[xref:], -1)
+	assert.Len(t, entries, 9)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		assert.True(t, bytes.HasPrefix(data[offset:], []byte(fmt.Sprintf("%d 0 obj", i+1))), "object %d", i+1)
+	}
+}
@@ -0,0 +1,146 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readCodewords undoes the mask and reads the codewords back in placement order.
+func readCodewords(q *QRCode) []byte {
+	q.applyMask(q.Mask)
+	defer q.applyMask(q.Mask)
+
+	var result []byte
+	var cur byte
+	n := 0
+	for right := q.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = q.Size - 1 - vert
+				}
+				if q.isFunc[y][x] {
+					continue
+				}
+				cur <<= 1
+				if q.modules[y][x] {
+					cur |= 1
+				}
+				if n++; n%8 == 0 {
+					result = append(result, cur)
+					cur = 0
+				}
+			}
+		}
+	}
+	return result[:numRawDataModules(q.Version)/8]
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, data := range []string{"A", "t:42:1.signature-goes-here", strings.Repeat("x", 150)} {
+		q, err := Encode(data)
+		assert.NoError(t, err)
+		assert.Equal(t, q.Version*4+17, q.Size)
+
+		codewords := readCodewords(q)
+		numBlocks := numEccBlocks[q.Version]
+		eccLen := eccCodewordsPerBlock[q.Version]
+		numShort := numBlocks - len(codewords)%numBlocks
+		shortLen := len(codewords) / numBlocks
+
+		// de-interleave and check every block is a valid Reed-Solomon codeword
+		blocks := make([][]byte, numBlocks)
+		k := 0
+		for i := 0; i < shortLen+1; i++ {
+			for j := 0; j < numBlocks; j++ {
+				if i == shortLen-eccLen && j < numShort {
+					continue
+				}
+				blocks[j] = append(blocks[j], codewords[k])
+				k++
+			}
+		}
+		var payload []byte
+		for j, block := range blocks {
+			dataLen := len(block) - eccLen
+			if j < numShort {
+				assert.Equal(t, shortLen, len(block))
+			}
+			root := byte(1)
+			for r := 0; r < eccLen; r++ {
+				var syndrome byte
+				for _, b := range block {
+					syndrome = gfMultiply(syndrome, root) ^ b
+				}
+				assert.Zero(t, syndrome, "block %d syndrome %d", j, r)
+				root = gfMultiply(root, 0x02)
+			}
+			payload = append(payload, block[:dataLen]...)
+		}
+
+		assert.Equal(t, byte(0x4), payload[0]>>4)
+		length := int(payload[0]&0xF)<<4 | int(payload[1]>>4)
+		decoded := make([]byte, length)
+		for i := range decoded {
+			decoded[i] = payload[1+i]<<4 | payload[2+i]>>4
+		}
+		assert.Equal(t, data, string(decoded))
+	}
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	q := newQRCode(7)
+	q.drawFormatBits(1)
+	var bits int
+	for i := 0; i <= 5; i++ {
+		if q.modules[i][8] {
+			bits |= 1 << i
+		}
+	}
+	if q.modules[7][8] {
+		bits |= 1 << 6
+	}
+	if q.modules[8][8] {
+		bits |= 1 << 7
+	}
+	if q.modules[8][7] {
+		bits |= 1 << 8
+	}
+	for i := 9; i < 15; i++ {
+		if q.modules[8][14-i] {
+			bits |= 1 << i
+		}
+	}
+	// level M with mask 1, from the specification table
+	assert.Equal(t, 0b101000100100101, bits)
+
+	q.drawVersion()
+	var version int
+	for i := 0; i < 18; i++ {
+		if q.modules[i/3][q.Size-11+i%3] {
+			version |= 1 << i
+		}
+	}
+	assert.Equal(t, 0b000111110010010100, version)
+}
+
+func TestPNGAndTooLong(t *testing.T) {
+	q, err := Encode("ticket")
+	assert.NoError(t, err)
+	data, err := q.PNG(4)
+	assert.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, (q.Size+8)*4, img.Bounds().Dx())
+
+	_, err = Encode(strings.Repeat("x", 500))
+	assert.ErrorIs(t, err, ErrTooLong)
+}
@@ -56,3 +56,8 @@ func GetEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// DevMode is true when APP_ENV is development, it enables the endpoints simulating payments.
+func DevMode() bool {
+	return os.Getenv("APP_ENV") == "development"
+}
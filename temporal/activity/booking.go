@@ -0,0 +1,43 @@
+package activity
+
+import (
+	"bytes"
+	"concert/temporal/workflow"
+	"html/template"
+	"log"
+
+	"github.com/resend/resend-go/v2"
+)
+
+var bookingConfirmationTemplate = template.Must(template.New("booking").Parse(`<p>Hi {{.Username}},</p>
+<p>Your booking #{{.BookingID}} is confirmed: {{.TicketCount}} ticket(s) for <strong>{{.ShowTitle}}</strong>,
+{{.Venue}}, {{.Date}}.</p>
+<p>Total paid: {{printf "%.2f" .TotalPrice}}{{if .InvoiceNumber}} (invoice {{.InvoiceNumber}}){{end}}.</p>
+<p>Your tickets and your receipt are attached, show the QR code of each ticket at the entrance.</p>`))
+
+func (e *EmailActivities) SendBookingConfirmationEmail(booking workflow.BookingConfirmation) error {
+	var body bytes.Buffer
+	if err := bookingConfirmationTemplate.Execute(&body, booking); err != nil {
+		return err
+	}
+
+	attachments := make([]*resend.Attachment, len(booking.Attachments))
+	for i, a := range booking.Attachments {
+		attachments[i] = &resend.Attachment{Filename: a.Filename, Content: a.Content, ContentType: "application/pdf"}
+	}
+
+	params := &resend.SendEmailRequest{
+		From:        "Concert Booking system <onboarding@resend.dev>",
+		To:          []string{booking.Email},
+		Subject:     "Your tickets for " + booking.ShowTitle,
+		Html:        body.String(),
+		Attachments: attachments,
+	}
+
+	sent, err := e.Client.Emails.Send(params)
+	if err != nil {
+		return err
+	}
+	log.Printf("Booking confirmation %d sent: %s", booking.BookingID, sent.Id)
+	return nil
+}
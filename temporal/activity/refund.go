@@ -0,0 +1,44 @@
+package activity
+
+import (
+	"concert/internal/concert"
+	"errors"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+type RefundActivities struct {
+	Service *concert.Service
+}
+
+func NewRefundActivities(service *concert.Service) *RefundActivities {
+	return &RefundActivities{Service: service}
+}
+
+// nonRetryable stops the retries of errors that won't go away
+func nonRetryable(err error) error {
+	if errors.Is(err, concert.ErrNotRefundable) {
+		return temporal.NewNonRetryableApplicationError(err.Error(), "NotRefundable", err)
+	}
+	if errors.Is(err, concert.ErrSeatsResold) {
+		return temporal.NewNonRetryableApplicationError(err.Error(), "SeatsResold", err)
+	}
+	return err
+}
+
+func (a *RefundActivities) BeginRefund(bookingID uint, amount, credit float64) error {
+	return nonRetryable(a.Service.BeginRefund(bookingID, amount, credit))
+}
+
+func (a *RefundActivities) IssueRefund(bookingID uint, amount float64) (string, error) {
+	refundID, err := a.Service.IssueRefund(bookingID, amount)
+	return refundID, nonRetryable(err)
+}
+
+func (a *RefundActivities) CompleteRefund(bookingID uint, refundID string) error {
+	return nonRetryable(a.Service.CompleteRefund(bookingID, refundID))
+}
+
+func (a *RefundActivities) AbortRefund(bookingID uint) error {
+	return nonRetryable(a.Service.AbortRefund(bookingID))
+}
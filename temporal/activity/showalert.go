@@ -0,0 +1,91 @@
+package activity
+
+import (
+	"bytes"
+	"concert/internal/concert"
+	"concert/internal/utils"
+	"concert/temporal/workflow"
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"time"
+
+	"github.com/resend/resend-go/v2"
+)
+
+type FollowerActivities struct {
+	Service *concert.Service
+}
+
+func NewFollowerActivities(service *concert.Service) *FollowerActivities {
+	return &FollowerActivities{Service: service}
+}
+
+// NextShowAlertBatch is the next batch of followers to tell about a show, an empty one
+// when they all were
+func (a *FollowerActivities) NextShowAlertBatch(showID, afterUserID uint, limit int) (workflow.ShowAlertBatch, error) {
+	users, err := a.Service.ShowAlertRecipients(showID, afterUserID, limit, time.Now())
+	if err != nil || len(users) == 0 {
+		return workflow.ShowAlertBatch{}, err
+	}
+	show, err := a.Service.GetShowByID(showID)
+	if err != nil {
+		return workflow.ShowAlertBatch{}, err
+	}
+
+	appURL := utils.GetEnvOrDefault("APP_URL", "http://localhost:8080")
+	batch := workflow.ShowAlertBatch{
+		ShowID:     show.ID,
+		ArtistName: show.Artist.Name,
+		ShowTitle:  show.Title,
+		Venue:      show.Venue,
+		Date:       concert.FormatShowDate(show),
+		ShowURL:    fmt.Sprintf("%s/concerts/%d", appURL, show.ID),
+		LastUserID: users[len(users)-1].ID,
+	}
+	for _, user := range users {
+		batch.Emails = append(batch.Emails, user.Email)
+	}
+	return batch, nil
+}
+
+// MarkShowAlerted marks the show as announced once its first batch is sent, an alert
+// started again for it sends nothing
+func (a *FollowerActivities) MarkShowAlerted(showID uint) error {
+	return a.Service.MarkShowAlerted(showID, time.Now())
+}
+
+var newShowAlertTemplate = template.Must(template.New("show-alert").Parse(`<p>Hi,</p>
+<p>{{.ArtistName}} just announced <strong>{{.ShowTitle}}</strong>, {{.Venue}}, {{.Date}}.</p>
+<p><a href="{{.ShowURL}}">See the show and book your tickets</a>.</p>
+<p>You get this email because you follow {{.ArtistName}}, you can turn these alerts off in your account.</p>`))
+
+// SendNewShowAlertEmails sends an email to each follower of the batch, in one call. The
+// batch is sent once even when the activity is retried.
+func (e *EmailActivities) SendNewShowAlertEmails(batch workflow.ShowAlertBatch) error {
+	var body bytes.Buffer
+	if err := newShowAlertTemplate.Execute(&body, batch); err != nil {
+		return err
+	}
+
+	params := make([]*resend.SendEmailRequest, len(batch.Emails))
+	for i, email := range batch.Emails {
+		params[i] = &resend.SendEmailRequest{
+			From:    "Concert Booking system <onboarding@resend.dev>",
+			To:      []string{email},
+			Subject: batch.ArtistName + " announced a new show: " + batch.ShowTitle,
+			Html:    body.String(),
+		}
+	}
+
+	options := &resend.BatchSendEmailOptions{
+		IdempotencyKey: fmt.Sprintf("show-alert-%d-%d", batch.ShowID, batch.LastUserID),
+	}
+	sent, err := e.Client.Batch.SendWithOptions(context.Background(), params, options)
+	if err != nil {
+		return err
+	}
+	log.Printf("New show alerts sent: %d emails", len(sent.Data))
+	return nil
+}
@@ -0,0 +1,36 @@
+package activity
+
+import (
+	"bytes"
+	"concert/temporal/workflow"
+	"html/template"
+	"log"
+
+	"github.com/resend/resend-go/v2"
+)
+
+var transferInviteTemplate = template.Must(template.New("transfer").Parse(`<p>Hi,</p>
+<p>{{.FromUsername}} is giving you {{.TicketCount}} ticket(s) for <strong>{{.ShowTitle}}</strong> on {{.Date}}.</p>
+<p><a href="{{.AcceptURL}}">Accept the tickets</a>, you can create an account with this email address if you don't have one.</p>
+<p>This invitation expires on {{.ExpiresAt}}.</p>`))
+
+func (e *EmailActivities) SendTransferInviteEmail(invite workflow.TransferInvite) error {
+	var body bytes.Buffer
+	if err := transferInviteTemplate.Execute(&body, invite); err != nil {
+		return err
+	}
+
+	params := &resend.SendEmailRequest{
+		From:    "Concert Booking system <onboarding@resend.dev>",
+		To:      []string{invite.Email},
+		Subject: invite.FromUsername + " sent you tickets for " + invite.ShowTitle,
+		Html:    body.String(),
+	}
+
+	sent, err := e.Client.Emails.Send(params)
+	if err != nil {
+		return err
+	}
+	log.Printf("Transfer invitation sent: %s", sent.Id)
+	return nil
+}
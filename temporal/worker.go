@@ -1,6 +1,8 @@
 package main
 
 import (
+	"concert/internal/concert"
+	"concert/internal/database"
 	"concert/internal/utils"
 	"concert/temporal/activity"
 	"concert/temporal/workflow"
@@ -30,14 +32,45 @@ func main() {
 	}
 	defer c.Close()
 
+	// the refund activities issue ticket codes again when a refund is undone
+	if err := utils.CheckSigningSecret(); err != nil {
+		log.Fatal(err)
+	}
+
 	resendAPI := os.Getenv("RESEND_API")
 	if resendAPI == "" {
 		log.Fatal("RESEND_API environment variable is required")
 	}
 	sendmail := activity.NewEmailActivities(resendAPI)
 
+	db, err := database.DbSetup()
+	if err != nil {
+		log.Fatalf("failed to setup database: %v", err)
+	}
+	service, err := concert.NewConcertFromEnv(db)
+	if err != nil {
+		log.Fatalf("failed to setup the payments: %v", err)
+	}
+	refunds := activity.NewRefundActivities(service)
+	followers := activity.NewFollowerActivities(service)
+
+	bw := worker.New(c, workflow.BookingTaskQueue, worker.Options{})
+	bw.RegisterWorkflow(workflow.RefundWorkflow)
+	bw.RegisterActivity(refunds)
+	if err := bw.Start(); err != nil {
+		log.Fatalf("failed to start the booking worker: %v", err)
+	}
+	defer bw.Stop()
+
 	w := worker.New(c, "email-task-queue", worker.Options{})
 	w.RegisterWorkflow(workflow.SendMailWorkflow)
+	w.RegisterWorkflow(workflow.BookingConfirmationWorkflow)
+	w.RegisterWorkflow(workflow.TransferInviteWorkflow)
+	w.RegisterWorkflow(workflow.NewShowAlertWorkflow)
 	w.RegisterActivity(sendmail.SendResetPasswordEmail)
+	w.RegisterActivity(sendmail.SendBookingConfirmationEmail)
+	w.RegisterActivity(sendmail.SendTransferInviteEmail)
+	w.RegisterActivity(sendmail.SendNewShowAlertEmails)
+	w.RegisterActivity(followers)
 	w.Run(worker.InterruptCh())
 }
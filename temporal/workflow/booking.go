@@ -0,0 +1,47 @@
+package workflow
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+type Attachment struct {
+	Filename string `json:"filename"`
+	Content  []byte `json:"content"`
+}
+
+type BookingConfirmation struct {
+	Email         string       `json:"email"`
+	Username      string       `json:"username"`
+	BookingID     uint         `json:"bookingId"`
+	ShowTitle     string       `json:"showTitle"`
+	Venue         string       `json:"venue"`
+	Date          string       `json:"date"`
+	TicketCount   int          `json:"ticketCount"`
+	TotalPrice    float64      `json:"totalPrice"`
+	InvoiceNumber string       `json:"invoiceNumber"`
+	Attachments   []Attachment `json:"attachments"`
+}
+
+func BookingConfirmationWorkflow(ctx workflow.Context, booking BookingConfirmation) error {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Sending booking confirmation", "booking", booking.BookingID)
+
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 3 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    5,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+	if err := workflow.ExecuteActivity(ctx, "SendBookingConfirmationEmail", booking).Get(ctx, nil); err != nil {
+		logger.Error("Error sending booking confirmation", "error", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package workflow
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const BookingTaskQueue = "booking-task-queue"
+
+// RefundInput is the refund of a booking, Amount goes back to the payment method and
+// Credit to the account credit of the fan.
+type RefundInput struct {
+	BookingID uint    `json:"bookingId"`
+	Amount    float64 `json:"amount"`
+	Credit    float64 `json:"credit"`
+}
+
+// RefundWorkflow is the saga cancelling a paid booking: the booking is cancelled, then the
+// refund is issued. If the provider keeps refusing the refund, the cancellation is undone
+// so the fan keeps their seats instead of losing both.
+func RefundWorkflow(ctx workflow.Context, input RefundInput) error {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Refunding booking", "booking", input.BookingID, "amount", input.Amount)
+
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:        time.Second,
+			BackoffCoefficient:     2.0,
+			MaximumInterval:        time.Minute,
+			MaximumAttempts:        5,
+			NonRetryableErrorTypes: []string{"NotRefundable"},
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	if err := workflow.ExecuteActivity(ctx, "BeginRefund", input.BookingID, input.Amount, input.Credit).Get(ctx, nil); err != nil {
+		logger.Error("Error cancelling booking", "error", err)
+		return err
+	}
+
+	var refundID string
+	if err := workflow.ExecuteActivity(ctx, "IssueRefund", input.BookingID, input.Amount).Get(ctx, &refundID); err != nil {
+		logger.Error("Refund failed, restoring the booking", "error", err)
+		// compensation must happen, retry it for much longer than the forward steps
+		compensation := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+			StartToCloseTimeout: time.Minute,
+			RetryPolicy: &temporal.RetryPolicy{
+				InitialInterval:    time.Second,
+				BackoffCoefficient: 2.0,
+				MaximumInterval:    10 * time.Minute,
+			},
+		})
+		if cerr := workflow.ExecuteActivity(compensation, "AbortRefund", input.BookingID).Get(ctx, nil); cerr != nil {
+			logger.Error("Error restoring booking", "error", cerr)
+			return cerr
+		}
+		return err
+	}
+
+	if err := workflow.ExecuteActivity(ctx, "CompleteRefund", input.BookingID, refundID).Get(ctx, nil); err != nil {
+		logger.Error("Error recording refund", "error", err)
+		return err
+	}
+	return nil
+}
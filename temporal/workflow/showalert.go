@@ -0,0 +1,90 @@
+package workflow
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// ShowAlertBatchSize is how many followers are emailed at once, the most a batch of
+// emails holds
+const ShowAlertBatchSize = 100
+
+// showAlertBatchesPerRun bounds the history of a run, the alert continues as new after
+const showAlertBatchesPerRun = 100
+
+// NewShowAlert tells the followers of the artists of a show that it is announced, at
+// PublishAt for a scheduled show. AfterUserID is where a continued alert picks up.
+type NewShowAlert struct {
+	ShowID      uint       `json:"showId"`
+	PublishAt   *time.Time `json:"publishAt,omitempty"`
+	AfterUserID uint       `json:"afterUserId"`
+}
+
+// ShowAlertBatch is a batch of followers to email about a show, LastUserID is the id of
+// the last of them.
+type ShowAlertBatch struct {
+	ShowID     uint     `json:"showId"`
+	ArtistName string   `json:"artistName"`
+	ShowTitle  string   `json:"showTitle"`
+	Venue      string   `json:"venue"`
+	Date       string   `json:"date"`
+	ShowURL    string   `json:"showUrl"`
+	Emails     []string `json:"emails"`
+	LastUserID uint     `json:"lastUserId"`
+}
+
+// NewShowAlertWorkflow emails the followers batch by batch. The followers are looked up
+// when each batch is sent, so a fan who opts out in the meantime is skipped, and nothing
+// is sent once the show is cancelled or hidden again. The show is marked as announced
+// once the first batch is sent, an alert that sent nothing leaves it to a later one.
+func NewShowAlertWorkflow(ctx workflow.Context, alert NewShowAlert) error {
+	logger := workflow.GetLogger(ctx)
+	if alert.PublishAt != nil {
+		if wait := alert.PublishAt.Sub(workflow.Now(ctx)); wait > 0 {
+			if err := workflow.Sleep(ctx, wait); err != nil {
+				return err
+			}
+		}
+		alert.PublishAt = nil
+	}
+
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 3 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    5,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	for range showAlertBatchesPerRun {
+		var batch ShowAlertBatch
+		if err := workflow.ExecuteActivity(ctx, "NextShowAlertBatch", alert.ShowID, alert.AfterUserID, ShowAlertBatchSize).Get(ctx, &batch); err != nil {
+			logger.Error("Error listing the followers to alert", "show", alert.ShowID, "error", err)
+			return err
+		}
+		if len(batch.Emails) == 0 {
+			return nil
+		}
+		if err := workflow.ExecuteActivity(ctx, "SendNewShowAlertEmails", batch).Get(ctx, nil); err != nil {
+			logger.Error("Error sending new show alerts", "show", alert.ShowID, "error", err)
+			return err
+		}
+		logger.Info("New show alerts sent", "show", alert.ShowID, "count", len(batch.Emails))
+		if alert.AfterUserID == 0 {
+			if err := workflow.ExecuteActivity(ctx, "MarkShowAlerted", alert.ShowID).Get(ctx, nil); err != nil {
+				logger.Error("Error marking the show as announced", "show", alert.ShowID, "error", err)
+				return err
+			}
+		}
+		alert.AfterUserID = batch.LastUserID
+		if len(batch.Emails) < ShowAlertBatchSize {
+			return nil
+		}
+	}
+	return workflow.NewContinueAsNewError(ctx, NewShowAlertWorkflow, alert)
+}
@@ -0,0 +1,38 @@
+package workflow
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+type TransferInvite struct {
+	Email        string `json:"email"`
+	FromUsername string `json:"fromUsername"`
+	ShowTitle    string `json:"showTitle"`
+	Date         string `json:"date"`
+	TicketCount  int    `json:"ticketCount"`
+	AcceptURL    string `json:"acceptUrl"`
+	ExpiresAt    string `json:"expiresAt"`
+}
+
+func TransferInviteWorkflow(ctx workflow.Context, invite TransferInvite) error {
+	logger := workflow.GetLogger(ctx)
+
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 3 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    5,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+	if err := workflow.ExecuteActivity(ctx, "SendTransferInviteEmail", invite).Get(ctx, nil); err != nil {
+		logger.Error("Error sending transfer invitation", "error", err)
+		return err
+	}
+	return nil
+}
@@ -1,8 +1,12 @@
 package mocks
 
 import (
+	"concert/internal/concert"
 	"concert/internal/models"
+	"concert/internal/payment"
 	"mime/multipart"
+	"net/http"
+	"time"
 )
 
 type MockConcertService struct {
@@ -28,6 +32,127 @@ type MockConcertService struct {
 	GetMyBookingsFunc     func(user models.User) ([]models.Booking, error)
 	GetAllBookingsFunc    func() ([]models.Booking, error)
 	GetBookingByIdFunc    func(id uint) (models.Booking, error)
+
+	ListWebhooksFunc              func() ([]models.WebhookSubscription, error)
+	GetWebhookByIDFunc            func(id uint) (models.WebhookSubscription, error)
+	SetWebhookFunc                func(sub models.WebhookSubscription) (models.WebhookSubscription, error)
+	DeleteWebhookFunc             func(id uint) error
+	PublishWebhookEventFunc       func(event string, data any) error
+	ListWebhookDeliveriesFunc     func(subscriptionID uint) ([]models.WebhookDelivery, error)
+	ListDeadWebhookDeliveriesFunc func() ([]models.WebhookDelivery, error)
+	RetryWebhookDeliveryFunc      func(id uint) (models.WebhookDelivery, error)
+	SendTestWebhookFunc           func(subscriptionID uint) (models.WebhookDelivery, error)
+
+	JoinQueueFunc           func(showID, userID uint) (concert.QueueStatus, error)
+	GetQueueStatusFunc      func(token string) (concert.QueueStatus, error)
+	CheckQueueAdmissionFunc func(show models.Show, userID uint, token string) (models.QueueTicket, error)
+
+	CheckSaleWindowFunc func(show models.Show, user models.User, accessCode string) error
+	ListPresalesFunc    func(showID uint) ([]models.Presale, error)
+	GetPresaleByIDFunc  func(id uint) (models.Presale, error)
+	SetPresaleFunc      func(presale models.Presale) (models.Presale, error)
+	DeletePresaleFunc   func(id uint) error
+
+	CheckTicketLimitsFunc   func(show models.Show, user models.User, count int) error
+	ListLimitOverridesFunc  func(showID uint) ([]models.TicketLimitOverride, error)
+	SetLimitOverrideFunc    func(override models.TicketLimitOverride) (models.TicketLimitOverride, error)
+	DeleteLimitOverrideFunc func(id uint) error
+
+	IssueTicketsFunc        func(booking models.Booking) ([]models.Ticket, error)
+	GetTicketsByBookingFunc func(bookingID uint) ([]models.Ticket, error)
+	VoidTicketsFunc         func(bookingID uint) error
+	CheckInFunc             func(code, gate string, showID, staffID uint) (concert.CheckInResult, error)
+
+	GetCheckInManifestFunc func(showID uint) (concert.SignedManifest, error)
+	SyncScansFunc          func(showID, staffID uint, deviceID string, scans []concert.OfflineScan) ([]concert.ScanResult, error)
+	ListScanEventsFunc     func(showID uint, result string) ([]models.ScanEvent, error)
+
+	GetInvoiceFunc func(booking models.Booking) (models.Invoice, error)
+	TicketsPDFFunc func(booking models.Booking) ([]byte, error)
+	ReceiptPDFFunc func(booking models.Booking) ([]byte, error)
+
+	StartPaymentFunc         func(booking models.Booking) (models.Payment, payment.Intent, error)
+	GetPaymentsByBookingFunc func(bookingID uint) ([]models.Payment, error)
+	HandlePaymentWebhookFunc func(payload []byte, header http.Header) (models.Booking, bool, error)
+	SimulatePaymentFunc      func(booking models.Booking) (models.Booking, bool, error)
+	ReleaseBookingFunc       func(bookingID uint, reason string) error
+
+	QuoteRefundFunc func(booking models.Booking, now time.Time) (concert.RefundQuote, error)
+
+	CreateTransferFunc       func(booking models.Booking, from models.User, toEmail string) (models.Transfer, string, error)
+	GetTransferByTokenFunc   func(token string) (models.Transfer, error)
+	AcceptTransferFunc       func(token string, recipient models.User) (models.Booking, error)
+	CancelTransferFunc       func(id uint, user models.User) error
+	ListBookingTransfersFunc func(bookingID uint) ([]models.Transfer, error)
+	ListBookingAuditFunc     func(bookingID uint) ([]models.BookingAudit, error)
+
+	CreateResaleListingFunc func(booking models.Booking, seller models.User, count int, price float64) (models.ResaleListing, error)
+	ListResaleListingsFunc  func(showID uint, status string) ([]models.ResaleListing, error)
+	GetResaleListingFunc    func(id uint) (models.ResaleListing, error)
+	CancelResaleListingFunc func(id uint, seller models.User) error
+	RemoveResaleListingFunc func(id uint, note string) error
+	BuyResaleListingFunc    func(id uint, buyer models.User) (models.Booking, error)
+
+	RefundToCreditFunc          func(bookingID uint, amount float64) error
+	GetCreditEntriesFunc        func(userID uint) ([]models.CreditEntry, error)
+	GrantCreditFunc             func(userID uint, amount float64, admin models.User, note string) (models.CreditEntry, error)
+	GetCreditLiabilitiesFunc    func(now time.Time) (concert.CreditLiabilities, error)
+	IssueGiftCardFunc           func(amount float64, admin models.User, recipientEmail, message string, expiresAt *time.Time) (models.GiftCard, error)
+	BuyGiftCardFunc             func(amount float64, purchaser models.User, recipientEmail, message string) (models.GiftCard, models.Payment, payment.Intent, error)
+	ListPurchasedGiftCardsFunc  func(userID uint) ([]models.GiftCard, error)
+	ListGiftCardsFunc           func(status string) ([]models.GiftCard, error)
+	SimulateGiftCardPaymentFunc func(cardID uint, purchaser models.User) (models.GiftCard, error)
+	RedeemGiftCardFunc          func(code string, user models.User, now time.Time) (models.CreditEntry, error)
+
+	TrialBalanceFunc       func() ([]concert.AccountBalance, error)
+	GetLedgerReportFunc    func(filter concert.LedgerFilter) (concert.LedgerReport, error)
+	CheckLedgerFunc        func() ([]concert.LedgerCheck, error)
+	ListJournalEntriesFunc func(bookingID uint, limit int) ([]models.JournalEntry, error)
+
+	SearchShowsFunc func(query concert.ShowQuery) (concert.ShowPage, error)
+	ShowsNearbyFunc func(query concert.NearbyQuery) (concert.NearbyPage, error)
+	SearchFunc      func(text string, includePast bool, limit int) (concert.SearchResults, error)
+
+	PriceBookingFunc  func(show models.Show, count int) (models.PriceBreakdown, error)
+	ListTaxRatesFunc  func() ([]models.TaxRate, error)
+	SetTaxRateFunc    func(rate models.TaxRate) (models.TaxRate, error)
+	DeleteTaxRateFunc func(id uint) error
+
+	ApplyVenueFunc  func(show *models.Show) error
+	ListVenuesFunc  func(city string) ([]models.Venue, error)
+	GetVenueFunc    func(id uint) (models.Venue, error)
+	CreateVenueFunc func(venue models.Venue) (models.Venue, error)
+	UpdateVenueFunc func(venue models.Venue, now time.Time) (models.Venue, error)
+	DeleteVenueFunc func(id uint) error
+
+	SetLineupFunc            func(showID uint, entries []concert.LineupEntry) (models.Show, error)
+	GetArtistAppearancesFunc func(artistID uint) ([]concert.Appearance, error)
+	ListFestivalsFunc        func() ([]models.Festival, error)
+	GetFestivalFunc          func(id uint) (models.Festival, error)
+	CreateFestivalFunc       func(festival models.Festival) (models.Festival, error)
+	UpdateFestivalFunc       func(festival models.Festival) (models.Festival, error)
+	DeleteFestivalFunc       func(id uint) error
+	FestivalPassShowFunc     func(festivalID uint) (models.Show, error)
+
+	ListToursFunc       func(artistID uint) ([]models.Tour, error)
+	GetTourFunc         func(id uint) (models.Tour, error)
+	CreateTourFunc      func(tour models.Tour) (models.Tour, error)
+	UpdateTourFunc      func(tour models.Tour) (models.Tour, error)
+	DeleteTourFunc      func(id uint) error
+	CreateTourShowsFunc func(tourID uint, template concert.TourTemplate, dates []concert.TourDate) ([]models.Show, error)
+	UpdateTourShowsFunc func(tourID uint, changes concert.TourShowChanges, now time.Time) ([]models.Show, error)
+
+	SetShowStatusFunc func(showID uint, change concert.ShowStatusChange, now time.Time) (models.Show, error)
+
+	FollowArtistFunc     func(userID, artistID uint) (models.Follow, error)
+	UnfollowArtistFunc   func(userID, artistID uint) error
+	GetFollowingFeedFunc func(userID uint, limit int, now time.Time) (concert.FollowingFeed, error)
+	SetShowAlertsFunc    func(userID uint, enabled bool) error
+
+	GetSettlementFunc      func(showID uint) (concert.SettlementStatement, error)
+	ListSettlementsFunc    func(status string) ([]models.Settlement, error)
+	ApproveSettlementFunc  func(id uint, admin models.User, now time.Time) (models.Settlement, error)
+	MarkSettlementPaidFunc func(id uint, reference string, now time.Time) (models.Settlement, error)
 }
 
 func (m *MockConcertService) GetFan(name string) ([]models.Booking, error) {
@@ -112,3 +237,407 @@ func (m *MockConcertService) GetAllBookings() ([]models.Booking, error) {
 func (m *MockConcertService) GetBookingById(id uint) (models.Booking, error) {
 	return m.GetBookingByIdFunc(id)
 }
+
+func (m *MockConcertService) ListWebhooks() ([]models.WebhookSubscription, error) {
+	return m.ListWebhooksFunc()
+}
+
+func (m *MockConcertService) GetWebhookByID(id uint) (models.WebhookSubscription, error) {
+	return m.GetWebhookByIDFunc(id)
+}
+
+func (m *MockConcertService) SetWebhook(sub models.WebhookSubscription) (models.WebhookSubscription, error) {
+	return m.SetWebhookFunc(sub)
+}
+
+func (m *MockConcertService) DeleteWebhook(id uint) error {
+	return m.DeleteWebhookFunc(id)
+}
+
+// events are fire and forget, tests that don't care about them don't have to stub it
+func (m *MockConcertService) PublishWebhookEvent(event string, data any) error {
+	if m.PublishWebhookEventFunc == nil {
+		return nil
+	}
+	return m.PublishWebhookEventFunc(event, data)
+}
+
+func (m *MockConcertService) ListWebhookDeliveries(subscriptionID uint) ([]models.WebhookDelivery, error) {
+	return m.ListWebhookDeliveriesFunc(subscriptionID)
+}
+
+func (m *MockConcertService) ListDeadWebhookDeliveries() ([]models.WebhookDelivery, error) {
+	return m.ListDeadWebhookDeliveriesFunc()
+}
+
+func (m *MockConcertService) RetryWebhookDelivery(id uint) (models.WebhookDelivery, error) {
+	return m.RetryWebhookDeliveryFunc(id)
+}
+
+func (m *MockConcertService) SendTestWebhook(subscriptionID uint) (models.WebhookDelivery, error) {
+	return m.SendTestWebhookFunc(subscriptionID)
+}
+
+func (m *MockConcertService) JoinQueue(showID, userID uint) (concert.QueueStatus, error) {
+	return m.JoinQueueFunc(showID, userID)
+}
+
+func (m *MockConcertService) GetQueueStatus(token string) (concert.QueueStatus, error) {
+	return m.GetQueueStatusFunc(token)
+}
+
+func (m *MockConcertService) CheckQueueAdmission(show models.Show, userID uint, token string) (models.QueueTicket, error) {
+	return m.CheckQueueAdmissionFunc(show, userID, token)
+}
+
+func (m *MockConcertService) CheckSaleWindow(show models.Show, user models.User, accessCode string) error {
+	return m.CheckSaleWindowFunc(show, user, accessCode)
+}
+
+func (m *MockConcertService) ListPresales(showID uint) ([]models.Presale, error) {
+	return m.ListPresalesFunc(showID)
+}
+
+func (m *MockConcertService) GetPresaleByID(id uint) (models.Presale, error) {
+	return m.GetPresaleByIDFunc(id)
+}
+
+func (m *MockConcertService) SetPresale(presale models.Presale) (models.Presale, error) {
+	return m.SetPresaleFunc(presale)
+}
+
+func (m *MockConcertService) DeletePresale(id uint) error {
+	return m.DeletePresaleFunc(id)
+}
+
+func (m *MockConcertService) CheckTicketLimits(show models.Show, user models.User, count int) error {
+	return m.CheckTicketLimitsFunc(show, user, count)
+}
+
+func (m *MockConcertService) ListLimitOverrides(showID uint) ([]models.TicketLimitOverride, error) {
+	return m.ListLimitOverridesFunc(showID)
+}
+
+func (m *MockConcertService) SetLimitOverride(override models.TicketLimitOverride) (models.TicketLimitOverride, error) {
+	return m.SetLimitOverrideFunc(override)
+}
+
+func (m *MockConcertService) DeleteLimitOverride(id uint) error {
+	return m.DeleteLimitOverrideFunc(id)
+}
+
+func (m *MockConcertService) IssueTickets(booking models.Booking) ([]models.Ticket, error) {
+	return m.IssueTicketsFunc(booking)
+}
+
+func (m *MockConcertService) GetTicketsByBooking(bookingID uint) ([]models.Ticket, error) {
+	return m.GetTicketsByBookingFunc(bookingID)
+}
+
+func (m *MockConcertService) VoidTickets(bookingID uint) error {
+	return m.VoidTicketsFunc(bookingID)
+}
+
+func (m *MockConcertService) CheckIn(code, gate string, showID, staffID uint) (concert.CheckInResult, error) {
+	return m.CheckInFunc(code, gate, showID, staffID)
+}
+
+func (m *MockConcertService) GetCheckInManifest(showID uint) (concert.SignedManifest, error) {
+	return m.GetCheckInManifestFunc(showID)
+}
+
+func (m *MockConcertService) SyncScans(showID, staffID uint, deviceID string, scans []concert.OfflineScan) ([]concert.ScanResult, error) {
+	return m.SyncScansFunc(showID, staffID, deviceID, scans)
+}
+
+func (m *MockConcertService) ListScanEvents(showID uint, result string) ([]models.ScanEvent, error) {
+	return m.ListScanEventsFunc(showID, result)
+}
+
+func (m *MockConcertService) GetInvoice(booking models.Booking) (models.Invoice, error) {
+	return m.GetInvoiceFunc(booking)
+}
+
+func (m *MockConcertService) TicketsPDF(booking models.Booking) ([]byte, error) {
+	return m.TicketsPDFFunc(booking)
+}
+
+func (m *MockConcertService) ReceiptPDF(booking models.Booking) ([]byte, error) {
+	return m.ReceiptPDFFunc(booking)
+}
+
+func (m *MockConcertService) StartPayment(booking models.Booking) (models.Payment, payment.Intent, error) {
+	return m.StartPaymentFunc(booking)
+}
+
+func (m *MockConcertService) GetPaymentsByBooking(bookingID uint) ([]models.Payment, error) {
+	return m.GetPaymentsByBookingFunc(bookingID)
+}
+
+func (m *MockConcertService) HandlePaymentWebhook(payload []byte, header http.Header) (models.Booking, bool, error) {
+	return m.HandlePaymentWebhookFunc(payload, header)
+}
+
+func (m *MockConcertService) SimulatePayment(booking models.Booking) (models.Booking, bool, error) {
+	return m.SimulatePaymentFunc(booking)
+}
+
+func (m *MockConcertService) ReleaseBooking(bookingID uint, reason string) error {
+	return m.ReleaseBookingFunc(bookingID, reason)
+}
+
+func (m *MockConcertService) QuoteRefund(booking models.Booking, now time.Time) (concert.RefundQuote, error) {
+	return m.QuoteRefundFunc(booking, now)
+}
+
+func (m *MockConcertService) CreateTransfer(booking models.Booking, from models.User, toEmail string) (models.Transfer, string, error) {
+	return m.CreateTransferFunc(booking, from, toEmail)
+}
+
+func (m *MockConcertService) GetTransferByToken(token string) (models.Transfer, error) {
+	return m.GetTransferByTokenFunc(token)
+}
+
+func (m *MockConcertService) AcceptTransfer(token string, recipient models.User) (models.Booking, error) {
+	return m.AcceptTransferFunc(token, recipient)
+}
+
+func (m *MockConcertService) CancelTransfer(id uint, user models.User) error {
+	return m.CancelTransferFunc(id, user)
+}
+
+func (m *MockConcertService) ListBookingTransfers(bookingID uint) ([]models.Transfer, error) {
+	return m.ListBookingTransfersFunc(bookingID)
+}
+
+func (m *MockConcertService) ListBookingAudit(bookingID uint) ([]models.BookingAudit, error) {
+	return m.ListBookingAuditFunc(bookingID)
+}
+
+func (m *MockConcertService) CreateResaleListing(booking models.Booking, seller models.User, count int, price float64) (models.ResaleListing, error) {
+	return m.CreateResaleListingFunc(booking, seller, count, price)
+}
+
+func (m *MockConcertService) ListResaleListings(showID uint, status string) ([]models.ResaleListing, error) {
+	return m.ListResaleListingsFunc(showID, status)
+}
+
+func (m *MockConcertService) GetResaleListing(id uint) (models.ResaleListing, error) {
+	return m.GetResaleListingFunc(id)
+}
+
+func (m *MockConcertService) CancelResaleListing(id uint, seller models.User) error {
+	return m.CancelResaleListingFunc(id, seller)
+}
+
+func (m *MockConcertService) RemoveResaleListing(id uint, note string) error {
+	return m.RemoveResaleListingFunc(id, note)
+}
+
+func (m *MockConcertService) BuyResaleListing(id uint, buyer models.User) (models.Booking, error) {
+	return m.BuyResaleListingFunc(id, buyer)
+}
+
+func (m *MockConcertService) RefundToCredit(bookingID uint, amount float64) error {
+	return m.RefundToCreditFunc(bookingID, amount)
+}
+
+func (m *MockConcertService) GetCreditEntries(userID uint) ([]models.CreditEntry, error) {
+	return m.GetCreditEntriesFunc(userID)
+}
+
+func (m *MockConcertService) GrantCredit(userID uint, amount float64, admin models.User, note string) (models.CreditEntry, error) {
+	return m.GrantCreditFunc(userID, amount, admin, note)
+}
+
+func (m *MockConcertService) GetCreditLiabilities(now time.Time) (concert.CreditLiabilities, error) {
+	return m.GetCreditLiabilitiesFunc(now)
+}
+
+func (m *MockConcertService) IssueGiftCard(amount float64, admin models.User, recipientEmail, message string, expiresAt *time.Time) (models.GiftCard, error) {
+	return m.IssueGiftCardFunc(amount, admin, recipientEmail, message, expiresAt)
+}
+
+func (m *MockConcertService) BuyGiftCard(amount float64, purchaser models.User, recipientEmail, message string) (models.GiftCard, models.Payment, payment.Intent, error) {
+	return m.BuyGiftCardFunc(amount, purchaser, recipientEmail, message)
+}
+
+func (m *MockConcertService) ListPurchasedGiftCards(userID uint) ([]models.GiftCard, error) {
+	return m.ListPurchasedGiftCardsFunc(userID)
+}
+
+func (m *MockConcertService) ListGiftCards(status string) ([]models.GiftCard, error) {
+	return m.ListGiftCardsFunc(status)
+}
+
+func (m *MockConcertService) SimulateGiftCardPayment(cardID uint, purchaser models.User) (models.GiftCard, error) {
+	return m.SimulateGiftCardPaymentFunc(cardID, purchaser)
+}
+
+func (m *MockConcertService) RedeemGiftCard(code string, user models.User, now time.Time) (models.CreditEntry, error) {
+	return m.RedeemGiftCardFunc(code, user, now)
+}
+
+func (m *MockConcertService) TrialBalance() ([]concert.AccountBalance, error) {
+	return m.TrialBalanceFunc()
+}
+
+func (m *MockConcertService) GetLedgerReport(filter concert.LedgerFilter) (concert.LedgerReport, error) {
+	return m.GetLedgerReportFunc(filter)
+}
+
+func (m *MockConcertService) CheckLedger() ([]concert.LedgerCheck, error) {
+	return m.CheckLedgerFunc()
+}
+
+func (m *MockConcertService) ListJournalEntries(bookingID uint, limit int) ([]models.JournalEntry, error) {
+	return m.ListJournalEntriesFunc(bookingID, limit)
+}
+
+func (m *MockConcertService) GetSettlement(showID uint) (concert.SettlementStatement, error) {
+	return m.GetSettlementFunc(showID)
+}
+
+func (m *MockConcertService) ListSettlements(status string) ([]models.Settlement, error) {
+	return m.ListSettlementsFunc(status)
+}
+
+func (m *MockConcertService) ApproveSettlement(id uint, admin models.User, now time.Time) (models.Settlement, error) {
+	return m.ApproveSettlementFunc(id, admin, now)
+}
+
+func (m *MockConcertService) MarkSettlementPaid(id uint, reference string, now time.Time) (models.Settlement, error) {
+	return m.MarkSettlementPaidFunc(id, reference, now)
+}
+
+func (m *MockConcertService) PriceBooking(show models.Show, count int) (models.PriceBreakdown, error) {
+	return m.PriceBookingFunc(show, count)
+}
+
+func (m *MockConcertService) ListTaxRates() ([]models.TaxRate, error) {
+	return m.ListTaxRatesFunc()
+}
+
+func (m *MockConcertService) SetTaxRate(rate models.TaxRate) (models.TaxRate, error) {
+	return m.SetTaxRateFunc(rate)
+}
+
+func (m *MockConcertService) DeleteTaxRate(id uint) error {
+	return m.DeleteTaxRateFunc(id)
+}
+
+func (m *MockConcertService) SearchShows(query concert.ShowQuery) (concert.ShowPage, error) {
+	return m.SearchShowsFunc(query)
+}
+
+func (m *MockConcertService) ShowsNearby(query concert.NearbyQuery) (concert.NearbyPage, error) {
+	return m.ShowsNearbyFunc(query)
+}
+
+func (m *MockConcertService) Search(text string, includePast bool, limit int) (concert.SearchResults, error) {
+	return m.SearchFunc(text, includePast, limit)
+}
+
+func (m *MockConcertService) ApplyVenue(show *models.Show) error {
+	return m.ApplyVenueFunc(show)
+}
+
+func (m *MockConcertService) ListVenues(city string) ([]models.Venue, error) {
+	return m.ListVenuesFunc(city)
+}
+
+func (m *MockConcertService) GetVenue(id uint) (models.Venue, error) {
+	return m.GetVenueFunc(id)
+}
+
+func (m *MockConcertService) CreateVenue(venue models.Venue) (models.Venue, error) {
+	return m.CreateVenueFunc(venue)
+}
+
+func (m *MockConcertService) UpdateVenue(venue models.Venue, now time.Time) (models.Venue, error) {
+	return m.UpdateVenueFunc(venue, now)
+}
+
+func (m *MockConcertService) DeleteVenue(id uint) error {
+	return m.DeleteVenueFunc(id)
+}
+
+func (m *MockConcertService) SetLineup(showID uint, entries []concert.LineupEntry) (models.Show, error) {
+	return m.SetLineupFunc(showID, entries)
+}
+
+func (m *MockConcertService) GetArtistAppearances(artistID uint) ([]concert.Appearance, error) {
+	return m.GetArtistAppearancesFunc(artistID)
+}
+
+func (m *MockConcertService) ListFestivals() ([]models.Festival, error) {
+	return m.ListFestivalsFunc()
+}
+
+func (m *MockConcertService) GetFestival(id uint) (models.Festival, error) {
+	return m.GetFestivalFunc(id)
+}
+
+func (m *MockConcertService) CreateFestival(festival models.Festival) (models.Festival, error) {
+	return m.CreateFestivalFunc(festival)
+}
+
+func (m *MockConcertService) UpdateFestival(festival models.Festival) (models.Festival, error) {
+	return m.UpdateFestivalFunc(festival)
+}
+
+func (m *MockConcertService) DeleteFestival(id uint) error {
+	return m.DeleteFestivalFunc(id)
+}
+
+func (m *MockConcertService) FestivalPassShow(festivalID uint) (models.Show, error) {
+	return m.FestivalPassShowFunc(festivalID)
+}
+
+func (m *MockConcertService) ListTours(artistID uint) ([]models.Tour, error) {
+	return m.ListToursFunc(artistID)
+}
+
+func (m *MockConcertService) GetTour(id uint) (models.Tour, error) {
+	return m.GetTourFunc(id)
+}
+
+func (m *MockConcertService) CreateTour(tour models.Tour) (models.Tour, error) {
+	return m.CreateTourFunc(tour)
+}
+
+func (m *MockConcertService) UpdateTour(tour models.Tour) (models.Tour, error) {
+	return m.UpdateTourFunc(tour)
+}
+
+func (m *MockConcertService) DeleteTour(id uint) error {
+	return m.DeleteTourFunc(id)
+}
+
+func (m *MockConcertService) CreateTourShows(tourID uint, template concert.TourTemplate, dates []concert.TourDate) ([]models.Show, error) {
+	return m.CreateTourShowsFunc(tourID, template, dates)
+}
+
+func (m *MockConcertService) UpdateTourShows(tourID uint, changes concert.TourShowChanges, now time.Time) ([]models.Show, error) {
+	return m.UpdateTourShowsFunc(tourID, changes, now)
+}
+
+func (m *MockConcertService) SetShowStatus(showID uint, change concert.ShowStatusChange, now time.Time) (models.Show, error) {
+	return m.SetShowStatusFunc(showID, change, now)
+}
+
+func (m *MockConcertService) FollowArtist(userID, artistID uint) (models.Follow, error) {
+	return m.FollowArtistFunc(userID, artistID)
+}
+
+func (m *MockConcertService) UnfollowArtist(userID, artistID uint) error {
+	return m.UnfollowArtistFunc(userID, artistID)
+}
+
+func (m *MockConcertService) GetFollowingFeed(userID uint, limit int, now time.Time) (concert.FollowingFeed, error) {
+	return m.GetFollowingFeedFunc(userID, limit, now)
+}
+
+func (m *MockConcertService) SetShowAlerts(userID uint, enabled bool) error {
+	return m.SetShowAlertsFunc(userID, enabled)
+}